@@ -17,7 +17,10 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"fmt"
+
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 )
@@ -33,16 +36,68 @@ type mapper interface {
 }
 
 // Exported constants used by provisioners: including conventional
-// environment variable names for S3 Access and Secret Key, and
+// environment variable names for S3 Access and Secret Key, tokens and
+// username/password credentials, Google service account keys, and
 // map key names. Eg. key to access a bucket name in a storage class
-// used for brownfield buckets, or the key to create an OB's
-// Authentication{}.
+// used for brownfield buckets, the keys of the default region and
+// subregion in a storage class, the keys of the bucket quota in a
+// storage class or OBC, or the key to create an OB's Authentication{}.
 const (
-	AwsKeyField        = "AWS_ACCESS_KEY_ID"
-	AwsSecretField     = "AWS_SECRET_ACCESS_KEY"
-	StorageClassBucket = "bucketName"
+	AwsKeyField           = "AWS_ACCESS_KEY_ID"
+	AwsSecretField        = "AWS_SECRET_ACCESS_KEY"
+	TokenField            = "TOKEN"
+	UsernameField         = corev1.BasicAuthUsernameKey
+	PasswordField         = corev1.BasicAuthPasswordKey
+	AzureAccountKeyField  = "AZURE_STORAGE_KEY"
+	GcsCredentialsField   = "GOOGLE_APPLICATION_CREDENTIALS_JSON"
+	StorageClassBucket    = "bucketName"
+	StorageClassRegion    = "region"
+	StorageClassSubRegion = "subRegion"
+	QuotaMaxObjects       = "maxObjects"
+	QuotaMaxSize          = "maxSize"
 )
 
+// StorageClassBucketNamePrefix is the StorageClass parameter prepended to the bucket names generated for the
+// class's claims
+const StorageClassBucketNamePrefix = "bucketNamePrefix"
+
+// StorageClassVersioning is the StorageClass parameter, also settable in an OBC's additionalConfig, which enables
+// object versioning on new buckets when "true"
+const StorageClassVersioning = "versioning"
+
+// StorageClassService is the StorageClass parameter naming, as "namespace/name", the Service fronting the object
+// store.  The bucket host and port are then resolved from the Service rather than taken from the provisioner.
+const StorageClassService = "service"
+
+// StorageClassServicePort is the StorageClass parameter naming the port of the StorageClassService Service to use,
+// its first port being used if unset
+const StorageClassServicePort = "servicePort"
+
+// StorageClassBucketPolicy is the StorageClass parameter holding the JSON bucket policy the provisioner applies to
+// the buckets of the class's claims
+const StorageClassBucketPolicy = "bucketPolicy"
+
+// StorageClassAllowExistingBuckets is the StorageClass parameter which, when "true", lets the class's claims name
+// an existing bucket in their spec.existingBucketName.  Claims of other classes may only name the bucket of their
+// class, if any.
+const StorageClassAllowExistingBuckets = "allowExistingBuckets"
+
+// CredentialsReferenceAnnotation is set to "true" on the Secrets holding a SecretReference to the credentials of
+// their claim, rather than the credentials themselves
+const CredentialsReferenceAnnotation = "objectbucket.io/credentials-reference"
+
+// SecretReferencePathField is the Secret key of the path of the credentials in the external secret store, see
+// SecretReference
+const SecretReferencePathField = "SECRET_REFERENCE_PATH"
+
+// ProvisionerVersionAnnotation holds the version of the controller which created the ObjectBucket, if known
+const ProvisionerVersionAnnotation = "objectbucket.io/provisioner-version"
+
+// ExistingBucketAnnotation is set to "true" on the ObjectBuckets granting access to the existing bucket named by
+// their claim's spec.existingBucketName.  Their bucket is never deleted with the claim, whatever the claim's spec
+// has become since.
+const ExistingBucketAnnotation = "objectbucket.io/existing-bucket"
+
 // AccessKeys is an Authentication type for passing AWS S3 style key pairs from the provisioner to the reconciler
 type AccessKeys struct {
 	// AccessKeyId is the S3 style access key to be written to a secret
@@ -60,32 +115,178 @@ func (ak *AccessKeys) toMap() map[string]string {
 	}
 }
 
+// PlainToken is an Authentication type for passing a bearer token from the provisioner to the reconciler
+type PlainToken struct {
+	// Token is the bearer token to be written to a secret
+	Token string `json:"-"`
+}
+
+var _ mapper = &PlainToken{}
+
+func (pt *PlainToken) toMap() map[string]string {
+	return map[string]string{
+		TokenField: pt.Token,
+	}
+}
+
+// UserPass is an Authentication type for passing username/password credentials from the provisioner to the
+// reconciler
+type UserPass struct {
+	// Username is the user name to be written to a secret
+	Username string `json:"-"`
+	// Password is the password to be written to a secret
+	Password string `json:"-"`
+}
+
+var _ mapper = &UserPass{}
+
+func (up *UserPass) toMap() map[string]string {
+	return map[string]string{
+		UsernameField: up.Username,
+		PasswordField: up.Password,
+	}
+}
+
+// AzureAccountKey is an Authentication type for passing an Azure storage account key from the provisioner to
+// the reconciler
+type AzureAccountKey struct {
+	// AccountKey is the storage account's access key to be written to a secret
+	AccountKey string `json:"-"`
+}
+
+var _ mapper = &AzureAccountKey{}
+
+func (ak *AzureAccountKey) toMap() map[string]string {
+	return map[string]string{
+		AzureAccountKeyField: ak.AccountKey,
+	}
+}
+
+// ServiceAccountJSON is an Authentication type for passing a Google service account's JSON key from the provisioner
+// to the reconciler.  It is written to the Secret's data as is, rather than as stringData.  GCS HMAC keys are
+// passed as AccessKeys.
+type ServiceAccountJSON []byte
+
+var _ mapper = ServiceAccountJSON(nil)
+
+func (sa ServiceAccountJSON) toMap() map[string]string {
+	return map[string]string{
+		GcsCredentialsField: string(sa),
+	}
+}
+
+// SecretReference locates credentials which the provisioner wrote to an external secret store, e.g. Vault, for
+// an operator such as external-secrets to resolve.  It is written to the Secret in place of the credentials when
+// the controller stores references.
+type SecretReference struct {
+	// Path is the path of the credentials in the external secret store
+	Path string `json:"-"`
+	// Keys maps the keys an app expects the credentials under, e.g. AWS_ACCESS_KEY_ID, to their property at
+	// Path
+	Keys map[string]string `json:"-"`
+}
+
 // Authentication wraps all supported auth types.  The design choice enables expansion of supported types while
-// protecting backwards compatibility.
+// protecting backwards compatibility.  At most one auth type may be defined.
 type Authentication struct {
-	AccessKeys           *AccessKeys       `json:"-"`
-	AdditionalSecretData map[string]string `json:"-"`
+	AccessKeys           *AccessKeys        `json:"-"`
+	PlainToken           *PlainToken        `json:"-"`
+	UserPass             *UserPass          `json:"-"`
+	AzureAccountKey      *AzureAccountKey   `json:"-"`
+	ServiceAccountJSON   ServiceAccountJSON `json:"-"`
+	AdditionalSecretData map[string]string  `json:"-"`
+	// SecretReference locates the credentials in an external secret store.  It is only written to the Secret,
+	// and required, when the controller stores references, in which case the auth types above are ignored.
+	SecretReference *SecretReference `json:"-"`
+	// Type overrides the type of the generated Secret, which is otherwise derived from the defined auth type
+	Type corev1.SecretType `json:"-"`
+}
+
+// SecretType returns the type of the Secret holding the credentials: kubernetes.io/basic-auth for username/password
+// credentials and Opaque for any other auth type, unless overridden by Type.
+func (a *Authentication) SecretType() corev1.SecretType {
+	if a == nil {
+		return corev1.SecretTypeOpaque
+	}
+	if a.Type != "" {
+		return a.Type
+	}
+	if a.UserPass != nil {
+		return corev1.SecretTypeBasicAuth
+	}
+	return corev1.SecretTypeOpaque
 }
 
-// ToMap converts the any defined authentication type into a map[string]string for writing to a Secret.StringData field
-func (a *Authentication) ToMap() map[string]string {
+// ToMap converts the defined authentication type into a map[string]string for writing to a Secret.StringData field.
+// An error is returned if more than one authentication type is defined.
+func (a *Authentication) ToMap() (map[string]string, error) {
 	if a == nil {
-		return map[string]string{}
+		return map[string]string{}, nil
 	}
+	var defined []mapper
 	if a.AccessKeys != nil {
-		return a.AccessKeys.toMap()
+		defined = append(defined, a.AccessKeys)
+	}
+	if a.PlainToken != nil {
+		defined = append(defined, a.PlainToken)
+	}
+	if a.UserPass != nil {
+		defined = append(defined, a.UserPass)
+	}
+	if a.AzureAccountKey != nil {
+		defined = append(defined, a.AzureAccountKey)
+	}
+	if len(a.ServiceAccountJSON) > 0 {
+		defined = append(defined, a.ServiceAccountJSON)
+	}
+	switch len(defined) {
+	case 0:
+		return map[string]string{}, nil
+	case 1:
+		return defined[0].toMap(), nil
+	default:
+		return nil, fmt.Errorf("expected at most one authentication type, got %d", len(defined))
 	}
-	return map[string]string{}
 }
 
+// EndpointKind is the kind of object store API serving a bucket, which determines the keys of its ConfigMap
+type EndpointKind string
+
+const (
+	// EndpointKindS3 is an S3 compatible endpoint, the default
+	EndpointKindS3 EndpointKind = "S3"
+	// EndpointKindAzure is an Azure Blob storage account, the bucket being a container of the account
+	EndpointKindAzure EndpointKind = "Azure"
+)
+
 // Endpoint contains all connection relevant data that an app may require for accessing
 // the bucket
 type Endpoint struct {
-	BucketHost           string            `json:"bucketHost"`
-	BucketPort           int               `json:"bucketPort"`
-	BucketName           string            `json:"bucketName"`
-	Region               string            `json:"region"`
-	SubRegion            string            `json:"subRegion"`
+	// Kind is the kind of API serving the bucket.  Defaults to S3.
+	Kind       EndpointKind `json:"kind,omitempty"`
+	BucketHost string       `json:"bucketHost"`
+	BucketPort int          `json:"bucketPort"`
+	BucketName string       `json:"bucketName"`
+	Region     string       `json:"region"`
+	SubRegion  string       `json:"subRegion"`
+	// SSL indicates that the bucket host is served over TLS
+	SSL bool `json:"ssl,omitempty"`
+	// CABundle is the PEM encoded CA certificate clients need to trust the bucket host. It is only
+	// written to the ConfigMap when SSL is true.
+	CABundle string `json:"caBundle,omitempty"`
+	// TLSMinVersion is the minimum TLS version accepted by the bucket host: one of 1.0, 1.1, 1.2 or 1.3.  It
+	// is only written to the ConfigMap when SSL is true.
+	TLSMinVersion string `json:"tlsMinVersion,omitempty"`
+	// PathStyle indicates that clients must use path-style addressing (host/bucket) rather than
+	// virtual-hosted-style addressing (bucket.host). It is implied when BucketHost is an IP address.
+	PathStyle bool `json:"pathStyle,omitempty"`
+	// Versioning indicates that object versioning is enabled on the bucket
+	Versioning bool `json:"versioning,omitempty"`
+	// AccountName is the Azure storage account holding the container named by BucketName
+	AccountName string `json:"accountName,omitempty"`
+	// EndpointSuffix is the DNS suffix of the Azure cloud serving the storage account, e.g.
+	// core.windows.net.  Clients use the public cloud's if empty.
+	EndpointSuffix       string            `json:"endpointSuffix,omitempty"`
 	AdditionalConfigData map[string]string `json:"additionalConfig"`
 }
 
@@ -93,8 +294,10 @@ type Endpoint struct {
 // interface method.  This makes it more clear to library consumers what specific values they should return from their
 // Provisioner interface implementation.
 type Connection struct {
-	Endpoint        *Endpoint         `json:"endpoint"`
-	Authentication  *Authentication   `json:"-"`
+	Endpoint       *Endpoint       `json:"endpoint"`
+	Authentication *Authentication `json:"-"`
+	// AdditionalState is opaque state returned by Provision or Grant, e.g. a backend tenant ID.  It is persisted
+	// on the OB and handed back verbatim to Delete and Revoke.
 	AdditionalState map[string]string `json:"additionalState"`
 }
 
@@ -104,7 +307,28 @@ type ObjectBucketSpec struct {
 	StorageClassName string                                `json:"storageClassName"`
 	ReclaimPolicy    *corev1.PersistentVolumeReclaimPolicy `json:"reclaimPolicy"`
 	ClaimRef         *corev1.ObjectReference               `json:"claimRef"`
-	*Connection      `json:",inline"`
+	// Quota records the limits requested for the bucket when it was provisioned
+	// +optional
+	Quota *Quota `json:"quota,omitempty"`
+	// Tags records the tags requested for the bucket when it was provisioned
+	// +optional
+	Tags map[string]string `json:"tags,omitempty"`
+	// BucketPolicyChecksum is the SHA-256 checksum of the bucket policy applied when the bucket was provisioned,
+	// for detecting a drift of the StorageClass's policy.  Insignificant whitespace is ignored.
+	// +optional
+	BucketPolicyChecksum string `json:"bucketPolicyChecksum,omitempty"`
+	*Connection          `json:",inline"`
+}
+
+// Quota defines the limits of a bucket, parsed from the OBC's additionalConfig or, when not set there,
+// the storage class parameters.  Enforcing them is left to the provisioner.
+type Quota struct {
+	// MaxObjects is the maximum number of objects in the bucket
+	// +optional
+	MaxObjects *int64 `json:"maxObjects,omitempty"`
+	// MaxSize is the maximum total size of the objects in the bucket
+	// +optional
+	MaxSize *resource.Quantity `json:"maxSize,omitempty"`
 }
 
 // ObjectBucketStatusPhase is set by the controller to save the state of the provisioning process.
@@ -123,11 +347,23 @@ const (
 	//  the OB is cleaned up.  Since we generate OBs for brownfield cases, we also would delete them on failures.  The
 	//  result is that if this phase is set, the OB would deleted soon after anyway.
 	ObjectBucketStatusPhaseFailed ObjectBucketStatusPhase = "Failed"
+	// ObjectBucketStatusPhaseQuarantined indicates that the claim of the object bucket has been deleted and that the
+	// deletion of the bucket is deferred until the quarantine ends.  Re-creating the claim cancels the deletion.
+	ObjectBucketStatusPhaseQuarantined ObjectBucketStatusPhase = "Quarantined"
 )
 
 // ObjectBucketStatus defines the observed state of ObjectBucket
 type ObjectBucketStatus struct {
 	Phase      ObjectBucketStatusPhase `json:"phase"`
+	// LastTransitionTime is when the phase last changed
+	LastTransitionTime *metav1.Time `json:"lastTransitionTime,omitempty"`
+	// ProvisionedAt is when the bucket was provisioned or access to it granted, i.e. when the OB was bound
+	ProvisionedAt *metav1.Time `json:"provisionedAt,omitempty"`
+	// ProvisionDuration is how long provisioning took, from the provisioner call to the OB being bound
+	ProvisionDuration *metav1.Duration `json:"provisionDuration,omitempty"`
+	// QuarantinedAt is when the claim was deleted and the bucket quarantined, the quarantine ending after the
+	// controller's quarantine period
+	QuarantinedAt *metav1.Time `json:"quarantinedAt,omitempty"`
 }
 
 // +genclient
@@ -17,12 +17,59 @@ limitations under the License.
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
 const ObjectBucketClaimKind = "ObjectBucketClaim"
 
+// DryRunAnnotation, when set to "true" on an ObjectBucketClaim, causes the claim to be validated without
+// provisioning a bucket or creating any resources.  The outcome is reported by the DryRun condition.
+const DryRunAnnotation = "objectbucket.io/dry-run"
+
+// RotateAnnotation requests the rotation of a bound claim's credentials each time its value changes.  The
+// value last acted upon is recorded in the claim's status.
+const RotateAnnotation = "objectbucket.io/rotate"
+
+// TagsAnnotation holds the comma separated key=value tags to apply to the claim's bucket, e.g.
+// "cost-center=1234,environment=prod".
+const TagsAnnotation = "objectbucket.io/tags"
+
+// RetryTimeoutAnnotation overrides, for the claim, how long in total the library retries an API call, e.g.
+// "2m" for a claim of a slow backend.  Its value is a Go duration.
+const RetryTimeoutAnnotation = "objectbucket.io/retry-timeout"
+
+// PausedAnnotation, when set to "true" on an ObjectBucketClaim, freezes the claim: it is neither provisioned nor
+// deleted, and its status is left alone, until the annotation is removed.
+const PausedAnnotation = "objectbucket.io/paused"
+
+// ManagedByAnnotation names the controller reconciling an ObjectBucketClaim, e.g. to hand claims over to a new
+// controller during a migration.  Other controllers leave the claim entirely alone.  Every controller serving the
+// claim's StorageClass reconciles it if unset.
+const ManagedByAnnotation = "objectbucket.io/managed-by"
+
+// KeepObjectBucketAnnotation, when set to "true" on an ObjectBucketClaim, keeps the claim's ObjectBucket and its
+// bucket when the claim is deleted.  The ObjectBucket is left in the Released phase without a claim reference, so
+// that it can be bound again.
+const KeepObjectBucketAnnotation = "objectbucket.io/keep-objectbucket"
+
+// ClaimNamespaceLabel and ClaimNameLabel are set on an ObjectBucket to the namespace and name of its claim, which
+// being namespaced cannot be its owner.  Values longer than a label value allows are truncated.
+const (
+	ClaimNamespaceLabel = "objectbucket.io/claim-namespace"
+	ClaimNameLabel      = "objectbucket.io/claim-name"
+)
+
+// ClaimUIDLabel may be set on a claim's Secret and ConfigMap to the claim's UID, for external controllers to
+// correlate them.
+const ClaimUIDLabel = "objectbucket.io/claim-uid"
+
+// SecretCopiesFromAnnotation is set on a Namespace to the comma-separated namespaces whose ObjectBucketClaims may
+// copy their secret into it with spec.additionalSecretNamespaces, or to "*" for all namespaces.  A namespace
+// without it accepts no copies.
+const SecretCopiesFromAnnotation = "objectbucket.io/secret-copies-from"
+
 func ObjectBucketClaimGVK() schema.GroupVersionKind {
 	return GroupKindVersion(ObjectBucketClaimKind)
 }
@@ -30,7 +77,8 @@ func ObjectBucketClaimGVK() schema.GroupVersionKind {
 // ObjectBucketClaimSpec defines the desired state of ObjectBucketClaim
 type ObjectBucketClaimSpec struct {
 
-	// StorageClass names the StorageClass object representing the desired provisioner and parameters
+	// StorageClass names the StorageClass object representing the desired provisioner and parameters.  If
+	// omitted, it is set to the provisioner's default StorageClass, if any.
 	StorageClassName string `json:"storageClassName"`
 
 	// BucketName (not recommended) the name of the bucket.  Caution!
@@ -45,11 +93,41 @@ type ObjectBucketClaimSpec struct {
 	// +optional
 	GenerateBucketName string `json:"generateBucketName,omitempty"`
 
+	// ExistingBucketName is the name of an existing bucket the claim is granted access to, rather than a new
+	// bucket being provisioned.  BucketName and GenerateBucketName are then ignored.  The storage class must
+	// allow it, see StorageClassAllowExistingBuckets.
+	// +optional
+	ExistingBucketName string `json:"existingBucketName,omitempty"`
+
+	// BucketSubPath is the prefix of the bucket the claim owns, e.g. for several claims to share one bucket.
+	// It is relative, so must not start with a slash, and must not contain "..".
+	// +optional
+	BucketSubPath string `json:"bucketSubPath,omitempty"`
+
 	// AdditionalConfig gives providers a location to set
 	// proprietary config values (tenant, namespace, etc)
 	// +optional
 	AdditionalConfig map[string]string `json:"additionalConfig,omitempty"`
 
+	// SecretName is the name of the generated secret, which defaults to the name of the claim
+	// +optional
+	SecretName string `json:"secretName,omitempty"`
+
+	// ConfigMapName is the name of the generated configMap, which defaults to the name of the claim
+	// +optional
+	ConfigMapName string `json:"configMapName,omitempty"`
+
+	// AdditionalSecretNamespaces lists other namespaces the generated secret is copied into, to share the
+	// bucket's credentials.  Only honored if the provisioner allows cross-namespace secrets, and each namespace
+	// accepts copies from the claim's, see SecretCopiesFromAnnotation.
+	// +optional
+	AdditionalSecretNamespaces []string `json:"additionalSecretNamespaces,omitempty"`
+
+	// ExtraConfigMapData are static keys added to the generated configMap for the app, e.g. a cache directory.
+	// Keys set by the provisioner take precedence, and the library's BUCKET_* keys may not be set.
+	// +optional
+	ExtraConfigMapData map[string]string `json:"extraConfigMapData,omitempty"`
+
 	// ObjectBucketName is the name of the object bucket resource.  This is the authoritative
 	// determintaion for binding.
 	ObjectBucketName string
@@ -62,6 +140,10 @@ const (
 	// ObjectBucketClaimStatusPhasePending indicates that the provisioner has begun handling the request and that it is
 	// still in process
 	ObjectBucketClaimStatusPhasePending = "Pending"
+	// ObjectBucketClaimStatusPhaseProvisioning indicates that the provisioner is creating the bucket, or granting
+	// access to it, and that the objectBucket, configMap and secret may not exist yet.  A claim left in this phase
+	// is provisioned again on the next reconcile.
+	ObjectBucketClaimStatusPhaseProvisioning = "Provisioning"
 	// ObjectBucketClaimStatusPhaseBound indicates that provisioning has succeeded, the objectBucket is marked bound, and
 	// there is now a configMap and secret containing the appropriate bucket data in the namespace of the claim
 	ObjectBucketClaimStatusPhaseBound = "Bound"
@@ -73,9 +155,56 @@ const (
 	ObjectBucketClaimStatusPhaseFailed = "Failed"
 )
 
+// ObjectBucketClaimConditionType is the type of an ObjectBucketClaimCondition
+type ObjectBucketClaimConditionType string
+
+const (
+	// ObjectBucketClaimConditionDryRun reports the outcome of validating a claim annotated for dry-run
+	ObjectBucketClaimConditionDryRun ObjectBucketClaimConditionType = "DryRun"
+	// ObjectBucketClaimConditionBucketReady reports whether the provisioner created the bucket or granted access to it
+	ObjectBucketClaimConditionBucketReady ObjectBucketClaimConditionType = "BucketReady"
+	// ObjectBucketClaimConditionSecretReady reports whether the secret holding the bucket credentials was created
+	ObjectBucketClaimConditionSecretReady ObjectBucketClaimConditionType = "SecretReady"
+	// ObjectBucketClaimConditionConfigMapReady reports whether the configMap holding the bucket endpoint was created
+	ObjectBucketClaimConditionConfigMapReady ObjectBucketClaimConditionType = "ConfigMapReady"
+	// ObjectBucketClaimConditionDeletionFailed reports that the provisioner kept failing to delete the bucket or
+	// revoke access to it past the controller's deletion timeout
+	ObjectBucketClaimConditionDeletionFailed ObjectBucketClaimConditionType = "DeletionFailed"
+)
+
+// ObjectBucketClaimCondition describes the state of an ObjectBucketClaim at a certain point
+type ObjectBucketClaimCondition struct {
+	Type   ObjectBucketClaimConditionType `json:"type"`
+	Status corev1.ConditionStatus         `json:"status"`
+	// Reason is a brief CamelCase reason for the condition's last transition
+	// +optional
+	Reason string `json:"reason,omitempty"`
+	// Message is a human readable description of the condition's last transition
+	// +optional
+	Message string `json:"message,omitempty"`
+	// +optional
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+}
+
 // ObjectBucketClaimStatus defines the observed state of ObjectBucketClaim
 type ObjectBucketClaimStatus struct {
 	Phase ObjectBucketClaimStatusPhase `json:"phase,omitempty"`
+	// +optional
+	Conditions []ObjectBucketClaimCondition `json:"conditions,omitempty"`
+	// FailureMessage is the error of the last failed attempt to provision the bucket or grant access to it,
+	// truncated and with credentials scrubbed.  It is cleared once the provisioner succeeds.
+	// +optional
+	FailureMessage string `json:"failureMessage,omitempty"`
+	// LastRotation is the value of the rotate annotation the claim's credentials were last issued for
+	// +optional
+	LastRotation string `json:"lastRotation,omitempty"`
+	// LastRotationTime is when the claim's credentials were last rotated
+	// +optional
+	LastRotationTime *metav1.Time `json:"lastRotationTime,omitempty"`
+	// SecretCopyNamespaces are the namespaces the claim's secret was copied into.  The copies in the namespaces
+	// since removed from spec.additionalSecretNamespaces are deleted.
+	// +optional
+	SecretCopyNamespaces []string `json:"secretCopyNamespaces,omitempty"`
 }
 
 // +genclient
@@ -22,6 +22,7 @@ package v1alpha1
 
 import (
 	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
@@ -49,6 +50,26 @@ func (in *Authentication) DeepCopyInto(out *Authentication) {
 		*out = new(AccessKeys)
 		**out = **in
 	}
+	if in.PlainToken != nil {
+		in, out := &in.PlainToken, &out.PlainToken
+		*out = new(PlainToken)
+		**out = **in
+	}
+	if in.UserPass != nil {
+		in, out := &in.UserPass, &out.UserPass
+		*out = new(UserPass)
+		**out = **in
+	}
+	if in.AzureAccountKey != nil {
+		in, out := &in.AzureAccountKey, &out.AzureAccountKey
+		*out = new(AzureAccountKey)
+		**out = **in
+	}
+	if in.ServiceAccountJSON != nil {
+		in, out := &in.ServiceAccountJSON, &out.ServiceAccountJSON
+		*out = make(ServiceAccountJSON, len(*in))
+		copy(*out, *in)
+	}
 	if in.AdditionalSecretData != nil {
 		in, out := &in.AdditionalSecretData, &out.AdditionalSecretData
 		*out = make(map[string]string, len(*in))
@@ -56,6 +77,11 @@ func (in *Authentication) DeepCopyInto(out *Authentication) {
 			(*out)[key] = val
 		}
 	}
+	if in.SecretReference != nil {
+		in, out := &in.SecretReference, &out.SecretReference
+		*out = new(SecretReference)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -69,6 +95,22 @@ func (in *Authentication) DeepCopy() *Authentication {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AzureAccountKey) DeepCopyInto(out *AzureAccountKey) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AzureAccountKey.
+func (in *AzureAccountKey) DeepCopy() *AzureAccountKey {
+	if in == nil {
+		return nil
+	}
+	out := new(AzureAccountKey)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Connection) DeepCopyInto(out *Connection) {
 	*out = *in
@@ -131,7 +173,7 @@ func (in *ObjectBucket) DeepCopyInto(out *ObjectBucket) {
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 	return
 }
 
@@ -159,7 +201,7 @@ func (in *ObjectBucketClaim) DeepCopyInto(out *ObjectBucketClaim) {
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 	return
 }
 
@@ -181,6 +223,23 @@ func (in *ObjectBucketClaim) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ObjectBucketClaimCondition) DeepCopyInto(out *ObjectBucketClaimCondition) {
+	*out = *in
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ObjectBucketClaimCondition.
+func (in *ObjectBucketClaimCondition) DeepCopy() *ObjectBucketClaimCondition {
+	if in == nil {
+		return nil
+	}
+	out := new(ObjectBucketClaimCondition)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ObjectBucketClaimList) DeepCopyInto(out *ObjectBucketClaimList) {
 	*out = *in
@@ -224,6 +283,18 @@ func (in *ObjectBucketClaimSpec) DeepCopyInto(out *ObjectBucketClaimSpec) {
 			(*out)[key] = val
 		}
 	}
+	if in.AdditionalSecretNamespaces != nil {
+		in, out := &in.AdditionalSecretNamespaces, &out.AdditionalSecretNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExtraConfigMapData != nil {
+		in, out := &in.ExtraConfigMapData, &out.ExtraConfigMapData
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 	return
 }
 
@@ -240,6 +311,22 @@ func (in *ObjectBucketClaimSpec) DeepCopy() *ObjectBucketClaimSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ObjectBucketClaimStatus) DeepCopyInto(out *ObjectBucketClaimStatus) {
 	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]ObjectBucketClaimCondition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.LastRotationTime != nil {
+		in, out := &in.LastRotationTime, &out.LastRotationTime
+		*out = (*in).DeepCopy()
+	}
+	if in.SecretCopyNamespaces != nil {
+		in, out := &in.SecretCopyNamespaces, &out.SecretCopyNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -299,6 +386,18 @@ func (in *ObjectBucketSpec) DeepCopyInto(out *ObjectBucketSpec) {
 		*out = new(v1.ObjectReference)
 		**out = **in
 	}
+	if in.Quota != nil {
+		in, out := &in.Quota, &out.Quota
+		*out = new(Quota)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Tags != nil {
+		in, out := &in.Tags, &out.Tags
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 	if in.Connection != nil {
 		in, out := &in.Connection, &out.Connection
 		*out = new(Connection)
@@ -320,6 +419,23 @@ func (in *ObjectBucketSpec) DeepCopy() *ObjectBucketSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ObjectBucketStatus) DeepCopyInto(out *ObjectBucketStatus) {
 	*out = *in
+	if in.LastTransitionTime != nil {
+		in, out := &in.LastTransitionTime, &out.LastTransitionTime
+		*out = (*in).DeepCopy()
+	}
+	if in.ProvisionedAt != nil {
+		in, out := &in.ProvisionedAt, &out.ProvisionedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.ProvisionDuration != nil {
+		in, out := &in.ProvisionDuration, &out.ProvisionDuration
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.QuarantinedAt != nil {
+		in, out := &in.QuarantinedAt, &out.QuarantinedAt
+		*out = (*in).DeepCopy()
+	}
 	return
 }
 
@@ -332,3 +448,104 @@ func (in *ObjectBucketStatus) DeepCopy() *ObjectBucketStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PlainToken) DeepCopyInto(out *PlainToken) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PlainToken.
+func (in *PlainToken) DeepCopy() *PlainToken {
+	if in == nil {
+		return nil
+	}
+	out := new(PlainToken)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Quota) DeepCopyInto(out *Quota) {
+	*out = *in
+	if in.MaxObjects != nil {
+		in, out := &in.MaxObjects, &out.MaxObjects
+		*out = new(int64)
+		**out = **in
+	}
+	if in.MaxSize != nil {
+		in, out := &in.MaxSize, &out.MaxSize
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Quota.
+func (in *Quota) DeepCopy() *Quota {
+	if in == nil {
+		return nil
+	}
+	out := new(Quota)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretReference) DeepCopyInto(out *SecretReference) {
+	*out = *in
+	if in.Keys != nil {
+		in, out := &in.Keys, &out.Keys
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretReference.
+func (in *SecretReference) DeepCopy() *SecretReference {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in ServiceAccountJSON) DeepCopyInto(out *ServiceAccountJSON) {
+	{
+		in := &in
+		*out = make(ServiceAccountJSON, len(*in))
+		copy(*out, *in)
+		return
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServiceAccountJSON.
+func (in ServiceAccountJSON) DeepCopy() ServiceAccountJSON {
+	if in == nil {
+		return nil
+	}
+	out := new(ServiceAccountJSON)
+	in.DeepCopyInto(out)
+	return *out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UserPass) DeepCopyInto(out *UserPass) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UserPass.
+func (in *UserPass) DeepCopy() *UserPass {
+	if in == nil {
+		return nil
+	}
+	out := new(UserPass)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -42,4 +42,29 @@ func NewBucketExistsError(msg string) *BucketExistsErr {
 func IsBucketExists(e error) (is bool) {
 	_, is = e.(BucketExistsErr)
 	return is
-}
\ No newline at end of file
+}
+
+// DeleteNotReadyErr MAY be returned by the Delete() method when the bucket cannot be deleted yet, e.g. because
+// it still holds objects, and deleting it later is expected to succeed.  The claim is then kept, with its
+// finalizer, and its deletion is retried with backoff rather than reported as failed.
+type DeleteNotReadyErr struct {
+	errString string
+}
+
+// Error implements the Error interface
+func (e DeleteNotReadyErr) Error() string {
+	return e.errString
+}
+
+// NewDeleteNotReadyError is a simple constructor for a DeleteNotReadyErr
+func NewDeleteNotReadyError(msg string) DeleteNotReadyErr {
+	return DeleteNotReadyErr{
+		errString: msg,
+	}
+}
+
+// IsDeleteNotReady returns true if the error is of type DeleteNotReadyErr
+func IsDeleteNotReady(e error) (is bool) {
+	_, is = e.(DeleteNotReadyErr)
+	return is
+}
@@ -17,6 +17,9 @@ limitations under the License.
 package api
 
 import (
+	"context"
+	"time"
+
 	corev1 "k8s.io/api/core/v1"
 
 	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/apis/objectbucket.io/v1alpha1"
@@ -24,16 +27,90 @@ import (
 
 // All provisioners must implement the Provisioner interface which defines the
 // methods used to create and delete new buckets, and to grant or revoke access
-// to buckets within the object store.
+// to buckets within the object store. The passed in context is cancelled when the
+// controller is shutting down.
 type Provisioner interface {
 	// Provision should be implemented to handle bucket creation
-	Provision(options *BucketOptions) (*v1alpha1.ObjectBucket, error)
+	Provision(ctx context.Context, options *BucketOptions) (*v1alpha1.ObjectBucket, error)
 	// Grant should be implemented to handle access to existing buckets
-	Grant(options *BucketOptions) (*v1alpha1.ObjectBucket, error)
+	Grant(ctx context.Context, options *BucketOptions) (*v1alpha1.ObjectBucket, error)
 	// Delete should be implemented to handle bucket deletion
-	Delete(ob *v1alpha1.ObjectBucket) error
+	Delete(ctx context.Context, ob *v1alpha1.ObjectBucket) error
 	// Revoke should be implemented to handle removing bucket access
-	Revoke(ob *v1alpha1.ObjectBucket) error
+	Revoke(ctx context.Context, ob *v1alpha1.ObjectBucket) error
+}
+
+// ConnectionProvisioner may be implemented by provisioners which leave building the OB of a new bucket to the
+// library.  ProvisionConnection is then called instead of Provision.
+type ConnectionProvisioner interface {
+	// ProvisionConnection should create the bucket and return its Endpoint and Authentication, and any
+	// AdditionalState handed back to Delete and Revoke on the OB.
+	ProvisionConnection(ctx context.Context, options *BucketOptions) (*v1alpha1.Connection, error)
+}
+
+// GrantingProvisioner may be implemented by provisioners which only issue credentials for existing buckets,
+// named by the OBC's ExistingBucketName, leaving building the OB to the library.  GrantConnection is then called
+// instead of Grant, and RevokeConnection instead of Revoke when the OBC is deleted.
+type GrantingProvisioner interface {
+	// GrantConnection should grant access to the existing bucket and return its Endpoint and Authentication,
+	// and any AdditionalState handed back to RevokeConnection on the OB.  The bucket must not be created.
+	GrantConnection(ctx context.Context, options *BucketOptions) (*v1alpha1.Connection, error)
+	// RevokeConnection should revoke the access granted by GrantConnection, leaving the bucket intact.
+	RevokeConnection(ctx context.Context, ob *v1alpha1.ObjectBucket) error
+}
+
+// ReadinessChecker may be implemented by provisioners whose new buckets are not usable right away, e.g. whose
+// credentials take a few seconds to work.  The OBC's OB, Secret and ConfigMap are created as usual, but the OBC
+// stays in the Provisioning phase until IsReady returns true.
+type ReadinessChecker interface {
+	// IsReady should return true once the bucket of ob and its credentials can be used.  Otherwise it is called
+	// again after requeueAfter, or after the controller's backoff if zero or if an error is returned.
+	IsReady(ctx context.Context, ob *v1alpha1.ObjectBucket) (ready bool, requeueAfter time.Duration, err error)
+}
+
+// CredentialRotator may be implemented by provisioners supporting the rotation of a bucket's credentials,
+// which is requested by bumping the objectbucket.io/rotate annotation of a bound OBC.
+type CredentialRotator interface {
+	// RotateCredentials should issue new credentials for the bucket, keeping the current ones valid.
+	// The returned Authentication replaces the content of the OBC's secret.  It may be called again
+	// if the secret cannot be updated.
+	RotateCredentials(ctx context.Context, ob *v1alpha1.ObjectBucket) (*v1alpha1.Authentication, error)
+	// RevokeCredentials should invalidate the replaced credentials, given as the previous data of the
+	// OBC's secret.  It is only called once the secret holds the new credentials.
+	RevokeCredentials(ctx context.Context, ob *v1alpha1.ObjectBucket, previous map[string]string) error
+}
+
+// ParameterSchema declares the StorageClass parameters understood by a provisioner
+type ParameterSchema struct {
+	// Keys are the parameter keys understood by the provisioner.  The keys interpreted by the library,
+	// e.g. bucketName, region or the quota keys, are always accepted.
+	Keys []string
+	// Strict fails the OBCs whose StorageClass has parameters not listed in Keys.  Otherwise unknown
+	// parameters are only logged.
+	Strict bool
+}
+
+// ParameterSchemaProvider may be implemented by provisioners to have the StorageClass parameters of
+// each OBC checked against their schema before Provision or Grant is called.
+type ParameterSchemaProvider interface {
+	ParameterSchema() ParameterSchema
+}
+
+// ParameterDefaulter may be implemented by provisioners to declare default values of the StorageClass
+// parameters they understand, sparing each StorageClass from repeating them.
+type ParameterDefaulter interface {
+	// DefaultParameters returns the values of the parameters a StorageClass omits.  Values set by the
+	// StorageClass take precedence, and so do those the OBC's additionalConfig may set, e.g. the quota keys.
+	// A default bucketName is ignored, as it would turn every StorageClass into one of an existing bucket.
+	DefaultParameters() map[string]string
+}
+
+// HealthChecker may be implemented by provisioners to report whether their object store can be reached.  It is
+// called periodically, and the controller's readiness reflects its last result.
+type HealthChecker interface {
+	// HealthCheck should return an error if the object store cannot be reached, e.g. its endpoint is down or
+	// rejects the provisioner's credentials.
+	HealthCheck(ctx context.Context) error
 }
 
 // BucketOptions wraps all pertinent data that the Provisioner requires to create a
@@ -45,6 +122,19 @@ type BucketOptions struct {
 	BucketName string
 	// ObjectBucketClaim is a copy of the reconciler's OBC
 	ObjectBucketClaim *v1alpha1.ObjectBucketClaim
-	// Parameters is a complete copy of the OBC's storage class Parameters field
+	// Parameters is a complete copy of the OBC's storage class Parameters field, completed with the
+	// provisioner's DefaultParameters if it implements ParameterDefaulter
 	Parameters map[string]string
+	// Quota holds the validated limits requested by the OBC or its storage class, nil if none
+	Quota *v1alpha1.Quota
+	// Tags holds the validated tags requested by the OBC, to be applied to the bucket, nil if none
+	Tags map[string]string
+	// SubPath is the validated prefix of the bucket owned by the OBC, empty if the OBC owns the whole bucket
+	SubPath string
+	// BucketPolicy is the validated JSON bucket policy of the OBC's storage class, to be applied to the bucket,
+	// empty if none
+	BucketPolicy string
+	// Versioning requests object versioning to be enabled on the new bucket, as set by the OBC or its storage
+	// class.  It is only set for new buckets.
+	Versioning bool
 }
\ No newline at end of file
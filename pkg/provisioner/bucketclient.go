@@ -0,0 +1,81 @@
+/*
+Copyright 2019 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/apis/objectbucket.io/v1alpha1"
+	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/client/clientset/versioned"
+)
+
+// bucketClient holds the OB and OBC API calls made while reconciling a claim.  It is the seam between the
+// controller and the clientset, so that tests can inject the behavior of a single call with a hand-written
+// mock rather than a fake clientset and its reactors.
+type bucketClient interface {
+	CreateObjectBucket(ob *v1alpha1.ObjectBucket) (*v1alpha1.ObjectBucket, error)
+	GetObjectBucket(name string) (*v1alpha1.ObjectBucket, error)
+	UpdateObjectBucket(ob *v1alpha1.ObjectBucket) (*v1alpha1.ObjectBucket, error)
+	UpdateObjectBucketStatus(ob *v1alpha1.ObjectBucket) (*v1alpha1.ObjectBucket, error)
+	DeleteObjectBucket(name string) error
+	GetClaim(namespace, name string) (*v1alpha1.ObjectBucketClaim, error)
+	UpdateClaim(obc *v1alpha1.ObjectBucketClaim) (*v1alpha1.ObjectBucketClaim, error)
+	UpdateClaimStatus(obc *v1alpha1.ObjectBucketClaim) (*v1alpha1.ObjectBucketClaim, error)
+}
+
+// clientsetBucketClient is the bucketClient of the controller, making the calls with a clientset
+type clientsetBucketClient struct {
+	c versioned.Interface
+}
+
+var _ bucketClient = &clientsetBucketClient{}
+
+func newBucketClient(c versioned.Interface) bucketClient {
+	return &clientsetBucketClient{c: c}
+}
+
+func (b *clientsetBucketClient) CreateObjectBucket(ob *v1alpha1.ObjectBucket) (*v1alpha1.ObjectBucket, error) {
+	return b.c.ObjectbucketV1alpha1().ObjectBuckets().Create(ob)
+}
+
+func (b *clientsetBucketClient) GetObjectBucket(name string) (*v1alpha1.ObjectBucket, error) {
+	return b.c.ObjectbucketV1alpha1().ObjectBuckets().Get(name, metav1.GetOptions{})
+}
+
+func (b *clientsetBucketClient) UpdateObjectBucket(ob *v1alpha1.ObjectBucket) (*v1alpha1.ObjectBucket, error) {
+	return b.c.ObjectbucketV1alpha1().ObjectBuckets().Update(ob)
+}
+
+func (b *clientsetBucketClient) UpdateObjectBucketStatus(ob *v1alpha1.ObjectBucket) (*v1alpha1.ObjectBucket, error) {
+	return b.c.ObjectbucketV1alpha1().ObjectBuckets().UpdateStatus(ob)
+}
+
+func (b *clientsetBucketClient) DeleteObjectBucket(name string) error {
+	return b.c.ObjectbucketV1alpha1().ObjectBuckets().Delete(name, &metav1.DeleteOptions{})
+}
+
+func (b *clientsetBucketClient) GetClaim(namespace, name string) (*v1alpha1.ObjectBucketClaim, error) {
+	return b.c.ObjectbucketV1alpha1().ObjectBucketClaims(namespace).Get(name, metav1.GetOptions{})
+}
+
+func (b *clientsetBucketClient) UpdateClaim(obc *v1alpha1.ObjectBucketClaim) (*v1alpha1.ObjectBucketClaim, error) {
+	return b.c.ObjectbucketV1alpha1().ObjectBucketClaims(obc.Namespace).Update(obc)
+}
+
+func (b *clientsetBucketClient) UpdateClaimStatus(obc *v1alpha1.ObjectBucketClaim) (*v1alpha1.ObjectBucketClaim, error) {
+	return b.c.ObjectbucketV1alpha1().ObjectBucketClaims(obc.Namespace).UpdateStatus(obc)
+}
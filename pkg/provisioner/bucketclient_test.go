@@ -0,0 +1,305 @@
+/*
+Copyright 2019 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/apis/objectbucket.io/v1alpha1"
+)
+
+var (
+	obResource  = v1alpha1.SchemeGroupVersion.WithResource("objectbuckets").GroupResource()
+	obcResource = v1alpha1.SchemeGroupVersion.WithResource("objectbucketclaims").GroupResource()
+)
+
+// mockBucketClient is a bucketClient keeping OBs and OBCs in maps.  Each call is recorded, and fails with the
+// next of the errors queued for its method, if any.
+type mockBucketClient struct {
+	calls []string
+	errs  map[string][]error
+	obs   map[string]*v1alpha1.ObjectBucket
+	obcs  map[string]*v1alpha1.ObjectBucketClaim
+}
+
+var _ bucketClient = &mockBucketClient{}
+
+func newMockBucketClient() *mockBucketClient {
+	return &mockBucketClient{
+		errs: map[string][]error{},
+		obs:  map[string]*v1alpha1.ObjectBucket{},
+		obcs: map[string]*v1alpha1.ObjectBucketClaim{},
+	}
+}
+
+func (m *mockBucketClient) call(method string) error {
+	m.calls = append(m.calls, method)
+	errs := m.errs[method]
+	if len(errs) == 0 {
+		return nil
+	}
+	m.errs[method] = errs[1:]
+	return errs[0]
+}
+
+func (m *mockBucketClient) CreateObjectBucket(ob *v1alpha1.ObjectBucket) (*v1alpha1.ObjectBucket, error) {
+	if err := m.call("CreateObjectBucket"); err != nil {
+		return nil, err
+	}
+	if _, ok := m.obs[ob.Name]; ok {
+		return nil, errors.NewAlreadyExists(obResource, ob.Name)
+	}
+	m.obs[ob.Name] = ob.DeepCopy()
+	return ob.DeepCopy(), nil
+}
+
+func (m *mockBucketClient) GetObjectBucket(name string) (*v1alpha1.ObjectBucket, error) {
+	if err := m.call("GetObjectBucket"); err != nil {
+		return nil, err
+	}
+	ob, ok := m.obs[name]
+	if !ok {
+		return nil, errors.NewNotFound(obResource, name)
+	}
+	return ob.DeepCopy(), nil
+}
+
+func (m *mockBucketClient) UpdateObjectBucket(ob *v1alpha1.ObjectBucket) (*v1alpha1.ObjectBucket, error) {
+	return m.updateObjectBucket("UpdateObjectBucket", ob)
+}
+
+func (m *mockBucketClient) UpdateObjectBucketStatus(ob *v1alpha1.ObjectBucket) (*v1alpha1.ObjectBucket, error) {
+	return m.updateObjectBucket("UpdateObjectBucketStatus", ob)
+}
+
+func (m *mockBucketClient) updateObjectBucket(method string, ob *v1alpha1.ObjectBucket) (*v1alpha1.ObjectBucket, error) {
+	if err := m.call(method); err != nil {
+		return nil, err
+	}
+	if _, ok := m.obs[ob.Name]; !ok {
+		return nil, errors.NewNotFound(obResource, ob.Name)
+	}
+	m.obs[ob.Name] = ob.DeepCopy()
+	return ob.DeepCopy(), nil
+}
+
+func (m *mockBucketClient) DeleteObjectBucket(name string) error {
+	if err := m.call("DeleteObjectBucket"); err != nil {
+		return err
+	}
+	if _, ok := m.obs[name]; !ok {
+		return errors.NewNotFound(obResource, name)
+	}
+	delete(m.obs, name)
+	return nil
+}
+
+func (m *mockBucketClient) GetClaim(namespace, name string) (*v1alpha1.ObjectBucketClaim, error) {
+	if err := m.call("GetClaim"); err != nil {
+		return nil, err
+	}
+	obc, ok := m.obcs[namespace+"/"+name]
+	if !ok {
+		return nil, errors.NewNotFound(obcResource, name)
+	}
+	return obc.DeepCopy(), nil
+}
+
+func (m *mockBucketClient) UpdateClaim(obc *v1alpha1.ObjectBucketClaim) (*v1alpha1.ObjectBucketClaim, error) {
+	return m.updateClaim("UpdateClaim", obc)
+}
+
+func (m *mockBucketClient) UpdateClaimStatus(obc *v1alpha1.ObjectBucketClaim) (*v1alpha1.ObjectBucketClaim, error) {
+	return m.updateClaim("UpdateClaimStatus", obc)
+}
+
+func (m *mockBucketClient) updateClaim(method string, obc *v1alpha1.ObjectBucketClaim) (*v1alpha1.ObjectBucketClaim, error) {
+	if err := m.call(method); err != nil {
+		return nil, err
+	}
+	key := obc.Namespace + "/" + obc.Name
+	if _, ok := m.obcs[key]; !ok {
+		return nil, errors.NewNotFound(obcResource, obc.Name)
+	}
+	m.obcs[key] = obc.DeepCopy()
+	return obc.DeepCopy(), nil
+}
+
+func TestBucketClientCalls(t *testing.T) {
+	b := retryBackoff{
+		interval: time.Millisecond,
+		timeout:  time.Millisecond * 10,
+	}
+	obc := &v1alpha1.ObjectBucketClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: testName, Namespace: testNamespace, UID: "obc-uid"},
+		Spec:       v1alpha1.ObjectBucketClaimSpec{StorageClassName: className},
+	}
+	claimRef := makeObjectReference(obc)
+	newOB := func() *v1alpha1.ObjectBucket {
+		ob := &v1alpha1.ObjectBucket{
+			ObjectMeta: metav1.ObjectMeta{Name: "obc-" + testNamespace + "-" + testName, UID: "ob-uid", Finalizers: []string{finalizer}},
+			Spec:       v1alpha1.ObjectBucketSpec{ClaimRef: claimRef},
+			Status:     v1alpha1.ObjectBucketStatus{Phase: v1alpha1.ObjectBucketStatusPhaseBound},
+		}
+		setClaimLabels(ob, claimRef)
+		return ob
+	}
+
+	tests := []struct {
+		name      string
+		setup     func(m *mockBucketClient)
+		run       func(ctx context.Context, m *mockBucketClient) error
+		wantErr   bool
+		wantCalls []string
+	}{
+		{
+			name: "create returns an API error",
+			setup: func(m *mockBucketClient) {
+				m.errs["CreateObjectBucket"] = []error{fmt.Errorf("connection refused")}
+			},
+			run: func(ctx context.Context, m *mockBucketClient) error {
+				_, err := createObjectBucket(ctx, newOB(), m, b, "")
+				return err
+			},
+			wantErr:   true,
+			wantCalls: []string{"CreateObjectBucket"},
+		},
+		{
+			name: "create adopts the claim's existing OB",
+			setup: func(m *mockBucketClient) {
+				m.obs[newOB().Name] = newOB()
+			},
+			run: func(ctx context.Context, m *mockBucketClient) error {
+				_, err := createObjectBucket(ctx, newOB(), m, b, "")
+				return err
+			},
+			wantCalls: []string{"CreateObjectBucket", "GetObjectBucket"},
+		},
+		{
+			name: "create fails on another claim's OB",
+			setup: func(m *mockBucketClient) {
+				ob := newOB()
+				ob.Spec.ClaimRef = ob.Spec.ClaimRef.DeepCopy()
+				ob.Spec.ClaimRef.UID = "other-uid"
+				m.obs[ob.Name] = ob
+			},
+			run: func(ctx context.Context, m *mockBucketClient) error {
+				_, err := createObjectBucket(ctx, newOB(), m, b, "")
+				return err
+			},
+			wantErr:   true,
+			wantCalls: []string{"CreateObjectBucket", "GetObjectBucket"},
+		},
+		{
+			name: "delete removes the finalizer first",
+			setup: func(m *mockBucketClient) {
+				m.obs[newOB().Name] = newOB()
+			},
+			run: func(ctx context.Context, m *mockBucketClient) error {
+				return deleteObjectBucket(newOB(), nil, m)
+			},
+			wantCalls: []string{"UpdateObjectBucket", "DeleteObjectBucket"},
+		},
+		{
+			name: "delete of a deleted OB",
+			run: func(ctx context.Context, m *mockBucketClient) error {
+				return deleteObjectBucket(newOB(), nil, m)
+			},
+			wantCalls: []string{"UpdateObjectBucket"},
+		},
+		{
+			name: "status update of a changed claim",
+			setup: func(m *mockBucketClient) {
+				m.obcs[testNamespace+"/"+testName] = obc.DeepCopy()
+				m.errs["UpdateClaimStatus"] = []error{errors.NewConflict(obcResource, testName, fmt.Errorf("changed"))}
+			},
+			run: func(ctx context.Context, m *mockBucketClient) error {
+				stale := obc.DeepCopy()
+				stale.Status.Phase = v1alpha1.ObjectBucketClaimStatusPhaseBound
+				_, err := updateClaimStatus(ctx, m, stale, b.interval, b.timeout)
+				return err
+			},
+			wantCalls: []string{"UpdateClaimStatus", "GetClaim", "UpdateClaimStatus"},
+		},
+		{
+			name: "release removes the current finalizer",
+			setup: func(m *mockBucketClient) {
+				withFinalizer := obc.DeepCopy()
+				withFinalizer.Finalizers = []string{finalizer, "other"}
+				m.obcs[testNamespace+"/"+testName] = withFinalizer
+			},
+			run: func(ctx context.Context, m *mockBucketClient) error {
+				return releaseOBC(obc.DeepCopy(), nil, m)
+			},
+			wantCalls: []string{"GetClaim", "UpdateClaim"},
+		},
+		{
+			name: "release of a claim without finalizer",
+			setup: func(m *mockBucketClient) {
+				withoutFinalizer := obc.DeepCopy()
+				withoutFinalizer.Finalizers = []string{"other"}
+				m.obcs[testNamespace+"/"+testName] = withoutFinalizer
+			},
+			run: func(ctx context.Context, m *mockBucketClient) error {
+				return releaseOBC(obc.DeepCopy(), nil, m)
+			},
+			wantCalls: []string{"GetClaim"},
+		},
+		{
+			name: "delete of an OB without finalizer",
+			setup: func(m *mockBucketClient) {
+				m.obs[newOB().Name] = newOB()
+			},
+			run: func(ctx context.Context, m *mockBucketClient) error {
+				ob := newOB()
+				ob.Finalizers = nil
+				return deleteObjectBucket(ob, nil, m)
+			},
+			wantCalls: []string{"DeleteObjectBucket"},
+		},
+		{
+			name: "release of a deleted claim",
+			run: func(ctx context.Context, m *mockBucketClient) error {
+				return releaseOBC(obc.DeepCopy(), nil, m)
+			},
+			wantErr:   true,
+			wantCalls: []string{"GetClaim"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newMockBucketClient()
+			if tt.setup != nil {
+				tt.setup(m)
+			}
+			if err := tt.run(context.Background(), m); (err != nil) != tt.wantErr {
+				t.Errorf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if diff := cmp.Diff(tt.wantCalls, m.calls); diff != "" {
+				t.Errorf("calls mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2019 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"encoding/json"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// childApplier holds the server-side apply calls converging the claim's Secret and ConfigMap.  It is the seam
+// between the controller and the API server's apply endpoint, which the fake clientset does not implement.
+type childApplier interface {
+	ApplySecret(secret *corev1.Secret, fieldManager string) (*corev1.Secret, error)
+	ApplyConfigMap(cm *corev1.ConfigMap, fieldManager string) (*corev1.ConfigMap, error)
+}
+
+// restChildApplier is the childApplier of the controller, applying with the REST client of a clientset.  The
+// vendored typed clients predate server-side apply and cannot set the field manager.
+type restChildApplier struct {
+	c rest.Interface
+}
+
+var _ childApplier = &restChildApplier{}
+
+func newChildApplier(c kubernetes.Interface) childApplier {
+	return &restChildApplier{c: c.CoreV1().RESTClient()}
+}
+
+// ApplySecret applies secret, taking over the fields other managers set on it
+func (a *restChildApplier) ApplySecret(secret *corev1.Secret, fieldManager string) (*corev1.Secret, error) {
+	secret = secret.DeepCopy()
+	secret.TypeMeta = metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"}
+	result := &corev1.Secret{}
+	if err := a.apply("secrets", secret.Namespace, secret.Name, secret, fieldManager, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ApplyConfigMap applies cm, taking over the fields other managers set on it
+func (a *restChildApplier) ApplyConfigMap(cm *corev1.ConfigMap, fieldManager string) (*corev1.ConfigMap, error) {
+	cm = cm.DeepCopy()
+	cm.TypeMeta = metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"}
+	result := &corev1.ConfigMap{}
+	if err := a.apply("configmaps", cm.Namespace, cm.Name, cm, fieldManager, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (a *restChildApplier) apply(resource, namespace, name string, obj runtime.Object, fieldManager string, into runtime.Object) error {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	return a.c.Patch(types.ApplyPatchType).
+		Namespace(namespace).
+		Resource(resource).
+		Name(name).
+		Param("fieldManager", fieldManager).
+		Param("force", "true").
+		Body(data).
+		Do().
+		Into(into)
+}
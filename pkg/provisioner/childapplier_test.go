@@ -0,0 +1,239 @@
+/*
+Copyright 2019 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/kubernetes/scheme"
+	restfake "k8s.io/client-go/rest/fake"
+
+	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/apis/objectbucket.io/v1alpha1"
+)
+
+// fakeChildApplier applies to the objects of a clientset as the API server does for a single forcing field
+// manager: the applied metadata is merged into that of the existing object, whose data is replaced.  The field
+// manager of each call is recorded.
+type fakeChildApplier struct {
+	c             kubernetes.Interface
+	fieldManagers []string
+}
+
+var _ childApplier = &fakeChildApplier{}
+
+func newFakeChildApplier(c kubernetes.Interface) *fakeChildApplier {
+	return &fakeChildApplier{c: c}
+}
+
+func (a *fakeChildApplier) ApplySecret(secret *corev1.Secret, fieldManager string) (*corev1.Secret, error) {
+	a.fieldManagers = append(a.fieldManagers, fieldManager)
+	secrets := a.c.CoreV1().Secrets(secret.Namespace)
+	existing, err := secrets.Get(secret.Name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return secrets.Create(secret)
+	}
+	if err != nil {
+		return nil, err
+	}
+	convergeMeta(existing, secret)
+	existing.Data = secret.Data
+	return secrets.Update(existing)
+}
+
+func (a *fakeChildApplier) ApplyConfigMap(cm *corev1.ConfigMap, fieldManager string) (*corev1.ConfigMap, error) {
+	a.fieldManagers = append(a.fieldManagers, fieldManager)
+	configMaps := a.c.CoreV1().ConfigMaps(cm.Namespace)
+	existing, err := configMaps.Get(cm.Name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return configMaps.Create(cm)
+	}
+	if err != nil {
+		return nil, err
+	}
+	convergeMeta(existing, cm)
+	existing.Data = cm.Data
+	return configMaps.Update(existing)
+}
+
+func TestRestChildApplier(t *testing.T) {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: testName, Namespace: testNamespace},
+		Data:       map[string]string{bucketName: "test-bucket"},
+	}
+	var req *http.Request
+	var body map[string]interface{}
+	client := &restfake.RESTClient{
+		NegotiatedSerializer: scheme.Codecs.WithoutConversion(),
+		Client: restfake.CreateHTTPClient(func(r *http.Request) (*http.Response, error) {
+			req = r
+			data, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				return nil, err
+			}
+			if err = json.Unmarshal(data, &body); err != nil {
+				return nil, err
+			}
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{"Content-Type": []string{"application/json"}},
+				Body:       ioutil.NopCloser(bytes.NewReader(data)),
+			}, nil
+		}),
+	}
+	a := &restChildApplier{c: client}
+
+	got, err := a.ApplyConfigMap(cm, provisionerName)
+	if err != nil {
+		t.Fatalf("ApplyConfigMap() error = %v", err)
+	}
+	if req.Method != http.MethodPatch || req.Header.Get("Content-Type") != string(types.ApplyPatchType) {
+		t.Errorf("want an apply patch, got %s with content type %q", req.Method, req.Header.Get("Content-Type"))
+	}
+	if want := "/namespaces/" + testNamespace + "/configmaps/" + testName; req.URL.Path != want {
+		t.Errorf("want path %q, got %q", want, req.URL.Path)
+	}
+	if q := req.URL.Query(); q.Get("fieldManager") != provisionerName || q.Get("force") != "true" {
+		t.Errorf("want forced apply by %q, got query %v", provisionerName, q)
+	}
+	if body["apiVersion"] != "v1" || body["kind"] != "ConfigMap" {
+		t.Errorf("want the applied object typed, got %v", body)
+	}
+	if got.Data[bucketName] != "test-bucket" {
+		t.Errorf("want the applied configmap returned, got %v", got)
+	}
+}
+
+func TestFieldManagerFor(t *testing.T) {
+	c := newTestController(nil)
+	pc, _ := c.forProvisioner(provisionerName)
+	if got := pc.children.fieldManager; got != provisionerName {
+		t.Errorf("want field manager %q, got %q", provisionerName, got)
+	}
+	long := string(make([]byte, maxFieldManagerLength+1))
+	if got := fieldManagerFor(long); len(got) != maxFieldManagerLength {
+		t.Errorf("want field manager truncated to %d characters, got %d", maxFieldManagerLength, len(got))
+	}
+}
+
+func TestCreateChildrenAppliesDrift(t *testing.T) {
+	const fieldManager = "test-provisioner"
+	obc := &v1alpha1.ObjectBucketClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: testName, Namespace: testNamespace, UID: "test-uid"},
+	}
+	ep := &v1alpha1.Endpoint{BucketHost: "s3.example.com", BucketPort: 443, BucketName: "test-bucket"}
+	auth := &v1alpha1.Authentication{AccessKeys: &v1alpha1.AccessKeys{AccessKeyID: "id", SecretAccessKey: "key"}}
+	b := retryBackoff{interval: time.Millisecond, timeout: time.Millisecond * 10}
+	opts := defaultChildOptions
+	opts.fieldManager = fieldManager
+
+	client := fake.NewSimpleClientset()
+	a := newFakeChildApplier(client)
+	if _, err := createSecret(context.Background(), obc, auth, nil, nil, opts, client, a, b); err != nil {
+		t.Fatalf("createSecret() error = %v", err)
+	}
+	if _, err := createConfigMap(context.Background(), obc, ep, nil, nil, opts, client, a, b); err != nil {
+		t.Fatalf("createConfigMap() error = %v", err)
+	}
+	if len(a.fieldManagers) != 0 {
+		t.Fatalf("want new children created, got %d applies", len(a.fieldManagers))
+	}
+	// the API server, unlike the fake clientset, stores the secret's string data as data
+	secret, err := client.CoreV1().Secrets(testNamespace).Get(testName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("error getting secret: %v", err)
+	}
+	secret.Data = map[string][]byte{}
+	for k, v := range secret.StringData {
+		secret.Data[k] = []byte(v)
+	}
+	secret.StringData = nil
+	if _, err = client.CoreV1().Secrets(testNamespace).Update(secret); err != nil {
+		t.Fatalf("error updating secret: %v", err)
+	}
+
+	// converged children are left alone
+	if _, err = createSecret(context.Background(), obc, auth, nil, nil, opts, client, a, b); err != nil {
+		t.Fatalf("createSecret() error = %v", err)
+	}
+	if _, err = createConfigMap(context.Background(), obc, ep, nil, nil, opts, client, a, b); err != nil {
+		t.Fatalf("createConfigMap() error = %v", err)
+	}
+	if len(a.fieldManagers) != 0 {
+		t.Fatalf("want converged children left alone, got %d applies", len(a.fieldManagers))
+	}
+
+	// drifted data is applied again, keeping the labels set by others
+	secret, err = client.CoreV1().Secrets(testNamespace).Get(testName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("error getting secret: %v", err)
+	}
+	secret.Data[v1alpha1.AwsKeyField] = []byte("drifted")
+	secret.Labels = map[string]string{"team": "a"}
+	if _, err = client.CoreV1().Secrets(testNamespace).Update(secret); err != nil {
+		t.Fatalf("error updating secret: %v", err)
+	}
+	cm, err := client.CoreV1().ConfigMaps(testNamespace).Get(testName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("error getting configmap: %v", err)
+	}
+	cm.Data[bucketHost] = "drifted.example.com"
+	cm.Labels = map[string]string{"team": "a"}
+	if _, err = client.CoreV1().ConfigMaps(testNamespace).Update(cm); err != nil {
+		t.Fatalf("error updating configmap: %v", err)
+	}
+	if _, err = createSecret(context.Background(), obc, auth, nil, nil, opts, client, a, b); err != nil {
+		t.Fatalf("createSecret() error = %v", err)
+	}
+	if _, err = createConfigMap(context.Background(), obc, ep, nil, nil, opts, client, a, b); err != nil {
+		t.Fatalf("createConfigMap() error = %v", err)
+	}
+	if diff := cmp.Diff([]string{fieldManager, fieldManager}, a.fieldManagers); diff != "" {
+		t.Errorf("field managers mismatch (-want +got):\n%s", diff)
+	}
+
+	if secret, err = client.CoreV1().Secrets(testNamespace).Get(testName, metav1.GetOptions{}); err != nil {
+		t.Fatalf("error getting secret: %v", err)
+	}
+	if got := secretData(secret)[v1alpha1.AwsKeyField]; got != "id" {
+		t.Errorf("want restored access key %q, got %q", "id", got)
+	}
+	if cm, err = client.CoreV1().ConfigMaps(testNamespace).Get(testName, metav1.GetOptions{}); err != nil {
+		t.Fatalf("error getting configmap: %v", err)
+	}
+	if got := cm.Data[bucketHost]; got != ep.BucketHost {
+		t.Errorf("want restored bucket host %q, got %q", ep.BucketHost, got)
+	}
+	for _, labels := range []map[string]string{secret.Labels, cm.Labels} {
+		if labels["team"] != "a" {
+			t.Errorf("want label of another manager kept, got %v", labels)
+		}
+	}
+}
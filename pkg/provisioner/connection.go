@@ -0,0 +1,157 @@
+/*
+Copyright 2019 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"fmt"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/apis/objectbucket.io/v1alpha1"
+)
+
+// ConnectionFromResources reads the Endpoint and Authentication back from a claim's generated ConfigMap and
+// Secret, e.g. for a consumer to validate them.  It is the inverse of the library's ConfigMap and Secret
+// generation: the derived BUCKET_URL and BUCKET_ENDPOINT keys and the claim's BUCKET_SUBPATH key are
+// ignored and the ConfigMap keys other than the BUCKET_* keys are returned as the endpoint's
+// AdditionalConfigData.  Note that PathStyle is also true for an IP address host, which always implies it.
+func ConnectionFromResources(cm *corev1.ConfigMap, sec *corev1.Secret) (*v1alpha1.Connection, error) {
+	if cm == nil {
+		return nil, fmt.Errorf("cannot read connection, got nil ConfigMap")
+	}
+	if sec == nil {
+		return nil, fmt.Errorf("cannot read connection, got nil Secret")
+	}
+	ep, err := endpointFromConfigMap(cm)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read endpoint from ConfigMap %q: %v", cm.Name, err)
+	}
+	auth, err := authenticationFromSecret(sec)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read authentication from Secret %q: %v", sec.Name, err)
+	}
+	return &v1alpha1.Connection{
+		Endpoint:       ep,
+		Authentication: auth,
+	}, nil
+}
+
+func endpointFromConfigMap(cm *corev1.ConfigMap) (*v1alpha1.Endpoint, error) {
+	ep := &v1alpha1.Endpoint{}
+	var err error
+	for k, v := range cm.Data {
+		switch k {
+		case bucketName:
+			ep.BucketName = v
+		case bucketHost:
+			ep.BucketHost = v
+		case bucketPort:
+			if ep.BucketPort, err = strconv.Atoi(v); err != nil {
+				return nil, fmt.Errorf("invalid %s %q: %v", bucketPort, v, err)
+			}
+		case bucketRegion:
+			ep.Region = v
+		case bucketSubRegion:
+			ep.SubRegion = v
+		case bucketSSL:
+			if ep.SSL, err = strconv.ParseBool(v); err != nil {
+				return nil, fmt.Errorf("invalid %s %q: %v", bucketSSL, v, err)
+			}
+		case bucketCACert:
+			ep.CABundle = v
+		case bucketTLSMin:
+			ep.TLSMinVersion = v
+		case bucketPathStyle:
+			if ep.PathStyle, err = strconv.ParseBool(v); err != nil {
+				return nil, fmt.Errorf("invalid %s %q: %v", bucketPathStyle, v, err)
+			}
+		case bucketVersioning:
+			if ep.Versioning, err = strconv.ParseBool(v); err != nil {
+				return nil, fmt.Errorf("invalid %s %q: %v", bucketVersioning, v, err)
+			}
+		case azureStorageAccount:
+			ep.Kind = v1alpha1.EndpointKindAzure
+			ep.AccountName = v
+		case azureContainer:
+			ep.BucketName = v
+		case azureEndpointSuffix:
+			ep.EndpointSuffix = v
+		case bucketURL, bucketEndpoint:
+			// derived from the host, port and SSL keys
+		case bucketSubPath:
+			// set from the claim, not the endpoint
+		default:
+			if ep.AdditionalConfigData == nil {
+				ep.AdditionalConfigData = make(map[string]string)
+			}
+			ep.AdditionalConfigData[k] = v
+		}
+	}
+	return ep, nil
+}
+
+// authenticationFromSecret returns the auth type found in the secret, which may hold at most one.  Keys of no
+// auth type are returned as AdditionalSecretData.
+func authenticationFromSecret(sec *corev1.Secret) (*v1alpha1.Authentication, error) {
+	auth := &v1alpha1.Authentication{}
+	data := secretData(sec)
+	var defined []string
+	if _, ok := data[v1alpha1.AwsKeyField]; ok {
+		auth.AccessKeys = &v1alpha1.AccessKeys{
+			AccessKeyID:     data[v1alpha1.AwsKeyField],
+			SecretAccessKey: data[v1alpha1.AwsSecretField],
+		}
+		defined = append(defined, v1alpha1.AwsKeyField, v1alpha1.AwsSecretField)
+	}
+	if _, ok := data[v1alpha1.TokenField]; ok {
+		auth.PlainToken = &v1alpha1.PlainToken{Token: data[v1alpha1.TokenField]}
+		defined = append(defined, v1alpha1.TokenField)
+	}
+	if _, ok := data[v1alpha1.UsernameField]; ok {
+		auth.UserPass = &v1alpha1.UserPass{
+			Username: data[v1alpha1.UsernameField],
+			Password: data[v1alpha1.PasswordField],
+		}
+		defined = append(defined, v1alpha1.UsernameField, v1alpha1.PasswordField)
+	}
+	if _, ok := data[v1alpha1.AzureAccountKeyField]; ok {
+		auth.AzureAccountKey = &v1alpha1.AzureAccountKey{AccountKey: data[v1alpha1.AzureAccountKeyField]}
+		defined = append(defined, v1alpha1.AzureAccountKeyField)
+	}
+	if v, ok := data[v1alpha1.GcsCredentialsField]; ok {
+		auth.ServiceAccountJSON = v1alpha1.ServiceAccountJSON(v)
+		defined = append(defined, v1alpha1.GcsCredentialsField)
+	}
+	if _, err := auth.ToMap(); err != nil {
+		return nil, err
+	}
+	for _, k := range defined {
+		delete(data, k)
+	}
+	for k, v := range data {
+		if auth.AdditionalSecretData == nil {
+			auth.AdditionalSecretData = make(map[string]string)
+		}
+		auth.AdditionalSecretData[k] = v
+	}
+	// the type is only recorded if it overrides the type derived from the auth type
+	if sec.Type != "" && sec.Type != auth.SecretType() {
+		auth.Type = sec.Type
+	}
+	return auth, nil
+}
@@ -0,0 +1,197 @@
+/*
+Copyright 2019 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/apis/objectbucket.io/v1alpha1"
+)
+
+func TestConnectionFromResources(t *testing.T) {
+	// the claim's subpath is not part of the connection
+	obc := &v1alpha1.ObjectBucketClaim{
+		ObjectMeta: objMeta,
+		Spec:       v1alpha1.ObjectBucketClaimSpec{BucketSubPath: "team-a"},
+	}
+
+	tests := []struct {
+		name     string
+		endpoint *v1alpha1.Endpoint
+		auth     *v1alpha1.Authentication
+		want     *v1alpha1.Connection
+	}{
+		{
+			name: "SSL endpoint and access keys",
+			endpoint: &v1alpha1.Endpoint{
+				BucketHost:           "s3.example.com",
+				BucketPort:           8443,
+				BucketName:           "bucket",
+				Region:               "us-east-1",
+				SubRegion:            "east-a",
+				SSL:                  true,
+				CABundle:             "ca",
+				TLSMinVersion:        "1.2",
+				PathStyle:            true,
+				AdditionalConfigData: map[string]string{"TENANT": "tenant-a"},
+			},
+			auth: &v1alpha1.Authentication{
+				AccessKeys: &v1alpha1.AccessKeys{AccessKeyID: "key", SecretAccessKey: "secret"},
+			},
+		},
+		{
+			name: "endpoint without port and user/password",
+			endpoint: &v1alpha1.Endpoint{
+				BucketHost: "s3.example.com",
+				BucketName: "bucket",
+			},
+			auth: &v1alpha1.Authentication{
+				UserPass: &v1alpha1.UserPass{Username: "user", Password: "pass"},
+			},
+		},
+		{
+			name: "Azure endpoint and account key",
+			endpoint: &v1alpha1.Endpoint{
+				Kind:           v1alpha1.EndpointKindAzure,
+				BucketName:     "container",
+				AccountName:    "account",
+				EndpointSuffix: "core.windows.net",
+			},
+			auth: &v1alpha1.Authentication{
+				AzureAccountKey: &v1alpha1.AzureAccountKey{AccountKey: "key"},
+			},
+		},
+		{
+			name: "GCS endpoint and service account JSON",
+			endpoint: &v1alpha1.Endpoint{
+				BucketHost: "storage.googleapis.com",
+				BucketPort: 443,
+				BucketName: "bucket",
+				SSL:        true,
+			},
+			auth: &v1alpha1.Authentication{
+				ServiceAccountJSON: v1alpha1.ServiceAccountJSON(`{"type": "service_account"}`),
+			},
+		},
+		{
+			name: "IP address host implies path style",
+			endpoint: &v1alpha1.Endpoint{
+				BucketHost: "10.0.0.1",
+				BucketPort: 80,
+				BucketName: "bucket",
+			},
+			auth: &v1alpha1.Authentication{
+				PlainToken: &v1alpha1.PlainToken{Token: "token"},
+				Type:       corev1.SecretTypeServiceAccountToken,
+			},
+			want: &v1alpha1.Connection{
+				Endpoint: &v1alpha1.Endpoint{
+					BucketHost: "10.0.0.1",
+					BucketPort: 80,
+					BucketName: "bucket",
+					PathStyle:  true,
+				},
+				Authentication: &v1alpha1.Authentication{
+					PlainToken: &v1alpha1.PlainToken{Token: "token"},
+					Type:       corev1.SecretTypeServiceAccountToken,
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cm, err := newBucketConfigMap(obc, tt.endpoint, nil, nil, defaultChildOptions)
+			if err != nil {
+				t.Fatalf("error generating ConfigMap: %v", err)
+			}
+			sec, err := newCredentialsSecret(obc, tt.auth, nil, nil, defaultChildOptions)
+			if err != nil {
+				t.Fatalf("error generating Secret: %v", err)
+			}
+			want := tt.want
+			if want == nil {
+				want = &v1alpha1.Connection{Endpoint: tt.endpoint, Authentication: tt.auth}
+			}
+
+			got, err := ConnectionFromResources(cm, sec)
+			if err != nil {
+				t.Fatalf("ConnectionFromResources() error = %v", err)
+			}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("ConnectionFromResources() (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestConnectionFromResourcesErrors(t *testing.T) {
+	validCM := &corev1.ConfigMap{Data: map[string]string{bucketHost: "s3.example.com"}}
+	validSecret := &corev1.Secret{StringData: map[string]string{v1alpha1.TokenField: "token"}}
+
+	tests := []struct {
+		name   string
+		cm     *corev1.ConfigMap
+		secret *corev1.Secret
+	}{
+		{
+			name:   "nil ConfigMap",
+			secret: validSecret,
+		},
+		{
+			name: "nil Secret",
+			cm:   validCM,
+		},
+		{
+			name:   "invalid port",
+			cm:     &corev1.ConfigMap{Data: map[string]string{bucketPort: "https"}},
+			secret: validSecret,
+		},
+		{
+			name:   "invalid SSL flag",
+			cm:     &corev1.ConfigMap{Data: map[string]string{bucketSSL: "yes please"}},
+			secret: validSecret,
+		},
+		{
+			name:   "invalid path style flag",
+			cm:     &corev1.ConfigMap{Data: map[string]string{bucketPathStyle: "sometimes"}},
+			secret: validSecret,
+		},
+		{
+			name: "several auth types",
+			cm:   validCM,
+			secret: &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: testName},
+				Data: map[string][]byte{
+					v1alpha1.TokenField:  []byte("token"),
+					v1alpha1.AwsKeyField: []byte("key"),
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ConnectionFromResources(tt.cm, tt.secret); err == nil {
+				t.Error("ConnectionFromResources() error = nil, want an error")
+			}
+		})
+	}
+}
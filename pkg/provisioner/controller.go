@@ -17,19 +17,32 @@ limitations under the License.
 package provisioner
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus"
+
 	corev1 "k8s.io/api/core/v1"
 	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/wait"
+	kubeinformers "k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
+	storagelisters "k8s.io/client-go/listers/storage/v1"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/workqueue"
 
 	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/apis/objectbucket.io/v1alpha1"
@@ -40,9 +53,341 @@ import (
 	pErr "github.com/kube-object-storage/lib-bucket-provisioner/pkg/provisioner/api/errors"
 )
 
+// ControllerOptions allows provisioner authors to tune the behavior of the claim controller.
+// Zero values are replaced by the library defaults.
+type ControllerOptions struct {
+	// RetryBaseInterval controls how long to wait between attempts of a single API call
+	RetryBaseInterval time.Duration
+	// RetryTimeout defines how long in total to retry an API call before ending the reconciliation attempt
+	RetryTimeout time.Duration
+	// RetryBackoffFactor multiplies the wait between consecutive attempts to create an API object
+	RetryBackoffFactor float64
+	// RetryBackoffCap is the longest wait between consecutive attempts to create an API object
+	RetryBackoffCap time.Duration
+	// SkipBucketNameValidation disables the S3 bucket naming rules enforced on new bucket names, for
+	// object stores with looser constraints
+	SkipBucketNameValidation bool
+	// BucketNameGenerator names the new buckets of claims which do not set spec.bucketName, in place of the
+	// library's generateBucketName based naming, e.g. to follow an organization's naming policy.  Its names
+	// must follow the S3 naming rules, even with SkipBucketNameValidation.
+	BucketNameGenerator BucketNameGenerator
+	// AnnotationPrefixes lists the OBC annotation key prefixes which are copied onto the generated
+	// Secret and ConfigMap. No annotations are copied if empty.
+	AnnotationPrefixes []string
+	// DryRun validates every claim without provisioning a bucket or creating any resources, as if each
+	// carried the dry-run annotation
+	DryRun bool
+	// MetricsRegisterer receives the controller's metrics, e.g. the controller-runtime metrics.Registry to
+	// serve them on the manager's /metrics endpoint.  Metrics are not registered if nil.
+	MetricsRegisterer prometheus.Registerer
+	// EventComponent is the source component of the events recorded on claims.  Defaults to the name of the
+	// provisioner, or objectbucket.io/provisioner if the controller serves several.
+	EventComponent string
+	// EventSink receives the events recorded on claims in place of the API server, e.g. for operators to
+	// route them to a dedicated stream rather than the tenants' namespaces.  Defaults to the API server.
+	EventSink record.EventSink
+	// MaxConcurrentReconciles is the number of claims reconciled in parallel.  A single claim is never
+	// reconciled by more than one worker at a time.  Defaults to the LIB_BUCKET_PROVISIONER_THREADS
+	// environment variable if set, otherwise 1.
+	MaxConcurrentReconciles int
+	// DeletionTimeout is how long after an OBC's deletion the provisioner may keep failing to delete its
+	// bucket or revoke access to it before the OBC's DeletionFailed condition is set.  Unlimited if 0.
+	DeletionTimeout time.Duration
+	// ForceDeletionAfterTimeout removes the finalizers of an OBC whose deletion timed out, so that it is not
+	// stuck terminating.  The bucket and the provisioner's resources may then be orphaned.
+	ForceDeletionAfterTimeout bool
+	// DeletionQuarantine defers the deletion of a deleted OBC's bucket: its OB is kept in the Quarantined phase
+	// for this long before the provisioner's Delete is called, and re-creating the OBC in the meantime cancels
+	// the deletion.  Only buckets which would be deleted are quarantined.  Disabled if 0.
+	// The OBC's secret is deleted with it, so only the buckets of provisioners implementing api.CredentialRotator,
+	// which issue new credentials to the re-created OBC, are quarantined.  Other buckets are deleted at once.
+	DeletionQuarantine time.Duration
+	// AllowCrossNamespaceSecrets lets OBCs request copies of their secret in other namespaces with
+	// spec.additionalSecretNamespaces.  Such OBCs fail to provision if not set.  Each namespace must also
+	// accept the copies, see v1alpha1.SecretCopiesFromAnnotation.
+	AllowCrossNamespaceSecrets bool
+	// DefaultStorageClass names the StorageClass of the claims which omit spec.storageClassName.  It is
+	// written to their spec when they are first reconciled.  Such claims fail to provision if empty.
+	DefaultStorageClass string
+	// MaxOBCsPerNamespace caps the number of OBCs holding a bucket, i.e. Bound or being provisioned, in a
+	// namespace.  New OBCs over the limit fail to provision.  Zero means unlimited.
+	MaxOBCsPerNamespace int
+	// RequeueJitterFactor is the largest fraction of a claim's requeue delay added at random, so that claims
+	// requeued together, e.g. on startup, do not all hit the object store at once.  Jitter is disabled if
+	// negative.
+	RequeueJitterFactor float64
+	// LeaderElection elects a single replica of the provisioner to reconcile claims.  Every replica
+	// reconciles if nil.  It is honored by Provisioner.Run.
+	LeaderElection *LeaderElectionOptions
+	// ResyncPeriod is how often bound claims are re-verified, recreating their Secret or ConfigMap if it was
+	// deleted out-of-band.  Periodic re-verification is disabled if zero.  It is honored by the Provisioner
+	// constructors, which own the informers.
+	ResyncPeriod time.Duration
+	// PostProvision is called once a claim's Secret, ConfigMap and OB are created, before they are bound,
+	// e.g. to register the bucket in an external catalog.  An error fails the reconcile: the bucket and
+	// resources are cleaned up as for any other provisioning error and the claim is requeued.
+	PostProvision PostProvisionHook
+	// Logger receives every log line of the library.  Defaults to a klog backed logger.  Secret contents
+	// are never logged, whatever the verbosity.
+	Logger logr.Logger
+	// DisableChildFinalizers creates the claims' Secrets and ConfigMaps without the library's finalizer, for
+	// clusters whose admission control rejects it.  They are then only cleaned up by the garbage collector,
+	// through their ownerReference to the claim.
+	DisableChildFinalizers bool
+	// OwnerReference overrides the Controller and BlockOwnerDeletion fields of the ownerReference from the
+	// claim's Secret and ConfigMap to the claim, both true if nil.
+	OwnerReference *OwnerReferenceOptions
+	// ImmutableChildren marks the claims' Secrets and ConfigMaps immutable, protecting the credentials from
+	// accidental edits and sparing the kubelet from watching them.  It requires Kubernetes 1.21 or later.  Their
+	// content is then changed, e.g. on credential rotation, by deleting and recreating them.  The copies of the
+	// Secret in other namespaces stay mutable.
+	ImmutableChildren bool
+	// ConfigMapFirst creates each claim's ConfigMap before its Secret.  By default the Secret is created first,
+	// so that applications watching for the ConfigMap find the credentials ready once it appears.  Either way,
+	// the claim is only bound once both exist.
+	ConfigMapFirst bool
+	// CredentialsReferences writes to the claims' Secrets a reference to credentials kept in an external secret
+	// store, e.g. Vault, for an operator such as external-secrets to resolve, rather than the credentials
+	// themselves.  The provisioner must then return an Authentication holding a SecretReference, and the Secrets
+	// are marked with the credentials-reference annotation.
+	CredentialsReferences bool
+	// OmitEmptySubRegion leaves the BUCKET_SUBREGION key out of the claims' ConfigMaps when the endpoint has no
+	// subregion, for object stores without subregions.  By default the key is always written, if empty.
+	OmitEmptySubRegion bool
+	// OmitEmptyRegion likewise leaves the BUCKET_REGION key out when the endpoint has no region.
+	OmitEmptyRegion bool
+	// ClaimUIDLabel sets the claim-uid label of the claims' Secrets and ConfigMaps to the claim's UID, so that
+	// external controllers can correlate them, see GetChildrenForClaim.
+	ClaimUIDLabel bool
+	// ControllerName is the value of the objectbucket.io/managed-by annotation of the OBCs this controller
+	// reconciles.  OBCs annotated with another value are left entirely alone, e.g. to hand them over to another
+	// controller during a migration, while OBCs without the annotation are reconciled.  Defaults to the names of
+	// the provisioners the controller serves.
+	ControllerName string
+	// LegacyFinalizers are finalizer names, e.g. of an earlier release of the provisioner, which are removed
+	// alongside the library's whenever it releases an OBC, OB, Secret or ConfigMap.  They are never added.  The
+	// names apply to every controller of the process.
+	LegacyFinalizers []string
+	// KeyNames renames the data keys of the claims' ConfigMaps and Secrets, e.g. for applications expecting
+	// ACCESS_KEY_ID rather than AWS_ACCESS_KEY_ID.  The library's names are kept if nil.
+	KeyNames *KeyNames
+	// DebugVerbosity is the verbosity of the library's debug log lines, which are only written if the
+	// Logger is enabled at that level, e.g. klog's -v flag is at least as high.  Defaults to 1.
+	DebugVerbosity int
+	// ProvisionerVersion is recorded in the provisioner-version annotation of the OBs the controller creates,
+	// to tell which release created them.  Defaults to Version.
+	ProvisionerVersion string
+	// ProvisionerCallTimeout bounds each call to the provisioner, e.g. Provision or Delete.  A call still
+	// running at the deadline has its context cancelled and is abandoned, releasing the worker, and the claim
+	// is requeued.  Provisioners should honor the cancellation, lest the abandoned call complete in the
+	// background.  Unlimited if zero.
+	ProvisionerCallTimeout time.Duration
+	// HealthCheckInterval is how often the HealthCheck of a provisioner implementing api.HealthChecker is called.
+	// A check is abandoned after ProvisionerCallTimeout if set, or else after the interval.  Defaults to 30s.
+	HealthCheckInterval time.Duration
+}
+
+// Version is the default ProvisionerVersion.  It is empty unless set at build time with the linker's -X flag,
+// e.g. -ldflags "-X github.com/kube-object-storage/lib-bucket-provisioner/pkg/provisioner.Version=v1.0.0".
+var Version string
+
+// OwnerReferenceOptions are the fields of the ownerReference from a claim's Secret and ConfigMap to the claim
+// which may be overridden, see ControllerOptions
+type OwnerReferenceOptions struct {
+	// Controller marks the claim as the managing controller of its Secret and ConfigMap, which tools such
+	// as kubectl rely on.  It does not affect garbage collection.
+	Controller bool
+	// BlockOwnerDeletion keeps a claim deleted with the foreground propagation policy from going away
+	// before its Secret and ConfigMap are deleted.  If false, the claim may be removed first and they are
+	// deleted in the background.  Setting it requires the permission to update the claims' finalizers.
+	BlockOwnerDeletion bool
+}
+
+// KeyNames are the names of the data keys of a claim's ConfigMap and Secret, see ControllerOptions.  Empty
+// fields keep the library's name, given in their comment.  The names must be distinct.
+type KeyNames struct {
+	// BucketName is the ConfigMap key of the bucket name, BUCKET_NAME
+	BucketName string
+	// BucketHost is the ConfigMap key of the bucket host, BUCKET_HOST
+	BucketHost string
+	// BucketPort is the ConfigMap key of the bucket port, BUCKET_PORT
+	BucketPort string
+	// BucketRegion is the ConfigMap key of the bucket region, BUCKET_REGION
+	BucketRegion string
+	// BucketSubRegion is the ConfigMap key of the bucket subregion, BUCKET_SUBREGION
+	BucketSubRegion string
+	// BucketSSL is the ConfigMap key set for SSL endpoints, BUCKET_SSL
+	BucketSSL string
+	// BucketCACert is the ConfigMap key of the endpoint's CA bundle, BUCKET_CA_CERT
+	BucketCACert string
+	// BucketPathStyle is the ConfigMap key set for path-style endpoints, BUCKET_PATH_STYLE
+	BucketPathStyle string
+	// BucketTLSMinVersion is the ConfigMap key of the endpoint's minimum TLS version, BUCKET_TLS_MIN_VERSION
+	BucketTLSMinVersion string
+	// BucketURL is the ConfigMap key of the endpoint URL, BUCKET_URL
+	BucketURL string
+	// BucketEndpoint is the ConfigMap key of the endpoint passed to S3 SDKs, BUCKET_ENDPOINT
+	BucketEndpoint string
+	// BucketSubPath is the ConfigMap key of the claim's bucket prefix, BUCKET_SUBPATH
+	BucketSubPath string
+	// BucketVersioning is the ConfigMap key set for buckets with versioning enabled, BUCKET_VERSIONING
+	BucketVersioning string
+	// AccessKeyID is the Secret key of the S3 access key, AWS_ACCESS_KEY_ID
+	AccessKeyID string
+	// SecretAccessKey is the Secret key of the S3 secret key, AWS_SECRET_ACCESS_KEY
+	SecretAccessKey string
+}
+
+// renames maps the library's key names to the overridden ones
+func (k *KeyNames) renames() map[string]string {
+	if k == nil {
+		return nil
+	}
+	renames := map[string]string{}
+	for name, newName := range map[string]string{
+		bucketName:              k.BucketName,
+		bucketHost:              k.BucketHost,
+		bucketPort:              k.BucketPort,
+		bucketRegion:            k.BucketRegion,
+		bucketSubRegion:         k.BucketSubRegion,
+		bucketSSL:               k.BucketSSL,
+		bucketCACert:            k.BucketCACert,
+		bucketPathStyle:         k.BucketPathStyle,
+		bucketTLSMin:            k.BucketTLSMinVersion,
+		bucketURL:               k.BucketURL,
+		bucketEndpoint:          k.BucketEndpoint,
+		bucketSubPath:           k.BucketSubPath,
+		bucketVersioning:        k.BucketVersioning,
+		v1alpha1.AwsKeyField:    k.AccessKeyID,
+		v1alpha1.AwsSecretField: k.SecretAccessKey,
+	} {
+		if newName != "" && newName != name {
+			renames[name] = newName
+		}
+	}
+	return renames
+}
+
+// childOptions control the metadata of the claim's Secret and ConfigMap
+type childOptions struct {
+	// finalize adds the library's finalizer
+	finalize bool
+	// ownerReference sets the fields of the ownerReference to the claim
+	ownerReference OwnerReferenceOptions
+	// keyNames maps the data keys to their new names
+	keyNames map[string]string
+	// immutable marks the Secret and ConfigMap immutable, see markSecretImmutable
+	immutable bool
+	// configMapFirst creates the ConfigMap before the Secret
+	configMapFirst bool
+	// credentialsReference writes the credentials' SecretReference to the Secret, see credentialsData
+	credentialsReference bool
+	// omitEmptySubRegion and omitEmptyRegion leave the empty subregion and region keys out of the ConfigMap
+	omitEmptySubRegion bool
+	omitEmptyRegion    bool
+	// claimUIDLabel labels the Secret and ConfigMap with the claim's UID
+	claimUIDLabel bool
+	// fieldManager owns the fields the library applies to the Secret and ConfigMap, see fieldManagerFor
+	fieldManager string
+	// legacyFinalizers are removed alongside the library's finalizer, see ControllerOptions.LegacyFinalizers
+	legacyFinalizers []string
+}
+
+// finalizers returns the finalizers of the claim's Secret and ConfigMap
+func (o childOptions) finalizers() []string {
+	if !o.finalize {
+		return nil
+	}
+	return []string{finalizer}
+}
+
+// labels returns the labels of the claim's Secret and ConfigMap, see childLabels, plus the claim UID label if set
+func (o childOptions) labels(obc *v1alpha1.ObjectBucketClaim, provisionerLabels map[string]string) map[string]string {
+	labels := childLabels(obc, provisionerLabels)
+	if !o.claimUIDLabel {
+		return labels
+	}
+	// childLabels may return the provisioner labels themselves
+	withUID := make(map[string]string, len(labels)+1)
+	for k, v := range labels {
+		withUID[k] = v
+	}
+	withUID[v1alpha1.ClaimUIDLabel] = string(obc.UID)
+	return withUID
+}
+
+// childOptions returns the options of the claims' Secrets and ConfigMaps
+func (o *ControllerOptions) childOptions() childOptions {
+	opts := childOptions{
+		finalize:             !o.DisableChildFinalizers,
+		ownerReference:       OwnerReferenceOptions{Controller: true, BlockOwnerDeletion: true},
+		keyNames:             o.KeyNames.renames(),
+		immutable:            o.ImmutableChildren,
+		configMapFirst:       o.ConfigMapFirst,
+		credentialsReference: o.CredentialsReferences,
+		omitEmptySubRegion:   o.OmitEmptySubRegion,
+		omitEmptyRegion:      o.OmitEmptyRegion,
+		claimUIDLabel:        o.ClaimUIDLabel,
+		legacyFinalizers:     o.LegacyFinalizers,
+	}
+	if o.OwnerReference != nil {
+		opts.ownerReference = *o.OwnerReference
+	}
+	return opts
+}
+
+// BucketNameGenerator returns the name of a new bucket for a claim, see ControllerOptions
+type BucketNameGenerator func(obc *v1alpha1.ObjectBucketClaim) (string, error)
+
+// PostProvisionHook is called with a claim and the resources created for it, see ControllerOptions
+type PostProvisionHook func(obc *v1alpha1.ObjectBucketClaim, ob *v1alpha1.ObjectBucket, cm *corev1.ConfigMap, secret *corev1.Secret) error
+
+// withDefaults returns a copy of the options with unset fields replaced by the library defaults.
+func (o *ControllerOptions) withDefaults() ControllerOptions {
+	opts := ControllerOptions{}
+	if o != nil {
+		opts = *o
+	}
+	if opts.RetryBaseInterval == 0 {
+		opts.RetryBaseInterval = defaultRetryBaseInterval
+	}
+	if opts.RetryTimeout == 0 {
+		opts.RetryTimeout = defaultRetryTimeout
+	}
+	if opts.RetryBackoffFactor == 0 {
+		opts.RetryBackoffFactor = defaultRetryBackoffFactor
+	}
+	if opts.RetryBackoffCap == 0 {
+		opts.RetryBackoffCap = defaultRetryBackoffCap
+	}
+	if opts.DebugVerbosity <= 0 {
+		opts.DebugVerbosity = defaultDebugVerbosity
+	}
+	if opts.RequeueJitterFactor == 0 {
+		opts.RequeueJitterFactor = defaultRequeueJitterFactor
+	}
+	if opts.ProvisionerVersion == "" {
+		opts.ProvisionerVersion = Version
+	}
+	if opts.HealthCheckInterval <= 0 {
+		opts.HealthCheckInterval = defaultHealthCheckInterval
+	}
+	if opts.MaxConcurrentReconciles <= 0 {
+		opts.MaxConcurrentReconciles = defaultMaxConcurrentReconciles
+		if threadiness, set := os.LookupEnv(threadsEnvVar); set {
+			if n, err := strconv.Atoi(threadiness); err == nil && n > 0 {
+				opts.MaxConcurrentReconciles = n
+			}
+		}
+	}
+	return opts
+}
+
 type controller interface {
 	Start(<-chan struct{}) error
 	SetLabels(map[string]string)
+	RunHealthChecks(<-chan struct{})
+	Healthy() error
 }
 
 // Provisioner is a CRD Controller responsible for executing the Reconcile() function
@@ -50,56 +395,176 @@ type controller interface {
 type obcController struct {
 	clientset    kubernetes.Interface
 	libClientset versioned.Interface
+	bucketClient bucketClient
+	applier      childApplier
 	obcLister    listers.ObjectBucketClaimLister
 	obLister     listers.ObjectBucketLister
 	obcInformer  informers.ObjectBucketClaimInformer
 	obcHasSynced cache.InformerSynced
 	obHasSynced  cache.InformerSynced
 	queue        workqueue.RateLimitingInterface
+	// classInformer caches the StorageClasses so that reconciles do not get them from the API server.  It
+	// is owned and run by the controller, see Start.
+	classInformer  cache.SharedIndexInformer
+	classLister    storagelisters.StorageClassLister
+	classHasSynced cache.InformerSynced
 	// static label containing provisioner name and provisioner-specific labels which are all added
 	// to the OB, OBC, configmap and secret
 	provisionerLabels map[string]string
-	provisioner       api.Provisioner
-	provisionerName   string
+	// provisioners are the registered provisioners, keyed by the StorageClass provisioner name they serve
+	provisioners map[string]*registeredProvisioner
+	// provisioner and provisionerName are set on the copy of the controller bound to the provisioner of
+	// the claim being reconciled, see forProvisioner
+	provisioner     api.Provisioner
+	provisionerName string
+	// retry controls the spacing and duration of retried API calls
+	retry retryBackoff
+	// annotationPrefixes selects the OBC annotations copied to the configmap and secret
+	annotationPrefixes []string
+	// recorder records events on the OBC being reconciled
+	recorder record.EventRecorder
+	// validateBucketNames enables S3 naming rules checks on new bucket names
+	validateBucketNames bool
+	// bucketNameGenerator names the new buckets of claims without a bucket name, if set
+	bucketNameGenerator BucketNameGenerator
+	// dryRun only validates claims, see handleDryRunClaim
+	dryRun bool
+	// metrics instruments the calls to provisioner
+	metrics *metrics
+	// workers is the number of claims reconciled in parallel
+	workers int
+	// deletionTimeout and forceDeletion control how a failing bucket deletion ends, see handleDeleteFailure
+	deletionTimeout time.Duration
+	forceDeletion   bool
+	// quarantine defers the deletion of the deleted claims' buckets, unless zero, see handleQuarantinedBucket
+	quarantine time.Duration
+	// allowSecretCopies allows copies of the claim's secret in its additional secret namespaces
+	allowSecretCopies bool
+	// defaultStorageClass is given to the claims which do not name a StorageClass, unless empty
+	defaultStorageClass string
+	// maxClaimsPerNamespace caps the claims holding a bucket in a namespace, unless zero
+	maxClaimsPerNamespace int
+	// postProvision is called before a provisioned claim is bound, if set
+	postProvision PostProvisionHook
+	// children controls the finalizers and ownerReference of the claim's secret and configmap
+	children childOptions
+	// version is recorded on the created OBs, unless empty
+	version string
+	// callTimeout bounds each call to the provisioner, unless zero, see callProvisioner
+	callTimeout time.Duration
+	// health keeps the result of the provisioners' health checks
+	health *healthMonitor
+	// managerNames are the values of the managed-by annotation of the claims the controller reconciles, see
+	// isManagedBy
+	managerNames []string
+	// legacyFinalizers are removed alongside the library's finalizer, see ControllerOptions.LegacyFinalizers
+	legacyFinalizers []string
 }
 
 var _ controller = &obcController{}
 
-func NewController(provisionerName string, provisioner api.Provisioner, clientset kubernetes.Interface, crdClientSet versioned.Interface, obcInformer informers.ObjectBucketClaimInformer, obInformer informers.ObjectBucketInformer) *obcController {
+// registeredProvisioner is a provisioner served by the controller and its metrics
+type registeredProvisioner struct {
+	provisioner api.Provisioner
+	metrics     *metrics
+}
+
+func NewController(provisionerName string, provisioner api.Provisioner, clientset kubernetes.Interface, crdClientSet versioned.Interface, obcInformer informers.ObjectBucketClaimInformer, obInformer informers.ObjectBucketInformer, options *ControllerOptions) *obcController {
+	return NewMultiController(map[string]api.Provisioner{provisionerName: provisioner}, clientset, crdClientSet, obcInformer, obInformer, options)
+}
+
+// NewMultiController returns a controller serving several provisioners, keyed by the StorageClass
+// provisioner name they serve.  Each claim is handed to the provisioner named by its StorageClass;
+// claims of other StorageClasses are ignored.
+func NewMultiController(provisioners map[string]api.Provisioner, clientset kubernetes.Interface, crdClientSet versioned.Interface, obcInformer informers.ObjectBucketClaimInformer, obInformer informers.ObjectBucketInformer, options *ControllerOptions) *obcController {
+	opts := options.withDefaults()
+	initLoggers(options)
+	// events are reported by the provisioner when it is the only one
+	component := api.Domain + "/provisioner"
+	if len(provisioners) == 1 {
+		for name := range provisioners {
+			component = name
+		}
+	}
+	if opts.EventComponent != "" {
+		component = opts.EventComponent
+	}
+	classInformer := kubeinformers.NewSharedInformerFactory(clientset, 0).Storage().V1().StorageClasses()
 	ctrl := &obcController{
-		clientset:    clientset,
-		libClientset: crdClientSet,
-		obcLister:    obcInformer.Lister(),
-		obLister:     obInformer.Lister(),
-		obcInformer:  obcInformer,
-		obcHasSynced: obcInformer.Informer().HasSynced,
-		obHasSynced:  obInformer.Informer().HasSynced,
-		queue:        workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
-		provisionerLabels: map[string]string{
-			provisionerLabelKey: labelValue(provisionerName),
+		clientset:         clientset,
+		libClientset:      crdClientSet,
+		bucketClient:      newBucketClient(crdClientSet),
+		applier:           newChildApplier(clientset),
+		obcLister:         obcInformer.Lister(),
+		obLister:          obInformer.Lister(),
+		obcInformer:       obcInformer,
+		obcHasSynced:      obcInformer.Informer().HasSynced,
+		obHasSynced:       obInformer.Informer().HasSynced,
+		classInformer:     classInformer.Informer(),
+		classLister:       classInformer.Lister(),
+		classHasSynced:    classInformer.Informer().HasSynced,
+		queue:             workqueue.NewRateLimitingQueue(newJitteredRateLimiter(workqueue.DefaultControllerRateLimiter(), opts.RequeueJitterFactor)),
+		provisionerLabels: map[string]string{},
+		provisioners:      make(map[string]*registeredProvisioner, len(provisioners)),
+		retry: retryBackoff{
+			interval:    opts.RetryBaseInterval,
+			factor:      opts.RetryBackoffFactor,
+			maxInterval: opts.RetryBackoffCap,
+			timeout:     opts.RetryTimeout,
 		},
-		provisionerName: provisionerName,
-		provisioner:     provisioner,
+		annotationPrefixes:    opts.AnnotationPrefixes,
+		recorder:              newEventRecorder(component, clientset, opts.EventSink),
+		validateBucketNames:   !opts.SkipBucketNameValidation,
+		bucketNameGenerator:   opts.BucketNameGenerator,
+		dryRun:                opts.DryRun,
+		workers:               opts.MaxConcurrentReconciles,
+		deletionTimeout:       opts.DeletionTimeout,
+		forceDeletion:         opts.ForceDeletionAfterTimeout,
+		quarantine:            opts.DeletionQuarantine,
+		allowSecretCopies:     opts.AllowCrossNamespaceSecrets,
+		defaultStorageClass:   opts.DefaultStorageClass,
+		maxClaimsPerNamespace: opts.MaxOBCsPerNamespace,
+		postProvision:         opts.PostProvision,
+		children:              opts.childOptions(),
+		version:               opts.ProvisionerVersion,
+		callTimeout:           opts.ProvisionerCallTimeout,
+		legacyFinalizers:      opts.LegacyFinalizers,
+	}
+	for name, p := range provisioners {
+		rp := &registeredProvisioner{
+			provisioner: p,
+			metrics: newMetrics(
+				name,
+				ctrl.obcLister,
+				labels.SelectorFromSet(labels.Set{provisionerLabelKey: labelValue(name)})),
+		}
+		if opts.MetricsRegisterer != nil {
+			if err := rp.metrics.register(opts.MetricsRegisterer); err != nil {
+				log.Error(err, "error registering metrics", "provisioner", name)
+			}
+		}
+		if _, ok := p.(api.CredentialRotator); !ok && opts.DeletionQuarantine > 0 {
+			log.Info("provisioner cannot issue new credentials, its buckets are not quarantined", "provisioner", name)
+		}
+		ctrl.provisioners[name] = rp
+	}
+	ctrl.health = newHealthMonitor(ctrl.provisioners, opts.HealthCheckInterval, opts.ProvisionerCallTimeout)
+	if opts.ControllerName != "" {
+		ctrl.managerNames = []string{opts.ControllerName}
+	} else {
+		for name := range provisioners {
+			ctrl.managerNames = append(ctrl.managerNames, name)
+		}
+		sort.Strings(ctrl.managerNames)
 	}
 
+	// a bound claim's configMap follows its OB's endpoint
+	obInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		UpdateFunc: ctrl.updateOB,
+	})
 	obcInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
-		AddFunc: ctrl.enqueueOBC,
-		UpdateFunc: func(old, new interface{}) {
-			oldObc := old.(*v1alpha1.ObjectBucketClaim)
-			newObc := new.(*v1alpha1.ObjectBucketClaim)
-			if newObc.ResourceVersion == oldObc.ResourceVersion {
-				// periodic re-sync can be ignored
-				return
-			}
-			// if old and new both have deletionTimestamps we can also ignore the
-			// update since these events are occurring on an obc marked for deletion,
-			// eg. extra finalizers being added and deleted.
-			if newObc.ObjectMeta.DeletionTimestamp != nil && oldObc.ObjectMeta.DeletionTimestamp != nil {
-				return
-			}
-			// handle this update
-			ctrl.enqueueOBC(new)
-		},
+		AddFunc:    ctrl.enqueueOBC,
+		UpdateFunc: ctrl.updateOBC,
 		DeleteFunc: func(obj interface{}) {
 			// Since a finalizer is added to the obc and thus the obc will remain
 			// visible, we do not need to handle delete events here. Instead, obc
@@ -114,20 +579,40 @@ func (c *obcController) Start(stopCh <-chan struct{}) error {
 	defer utilruntime.HandleCrash()
 	defer c.queue.ShutDown()
 
-	if !cache.WaitForCacheSync(stopCh, c.obcHasSynced, c.obHasSynced) {
+	go c.classInformer.Run(stopCh)
+	if !cache.WaitForCacheSync(stopCh, c.obcHasSynced, c.obHasSynced, c.classHasSynced) {
 		return fmt.Errorf("failed to waith for caches to sync ")
 	}
-	count := 1
-	if threadiness, set := os.LookupEnv("LIB_BUCKET_PROVISIONER_THREADS"); set {
-		count, _ = strconv.Atoi(threadiness)
+	// release the leftovers of interrupted provisioning before any claim is reconciled again
+	for name := range c.provisioners {
+		pc, _ := c.forProvisioner(name)
+		pc.collectOrphans()
 	}
-	for i := 0; i < count; i++ {
-		go wait.Until(c.runWorker, time.Second, stopCh)
+	c.enqueueQuarantined()
+
+	// ctx is cancelled on stop in order to interrupt in-flight retries and provisioner calls
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// the workqueue never hands out a key which is being processed, so a claim's OB, Secret and
+	// ConfigMap are only ever handled by one worker at a time
+	for i := 0; i < c.workers; i++ {
+		go wait.Until(func() { c.runWorker(ctx) }, time.Second, stopCh)
 	}
 	<-stopCh
 	return nil
 }
 
+// RunHealthChecks periodically checks the health of the provisioners implementing api.HealthChecker until stopCh
+// is closed.  Unlike Start, it runs on every replica, so that each reports its own readiness.
+func (c *obcController) RunHealthChecks(stopCh <-chan struct{}) {
+	c.health.run(stopCh)
+}
+
+// Healthy returns an error if the last health check of a provisioner failed, or if it was not checked yet
+func (c *obcController) Healthy() error {
+	return c.health.healthy()
+}
+
 // add provisioner-specific labels to the existing static label in the obcController struct.
 func (c *obcController) SetLabels(labels map[string]string) {
 	for k, v := range labels {
@@ -135,6 +620,45 @@ func (c *obcController) SetLabels(labels map[string]string) {
 	}
 }
 
+// forProvisioner returns a copy of the controller bound to the named provisioner, labelling the resources
+// it creates with the provisioner's name.  ok is false if no provisioner is registered under name.
+func (c *obcController) forProvisioner(name string) (pc *obcController, ok bool) {
+	rp, ok := c.provisioners[name]
+	if !ok {
+		return nil, false
+	}
+	bound := *c
+	bound.provisionerName = name
+	bound.provisioner = rp.provisioner
+	bound.metrics = rp.metrics
+	bound.provisionerLabels = make(map[string]string, len(c.provisionerLabels)+1)
+	for k, v := range c.provisionerLabels {
+		bound.provisionerLabels[k] = v
+	}
+	bound.provisionerLabels[provisionerLabelKey] = labelValue(name)
+	bound.children.fieldManager = fieldManagerFor(name)
+	return &bound, true
+}
+
+// jitteredRateLimiter adds up to factor times the delay of the wrapped rate limiter to each requeue
+type jitteredRateLimiter struct {
+	workqueue.RateLimiter
+	factor float64
+}
+
+// newJitteredRateLimiter returns limiter with jitter added to its delays, or limiter itself if factor is
+// not positive.
+func newJitteredRateLimiter(limiter workqueue.RateLimiter, factor float64) workqueue.RateLimiter {
+	if factor <= 0 {
+		return limiter
+	}
+	return &jitteredRateLimiter{RateLimiter: limiter, factor: factor}
+}
+
+func (r *jitteredRateLimiter) When(item interface{}) time.Duration {
+	return wait.Jitter(r.RateLimiter.When(item), r.factor)
+}
+
 func (c *obcController) enqueueOBC(obj interface{}) {
 	var key string
 	var err error
@@ -145,12 +669,63 @@ func (c *obcController) enqueueOBC(obj interface{}) {
 	c.queue.AddRateLimited(key)
 }
 
-func (c *obcController) runWorker() {
-	for c.processNextItemInQueue() {
+func (c *obcController) updateOBC(old, new interface{}) {
+	oldObc := old.(*v1alpha1.ObjectBucketClaim)
+	newObc := new.(*v1alpha1.ObjectBucketClaim)
+	if newObc.ResourceVersion == oldObc.ResourceVersion {
+		// periodic re-syncs only re-verify bound claims, see ControllerOptions.ResyncPeriod
+		if newObc.Status.Phase == v1alpha1.ObjectBucketClaimStatusPhaseBound && newObc.DeletionTimestamp == nil {
+			c.enqueueOBC(new)
+		}
+		return
+	}
+	// if old and new both have deletionTimestamps we can also ignore the
+	// update since these events are occurring on an obc marked for deletion,
+	// eg. extra finalizers being added and deleted.  A claim deleted while paused, or while managed by another
+	// controller, is cleaned up once resumed or handed back.
+	if newObc.ObjectMeta.DeletionTimestamp != nil && oldObc.ObjectMeta.DeletionTimestamp != nil &&
+		isPaused(newObc) == isPaused(oldObc) &&
+		isManagedBy(newObc, c.managerNames) == isManagedBy(oldObc, c.managerNames) {
+		return
+	}
+	// status updates, mostly our own phase and condition writes, do not need another pass
+	if isStatusOnlyUpdate(oldObc, newObc) {
+		return
+	}
+	// handle this update
+	c.enqueueOBC(new)
+}
+
+// updateOB enqueues the claim of an OB whose endpoint changed, for its configMap to be updated, see
+// handleBoundClaim
+func (c *obcController) updateOB(old, new interface{}) {
+	oldOb := old.(*v1alpha1.ObjectBucket)
+	newOb := new.(*v1alpha1.ObjectBucket)
+	if newOb.ResourceVersion == oldOb.ResourceVersion || newOb.Spec.ClaimRef == nil {
+		return
+	}
+	if equality.Semantic.DeepEqual(oldOb.Spec.Endpoint, newOb.Spec.Endpoint) {
+		return
+	}
+	ref := newOb.Spec.ClaimRef
+	logD.Info("endpoint of OB changed, enqueuing its claim", "ob", newOb.Name, "obc", ref.Namespace+"/"+ref.Name)
+	c.queue.Add(ref.Namespace + "/" + ref.Name)
+}
+
+func (c *obcController) runWorker(ctx context.Context) {
+	for c.processNextItemInQueue(ctx) {
 	}
 }
 
-func (c *obcController) processNextItemInQueue() bool {
+// requeueError is returned by the syncHandler for the claim to be reconciled again after a backoff, or after
+// the given delay if set, when it is waiting on something rather than failing
+type requeueError struct {
+	error
+	after time.Duration
+}
+
+func (c *obcController) processNextItemInQueue(ctx context.Context) bool {
+	log := requestLog(ctx)
 	obj, shutdown := c.queue.Get()
 	if shutdown {
 		return false
@@ -182,9 +757,19 @@ func (c *obcController) processNextItemInQueue() bool {
 		}
 		// Run the syncHandler, passing it the namespace/name string of the
 		// Foo resource to be synced.
-		if err := c.syncHandler(key); err != nil {
+		if err := c.syncHandler(ctx, key); err != nil {
+			if rErr, ok := err.(requeueError); ok && rErr.after > 0 {
+				c.queue.Forget(obj)
+				c.queue.AddAfter(key, rErr.after)
+				log.Info("requeuing", "reason", err.Error(), "after", rErr.after)
+				return nil
+			}
 			// Put the item back on the workqueue to handle any transient errors.
 			c.queue.AddRateLimited(key)
+			if _, ok := err.(requeueError); ok {
+				log.Info("requeuing", "reason", err.Error())
+				return nil
+			}
 			return fmt.Errorf("error syncing '%s': %s, requeuing", key, err.Error())
 		}
 		// Finally, if no error occurs we Forget this item so it does not
@@ -204,245 +789,1431 @@ func (c *obcController) processNextItemInQueue() bool {
 // Note: the obc obtained from the key is not expected to be nil. In other words, this func is
 //   not called when informers detect an object is missing and trigger a formal delete event.
 //   Instead, delete is indicated by the deletionTimestamp being non-nil on an update event.
-func (c *obcController) syncHandler(key string) error {
+func (c *obcController) syncHandler(ctx context.Context, key string) error {
 
-	setLoggersWithRequest(key)
+	ctx = withRequestLogger(ctx, key)
+	log := requestLog(ctx)
+	logD := requestLogD(ctx)
 	logD.Info("reconciling claim")
 
-	obc, err := claimForKey(key, c.libClientset)
+	obc, err := claimForKey(key, c.bucketClient)
 	if err != nil {
 		//      The OBC was deleted immediately after creation, before it could be processed by
 		//      handleProvisionClaim.  As a finalizer is immediately applied to the OBC before processing,
 		//      if it does not have a finalizer, it was not processed, and no artifacts were created.
 		//      Therefore, it is safe to assume nothing needs to be done.
 		if errors.IsNotFound(err) {
+			// a claim deleted during its bucket's quarantine leaves the OB to delete once the quarantine ends
+			if ob, obErr := c.objectBucketForClaimKey(key); obErr == nil && isQuarantined(ob) {
+				return c.handleQuarantinedBucket(ctx, ob)
+			}
 			log.Info("OBC vanished, assuming it was deleted")
 			return nil
 		}
 		return fmt.Errorf("could not sync OBC %s: %v", key, err)
 	}
+	// a quarantined bucket is deleted once its quarantine ends even if its claim was re-created since, e.g. in
+	// another StorageClass or for another controller, as long as the claim did not get the bucket back
+	if obc.Spec.ObjectBucketName == "" && obc.DeletionTimestamp == nil {
+		if ob, obErr := c.objectBucketForClaimKey(key); obErr == nil && isQuarantined(ob) && quarantineEnded(ob, c.quarantine) {
+			if err = c.handleQuarantinedBucket(ctx, ob); err != nil {
+				return err
+			}
+		}
+	}
+	// a claim handed to another controller, e.g. during a migration, is left entirely to it
+	if !isManagedBy(obc, c.managerNames) {
+		log.Info("OBC managed by another controller, skipping reconcile", "managedBy", obc.Annotations[v1alpha1.ManagedByAnnotation])
+		return nil
+	}
+	// a paused claim is left as is, even when deleted, so that no teardown is left half-finished
+	if isPaused(obc) {
+		log.Info("OBC paused, skipping reconcile", "annotation", v1alpha1.PausedAnnotation)
+		return nil
+	}
 
-	class, err := storageClassForClaim(c.clientset, obc)
+	if obc.Spec.StorageClassName == "" {
+		if obc, err = c.handleMissingClassName(ctx, obc); obc == nil || err != nil {
+			return err
+		}
+	}
+	// a cold cache would report every StorageClass as missing
+	if !c.classHasSynced() {
+		return requeueError{error: fmt.Errorf("StorageClass cache not synced yet")}
+	}
+	class, err := storageClassForClaim(c.classLister, obc)
+	if errors.IsNotFound(err) {
+		return c.handleMissingClass(ctx, key, obc)
+	}
 	if err != nil {
 		return err
 	}
-	if !c.supportedProvisioner(class.Provisioner) {
-		log.Info("unsupported provisioner", "got", class.Provisioner)
+	// claims of another provisioner's StorageClass are left to it, without a trace in their status or events
+	pc, ok := c.forProvisioner(class.Provisioner)
+	if !ok {
+		logD.Info("unsupported provisioner, skipping reconcile", "got", class.Provisioner)
 		return nil
 	}
+	// the claim is handled on behalf of its StorageClass's provisioner from here on
+	c = pc
+	c.retry = c.claimRetry(obc)
 
 	// ***********************
 	// Delete or Revoke Bucket
 	// ***********************
 	if obc.ObjectMeta.DeletionTimestamp != nil {
 		log.Info("OBC deleted, proceeding with cleanup")
-		return c.handleDeleteClaim(key, obc)
+		return c.handleDeleteClaim(ctx, key, obc)
+	}
+
+	// ******************
+	// Rotate Credentials
+	// ******************
+	if rotationRequested(obc) {
+		return c.handleRotateClaim(ctx, key, obc)
 	}
 
 	// *******************************************************
 	// Provision New Bucket or Grant Access to Existing Bucket
 	// *******************************************************
 	if !shouldProvision(obc) {
+		switch obc.Status.Phase {
+		case v1alpha1.ObjectBucketClaimStatusPhaseBound:
+			return c.handleBoundClaim(ctx, key, obc)
+		case v1alpha1.ObjectBucketClaimStatusPhaseProvisioning:
+			return c.handleProvisionedClaim(ctx, key, obc)
+		}
 		log.Info("skipping provision")
 		return nil
 	}
 
+	// a dry-run only reports whether the claim would provision, it must not alter the claim otherwise
+	if c.dryRun || isDryRun(obc) {
+		return c.handleDryRunClaim(ctx, obc, class)
+	}
+
+	// a claim re-created while its bucket is quarantined gets the bucket back.  A claim of another StorageClass
+	// cannot, and waits for the end of the quarantine as its OB's name is taken until then.
+	if ob, obErr := c.objectBucketForClaimKey(key); obErr == nil && isQuarantined(ob) {
+		if ob.Spec.StorageClassName == obc.Spec.StorageClassName {
+			return c.handleRestoreClaim(ctx, key, obc, ob)
+		}
+		log.Info("OB of the claim quarantined in another StorageClass, waiting for its deletion", "ob", ob.Name)
+		return c.handleQuarantinedBucket(ctx, ob)
+	}
+
 	// update the OBC's status to pending before any provisioning related errors can occur
 	obc, err = updateObjectBucketClaimPhase(
-		c.libClientset,
+		ctx,
+		c.bucketClient,
+		c.metrics,
 		obc,
 		v1alpha1.ObjectBucketClaimStatusPhasePending,
-		defaultRetryBaseInterval,
-		defaultRetryTimeout)
+		c.retry.interval,
+		c.retry.timeout)
 	if err != nil {
 		return fmt.Errorf("error updating OBC status: %s", err)
 	}
 
 	// By now, we should know that the OBC matches our provisioner, lacks an OB, and thus requires provisioning
-	err = c.handleProvisionClaim(key, obc, class)
+	err = c.handleProvisionClaim(ctx, key, obc, class)
 
 	// If handleReconcile() errors, the request will be re-queued.  In the distant future, we will likely want some ignorable error types in order to skip re-queuing
 	return err
 }
 
-// handleProvision is an extraction of the core provisioning process in order to defer clean up
-// on a provisioning failure
-func (c *obcController) handleProvisionClaim(key string, obc *v1alpha1.ObjectBucketClaim, class *storagev1.StorageClass) error {
-
-	log.Info("syncing obc creation")
-
-	var (
-		ob        *v1alpha1.ObjectBucket
-		secret    *corev1.Secret
-		configMap *corev1.ConfigMap
-		err       error
-	)
-
-	// set finalizer in OBC so that resources cleaned up is controlled when the obc is deleted
-	if err = c.setOBCMetaFields(obc); err != nil {
-		return err
-	}
-
-	// If a storage class contains a non-nil value for the "bucketName" key, it is assumed
-	// to be a Grant request to the given bucket (brownfield).  If the value is nil or the
-	// key is undefined, it is assumed to be a provisioning request.  This allows administrators
-	// to control access to static buckets via RBAC rules on storage classes.
-	isDynamicProvisioning := isNewBucketByStorageClass(class)
-
-	// Should an error be returned, attempt to clean up the object store and API servers by
-	// calling the appropriate provisioner method.  In cases where Provision() or Revoke()
-	// return an err, it's likely that the ob == nil, hindering cleanup.
-	defer func() {
-		if err != nil && ob != nil {
-			log.Info("cleaning up provisioning artifacts")
-			if /*greenfield*/ isDynamicProvisioning && !pErr.IsBucketExists(err) {
-				log.Info("deleting provisioned resources")
-				if dErr := c.provisioner.Delete(ob); dErr != nil {
-					log.Error(dErr, "could not delete provisioned resources")
-				}
-			} else /*brownfield*/ {
-				log.Info("revoking access")
-				if dErr := c.provisioner.Revoke(ob); dErr != nil {
-					log.Error(err, "could not revoke access")
-				}
+// handleMissingClass handles a claim whose StorageClass has been deleted.  A bound claim keeps its bucket
+// and is left alone, a deleted one is cleaned up by the provisioner that labeled it, and a claim not yet
+// provisioned can no longer be and fails.
+func (c *obcController) handleMissingClass(ctx context.Context, key string, obc *v1alpha1.ObjectBucketClaim) (err error) {
+	log := requestLog(ctx)
+	if obc.ObjectMeta.DeletionTimestamp != nil {
+		for name := range c.provisioners {
+			if labelValue(name) != obc.Labels[provisionerLabelKey] {
+				continue
 			}
-			_ = c.deleteResources(ob, configMap, secret, nil)
+			pc, _ := c.forProvisioner(name)
+			log.Info("OBC of a missing StorageClass deleted, proceeding with cleanup")
+			return pc.handleDeleteClaim(ctx, key, obc)
 		}
-	}()
+		log.Info("OBC of a missing StorageClass deleted, not labeled by a supported provisioner")
+		return nil
+	}
 
-	bucketName := class.Parameters[v1alpha1.StorageClassBucket]
-	if isDynamicProvisioning {
-		bucketName, err = composeBucketName(obc)
-		if err != nil {
-			return fmt.Errorf("error composing bucket name: %v", err)
-		}
+	switch obc.Status.Phase {
+	case v1alpha1.ObjectBucketClaimStatusPhaseBound:
+		log.Info("StorageClass of bound OBC not found, skipping", "StorageClass", obc.Spec.StorageClassName)
+		return nil
+	case v1alpha1.ObjectBucketClaimStatusPhaseFailed, v1alpha1.ObjectBucketClaimStatusPhaseReleased:
+		return nil
 	}
-	if len(bucketName) == 0 {
-		return fmt.Errorf("bucket name missing")
+	return c.failClaim(ctx, obc, eventReasonStorageClassNotFound, fmt.Errorf("StorageClass %q not found", obc.Spec.StorageClassName))
+}
+
+// handleMissingClassName handles a claim which does not name its StorageClass.  A claim not yet provisioned is
+// given the default StorageClass, if any, or fails.  A dry-run claim is only given it in memory.  Returns the
+// claim to reconcile further, nil if there is nothing left to do.
+func (c *obcController) handleMissingClassName(ctx context.Context, obc *v1alpha1.ObjectBucketClaim) (*v1alpha1.ObjectBucketClaim, error) {
+	log := requestLog(ctx)
+	if obc.ObjectMeta.DeletionTimestamp != nil {
+		log.Info("OBC without StorageClass deleted, nothing to clean up")
+		return nil, nil
 	}
+	switch obc.Status.Phase {
+	case v1alpha1.ObjectBucketClaimStatusPhaseBound:
+		log.Info("bound OBC names no StorageClass, skipping")
+		return nil, nil
+	case v1alpha1.ObjectBucketClaimStatusPhaseFailed, v1alpha1.ObjectBucketClaimStatusPhaseReleased:
+		return nil, nil
+	}
+	dryRun := c.dryRun || isDryRun(obc)
 
-	// Re-Get the claim in order to shorten the race condition where the claim was deleted after Reconcile() started
-	obc, err = claimForKey(key, c.libClientset)
-	if err != nil {
-		if errors.IsNotFound(err) {
-			return fmt.Errorf("OBC was lost before we could provision: %v", err)
+	if c.defaultStorageClass == "" {
+		cErr := fmt.Errorf("no StorageClass specified and no default StorageClass configured")
+		if dryRun {
+			c.recorder.Eventf(obc, corev1.EventTypeWarning, eventReasonNoStorageClass, "%v", cErr)
+			return nil, nil
 		}
-		return err
+		return nil, c.failClaim(ctx, obc, eventReasonNoStorageClass, cErr)
 	}
 
-	options := &api.BucketOptions{
-		ReclaimPolicy:     class.ReclaimPolicy,
-		BucketName:        bucketName,
-		ObjectBucketClaim: obc.DeepCopy(),
-		Parameters:        class.Parameters,
+	log.Info("OBC names no StorageClass, using the default", "StorageClass", c.defaultStorageClass)
+	obc = obc.DeepCopy()
+	obc.Spec.StorageClassName = c.defaultStorageClass
+	if dryRun {
+		return obc, nil
 	}
-
-	verb := "provisioning"
-	if !isDynamicProvisioning {
-		verb = "granting access to"
+	obc, err := updateClaim(ctx, c.bucketClient, obc, c.retry.interval, c.retry.timeout)
+	if err != nil {
+		return nil, fmt.Errorf("error setting default StorageClass of OBC: %v", err)
 	}
-	logD.Info(verb, "bucket", options.BucketName)
+	c.recorder.Eventf(obc, corev1.EventTypeNormal, eventReasonDefaultStorageClass, "Using default StorageClass %q", c.defaultStorageClass)
+	return obc, nil
+}
 
-	if isDynamicProvisioning {
-		ob, err = c.provisioner.Provision(options)
-	} else {
-		ob, err = c.provisioner.Grant(options)
+// handleDryRunClaim validates the claim and templates its Secret and ConfigMap in memory.  The provisioner
+// is not called and no API objects are created; the outcome is recorded as the claim's DryRun condition.
+func (c *obcController) handleDryRunClaim(ctx context.Context, obc *v1alpha1.ObjectBucketClaim, class *storagev1.StorageClass) error {
+	log := requestLog(ctx)
+	logD := requestLogD(ctx)
+
+	log.Info("validating obc (dry-run)")
+
+	cond := v1alpha1.ObjectBucketClaimCondition{
+		Type:   v1alpha1.ObjectBucketClaimConditionDryRun,
+		Status: corev1.ConditionTrue,
+		Reason: eventReasonDryRunSucceeded,
 	}
-	if err != nil {
-		return fmt.Errorf("error %s bucket: %v", verb, err)
-	} else if ob == (&v1alpha1.ObjectBucket{}) {
-		return fmt.Errorf("provisioner returned nil/empty object bucket")
+	bucketName, vErr := c.dryRunClaim(obc, class)
+	if vErr != nil {
+		log.Info("dry-run failed", "reason", vErr.Error())
+		cond.Status = corev1.ConditionFalse
+		cond.Reason = eventReasonDryRunFailed
+		cond.Message = vErr.Error()
+	} else {
+		cond.Message = fmt.Sprintf("bucket %q would be provisioned", bucketName)
 	}
 
-	// create Secret and ConfigMap
-	secret, err = createSecret(
-		obc,
-		ob.Spec.Authentication,
-		c.provisionerLabels,
-		c.clientset,
-		defaultRetryBaseInterval,
-		defaultRetryTimeout)
-	if err != nil {
-		return fmt.Errorf("error creating secret for OBC: %v", err)
+	obc = obc.DeepCopy()
+	if !setClaimCondition(obc, cond) {
+		logD.Info("dry-run condition unchanged")
+		return nil
 	}
-	configMap, err = createConfigMap(
-		obc,
-		ob.Spec.Endpoint,
-		c.provisionerLabels,
-		c.clientset,
-		defaultRetryBaseInterval,
-		defaultRetryTimeout)
-	if err != nil {
-		return fmt.Errorf("error creating configmap for OBC: %v", err)
+	eventType := corev1.EventTypeNormal
+	if cond.Status != corev1.ConditionTrue {
+		eventType = corev1.EventTypeWarning
 	}
+	c.recorder.Event(obc, eventType, cond.Reason, cond.Message)
 
-	// Create OB
-	// Note: do not move ob create/update calls before secret or vice versa.
-	//   spec.Authentication is lost after create/update, which break secret creation
-	setObjectBucketName(ob, key)
-	ob.Spec.StorageClassName = obc.Spec.StorageClassName
-	ob.Spec.ClaimRef, err = claimRefForKey(key, c.libClientset)
-	ob.Spec.ReclaimPolicy = options.ReclaimPolicy
-	ob.SetFinalizers([]string{finalizer})
-	ob.SetLabels(c.provisionerLabels)
+	_, err := updateClaimStatus(ctx, c.bucketClient, obc, c.retry.interval, c.retry.timeout)
+	return err
+}
 
-	ob, err = createObjectBucket(
-		ob,
-		c.libClientset,
-		defaultRetryBaseInterval,
-		defaultRetryTimeout)
-	if err != nil {
-		return fmt.Errorf("error creating OB %q: %v", ob.Name, err)
+// failClaim moves the claim to the Failed phase, for errors which retrying will not fix, e.g. an invalid spec.
+// err is recorded as the claim's failure message and by a warning event of the given reason.  Returns the error
+// of the status update, if any.
+func (c *obcController) failClaim(ctx context.Context, obc *v1alpha1.ObjectBucketClaim, reason string, err error) error {
+	log := requestLog(ctx)
+	log.Error(err, "failing OBC", "reason", reason)
+	c.recorder.Eventf(obc, corev1.EventTypeWarning, reason, "%v", err)
+	obc.Status.FailureMessage = failureMessage(err)
+	_, uErr := updateObjectBucketClaimPhase(
+		ctx,
+		c.bucketClient,
+		c.metrics,
+		obc,
+		v1alpha1.ObjectBucketClaimStatusPhaseFailed,
+		c.retry.interval,
+		c.retry.timeout)
+	return uErr
+}
+
+// setCondition records the outcome of a provisioning step as a condition on the claim, which is False if
+// stepErr is not nil.  The condition's message is then stepErr's, as given by failureMessage.  Returns the
+// updated claim.  As the step's outcome matters more than its condition, a failure to persist the condition
+// is only logged and the given claim is returned.
+func (c *obcController) setCondition(ctx context.Context, obc *v1alpha1.ObjectBucketClaim, condType v1alpha1.ObjectBucketClaimConditionType, reason string, stepErr error, message string) *v1alpha1.ObjectBucketClaim {
+	log := requestLog(ctx)
+	cond := v1alpha1.ObjectBucketClaimCondition{
+		Type:    condType,
+		Status:  corev1.ConditionTrue,
+		Reason:  reason,
+		Message: message,
 	}
-	ob, err = updateObjectBucketPhase(
-		c.libClientset,
-		ob,
-		v1alpha1.ObjectBucketStatusPhaseBound,
-		defaultRetryBaseInterval,
-		defaultRetryTimeout)
+	if stepErr != nil {
+		cond.Status = corev1.ConditionFalse
+		cond.Message = failureMessage(stepErr)
+	}
+	if !setClaimCondition(obc, cond) {
+		return obc
+	}
+	result, err := updateClaimStatus(ctx, c.bucketClient, obc, c.retry.interval, c.retry.timeout)
 	if err != nil {
-		return fmt.Errorf("error updating OB %q's status to %q: %v", ob.Name, v1alpha1.ObjectBucketStatusPhaseBound, err)
+		log.Error(err, "error updating OBC condition", "type", condType)
+		return obc
 	}
+	return result
+}
 
-	// update OBC
-	obc.Spec.ObjectBucketName = ob.Name
-	obc.Spec.BucketName = bucketName
-	obc, err = updateClaim(
-		c.libClientset,
-		obc,
-		defaultRetryBaseInterval,
-		defaultRetryTimeout)
-	if err != nil {
-		return fmt.Errorf("error updating OBC: %v", err)
+// composeBucketName returns the claim's bucket name.  Claims which do not set one are named by the
+// bucketNameGenerator if set, otherwise as by the composeBucketName function.
+func (c *obcController) composeBucketName(obc *v1alpha1.ObjectBucketClaim, parameters map[string]string) (string, error) {
+	if c.bucketNameGenerator == nil || obc.Spec.BucketName != "" {
+		return composeBucketName(obc, parameters)
 	}
-	obc, err = updateObjectBucketClaimPhase(
-		c.libClientset,
-		obc,
-		v1alpha1.ObjectBucketClaimStatusPhaseBound,
-		defaultRetryBaseInterval,
-		defaultRetryTimeout)
+	name, err := c.bucketNameGenerator(obc.DeepCopy())
 	if err != nil {
-		return fmt.Errorf("error updating OBC %q's status to: %v", v1alpha1.ObjectBucketClaimStatusPhaseBound, err)
+		return "", fmt.Errorf("error generating bucket name: %v", err)
 	}
+	return name, nil
+}
 
-	log.Info("provisioning succeeded")
-	return nil
+// validatesBucketName returns true if the claim's new bucket name must follow the S3 naming rules.  Names
+// returned by the bucketNameGenerator always must.
+func (c *obcController) validatesBucketName(obc *v1alpha1.ObjectBucketClaim) bool {
+	return c.validateBucketNames || (c.bucketNameGenerator != nil && obc.Spec.BucketName == "")
 }
 
-// Delete or Revoke access to bucket defined by passed-in key and obc.
-func (c *obcController) handleDeleteClaim(key string, obc *v1alpha1.ObjectBucketClaim) error {
-	// Call `Delete` for new (greenfield) buckets with reclaimPolicy == "Delete".
-	// Call `Revoke` for new buckets with reclaimPolicy != "Delete".
-	// Call `Revoke` for existing (brownfield) buckets regardless of reclaimPolicy.
+// resolveService returns the host and port of the Service the storage class's service parameter refers to, see
+// serviceEndpoint, or an empty host if the parameter is unset.  A missing Service is returned as the NotFound error
+// of the Get.
+func (c *obcController) resolveService(parameters map[string]string) (string, int, error) {
+	namespace, name, err := parseService(parameters)
+	if err != nil || name == "" {
+		return "", 0, err
+	}
+	svc, err := c.clientset.CoreV1().Services(namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		return "", 0, err
+	}
+	return serviceEndpoint(svc, parameters[v1alpha1.StorageClassServicePort])
+}
 
-	log.Info("syncing obc deletion")
+// dryRunClaim runs the checks and templating of handleProvisionClaim which do not depend on the
+// provisioner, returning the bucket name the claim would be bound to.
+func (c *obcController) dryRunClaim(obc *v1alpha1.ObjectBucketClaim, class *storagev1.StorageClass) (string, error) {
+	isDynamicProvisioning := isNewBucketByStorageClass(class) && obc.Spec.ExistingBucketName == ""
+	parameters := c.parameters(class)
 
-	ob, cm, secret, errs := c.getExistingResourcesFromKey(key)
-	if len(errs) > 0 {
-		return fmt.Errorf("error getting resources: %v", errs)
+	bucketName := class.Parameters[v1alpha1.StorageClassBucket]
+	if bucketName == "" {
+		bucketName = obc.Spec.ExistingBucketName
 	}
-
-	// Delete/Revoke cannot be called if the ob is nil; however, if the secret
+	if isDynamicProvisioning {
+		var err error
+		bucketName, err = c.composeBucketName(obc, parameters)
+		if err != nil {
+			return "", fmt.Errorf("error composing bucket name: %v", err)
+		}
+		if c.validatesBucketName(obc) {
+			if err = validateBucketName(bucketName); err != nil {
+				return "", fmt.Errorf("invalid bucket name %q: %v", bucketName, err)
+			}
+		}
+	}
+	if len(bucketName) == 0 {
+		return "", fmt.Errorf("bucket name missing")
+	}
+	if err := validateExistingBucketName(obc, class, bucketName); err != nil {
+		return "", err
+	}
+	if _, err := parseQuota(obc, parameters); err != nil {
+		return "", err
+	}
+	if _, err := parseVersioning(obc, parameters); err != nil {
+		return "", err
+	}
+	if _, _, err := c.resolveService(parameters); err != nil {
+		return "", err
+	}
+	if err := c.validateParameters(class.Parameters); err != nil {
+		return "", err
+	}
+	if _, err := parseTags(obc); err != nil {
+		return "", err
+	}
+	if _, _, err := parseBucketPolicy(parameters); err != nil {
+		return "", err
+	}
+	if err := validateBucketSubPath(obc.Spec.BucketSubPath); err != nil {
+		return "", err
+	}
+	if err := validateSecretNamespaces(obc, c.allowSecretCopies); err != nil {
+		return "", err
+	}
+	if err := checkSecretCopyConsent(obc, c.clientset); err != nil {
+		return "", err
+	}
+	if err := checkReservedKeys(obc.Spec.ExtraConfigMapData, reservedKeys(c.children.keyNames)); err != nil {
+		return "", err
+	}
+	if c.maxClaimsPerNamespace > 0 {
+		n, err := c.claimsInNamespace(obc)
+		if err != nil {
+			return "", err
+		}
+		if n >= c.maxClaimsPerNamespace {
+			return "", fmt.Errorf("namespace %q already holds %d OBCs, the maximum", obc.Namespace, n)
+		}
+	}
+	if err := c.objectBucketNameConflict(obc); err != nil {
+		return "", err
+	}
+	if err := c.secretNameConflict(obc); err != nil {
+		return "", err
+	}
+
+	ep := &v1alpha1.Endpoint{BucketName: bucketName}
+	setEndpointDefaults(ep, parameters)
+	// the credentials are only known to the provisioner
+	auth := &v1alpha1.Authentication{}
+	if c.children.credentialsReference {
+		auth.SecretReference = &v1alpha1.SecretReference{Path: "dry-run"}
+	}
+	if _, err := newCredentialsSecret(obc, auth, c.provisionerLabels, c.annotationPrefixes, c.children); err != nil {
+		return "", err
+	}
+	if _, err := newBucketConfigMap(obc, ep, c.provisionerLabels, c.annotationPrefixes, c.children); err != nil {
+		return "", err
+	}
+	return bucketName, nil
+}
+
+// parameters returns the class's parameters, completed with the provisioner's defaults if it implements
+// api.ParameterDefaulter
+func (c *obcController) parameters(class *storagev1.StorageClass) map[string]string {
+	pd, ok := c.provisioner.(api.ParameterDefaulter)
+	if !ok {
+		return class.Parameters
+	}
+	return mergeParameters(pd.DefaultParameters(), class.Parameters)
+}
+
+// validateParameters checks the storage class parameters against the provisioner's schema, if it declares
+// one.  Unknown parameters are an error for strict schemas and are logged otherwise.
+func (c *obcController) validateParameters(parameters map[string]string) error {
+	sp, ok := c.provisioner.(api.ParameterSchemaProvider)
+	if !ok {
+		return nil
+	}
+	schema := sp.ParameterSchema()
+	unknown := unknownParameters(parameters, schema)
+	if len(unknown) == 0 {
+		return nil
+	}
+	if schema.Strict {
+		return fmt.Errorf("unknown parameters %s", strings.Join(unknown, ", "))
+	}
+	log.Info("ignoring unknown storage class parameters", "keys", unknown)
+	return nil
+}
+
+// claimsInNamespace counts the other claims of obc's namespace which hold a bucket, i.e. are bound or
+// being provisioned.  The claims are listed from the informer's cache rather than the API server, on every
+// provisioning.
+func (c *obcController) claimsInNamespace(obc *v1alpha1.ObjectBucketClaim) (int, error) {
+	obcs, err := c.obcLister.ObjectBucketClaims(obc.Namespace).List(labels.Everything())
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, other := range obcs {
+		if other.Name == obc.Name {
+			continue
+		}
+		switch other.Status.Phase {
+		case v1alpha1.ObjectBucketClaimStatusPhaseBound, v1alpha1.ObjectBucketClaimStatusPhaseProvisioning:
+			n++
+		}
+	}
+	return n, nil
+}
+
+// callProvisioner runs call, a call to the provisioner's method of the given name, with the controller's call
+// timeout if set.  A call still running at the deadline is abandoned, reported by a warning event on obj, and a
+// requeueError is returned, so that the worker is released even if the provisioner ignores the cancellation of
+// its context.
+func (c *obcController) callProvisioner(ctx context.Context, obj runtime.Object, method string, call func(ctx context.Context) error) error {
+	log := requestLog(ctx)
+	if c.callTimeout <= 0 {
+		return call(ctx)
+	}
+	ctx, cancel := context.WithTimeout(ctx, c.callTimeout)
+	defer cancel()
+	// buffered so that an abandoned call does not block on returning
+	done := make(chan error, 1)
+	go func() {
+		done <- call(ctx)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+	}
+	if ctx.Err() != context.DeadlineExceeded {
+		return ctx.Err()
+	}
+	err := fmt.Errorf("provisioner's %s did not return within %v", method, c.callTimeout)
+	log.Error(err, "abandoning provisioner call")
+	c.recorder.Eventf(obj, corev1.EventTypeWarning, eventReasonProvisionerTimeout, "%v", err)
+	return requeueError{error: err}
+}
+
+// provision calls the provisioner's ProvisionConnection if it implements api.ConnectionProvisioner, returning
+// an OB of the connection, and its Provision otherwise
+func (c *obcController) provision(ctx context.Context, options *api.BucketOptions) (*v1alpha1.ObjectBucket, error) {
+	cp, ok := c.provisioner.(api.ConnectionProvisioner)
+	if !ok {
+		var ob *v1alpha1.ObjectBucket
+		err := c.callProvisioner(ctx, options.ObjectBucketClaim, "Provision", func(ctx context.Context) (err error) {
+			ob, err = c.provisioner.Provision(ctx, options)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		return ob, nil
+	}
+	var conn *v1alpha1.Connection
+	err := c.callProvisioner(ctx, options.ObjectBucketClaim, "ProvisionConnection", func(ctx context.Context) (err error) {
+		conn, err = cp.ProvisionConnection(ctx, options)
+		return err
+	})
+	if err != nil || conn == nil {
+		return nil, err
+	}
+	return &v1alpha1.ObjectBucket{Spec: v1alpha1.ObjectBucketSpec{Connection: conn}}, nil
+}
+
+// grant calls the provisioner's GrantConnection if it implements api.GrantingProvisioner and the OBC names an
+// existing bucket, returning an OB of the connection, and its Grant otherwise
+func (c *obcController) grant(ctx context.Context, options *api.BucketOptions) (*v1alpha1.ObjectBucket, error) {
+	gp, ok := c.provisioner.(api.GrantingProvisioner)
+	if !ok || options.ObjectBucketClaim.Spec.ExistingBucketName == "" {
+		var ob *v1alpha1.ObjectBucket
+		err := c.callProvisioner(ctx, options.ObjectBucketClaim, "Grant", func(ctx context.Context) (err error) {
+			ob, err = c.provisioner.Grant(ctx, options)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		return ob, nil
+	}
+	var conn *v1alpha1.Connection
+	err := c.callProvisioner(ctx, options.ObjectBucketClaim, "GrantConnection", func(ctx context.Context) (err error) {
+		conn, err = gp.GrantConnection(ctx, options)
+		return err
+	})
+	if err != nil || conn == nil {
+		return nil, err
+	}
+	// the bucket name is known to the library, the provisioner need not repeat it
+	if conn.Endpoint != nil && conn.Endpoint.BucketName == "" {
+		conn.Endpoint.BucketName = options.BucketName
+	}
+	return &v1alpha1.ObjectBucket{Spec: v1alpha1.ObjectBucketSpec{Connection: conn}}, nil
+}
+
+// revoke calls the provisioner's RevokeConnection if the OBC's access was granted by GrantConnection, as recorded
+// on the OB, and its Revoke otherwise
+func (c *obcController) revoke(ctx context.Context, obc *v1alpha1.ObjectBucketClaim, ob *v1alpha1.ObjectBucket) error {
+	gp, ok := c.provisioner.(api.GrantingProvisioner)
+	if !ok || !isExistingBucketOfClaim(ob) {
+		return c.callProvisioner(ctx, obc, "Revoke", func(ctx context.Context) error {
+			return c.provisioner.Revoke(ctx, ob)
+		})
+	}
+	return c.callProvisioner(ctx, obc, "RevokeConnection", func(ctx context.Context) error {
+		return gp.RevokeConnection(ctx, ob)
+	})
+}
+
+// deleteBucket calls the provisioner's Delete, reporting a timeout on obj
+func (c *obcController) deleteBucket(ctx context.Context, obj runtime.Object, ob *v1alpha1.ObjectBucket) error {
+	return c.callProvisioner(ctx, obj, "Delete", func(ctx context.Context) error {
+		return c.provisioner.Delete(ctx, ob)
+	})
+}
+
+// claimRetry returns the controller's retry backoff, with the timeout of the claim's retry-timeout annotation
+// if set.  An invalid timeout is reported by a warning event and ignored.
+func (c *obcController) claimRetry(obc *v1alpha1.ObjectBucketClaim) retryBackoff {
+	value, ok := obc.Annotations[v1alpha1.RetryTimeoutAnnotation]
+	if !ok {
+		return c.retry
+	}
+	timeout, err := time.ParseDuration(value)
+	if err == nil && timeout <= 0 {
+		err = fmt.Errorf("must be positive")
+	}
+	if err != nil {
+		log.Info("ignoring invalid retry timeout", "value", value, "error", err.Error())
+		c.recorder.Eventf(obc, corev1.EventTypeWarning, eventReasonInvalidRetryTimeout, "Ignoring invalid %s annotation %q: %v", v1alpha1.RetryTimeoutAnnotation, value, err)
+		return c.retry
+	}
+	retry := c.retry
+	retry.timeout = timeout
+	return retry
+}
+
+// objectBucketNameConflict returns an error if the claim's OB name is taken by the OB of another claim.  OB
+// names join the claim's namespace and name, so that e.g. the OBCs "a-b/c" and "a/b-c" collide.
+func (c *obcController) objectBucketNameConflict(obc *v1alpha1.ObjectBucketClaim) error {
+	ob, err := c.objectBucketForClaimKey(obc.Namespace + "/" + obc.Name)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error getting OB: %v", err)
+	}
+	if refersToClaim(ob, makeObjectReference(obc)) {
+		return nil
+	}
+	return &bucketNameInUseError{name: ob.Name, claim: ob.Spec.ClaimRef}
+}
+
+// bucketNameInUseError reports the claim holding an OB name, see objectBucketNameConflict
+type bucketNameInUseError struct {
+	name  string
+	claim *corev1.ObjectReference
+}
+
+func (e *bucketNameInUseError) Error() string {
+	return fmt.Sprintf("bucket name already in use: ObjectBucket %q belongs to OBC %s/%s", e.name, e.claim.Namespace, e.claim.Name)
+}
+
+// secretNameConflict returns an error if the claim's secret name is taken by a secret which the claim does not
+// own, e.g. the secret of another claim naming the same spec.secretName.  Updating it would overwrite the other
+// claim's credentials.
+func (c *obcController) secretNameConflict(obc *v1alpha1.ObjectBucketClaim) error {
+	secret, err := secretForClaim(obc, c.clientset)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error getting secret: %v", err)
+	}
+	if isOwnedByClaim(secret, obc) {
+		return nil
+	}
+	return newSecretInUseError(secret)
+}
+
+// secretInUseError reports an existing secret of the claim's secret name which the claim does not own, see
+// secretNameConflict
+type secretInUseError struct {
+	name string
+	// claim is the namespace/name of the claim owning the secret, empty if it is not owned by a claim
+	claim string
+}
+
+func newSecretInUseError(secret *corev1.Secret) *secretInUseError {
+	e := &secretInUseError{name: secret.Name, claim: secret.Annotations[claimAnnotation]}
+	for _, ref := range secret.OwnerReferences {
+		if e.claim == "" && ref.Kind == v1alpha1.ObjectBucketClaimKind {
+			e.claim = secret.Namespace + "/" + ref.Name
+		}
+	}
+	return e
+}
+
+func (e *secretInUseError) Error() string {
+	if e.claim == "" {
+		return fmt.Sprintf("secret name already in use: Secret %q is not owned by an OBC", e.name)
+	}
+	return fmt.Sprintf("secret name already in use: Secret %q belongs to OBC %s", e.name, e.claim)
+}
+
+// handleProvision is an extraction of the core provisioning process in order to defer clean up
+// on a provisioning failure
+func (c *obcController) handleProvisionClaim(ctx context.Context, key string, obc *v1alpha1.ObjectBucketClaim, class *storagev1.StorageClass) error {
+	log := requestLog(ctx)
+	logD := requestLogD(ctx)
+
+	log.Info("syncing obc creation")
+
+	var (
+		ob        *v1alpha1.ObjectBucket
+		secret    *corev1.Secret
+		configMap *corev1.ConfigMap
+		err       error
+	)
+
+	// set finalizer in OBC so that resources cleaned up is controlled when the obc is deleted
+	if err = c.setOBCMetaFields(ctx, obc); err != nil {
+		return err
+	}
+
+	// If a storage class contains a non-nil value for the "bucketName" key, it is assumed
+	// to be a Grant request to the given bucket (brownfield).  If the value is nil or the
+	// key is undefined, it is assumed to be a provisioning request.  This allows administrators
+	// to control access to static buckets via RBAC rules on storage classes.  An OBC may also name
+	// an existing bucket itself, see ExistingBucketName.
+	isDynamicProvisioning := isNewBucketByStorageClass(class) && obc.Spec.ExistingBucketName == ""
+	parameters := c.parameters(class)
+
+	// Should an error be returned, attempt to clean up the object store and API servers by
+	// calling the appropriate provisioner method.  In cases where Provision() or Revoke()
+	// return an err, it's likely that the ob == nil, hindering cleanup.
+	defer func() {
+		if err != nil && ob != nil {
+			log.Info("cleaning up provisioning artifacts")
+			if /*greenfield*/ isDynamicProvisioning && !pErr.IsBucketExists(err) {
+				log.Info("deleting provisioned resources")
+				if dErr := c.deleteBucket(ctx, obc, ob); dErr != nil {
+					log.Error(dErr, "could not delete provisioned resources")
+				}
+			} else /*brownfield*/ {
+				log.Info("revoking access")
+				if dErr := c.revoke(ctx, obc, ob); dErr != nil {
+					log.Error(err, "could not revoke access")
+				}
+			}
+			if secret != nil {
+				if dErr := deleteSecretCopies(obc, secretCopyNamespaces(obc), c.legacyFinalizers, c.clientset); dErr != nil {
+					log.Error(dErr, "could not delete secret copies")
+				}
+			}
+			_ = c.deleteResources(ob, configMap, secret, nil)
+		}
+	}()
+
+	bucketName := class.Parameters[v1alpha1.StorageClassBucket]
+	if bucketName == "" {
+		bucketName = obc.Spec.ExistingBucketName
+	}
+	if isDynamicProvisioning {
+		bucketName, err = c.composeBucketName(obc, parameters)
+		if err != nil {
+			return fmt.Errorf("error composing bucket name: %v", err)
+		}
+	}
+	if len(bucketName) == 0 {
+		return fmt.Errorf("bucket name missing")
+	}
+
+	// Re-Get the claim in order to shorten the race condition where the claim was deleted after Reconcile() started
+	obc, err = claimForKey(key, c.bucketClient)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return fmt.Errorf("OBC was lost before we could provision: %v", err)
+		}
+		return err
+	}
+
+	// An invalid bucket name will not become valid by retrying, so fail the claim instead of requeuing it
+	if isDynamicProvisioning && c.validatesBucketName(obc) {
+		if vErr := validateBucketName(bucketName); vErr != nil {
+			return c.failClaim(ctx, obc, eventReasonInvalidBucketName, fmt.Errorf("invalid bucket name %q: %v", bucketName, vErr))
+		}
+	}
+
+	// Nor will an existing bucket the storage class does not grant access to
+	if eErr := validateExistingBucketName(obc, class, bucketName); eErr != nil {
+		return c.failClaim(ctx, obc, eventReasonInvalidBucketName, eErr)
+	}
+
+	// Neither will an invalid quota
+	quota, qErr := parseQuota(obc, parameters)
+	if qErr != nil {
+		return c.failClaim(ctx, obc, eventReasonInvalidQuota, fmt.Errorf("invalid quota: %v", qErr))
+	}
+
+	// Nor will an invalid versioning toggle
+	versioning, vErr := parseVersioning(obc, parameters)
+	if vErr != nil {
+		return c.failClaim(ctx, obc, eventReasonInvalidParameters, fmt.Errorf("invalid versioning: %v", vErr))
+	}
+
+	// Nor will a missing Service to resolve the bucket host from, or an invalid reference to it
+	svcHost, svcPort, sErr := c.resolveService(parameters)
+	switch _, isAPIErr := sErr.(errors.APIStatus); {
+	case errors.IsNotFound(sErr):
+		return c.failClaim(ctx, obc, eventReasonServiceNotFound, fmt.Errorf("%s %q: %v", v1alpha1.StorageClassService, parameters[v1alpha1.StorageClassService], sErr))
+	case isAPIErr:
+		return fmt.Errorf("error getting %s %q: %v", v1alpha1.StorageClassService, parameters[v1alpha1.StorageClassService], sErr)
+	case sErr != nil:
+		return c.failClaim(ctx, obc, eventReasonInvalidParameters, fmt.Errorf("invalid StorageClass parameters: %v", sErr))
+	}
+
+	// Nor will unknown parameters, if the provisioner is strict about them
+	if pErr := c.validateParameters(class.Parameters); pErr != nil {
+		return c.failClaim(ctx, obc, eventReasonInvalidParameters, fmt.Errorf("invalid StorageClass parameters: %v", pErr))
+	}
+
+	// Nor will invalid tags
+	tags, tErr := parseTags(obc)
+	if tErr != nil {
+		return c.failClaim(ctx, obc, eventReasonInvalidTags, fmt.Errorf("invalid %s annotation: %v", v1alpha1.TagsAnnotation, tErr))
+	}
+
+	// Nor will a bucket policy which is not JSON
+	policy, policyChecksum, bpErr := parseBucketPolicy(parameters)
+	if bpErr != nil {
+		return c.failClaim(ctx, obc, eventReasonInvalidBucketPolicy, fmt.Errorf("invalid StorageClass parameters: %v", bpErr))
+	}
+
+	// Nor will a subpath escaping the bucket
+	if sErr := validateBucketSubPath(obc.Spec.BucketSubPath); sErr != nil {
+		return c.failClaim(ctx, obc, eventReasonInvalidSubPath, fmt.Errorf("invalid bucket subpath: %v", sErr))
+	}
+
+	// Nor will secret copies the provisioner does not allow
+	if nErr := validateSecretNamespaces(obc, c.allowSecretCopies); nErr != nil {
+		return c.failClaim(ctx, obc, eventReasonInvalidSecretNamespaces, fmt.Errorf("invalid additional secret namespaces: %v", nErr))
+	}
+
+	// Nor will secret copies into namespaces which do not accept them
+	cErr := checkSecretCopyConsent(obc, c.clientset)
+	switch _, isAPIErr := cErr.(errors.APIStatus); {
+	case isAPIErr:
+		return fmt.Errorf("error getting additional secret namespaces: %v", cErr)
+	case cErr != nil:
+		return c.failClaim(ctx, obc, eventReasonInvalidSecretNamespaces, fmt.Errorf("invalid additional secret namespaces: %v", cErr))
+	}
+
+	// Nor will extra configmap data overriding the library's keys
+	if kErr := checkReservedKeys(obc.Spec.ExtraConfigMapData, reservedKeys(c.children.keyNames)); kErr != nil {
+		return c.failClaim(ctx, obc, eventReasonInvalidConfigData, fmt.Errorf("invalid extra configmap data: %v", kErr))
+	}
+
+	// Nor will claims over their namespace's limit
+	if c.maxClaimsPerNamespace > 0 {
+		n, cErr := c.claimsInNamespace(obc)
+		if cErr != nil {
+			return fmt.Errorf("error counting OBCs in namespace %q: %v", obc.Namespace, cErr)
+		}
+		if n >= c.maxClaimsPerNamespace {
+			return c.failClaim(ctx, obc, eventReasonNamespaceLimitExceeded,
+				fmt.Errorf("namespace %q already holds %d OBCs, the maximum", obc.Namespace, n))
+		}
+	}
+
+	// Nor will an OB name held by another claim, which createObjectBucket would only fail on after provisioning
+	if cErr := c.objectBucketNameConflict(obc); cErr != nil {
+		if _, inUse := cErr.(*bucketNameInUseError); !inUse {
+			return cErr
+		}
+		return c.failClaim(ctx, obc, eventReasonBucketNameInUse, cErr)
+	}
+
+	// Nor will a secret of another claim, whose credentials createSecret must not overwrite
+	if cErr := c.secretNameConflict(obc); cErr != nil {
+		if _, inUse := cErr.(*secretInUseError); !inUse {
+			return cErr
+		}
+		return c.failClaim(ctx, obc, eventReasonSecretInUse, cErr)
+	}
+
+	options := &api.BucketOptions{
+		ReclaimPolicy:     class.ReclaimPolicy,
+		BucketName:        bucketName,
+		ObjectBucketClaim: obc.DeepCopy(),
+		Parameters:        parameters,
+		Quota:             quota,
+		Tags:              tags,
+		SubPath:           obc.Spec.BucketSubPath,
+		BucketPolicy:      policy,
+		Versioning:        versioning && isDynamicProvisioning,
+	}
+
+	verb := "provisioning"
+	if !isDynamicProvisioning {
+		verb = "granting access to"
+	}
+	logD.Info(verb, "bucket", options.BucketName)
+
+	obc, err = updateObjectBucketClaimPhase(
+		ctx,
+		c.bucketClient,
+		c.metrics,
+		obc,
+		v1alpha1.ObjectBucketClaimStatusPhaseProvisioning,
+		c.retry.interval,
+		c.retry.timeout)
+	if err != nil {
+		return fmt.Errorf("error updating OBC status: %v", err)
+	}
+
+	start := time.Now()
+	if isDynamicProvisioning {
+		ob, err = c.provision(ctx, options)
+	} else {
+		ob, err = c.grant(ctx, options)
+	}
+	c.metrics.observeProvision(time.Since(start), err)
+	if err != nil {
+		obc.Status.FailureMessage = failureMessage(err)
+		c.setCondition(ctx, obc, v1alpha1.ObjectBucketClaimConditionBucketReady, eventReasonBucketProvisionFailed, err, "")
+		return fmt.Errorf("error %s bucket: %v", verb, err)
+	} else if ob == nil || ob.Spec.Connection == nil || ob.Spec.Endpoint == nil {
+		emptyErr := fmt.Errorf("provisioner returned nil/empty object bucket")
+		obc.Status.FailureMessage = failureMessage(emptyErr)
+		c.setCondition(ctx, obc, v1alpha1.ObjectBucketClaimConditionBucketReady, eventReasonBucketProvisionFailed, emptyErr, "")
+		return emptyErr
+	}
+	if !isDynamicProvisioning && options.ObjectBucketClaim.Spec.ExistingBucketName != "" {
+		// the claim's spec may be edited, so the OB records that its bucket was named by the claim, by which
+		// the bucket is revoked rather than deleted
+		if ob.Annotations == nil {
+			ob.Annotations = map[string]string{}
+		}
+		ob.Annotations[v1alpha1.ExistingBucketAnnotation] = "true"
+	}
+	if isDynamicProvisioning {
+		// record the chosen bucket name, which may have been generated, if the provisioner did not
+		if ob.Spec.Endpoint.BucketName == "" {
+			ob.Spec.Endpoint.BucketName = bucketName
+		}
+		setEndpointDefaults(ob.Spec.Endpoint, parameters)
+		// record whether versioning was requested, the ConfigMap reporting it
+		ob.Spec.Endpoint.Versioning = options.Versioning
+	}
+	// the Service fronting the object store takes precedence over the host and port the provisioner returned
+	if svcHost != "" {
+		ob.Spec.Endpoint.BucketHost = svcHost
+		ob.Spec.Endpoint.BucketPort = svcPort
+	}
+	// the endpoint is only known to the provisioner and is written verbatim, so an incomplete one will not be
+	// fixed by retrying, nor should the claim be bound to a useless ConfigMap
+	if err = validateEndpoint(ob.Spec.Endpoint, !isDynamicProvisioning); err != nil {
+		err = fmt.Errorf("invalid endpoint for bucket %q: %v", bucketName, err)
+		obc = c.setCondition(ctx, obc, v1alpha1.ObjectBucketClaimConditionBucketReady, eventReasonInvalidEndpoint, err, "")
+		if uErr := c.failClaim(ctx, obc, eventReasonInvalidEndpoint, err); uErr != nil {
+			log.Error(uErr, "error updating OBC status")
+		}
+		return err
+	}
+	obc.Status.FailureMessage = ""
+	obc = c.setCondition(ctx, obc, v1alpha1.ObjectBucketClaimConditionBucketReady, eventReasonBucketProvisioned, nil,
+		fmt.Sprintf("%s bucket %q succeeded", verb, ob.Spec.Endpoint.BucketName))
+
+	// create Secret and ConfigMap, the Secret first unless configured otherwise
+	createSecretStep := func() error {
+		secret, err = createSecret(
+			ctx,
+			obc,
+			ob.Spec.Authentication,
+			c.provisionerLabels,
+			c.annotationPrefixes,
+			c.children,
+			c.clientset,
+			c.applier,
+			c.retry)
+		if _, inUse := err.(*secretInUseError); inUse {
+			// the secret was created since secretNameConflict, the provisioned bucket is cleaned up and the
+			// claim failed as it would have been then
+			if uErr := c.failClaim(ctx, obc, eventReasonSecretInUse, err); uErr != nil {
+				log.Error(uErr, "error updating OBC status")
+			}
+			return fmt.Errorf("error creating secret for OBC: %v", err)
+		}
+		if err != nil {
+			c.recorder.Eventf(obc, corev1.EventTypeWarning, eventReasonSecretCreateFailed, "Error creating Secret: %v", err)
+			c.setCondition(ctx, obc, v1alpha1.ObjectBucketClaimConditionSecretReady, eventReasonSecretCreateFailed, err, "")
+			return fmt.Errorf("error creating secret for OBC: %v", err)
+		}
+		if err = createSecretCopies(ctx, obc, secret, c.clientset, c.retry); err != nil {
+			c.recorder.Eventf(obc, corev1.EventTypeWarning, eventReasonSecretCopyFailed, "Error copying Secret: %v", err)
+			c.setCondition(ctx, obc, v1alpha1.ObjectBucketClaimConditionSecretReady, eventReasonSecretCopyFailed, err, "")
+			return fmt.Errorf("error copying secret for OBC: %v", err)
+		}
+		if obc, err = c.recordSecretCopies(ctx, obc); err != nil {
+			return err
+		}
+		c.recorder.Eventf(obc, corev1.EventTypeNormal, eventReasonSecretCreated, "Created Secret %q", secret.Name)
+		obc = c.setCondition(ctx, obc, v1alpha1.ObjectBucketClaimConditionSecretReady, eventReasonSecretCreated, nil,
+			fmt.Sprintf("created Secret %q", secret.Name))
+		return nil
+	}
+	createConfigMapStep := func() error {
+		configMap, err = createConfigMap(
+			ctx,
+			obc,
+			ob.Spec.Endpoint,
+			c.provisionerLabels,
+			c.annotationPrefixes,
+			c.children,
+			c.clientset,
+			c.applier,
+			c.retry)
+		if err != nil {
+			c.recorder.Eventf(obc, corev1.EventTypeWarning, eventReasonConfigMapCreateFailed, "Error creating ConfigMap: %v", err)
+			c.setCondition(ctx, obc, v1alpha1.ObjectBucketClaimConditionConfigMapReady, eventReasonConfigMapCreateFailed, err, "")
+			return fmt.Errorf("error creating configmap for OBC: %v", err)
+		}
+		c.recorder.Eventf(obc, corev1.EventTypeNormal, eventReasonConfigMapCreated, "Created ConfigMap %q", configMap.Name)
+		obc = c.setCondition(ctx, obc, v1alpha1.ObjectBucketClaimConditionConfigMapReady, eventReasonConfigMapCreated, nil,
+			fmt.Sprintf("created ConfigMap %q", configMap.Name))
+		return nil
+	}
+	steps := []func() error{createSecretStep, createConfigMapStep}
+	if c.children.configMapFirst {
+		steps[0], steps[1] = steps[1], steps[0]
+	}
+	for _, step := range steps {
+		if err = step(); err != nil {
+			return err
+		}
+	}
+
+	// Create OB
+	// Note: do not move ob create/update calls before secret or vice versa.
+	//   spec.Authentication is lost after create/update, which break secret creation
+	setObjectBucketName(ob, key)
+	ob.Spec.StorageClassName = obc.Spec.StorageClassName
+	ob.Spec.ClaimRef, err = claimRefForKey(key, c.bucketClient)
+	ob.Spec.ReclaimPolicy = options.ReclaimPolicy
+	ob.Spec.Quota = options.Quota
+	ob.Spec.Tags = options.Tags
+	ob.Spec.BucketPolicyChecksum = policyChecksum
+	ob.SetFinalizers([]string{finalizer})
+	ob.SetLabels(c.provisionerLabels)
+
+	ob, err = createObjectBucket(
+		ctx,
+		ob,
+		c.bucketClient,
+		c.retry,
+		c.version)
+	if err != nil {
+		c.recorder.Eventf(obc, corev1.EventTypeWarning, eventReasonObjectBucketCreateFailed, "Error creating ObjectBucket: %v", err)
+		return fmt.Errorf("error creating OB %q: %v", ob.Name, err)
+	}
+	c.recorder.Eventf(obc, corev1.EventTypeNormal, eventReasonObjectBucketCreated, "Created ObjectBucket %q", ob.Name)
+	if c.postProvision != nil {
+		if err = c.postProvision(obc.DeepCopy(), ob.DeepCopy(), configMap.DeepCopy(), secret.DeepCopy()); err != nil {
+			c.recorder.Eventf(obc, corev1.EventTypeWarning, eventReasonPostProvisionFailed, "Post-provision hook failed: %v", err)
+			return fmt.Errorf("post-provision hook failed: %v", err)
+		}
+	}
+	// an OB adopted from an interrupted reconcile keeps its original provisioning time
+	if ob.Status.ProvisionedAt == nil {
+		now := metav1.Now()
+		ob.Status.ProvisionedAt = &now
+		ob.Status.ProvisionDuration = &metav1.Duration{Duration: now.Sub(start)}
+	}
+	ob, err = updateObjectBucketPhase(
+		ctx,
+		c.bucketClient,
+		ob,
+		v1alpha1.ObjectBucketStatusPhaseBound,
+		c.retry.interval,
+		c.retry.timeout)
+	if err != nil {
+		return fmt.Errorf("error updating OB %q's status to %q: %v", ob.Name, v1alpha1.ObjectBucketStatusPhaseBound, err)
+	}
+
+	// update OBC
+	obc.Spec.ObjectBucketName = ob.Name
+	obc.Spec.BucketName = bucketName
+	obc, err = updateClaim(
+		ctx,
+		c.bucketClient,
+		obc,
+		c.retry.interval,
+		c.retry.timeout)
+	if err != nil {
+		return fmt.Errorf("error updating OBC: %v", err)
+	}
+	log.Info("provisioning succeeded")
+
+	// a bucket which is not usable yet is bound by a later reconcile, see handleProvisionedClaim.  rErr is kept
+	// apart from err as the provisioned resources must not be cleaned up.
+	var rErr error
+	if obc, rErr = c.checkBucketReady(ctx, obc, ob); rErr != nil {
+		return rErr
+	}
+	return c.bindClaim(ctx, obc, ob)
+}
+
+// checkBucketReady asks a provisioner implementing api.ReadinessChecker whether the claim's new bucket is
+// usable.  If not, the claim's BucketReady condition is set to False and a requeueError is returned.  Returns
+// the updated claim.
+func (c *obcController) checkBucketReady(ctx context.Context, obc *v1alpha1.ObjectBucketClaim, ob *v1alpha1.ObjectBucket) (*v1alpha1.ObjectBucketClaim, error) {
+	checker, ok := c.provisioner.(api.ReadinessChecker)
+	if !ok {
+		return obc, nil
+	}
+	var (
+		ready bool
+		after time.Duration
+	)
+	err := c.callProvisioner(ctx, obc, "IsReady", func(ctx context.Context) (err error) {
+		ready, after, err = checker.IsReady(ctx, ob.DeepCopy())
+		return err
+	})
+	if err != nil {
+		err = fmt.Errorf("error checking readiness of bucket %q: %v", obc.Spec.BucketName, err)
+		obc = c.setCondition(ctx, obc, v1alpha1.ObjectBucketClaimConditionBucketReady, eventReasonBucketNotReady, err, "")
+		return obc, requeueError{error: err}
+	}
+	if !ready {
+		err = fmt.Errorf("bucket %q not ready yet", obc.Spec.BucketName)
+		obc = c.setCondition(ctx, obc, v1alpha1.ObjectBucketClaimConditionBucketReady, eventReasonBucketNotReady, err, "")
+		return obc, requeueError{error: err, after: after}
+	}
+	return obc, nil
+}
+
+// bindClaim moves the claim to the Bound phase once its bucket is provisioned and usable.
+func (c *obcController) bindClaim(ctx context.Context, obc *v1alpha1.ObjectBucketClaim, ob *v1alpha1.ObjectBucket) (err error) {
+	// the new credentials satisfy any rotation requested before the claim was bound
+	obc.Status.LastRotation = obc.Annotations[v1alpha1.RotateAnnotation]
+	obc, err = updateObjectBucketClaimPhase(
+		ctx,
+		c.bucketClient,
+		c.metrics,
+		obc,
+		v1alpha1.ObjectBucketClaimStatusPhaseBound,
+		c.retry.interval,
+		c.retry.timeout)
+	if err != nil {
+		return fmt.Errorf("error updating OBC %q's status to: %v", v1alpha1.ObjectBucketClaimStatusPhaseBound, err)
+	}
+	c.recorder.Eventf(obc, corev1.EventTypeNormal, eventReasonBound, "Bound to ObjectBucket %q", ob.Name)
+	return nil
+}
+
+// handleProvisionedClaim binds a claim whose bucket and resources were provisioned but which was not bound,
+// e.g. as its bucket was not ready yet, see api.ReadinessChecker.
+func (c *obcController) handleProvisionedClaim(ctx context.Context, key string, obc *v1alpha1.ObjectBucketClaim) error {
+	log := requestLog(ctx)
+	logD := requestLogD(ctx)
+
+	logD.Info("checking provisioned obc's bucket readiness")
+	ob, err := c.objectBucketForClaimKey(key)
+	if errors.IsNotFound(err) {
+		log.Info("OB of provisioned OBC not found, skipping")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error getting OB of OBC: %v", err)
+	}
+	ready := obc.DeepCopy()
+	if ready, err = c.checkBucketReady(ctx, ready, ob); err != nil {
+		return err
+	}
+	ready = c.setCondition(ctx, ready, v1alpha1.ObjectBucketClaimConditionBucketReady, eventReasonBucketProvisioned, nil,
+		fmt.Sprintf("bucket %q is ready", obc.Spec.BucketName))
+	return c.bindClaim(ctx, ready, ob)
+}
+
+// handleBoundClaim corrects the drift of the bound claim's configMap, e.g. a manually edited BUCKET_HOST, or
+// updates it after a change of its OB's endpoint, e.g. by a provisioner migrating the bucket to a new host.
+// The secret cannot be checked as the credentials are only ever stored in the secret itself.
+func (c *obcController) handleBoundClaim(ctx context.Context, key string, obc *v1alpha1.ObjectBucketClaim) error {
+	log := requestLog(ctx)
+	logD := requestLogD(ctx)
+
+	logD.Info("checking bound obc's configmap for drift")
+	ob, err := c.objectBucketForClaimKey(key)
+	if errors.IsNotFound(err) {
+		log.Info("OB of bound OBC not found, skipping drift check")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error getting OB of bound OBC: %v", err)
+	}
+	// OBs created by earlier versions of the library are brought up to date
+	if claim := makeObjectReference(obc); refersToClaim(ob, claim) {
+		if ob, err = migrateObjectBucket(ctx, ob, claim, c.bucketClient, c.retry); err != nil {
+			return fmt.Errorf("error migrating OB of bound OBC: %v", err)
+		}
+	}
+	if obc, err = c.restoreConfigMap(ctx, obc, ob); err != nil {
+		return err
+	}
+	if obc, err = c.restoreSecret(ctx, obc, ob); err != nil {
+		return err
+	}
+	if obc, err = c.pruneSecretCopies(ctx, obc); err != nil {
+		return err
+	}
+	configMap, drift, err := reconcileConfigMap(
+		ctx,
+		obc,
+		ob.Spec.Endpoint,
+		c.provisionerLabels,
+		c.annotationPrefixes,
+		c.children,
+		c.clientset,
+		c.retry)
+	if errors.IsNotFound(err) {
+		log.Info("configmap of bound OBC not found, skipping drift check")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error reconciling configmap of bound OBC: %v", err)
+	}
+	switch drift {
+	case configMapDrifted:
+		c.recorder.Eventf(obc, corev1.EventTypeNormal, eventReasonConfigMapUpdated, "Reconciled drifted ConfigMap %q", configMap.Name)
+	case configMapEndpointChanged:
+		log.Info("updated configmap of bound OBC to the OB's endpoint", "configMap", configMap.Name, "ob", ob.Name)
+		c.recorder.Eventf(obc, corev1.EventTypeNormal, eventReasonEndpointChanged, "Updated ConfigMap %q to the endpoint of ObjectBucket %q", configMap.Name, ob.Name)
+	}
+	return nil
+}
+
+// restoreConfigMap recreates the configmap of a bound claim from its OB's endpoint if it was deleted
+// out-of-band.  A configmap being deleted is released and the claim requeued, so that it is recreated once
+// gone.  Returns the updated claim.
+func (c *obcController) restoreConfigMap(ctx context.Context, obc *v1alpha1.ObjectBucketClaim, ob *v1alpha1.ObjectBucket) (*v1alpha1.ObjectBucketClaim, error) {
+	log := requestLog(ctx)
+	configMap, err := configMapForClaim(obc, c.clientset)
+	if err == nil && configMap.DeletionTimestamp == nil {
+		return obc, nil
+	}
+	if err != nil && !errors.IsNotFound(err) {
+		return obc, fmt.Errorf("error getting configmap of bound OBC: %v", err)
+	}
+	if err == nil {
+		if err = releaseConfigMap(configMap, c.legacyFinalizers, c.clientset); err != nil {
+			return obc, fmt.Errorf("error releasing deleted configmap of bound OBC: %v", err)
+		}
+		return obc, fmt.Errorf("configmap %q of bound OBC is being deleted, requeueing", configMap.Name)
+	}
+	if ob.Spec.Endpoint == nil {
+		log.Info("configmap of bound OBC not found and OB has no endpoint, skipping restore")
+		return obc, nil
+	}
+
+	log.Info("recreating missing configmap of bound OBC")
+	configMap, err = createConfigMap(
+		ctx,
+		obc,
+		ob.Spec.Endpoint,
+		c.provisionerLabels,
+		c.annotationPrefixes,
+		c.children,
+		c.clientset,
+		c.applier,
+		c.retry)
+	if err != nil {
+		c.recorder.Eventf(obc, corev1.EventTypeWarning, eventReasonConfigMapCreateFailed, "Error recreating ConfigMap: %v", err)
+		return obc, fmt.Errorf("error recreating configmap of bound OBC: %v", err)
+	}
+	c.recorder.Eventf(obc, corev1.EventTypeWarning, eventReasonConfigMapRecreated, "Recreated missing ConfigMap %q", configMap.Name)
+	return c.setCondition(ctx, obc, v1alpha1.ObjectBucketClaimConditionConfigMapReady, eventReasonConfigMapRecreated, nil,
+		fmt.Sprintf("recreated ConfigMap %q", configMap.Name)), nil
+}
+
+// restoreSecret recreates the secret of a bound claim if it was deleted out-of-band.  The credentials are
+// not stored on the OB, so new ones are issued by the provisioner if it is a CredentialRotator.  Otherwise
+// the claim's SecretReady condition is set to False, as only the provisioner's administrator can restore
+// the secret.  Returns the updated claim.
+func (c *obcController) restoreSecret(ctx context.Context, obc *v1alpha1.ObjectBucketClaim, ob *v1alpha1.ObjectBucket) (*v1alpha1.ObjectBucketClaim, error) {
+	log := requestLog(ctx)
+	secret, err := secretForClaim(obc, c.clientset)
+	if err == nil && secret.DeletionTimestamp == nil {
+		return obc, nil
+	}
+	if err != nil && !errors.IsNotFound(err) {
+		return obc, fmt.Errorf("error getting secret of bound OBC: %v", err)
+	}
+	if err == nil {
+		if err = releaseSecret(secret, c.legacyFinalizers, c.clientset); err != nil {
+			return obc, fmt.Errorf("error releasing deleted secret of bound OBC: %v", err)
+		}
+		return obc, fmt.Errorf("secret %q of bound OBC is being deleted, requeueing", secret.Name)
+	}
+
+	name := secretNameForClaim(obc)
+	rotator, ok := c.provisioner.(api.CredentialRotator)
+	if !ok {
+		mErr := fmt.Errorf("secret %q is missing and the provisioner cannot issue new credentials", name)
+		log.Error(mErr, "cannot restore secret of bound OBC")
+		c.recorder.Event(obc, corev1.EventTypeWarning, eventReasonSecretMissing, mErr.Error())
+		return c.setCondition(ctx, obc, v1alpha1.ObjectBucketClaimConditionSecretReady, eventReasonSecretMissing, mErr, ""), nil
+	}
+
+	log.Info("recreating missing secret of bound OBC with new credentials")
+	auth, err := c.rotateCredentials(ctx, rotator, obc, ob)
+	if err != nil {
+		c.recorder.Eventf(obc, corev1.EventTypeWarning, eventReasonSecretCreateFailed, "Error issuing credentials for missing Secret: %v", err)
+		return obc, fmt.Errorf("provisioner error issuing credentials for missing secret: %v", err)
+	}
+	secret, err = createSecret(
+		ctx,
+		obc,
+		auth,
+		c.provisionerLabels,
+		c.annotationPrefixes,
+		c.children,
+		c.clientset,
+		c.applier,
+		c.retry)
+	if err != nil {
+		c.recorder.Eventf(obc, corev1.EventTypeWarning, eventReasonSecretCreateFailed, "Error recreating Secret: %v", err)
+		return obc, fmt.Errorf("error recreating secret of bound OBC: %v", err)
+	}
+	// the copies hold the credentials of the lost secret
+	if err = createSecretCopies(ctx, obc, secret, c.clientset, c.retry); err != nil {
+		c.recorder.Eventf(obc, corev1.EventTypeWarning, eventReasonSecretCopyFailed, "Error updating Secret copies: %v", err)
+		return obc, fmt.Errorf("error updating secret copies of bound OBC: %v", err)
+	}
+	if obc, err = c.recordSecretCopies(ctx, obc); err != nil {
+		return obc, err
+	}
+	c.recorder.Eventf(obc, corev1.EventTypeWarning, eventReasonSecretRecreated, "Recreated missing Secret %q with new credentials", secret.Name)
+	return c.setCondition(ctx, obc, v1alpha1.ObjectBucketClaimConditionSecretReady, eventReasonSecretRecreated, nil,
+		fmt.Sprintf("recreated Secret %q with new credentials", secret.Name)), nil
+}
+
+// recordSecretCopies records in the claim's status that its secret was copied into its additional secret
+// namespaces, so that the copies are deleted once a namespace is removed from its spec, see pruneSecretCopies.
+// Returns the updated claim.
+func (c *obcController) recordSecretCopies(ctx context.Context, obc *v1alpha1.ObjectBucketClaim) (*v1alpha1.ObjectBucketClaim, error) {
+	namespaces := secretCopyNamespaces(obc)
+	if sets.NewString(namespaces...).Equal(sets.NewString(obc.Status.SecretCopyNamespaces...)) {
+		return obc, nil
+	}
+	obc.Status.SecretCopyNamespaces = namespaces
+	result, err := updateClaimStatus(ctx, c.bucketClient, obc, c.retry.interval, c.retry.timeout)
+	if err != nil {
+		return obc, fmt.Errorf("error recording secret copies of OBC: %v", err)
+	}
+	return result, nil
+}
+
+// pruneSecretCopies deletes the copies of the bound claim's secret in the namespaces removed from its
+// spec.additionalSecretNamespaces since the secret was copied into them.  Returns the updated claim.
+func (c *obcController) pruneSecretCopies(ctx context.Context, obc *v1alpha1.ObjectBucketClaim) (*v1alpha1.ObjectBucketClaim, error) {
+	log := requestLog(ctx)
+	stale := staleSecretCopyNamespaces(obc)
+	if len(stale) == 0 {
+		return obc, nil
+	}
+	log.Info("deleting secret copies of removed additional secret namespaces", "namespaces", stale)
+	if err := deleteSecretCopies(obc, stale, c.legacyFinalizers, c.clientset); err != nil {
+		return obc, fmt.Errorf("error deleting secret copies of bound OBC: %v", err)
+	}
+	for _, ns := range stale {
+		c.recorder.Eventf(obc, corev1.EventTypeNormal, eventReasonSecretCopyDeleted, "Deleted Secret copy from namespace %q", ns)
+	}
+	obc.Status.SecretCopyNamespaces = sets.NewString(obc.Status.SecretCopyNamespaces...).Delete(stale...).List()
+	result, err := updateClaimStatus(ctx, c.bucketClient, obc, c.retry.interval, c.retry.timeout)
+	if err != nil {
+		return obc, fmt.Errorf("error recording secret copies of OBC: %v", err)
+	}
+	return result, nil
+}
+
+// handleRotateClaim replaces the credentials in the claim's secret with new ones issued by the provisioner.
+// The previous credentials are revoked only once the secret is updated, so that pods reading the secret
+// never see revoked credentials.  The rotation is recorded in the claim's status before the revocation, a
+// failed revocation is reported but not retried.
+func (c *obcController) handleRotateClaim(ctx context.Context, key string, obc *v1alpha1.ObjectBucketClaim) error {
+	log := requestLog(ctx)
+
+	log.Info("rotating credentials")
+	rotation := obc.Annotations[v1alpha1.RotateAnnotation]
+
+	rotator, ok := c.provisioner.(api.CredentialRotator)
+	if !ok {
+		c.recorder.Event(obc, corev1.EventTypeWarning, eventReasonRotationUnsupported, "Provisioner does not support credentials rotation")
+		return nil
+	}
+	ob, err := c.objectBucketForClaimKey(key)
+	if err != nil {
+		return fmt.Errorf("error getting OB for rotation: %v", err)
+	}
+	secret, err := secretForClaim(obc, c.clientset)
+	if err != nil {
+		return fmt.Errorf("error getting secret for rotation: %v", err)
+	}
+	previous := secretData(secret)
+
+	auth, err := c.rotateCredentials(ctx, rotator, obc, ob)
+	if err != nil {
+		c.recorder.Eventf(obc, corev1.EventTypeWarning, eventReasonRotationFailed, "Error rotating credentials: %v", err)
+		return fmt.Errorf("provisioner error rotating credentials: %v", err)
+	}
+	if secret, err = updateSecret(ctx, secret, auth, c.children, c.clientset, c.retry); err != nil {
+		c.recorder.Eventf(obc, corev1.EventTypeWarning, eventReasonRotationFailed, "Error updating Secret: %v", err)
+		return fmt.Errorf("error updating secret with rotated credentials: %v", err)
+	}
+	if err = createSecretCopies(ctx, obc, secret, c.clientset, c.retry); err != nil {
+		c.recorder.Eventf(obc, corev1.EventTypeWarning, eventReasonRotationFailed, "Error updating Secret copies: %v", err)
+		return fmt.Errorf("error updating secret copies with rotated credentials: %v", err)
+	}
+	if obc, err = c.recordSecretCopies(ctx, obc); err != nil {
+		return err
+	}
+
+	now := metav1.Now()
+	obc.Status.LastRotation = rotation
+	obc.Status.LastRotationTime = &now
+	obc, err = updateClaimStatus(ctx, c.bucketClient, obc, c.retry.interval, c.retry.timeout)
+	if err != nil {
+		return fmt.Errorf("error recording credentials rotation: %v", err)
+	}
+
+	err = c.callProvisioner(ctx, obc, "RevokeCredentials", func(ctx context.Context) error {
+		return rotator.RevokeCredentials(ctx, ob, previous)
+	})
+	if err != nil {
+		c.recorder.Eventf(obc, corev1.EventTypeWarning, eventReasonRotationFailed, "Error revoking previous credentials: %v", err)
+		return fmt.Errorf("provisioner error revoking previous credentials: %v", err)
+	}
+	c.recorder.Eventf(obc, corev1.EventTypeNormal, eventReasonCredentialsRotated, "Rotated credentials in Secret %q", secret.Name)
+	log.Info("credentials rotated")
+	return nil
+}
+
+// rotateCredentials calls the rotator's RotateCredentials, reporting a timeout on obc
+func (c *obcController) rotateCredentials(ctx context.Context, rotator api.CredentialRotator, obc *v1alpha1.ObjectBucketClaim, ob *v1alpha1.ObjectBucket) (*v1alpha1.Authentication, error) {
+	var auth *v1alpha1.Authentication
+	err := c.callProvisioner(ctx, obc, "RotateCredentials", func(ctx context.Context) (err error) {
+		auth, err = rotator.RotateCredentials(ctx, ob)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return auth, nil
+}
+
+// Delete or Revoke access to bucket defined by passed-in key and obc.
+func (c *obcController) handleDeleteClaim(ctx context.Context, key string, obc *v1alpha1.ObjectBucketClaim) error {
+	log := requestLog(ctx)
+	// Call `Delete` for new (greenfield) buckets with reclaimPolicy == "Delete".
+	// Call `Revoke` for new buckets with reclaimPolicy != "Delete".
+	// Call `Revoke` for existing (brownfield) buckets regardless of reclaimPolicy.
+	// Keep the OB, in the Released phase, when reclaimPolicy == "Retain".
+	// Call `Revoke` and keep the OB, released from the claim, when the claim's keep annotation is set.
+	// Defer `Delete`, keeping the OB in the Quarantined phase, when a quarantine period is set.
+
+	log.Info("syncing obc deletion")
+
+	ob, cm, secret, errs := c.getExistingResourcesForClaim(key, obc)
+	if len(errs) > 0 {
+		return fmt.Errorf("error getting resources: %v", errs)
+	}
+
+	// Delete/Revoke cannot be called if the ob is nil; however, if the secret
 	// and/or cm != nil we can delete them
 	if ob == nil {
 		log.Error(nil, "nil ObjectBucket, assuming it has been deleted")
@@ -454,35 +2225,194 @@ func (c *obcController) handleDeleteClaim(key string, obc *v1alpha1.ObjectBucket
 		return nil
 	}
 
+	// decide whether Delete or Revoke is called
+	deletesBucket := !keepsObjectBucket(obc) && !isExistingBucketOfClaim(ob) && isNewBucketByObjectBucket(c.classLister, ob) &&
+		*ob.Spec.ReclaimPolicy == corev1.PersistentVolumeReclaimDelete
+	if deletesBucket && c.quarantines() {
+		return c.quarantineClaim(ctx, obc, ob, cm, secret)
+	}
+
 	// call Delete or Revoke and then delete generated k8s resources
 	// Note: if Delete or Revoke return err then we do not try to delete resources
-	ob, err := updateObjectBucketPhase(c.libClientset, ob, v1alpha1.ObjectBucketClaimStatusPhaseReleased, defaultRetryBaseInterval, defaultRetryTimeout)
+	// Note: a Released OB with a Retain reclaimPolicy is not deleted
+	ob, err := updateObjectBucketPhase(ctx, c.bucketClient, ob, v1alpha1.ObjectBucketStatusPhaseReleased, c.retry.interval, c.retry.timeout)
 	if err != nil {
+		// the OB vanished since it was read, e.g. removed by a concurrent reconcile: there is nothing left to delete
+		if errors.IsNotFound(err) {
+			log.Info("ObjectBucket vanished, assuming it has been deleted")
+			return c.deleteResources(nil, cm, secret, obc)
+		}
 		return err
 	}
 
-	// decide whether Delete or Revoke is called
-	if isNewBucketByObjectBucket(c.clientset, ob) && *ob.Spec.ReclaimPolicy == corev1.PersistentVolumeReclaimDelete {
-		if err = c.provisioner.Delete(ob); err != nil {
+	start := time.Now()
+	if deletesBucket {
+		err = c.deleteBucket(ctx, obc, ob)
+		c.metrics.observeDelete(time.Since(start), err)
+		if pErr.IsDeleteNotReady(err) {
+			// the bucket cannot be deleted yet, e.g. it still holds objects: the claim is kept terminating, with
+			// its finalizer, until it can
+			c.recorder.Eventf(obc, corev1.EventTypeNormal, eventReasonDeletionPending, "Bucket not ready for deletion: %v", err)
+			return requeueError{error: fmt.Errorf("bucket not ready for deletion: %v", err)}
+		}
+		if err != nil {
 			// Do not proceed to deleting the ObjectBucket if the deprovisioning fails for bookkeeping purposes
-			return fmt.Errorf("provisioner error deleting bucket %v", err)
+			return c.handleDeleteFailure(ctx, obc, ob, cm, secret, fmt.Errorf("provisioner error deleting bucket %v", err))
 		}
 	} else {
-		if err = c.provisioner.Revoke(ob); err != nil {
-			return fmt.Errorf("provisioner error revoking access to bucket %v", err)
+		err = c.revoke(ctx, obc, ob)
+		c.metrics.observeDelete(time.Since(start), err)
+		if err != nil {
+			return c.handleDeleteFailure(ctx, obc, ob, cm, secret, fmt.Errorf("provisioner error revoking access to bucket %v", err))
+		}
+	}
+
+	return c.deleteClaimResources(obc, ob, cm, secret)
+}
+
+// quarantines returns true if the deletion of the claims' buckets is deferred, see quarantineClaim.  A claim
+// restored from quarantine needs new credentials, its secret having been deleted, so that the buckets of a
+// provisioner which is not a CredentialRotator are never quarantined.
+func (c *obcController) quarantines() bool {
+	_, ok := c.provisioner.(api.CredentialRotator)
+	return ok && c.quarantine > 0
+}
+
+// quarantineClaim defers the deletion of the deleted claim's bucket until the quarantine period has passed.  The
+// OB is kept, in the Quarantined phase, while the claim's other resources are released so that the claim goes
+// away and can be re-created, which cancels the deletion, see handleRestoreClaim.  The claim's key is requeued
+// for the end of the quarantine, see handleQuarantinedBucket.
+func (c *obcController) quarantineClaim(ctx context.Context, obc *v1alpha1.ObjectBucketClaim, ob *v1alpha1.ObjectBucket, cm *corev1.ConfigMap, secret *corev1.Secret) error {
+	log := requestLog(ctx)
+	if !isQuarantined(ob) {
+		name := ob.Name
+		now := metav1.Now()
+		ob.Status.QuarantinedAt = &now
+		var err error
+		ob, err = updateObjectBucketPhase(ctx, c.bucketClient, ob, v1alpha1.ObjectBucketStatusPhaseQuarantined, c.retry.interval, c.retry.timeout)
+		if errors.IsNotFound(err) {
+			log.Info("ObjectBucket vanished, assuming it has been deleted")
+			return c.deleteResources(nil, cm, secret, obc)
+		}
+		if err != nil {
+			return fmt.Errorf("error quarantining ObjectBucket %q: %v", name, err)
+		}
+	}
+
+	end := quarantineEnd(ob, c.quarantine)
+	log.Info("quarantining bucket", "ob", ob.Name, "until", end)
+	c.recorder.Eventf(obc, corev1.EventTypeNormal, eventReasonBucketQuarantined, "Bucket deletion deferred until %s, re-create the claim to cancel it", end.Format(time.RFC3339))
+	if err := c.deleteResources(nil, cm, secret, obc); err != nil {
+		return err
+	}
+	return requeueError{error: fmt.Errorf("bucket quarantined until %s", end.Format(time.RFC3339)), after: time.Until(end)}
+}
+
+// handleQuarantinedBucket deletes the quarantined bucket of a deleted claim, by the provisioner which labeled its
+// OB, once the quarantine has ended.  The claim's key is requeued until then, whether or not the claim was
+// re-created since.
+func (c *obcController) handleQuarantinedBucket(ctx context.Context, ob *v1alpha1.ObjectBucket) error {
+	log := requestLog(ctx)
+	end := quarantineEnd(ob, c.quarantine)
+	if remaining := time.Until(end); remaining > 0 {
+		return requeueError{error: fmt.Errorf("bucket quarantined until %s", end.Format(time.RFC3339)), after: remaining}
+	}
+	for name := range c.provisioners {
+		if labelValue(name) != ob.Labels[provisionerLabelKey] {
+			continue
+		}
+		pc, _ := c.forProvisioner(name)
+		log.Info("quarantine ended, deleting bucket", "ob", ob.Name)
+		start := time.Now()
+		err := pc.deleteBucket(ctx, ob, ob)
+		pc.metrics.observeDelete(time.Since(start), err)
+		if pErr.IsDeleteNotReady(err) {
+			return requeueError{error: fmt.Errorf("bucket not ready for deletion: %v", err)}
 		}
+		if err != nil {
+			return fmt.Errorf("provisioner error deleting quarantined bucket %v", err)
+		}
+		return deleteObjectBucket(ob, pc.legacyFinalizers, pc.bucketClient)
+	}
+	log.Info("quarantined ObjectBucket not labeled by a supported provisioner, skipping", "ob", ob.Name)
+	return nil
+}
+
+// handleRestoreClaim cancels the deletion of the quarantined bucket of a re-created claim by binding its OB to
+// the new claim.  The claim's ConfigMap and Secret are then recreated as for any bound claim, see
+// handleBoundClaim.
+func (c *obcController) handleRestoreClaim(ctx context.Context, key string, obc *v1alpha1.ObjectBucketClaim, ob *v1alpha1.ObjectBucket) (err error) {
+	log := requestLog(ctx)
+	log.Info("claim re-created, cancelling the deletion of its quarantined bucket", "ob", ob.Name)
+	if ob, err = migrateObjectBucket(ctx, ob, makeObjectReference(obc), c.bucketClient, c.retry); err != nil {
+		return fmt.Errorf("error binding quarantined OB to OBC: %v", err)
+	}
+	ob.Status.QuarantinedAt = nil
+	if ob, err = updateObjectBucketPhase(ctx, c.bucketClient, ob, v1alpha1.ObjectBucketStatusPhaseBound, c.retry.interval, c.retry.timeout); err != nil {
+		return fmt.Errorf("error updating OB %q's status to %q: %v", ob.Name, v1alpha1.ObjectBucketStatusPhaseBound, err)
+	}
+
+	obc.SetFinalizers([]string{finalizer})
+	obc.SetLabels(childLabels(obc, c.provisionerLabels))
+	obc.Spec.ObjectBucketName = ob.Name
+	if ob.Spec.Endpoint != nil {
+		obc.Spec.BucketName = ob.Spec.Endpoint.BucketName
+	}
+	if obc, err = updateClaim(ctx, c.bucketClient, obc, c.retry.interval, c.retry.timeout); err != nil {
+		return fmt.Errorf("error updating OBC: %v", err)
+	}
+	c.recorder.Eventf(obc, corev1.EventTypeNormal, eventReasonQuarantineCancelled, "Cancelled the deletion of quarantined ObjectBucket %q", ob.Name)
+	if err = c.bindClaim(ctx, obc, ob); err != nil {
+		return err
 	}
+	return c.handleBoundClaim(ctx, key, obc)
+}
 
-	return c.deleteResources(ob, cm, secret, obc)
+// deleteClaimResources deletes the resources of the deleted claim, but for its OB which is only released when
+// the claim's keep annotation is set.
+func (c *obcController) deleteClaimResources(obc *v1alpha1.ObjectBucketClaim, ob *v1alpha1.ObjectBucket, cm *corev1.ConfigMap, secret *corev1.Secret) error {
+	if !keepsObjectBucket(obc) {
+		return c.deleteResources(ob, cm, secret, obc)
+	}
+	if err := releaseObjectBucket(ob, c.legacyFinalizers, c.bucketClient); err != nil {
+		return fmt.Errorf("error releasing ObjectBucket %q: %v", ob.Name, err)
+	}
+	log.Info("keeping released ObjectBucket", "name", ob.Name)
+	c.recorder.Eventf(obc, corev1.EventTypeNormal, eventReasonObjectBucketKept, "Kept ObjectBucket %q, released from the claim", ob.Name)
+	return c.deleteResources(nil, cm, secret, obc)
 }
 
-func (c *obcController) supportedProvisioner(provisioner string) bool {
-	return provisioner == c.provisionerName
+// handleDeleteFailure returns the provisioner's deletion error, so that the deletion is retried, until the
+// claim has been deleted for longer than the deletion timeout.  The failure is then reported by the claim's
+// DeletionFailed condition and, if forced deletion is enabled, the claim's resources are released anyway.
+func (c *obcController) handleDeleteFailure(ctx context.Context, obc *v1alpha1.ObjectBucketClaim, ob *v1alpha1.ObjectBucket, cm *corev1.ConfigMap, secret *corev1.Secret, deleteErr error) error {
+	log := requestLog(ctx)
+	if c.deletionTimeout == 0 || time.Since(obc.DeletionTimestamp.Time) < c.deletionTimeout {
+		return deleteErr
+	}
+
+	c.recorder.Eventf(obc, corev1.EventTypeWarning, eventReasonDeletionFailed, "Bucket cleanup failing for more than %v: %v", c.deletionTimeout, deleteErr)
+	if setClaimCondition(obc, v1alpha1.ObjectBucketClaimCondition{
+		Type:    v1alpha1.ObjectBucketClaimConditionDeletionFailed,
+		Status:  corev1.ConditionTrue,
+		Reason:  eventReasonDeletionFailed,
+		Message: failureMessage(deleteErr),
+	}) {
+		if _, err := updateClaimStatus(ctx, c.bucketClient, obc, c.retry.interval, c.retry.timeout); err != nil {
+			log.Error(err, "error updating OBC condition", "type", v1alpha1.ObjectBucketClaimConditionDeletionFailed)
+		}
+	}
+	if !c.forceDeletion {
+		return deleteErr
+	}
+
+	log.Error(deleteErr, "deletion timed out, removing finalizers anyway, the bucket may be orphaned", "ob", ob.Name)
+	return c.deleteClaimResources(obc, ob, cm, secret)
 }
 
 // trim the errors resulting from objects not being found
-func (c *obcController) getExistingResourcesFromKey(key string) (*v1alpha1.ObjectBucket, *corev1.ConfigMap, *corev1.Secret, []error) {
-	ob, cm, secret, errs := c.getResourcesFromKey(key)
+func (c *obcController) getExistingResourcesForClaim(key string, obc *v1alpha1.ObjectBucketClaim) (*v1alpha1.ObjectBucket, *corev1.ConfigMap, *corev1.Secret, []error) {
+	ob, cm, secret, errs := c.getResourcesForClaim(key, obc)
 	for i := len(errs) - 1; i >= 0; i-- {
 		if errors.IsNotFound(errs[i]) {
 			errs = append(errs[:i], errs[i+1:]...)
@@ -491,13 +2421,13 @@ func (c *obcController) getExistingResourcesFromKey(key string) (*v1alpha1.Objec
 	return ob, cm, secret, errs
 }
 
-// Gathers resources by names derived from key.
+// Gathers resources by names derived from key and the claim's spec.
 // Returns pointers to those resources if they exist, nil otherwise and an slice of errors who's
 // len() == n errors. If no errors occur, len() is 0.
-func (c *obcController) getResourcesFromKey(key string) (ob *v1alpha1.ObjectBucket, cm *corev1.ConfigMap, sec *corev1.Secret, errs []error) {
+func (c *obcController) getResourcesForClaim(key string, obc *v1alpha1.ObjectBucketClaim) (ob *v1alpha1.ObjectBucket, cm *corev1.ConfigMap, sec *corev1.Secret, errs []error) {
 
 	var err error
-	// The cap(errs) must be large enough to encapsulate errors returned by all 3 *ForClaimKey funcs
+	// The cap(errs) must be large enough to encapsulate errors returned by all 3 *ForClaim* funcs
 	errs = make([]error, 0, 3)
 	groupErrors := func(err error) {
 		if err != nil {
@@ -507,9 +2437,9 @@ func (c *obcController) getResourcesFromKey(key string) (ob *v1alpha1.ObjectBuck
 
 	ob, err = c.objectBucketForClaimKey(key)
 	groupErrors(err)
-	cm, err = configMapForClaimKey(key, c.clientset)
+	cm, err = configMapForClaim(obc, c.clientset)
 	groupErrors(err)
-	sec, err = secretForClaimKey(key, c.clientset)
+	sec, err = secretForClaim(obc, c.clientset)
 	groupErrors(err)
 
 	return
@@ -525,40 +2455,122 @@ func (c *obcController) getResourcesFromKey(key string) (ob *v1alpha1.ObjectBuck
 // somewhat arbitrary.
 func (c *obcController) deleteResources(ob *v1alpha1.ObjectBucket, cm *corev1.ConfigMap, s *corev1.Secret, obc *v1alpha1.ObjectBucketClaim) (err error) {
 
-	if delErr := deleteObjectBucket(ob, c.libClientset); delErr != nil {
+	if delErr := deleteObjectBucket(ob, c.legacyFinalizers, c.bucketClient); delErr != nil {
 		log.Error(delErr, "error deleting objectBucket", ob.Name)
 		err = delErr
 	}
-	if delErr := releaseSecret(s, c.clientset); delErr != nil {
-		log.Error(delErr, "error releasing secret")
+	if delErr := releaseSecret(s, c.legacyFinalizers, c.clientset); delErr != nil {
+		log.Error(delErr, "error releasing secret", "name", logSafeSecretRef(s))
 		err = delErr
 	}
-	if delErr := releaseConfigMap(cm, c.clientset); delErr != nil {
+	if obc != nil {
+		if delErr := deleteSecretCopies(obc, secretCopyNamespaces(obc), c.legacyFinalizers, c.clientset); delErr != nil {
+			log.Error(delErr, "error deleting secret copies")
+			err = delErr
+		}
+	}
+	if delErr := releaseConfigMap(cm, c.legacyFinalizers, c.clientset); delErr != nil {
 		log.Error(delErr, "error releasing configMap")
 		err = delErr
 	}
-	if delErr := releaseOBC(obc, c.libClientset); delErr != nil {
+	if delErr := releaseOBC(obc, c.legacyFinalizers, c.bucketClient); delErr != nil {
 		log.Error(delErr, "error releasing obc")
 		err = delErr
 	}
 	return err
 }
 
+// collectOrphans releases the ConfigMaps and Secrets left behind when provisioning was interrupted, e.g. by
+// a restart between creating the Secret and binding the claim.  A resource is orphaned if its claim is
+// missing, has been re-created, or is not bound.  It is idempotent, but must not run concurrently with
+// the workers as it cannot tell an interrupted provisioning from one in progress.
+func (c *obcController) collectOrphans() {
+	logD.Info("collecting orphaned configmaps and secrets")
+	opts := metav1.ListOptions{
+		LabelSelector: labels.Set{provisionerLabelKey: c.provisionerLabels[provisionerLabelKey]}.String(),
+	}
+
+	secrets, err := c.clientset.CoreV1().Secrets(metav1.NamespaceAll).List(opts)
+	if err != nil {
+		log.Error(err, "error listing secrets, skipping orphan collection")
+	} else {
+		for i := range secrets.Items {
+			s := &secrets.Items[i]
+			c.collectOrphan(s, "secret",
+				func() error { return releaseSecret(s, c.legacyFinalizers, c.clientset) },
+				func() error {
+					return c.clientset.CoreV1().Secrets(s.Namespace).Delete(s.Name, &metav1.DeleteOptions{})
+				})
+		}
+	}
+
+	configMaps, err := c.clientset.CoreV1().ConfigMaps(metav1.NamespaceAll).List(opts)
+	if err != nil {
+		log.Error(err, "error listing configmaps, skipping orphan collection")
+	} else {
+		for i := range configMaps.Items {
+			cm := &configMaps.Items[i]
+			c.collectOrphan(cm, "configmap",
+				func() error { return releaseConfigMap(cm, c.legacyFinalizers, c.clientset) },
+				func() error {
+					return c.clientset.CoreV1().ConfigMaps(cm.Namespace).Delete(cm.Name, &metav1.DeleteOptions{})
+				})
+		}
+	}
+}
+
+// collectOrphan releases obj if it is orphaned.  The garbage collector removes it once its claim is gone.
+// If the claim still exists, obj is deleted so that the claim can be provisioned again.
+func (c *obcController) collectOrphan(obj metav1.Object, kind string, release, remove func() error) {
+	if !hasLibraryFinalizer(obj, c.legacyFinalizers) {
+		return
+	}
+	namespace, name := claimFor(obj)
+	obc, err := c.bucketClient.GetClaim(namespace, name)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			log.Error(err, "error getting OBC of "+kind, "namespace", obj.GetNamespace(), "name", obj.GetName())
+			return
+		}
+		obc = nil
+	}
+	// the children of a claim handed to another controller are its to collect
+	if obc != nil && !isManagedBy(obc, c.managerNames) {
+		return
+	}
+	owned := obc != nil && isOwnedByClaim(obj, obc)
+	if owned && obc.Spec.ObjectBucketName != "" {
+		return
+	}
+
+	log.Info("releasing orphaned "+kind, "namespace", obj.GetNamespace(), "name", obj.GetName())
+	if err = release(); err != nil {
+		log.Error(err, "error releasing orphaned "+kind)
+		return
+	}
+	if owned {
+		if err = remove(); err != nil && !errors.IsNotFound(err) {
+			log.Error(err, "error deleting orphaned "+kind)
+		}
+	}
+}
+
 // Add finalizer and labels to the OBC.
-func (c *obcController) setOBCMetaFields(obc *v1alpha1.ObjectBucketClaim) (err error) {
-	clib := c.libClientset
+func (c *obcController) setOBCMetaFields(ctx context.Context, obc *v1alpha1.ObjectBucketClaim) (err error) {
+	logD := requestLogD(ctx)
+	clib := c.bucketClient
 
 	logD.Info("getting OBC to set metadata fields")
-	obc, err = clib.ObjectbucketV1alpha1().ObjectBucketClaims(obc.Namespace).Get(obc.Name, metav1.GetOptions{})
+	obc, err = clib.GetClaim(obc.Namespace, obc.Name)
 	if err != nil {
 		return fmt.Errorf("error getting obc: %v", err)
 	}
 
 	obc.SetFinalizers([]string{finalizer})
-	obc.SetLabels(c.provisionerLabels)
+	obc.SetLabels(childLabels(obc, c.provisionerLabels))
 
 	logD.Info("updating OBC metadata")
-	obc, err = updateClaim(clib, obc, defaultRetryBaseInterval, defaultRetryTimeout)
+	obc, err = updateClaim(ctx, clib, obc, c.retry.interval, c.retry.timeout)
 	if err != nil {
 		return fmt.Errorf("error configuring obc metadata: %v", err)
 	}
@@ -566,13 +2578,28 @@ func (c *obcController) setOBCMetaFields(obc *v1alpha1.ObjectBucketClaim) (err e
 	return nil
 }
 
+// enqueueQuarantined queues the keys of the claims of the quarantined OBs, so that their buckets are deleted
+// once their quarantine ends even if the controller restarted since the claims were deleted.
+func (c *obcController) enqueueQuarantined() {
+	obs, err := c.obLister.List(labels.Everything())
+	if err != nil {
+		log.Error(err, "error listing ObjectBuckets, skipping quarantined buckets")
+		return
+	}
+	for _, ob := range obs {
+		if isQuarantined(ob) && ob.Spec.ClaimRef != nil {
+			c.queue.Add(ob.Spec.ClaimRef.Namespace + "/" + ob.Spec.ClaimRef.Name)
+		}
+	}
+}
+
 func (c *obcController) objectBucketForClaimKey(key string) (*v1alpha1.ObjectBucket, error) {
 	logD.Info("getting objectBucket for key", "key", key)
 	name, err := objectBucketNameFromClaimKey(key)
 	if err != nil {
 		return nil, err
 	}
-	ob, err := c.libClientset.ObjectbucketV1alpha1().ObjectBuckets().Get(name, metav1.GetOptions{})
+	ob, err := c.bucketClient.GetObjectBucket(name)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,4903 @@
+/*
+Copyright 2019 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+
+	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/apis/objectbucket.io/v1alpha1"
+	externalFake "github.com/kube-object-storage/lib-bucket-provisioner/pkg/client/clientset/versioned/fake"
+	informers "github.com/kube-object-storage/lib-bucket-provisioner/pkg/client/informers/externalversions"
+	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/provisioner/api"
+)
+
+func newTestController(options *ControllerOptions) *obcController {
+	extClient := externalFake.NewSimpleClientset()
+	factory := informers.NewSharedInformerFactory(extClient, 0)
+	c := NewController(
+		provisionerName,
+		&fakeProvisioner{},
+		fake.NewSimpleClientset(),
+		extClient,
+		factory.Objectbucket().V1alpha1().ObjectBucketClaims(),
+		factory.Objectbucket().V1alpha1().ObjectBuckets(),
+		options)
+	// the StorageClass informer is not run, createTestClass fills its cache
+	c.classHasSynced = func() bool { return true }
+	// the fake clientset does not implement server-side apply
+	c.applier = newFakeChildApplier(c.clientset)
+	return c
+}
+
+func TestNewControllerRetryOptions(t *testing.T) {
+	tests := []struct {
+		name         string
+		options      *ControllerOptions
+		wantInterval time.Duration
+		wantTimeout  time.Duration
+	}{
+		{
+			name:         "nil options",
+			options:      nil,
+			wantInterval: defaultRetryBaseInterval,
+			wantTimeout:  defaultRetryTimeout,
+		},
+		{
+			name:         "empty options",
+			options:      &ControllerOptions{},
+			wantInterval: defaultRetryBaseInterval,
+			wantTimeout:  defaultRetryTimeout,
+		},
+		{
+			name: "overridden interval and timeout",
+			options: &ControllerOptions{
+				RetryBaseInterval: time.Second,
+				RetryTimeout:      time.Second * 5,
+			},
+			wantInterval: time.Second,
+			wantTimeout:  time.Second * 5,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestController(tt.options)
+			if c.retry.interval != tt.wantInterval {
+				t.Errorf("want retry interval %v, got %v", tt.wantInterval, c.retry.interval)
+			}
+			if c.retry.timeout != tt.wantTimeout {
+				t.Errorf("want retry timeout %v, got %v", tt.wantTimeout, c.retry.timeout)
+			}
+		})
+	}
+}
+
+func TestNewControllerLegacyFinalizers(t *testing.T) {
+	const legacy = "legacy.example.com/finalizer"
+	c := newTestController(&ControllerOptions{LegacyFinalizers: []string{legacy}})
+	// a later controller does not change the legacy finalizers of the earlier one
+	other := newTestController(nil)
+
+	if diff := cmp.Diff([]string{legacy}, c.legacyFinalizers); diff != "" {
+		t.Errorf("legacyFinalizers mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{legacy}, c.children.legacyFinalizers); diff != "" {
+		t.Errorf("children legacyFinalizers mismatch (-want +got):\n%s", diff)
+	}
+	if len(other.legacyFinalizers) != 0 {
+		t.Errorf("want no legacy finalizers, got %v", other.legacyFinalizers)
+	}
+}
+
+func TestSyncHandlerEvents(t *testing.T) {
+	const key = testNamespace + "/" + testName
+
+	tests := []struct {
+		name        string
+		reactor     k8stesting.ReactionFunc
+		wantErr     bool
+		wantReasons []string
+	}{
+		{
+			name:    "successful provisioning",
+			wantErr: false,
+			wantReasons: []string{
+				corev1.EventTypeNormal + " " + eventReasonSecretCreated,
+				corev1.EventTypeNormal + " " + eventReasonConfigMapCreated,
+				corev1.EventTypeNormal + " " + eventReasonObjectBucketCreated,
+				corev1.EventTypeNormal + " " + eventReasonBound,
+			},
+		},
+		{
+			name: "secret creation fails",
+			reactor: func(action k8stesting.Action) (bool, runtime.Object, error) {
+				return true, nil, fmt.Errorf("injected error")
+			},
+			wantErr: true,
+			wantReasons: []string{
+				corev1.EventTypeWarning + " " + eventReasonSecretCreateFailed,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestController(&ControllerOptions{
+				RetryBaseInterval: time.Millisecond,
+				RetryTimeout:      time.Millisecond * 10,
+			})
+			recorder := record.NewFakeRecorder(10)
+			c.recorder = recorder
+
+			client := c.clientset.(*fake.Clientset)
+			if tt.reactor != nil {
+				client.PrependReactor("create", "secrets", tt.reactor)
+			}
+			createTestClaim(t, c, &storagev1.StorageClass{
+				ObjectMeta:  metav1.ObjectMeta{Name: className},
+				Provisioner: provisionerName,
+			})
+
+			if err := c.syncHandler(context.Background(), key); (err != nil) != tt.wantErr {
+				t.Errorf("wantErr %v, error = %v", tt.wantErr, err)
+			}
+
+			close(recorder.Events)
+			var got []string
+			for e := range recorder.Events {
+				got = append(got, e)
+			}
+			if len(got) != len(tt.wantReasons) {
+				t.Fatalf("want events %v, got %v", tt.wantReasons, got)
+			}
+			for i, reason := range tt.wantReasons {
+				if !strings.HasPrefix(got[i], reason+" ") {
+					t.Errorf("want event with reason %q, got %q", reason, got[i])
+				}
+			}
+		})
+	}
+}
+
+// createTestClaim pre-creates the given StorageClass and an OBC of that class
+// createTestClass creates the StorageClass and adds it to the controller's StorageClass cache
+func createTestClass(t *testing.T, c *obcController, class *storagev1.StorageClass) {
+	class, err := c.clientset.StorageV1().StorageClasses().Create(class)
+	if err != nil {
+		t.Fatalf("error pre-creating StorageClass: %v", err)
+	}
+	if err = c.classInformer.GetIndexer().Add(class); err != nil {
+		t.Fatalf("error caching StorageClass: %v", err)
+	}
+}
+
+func createTestClaim(t *testing.T, c *obcController, class *storagev1.StorageClass) {
+	createTestClass(t, c, class)
+	if _, err := c.libClientset.ObjectbucketV1alpha1().ObjectBucketClaims(testNamespace).Create(&v1alpha1.ObjectBucketClaim{
+		ObjectMeta: objMeta,
+		Spec: v1alpha1.ObjectBucketClaimSpec{
+			StorageClassName:   class.Name,
+			GenerateBucketName: "test-bucket",
+		},
+	}); err != nil {
+		t.Fatalf("error pre-creating OBC: %v", err)
+	}
+}
+
+// claimCondition returns the claim's condition of the given type, nil if it has none
+func claimCondition(obc *v1alpha1.ObjectBucketClaim, condType v1alpha1.ObjectBucketClaimConditionType) *v1alpha1.ObjectBucketClaimCondition {
+	for i := range obc.Status.Conditions {
+		if obc.Status.Conditions[i].Type == condType {
+			return &obc.Status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// deleteTestClaim marks the test OBC as deleted, as the API server does for objects with finalizers
+func deleteTestClaim(t *testing.T, c *obcController) {
+	obcs := c.libClientset.ObjectbucketV1alpha1().ObjectBucketClaims(testNamespace)
+	obc, err := obcs.Get(testName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("error getting OBC: %v", err)
+	}
+	now := metav1.Now()
+	obc.SetDeletionTimestamp(&now)
+	if _, err = obcs.Update(obc); err != nil {
+		t.Fatalf("error marking OBC deleted: %v", err)
+	}
+}
+
+func TestSyncHandlerInvalidClaims(t *testing.T) {
+	const key = testNamespace + "/" + testName
+
+	tests := []struct {
+		name        string
+		parameters  map[string]string
+		spec        func(*v1alpha1.ObjectBucketClaimSpec)
+		wantReason  string
+		wantMessage string
+	}{
+		{
+			name:        "invalid subpath",
+			spec:        func(s *v1alpha1.ObjectBucketClaimSpec) { s.BucketSubPath = "/photos" },
+			wantReason:  eventReasonInvalidSubPath,
+			wantMessage: "invalid bucket subpath",
+		},
+		{
+			name: "reserved config data key",
+			spec: func(s *v1alpha1.ObjectBucketClaimSpec) {
+				s.ExtraConfigMapData = map[string]string{"BUCKET_HOST": "x"}
+			},
+			wantReason:  eventReasonInvalidConfigData,
+			wantMessage: "invalid extra configmap data",
+		},
+		{
+			name:        "existing bucket not granted by the storage class",
+			parameters:  map[string]string{v1alpha1.StorageClassBucket: "class-bucket"},
+			spec:        func(s *v1alpha1.ObjectBucketClaimSpec) { s.ExistingBucketName = "other-bucket" },
+			wantReason:  eventReasonInvalidBucketName,
+			wantMessage: "only grants access to bucket",
+		},
+		{
+			name:        "existing bucket not allowed by the storage class",
+			spec:        func(s *v1alpha1.ObjectBucketClaimSpec) { s.ExistingBucketName = "existing-bucket" },
+			wantReason:  eventReasonInvalidBucketName,
+			wantMessage: "does not allow claims to name an existing bucket",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestController(&ControllerOptions{
+				RetryBaseInterval: time.Millisecond,
+				RetryTimeout:      time.Millisecond * 10,
+			})
+			recorder := record.NewFakeRecorder(20)
+			c.recorder = recorder
+			p := c.provisioners[provisionerName].provisioner.(*fakeProvisioner)
+			createTestClaim(t, c, &storagev1.StorageClass{
+				ObjectMeta:  metav1.ObjectMeta{Name: className},
+				Provisioner: provisionerName,
+				Parameters:  tt.parameters,
+			})
+			obcs := c.libClientset.ObjectbucketV1alpha1().ObjectBucketClaims(testNamespace)
+			obc, err := obcs.Get(testName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("error getting OBC: %v", err)
+			}
+			tt.spec(&obc.Spec)
+			if _, err = obcs.Update(obc); err != nil {
+				t.Fatalf("error updating OBC: %v", err)
+			}
+
+			if err = c.syncHandler(context.Background(), key); err != nil {
+				t.Fatalf("syncHandler() error = %v", err)
+			}
+			if len(p.calls) != 0 {
+				t.Errorf("want no provisioner calls, got %v", p.calls)
+			}
+			if obc, err = obcs.Get(testName, metav1.GetOptions{}); err != nil {
+				t.Fatalf("error getting OBC: %v", err)
+			}
+			if obc.Status.Phase != v1alpha1.ObjectBucketClaimStatusPhaseFailed {
+				t.Errorf("want phase %q, got %q", v1alpha1.ObjectBucketClaimStatusPhaseFailed, obc.Status.Phase)
+			}
+			if !strings.Contains(obc.Status.FailureMessage, tt.wantMessage) {
+				t.Errorf("want failure message containing %q, got %q", tt.wantMessage, obc.Status.FailureMessage)
+			}
+			found := false
+			for len(recorder.Events) > 0 {
+				if e := <-recorder.Events; strings.Contains(e, tt.wantReason) && strings.Contains(e, tt.wantMessage) {
+					found = true
+				}
+			}
+			if !found {
+				t.Errorf("want %s event", tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestSyncHandlerProvisioningFlows(t *testing.T) {
+	const key = testNamespace + "/" + testName
+	reclaimDelete := corev1.PersistentVolumeReclaimDelete
+	reclaimRetain := corev1.PersistentVolumeReclaimRetain
+
+	tests := []struct {
+		name           string
+		parameters     map[string]string
+		reclaimPolicy  *corev1.PersistentVolumeReclaimPolicy
+		existingBucket string
+		clearExisting  bool
+		granting       bool
+		wantCalls      []string
+		wantOBPhase    v1alpha1.ObjectBucketStatusPhase
+	}{
+		{
+			name:          "greenfield provisions and deletes the bucket",
+			parameters:    nil,
+			reclaimPolicy: &reclaimDelete,
+			wantCalls:     []string{"Provision", "Delete"},
+		},
+		{
+			name:          "greenfield with retain policy revokes access and keeps the OB",
+			parameters:    nil,
+			reclaimPolicy: &reclaimRetain,
+			wantCalls:     []string{"Provision", "Revoke"},
+			wantOBPhase:   v1alpha1.ObjectBucketStatusPhaseReleased,
+		},
+		{
+			name:          "brownfield grants and revokes access to the existing bucket",
+			parameters:    map[string]string{v1alpha1.StorageClassBucket: "existing-bucket"},
+			reclaimPolicy: &reclaimDelete,
+			wantCalls:     []string{"Grant", "Revoke"},
+		},
+		{
+			name:           "existing bucket of the claim is granted and revoked by Grant and Revoke",
+			parameters:     map[string]string{v1alpha1.StorageClassAllowExistingBuckets: "true"},
+			reclaimPolicy:  &reclaimDelete,
+			existingBucket: "existing-bucket",
+			wantCalls:      []string{"Grant", "Revoke"},
+		},
+		{
+			name:           "existing bucket of the claim is granted and revoked by a granting provisioner",
+			parameters:     map[string]string{v1alpha1.StorageClassAllowExistingBuckets: "true"},
+			reclaimPolicy:  &reclaimDelete,
+			existingBucket: "existing-bucket",
+			granting:       true,
+			wantCalls:      []string{"GrantConnection", "RevokeConnection"},
+		},
+		{
+			name:           "existing bucket is revoked even once the claim no longer names it",
+			parameters:     map[string]string{v1alpha1.StorageClassAllowExistingBuckets: "true"},
+			reclaimPolicy:  &reclaimDelete,
+			existingBucket: "existing-bucket",
+			clearExisting:  true,
+			granting:       true,
+			wantCalls:      []string{"GrantConnection", "RevokeConnection"},
+		},
+		{
+			name:          "granting provisioner provisions and deletes new buckets",
+			reclaimPolicy: &reclaimDelete,
+			granting:      true,
+			wantCalls:     []string{"Provision", "Delete"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestController(&ControllerOptions{
+				RetryBaseInterval: time.Millisecond,
+				RetryTimeout:      time.Millisecond * 10,
+			})
+			c.recorder = record.NewFakeRecorder(10)
+			p := c.provisioners[provisionerName].provisioner.(*fakeProvisioner)
+			if tt.granting {
+				gp := &grantingProvisioner{}
+				c.provisioners[provisionerName].provisioner = gp
+				p = &gp.fakeProvisioner
+			}
+			obs := c.libClientset.ObjectbucketV1alpha1().ObjectBuckets()
+			obName, _ := objectBucketNameFromClaimKey(key)
+
+			createTestClaim(t, c, &storagev1.StorageClass{
+				ObjectMeta:    metav1.ObjectMeta{Name: className},
+				Provisioner:   provisionerName,
+				Parameters:    tt.parameters,
+				ReclaimPolicy: tt.reclaimPolicy,
+			})
+			if tt.existingBucket != "" {
+				obcs := c.libClientset.ObjectbucketV1alpha1().ObjectBucketClaims(testNamespace)
+				obc, err := obcs.Get(testName, metav1.GetOptions{})
+				if err != nil {
+					t.Fatalf("error getting OBC: %v", err)
+				}
+				obc.Spec.ExistingBucketName = tt.existingBucket
+				if _, err = obcs.Update(obc); err != nil {
+					t.Fatalf("error updating OBC: %v", err)
+				}
+			}
+			if err := c.syncHandler(context.Background(), key); err != nil {
+				t.Fatalf("error provisioning: %v", err)
+			}
+			// the fake clientset does not set UIDs, which the OB requires to be deleted
+			ob, err := obs.Get(obName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("error getting OB: %v", err)
+			}
+			if tt.existingBucket != "" && ob.Spec.Endpoint.BucketName != tt.existingBucket {
+				t.Errorf("want bucket %q, got %q", tt.existingBucket, ob.Spec.Endpoint.BucketName)
+			}
+			ob.UID = "test-uid"
+			if _, err = obs.Update(ob); err != nil {
+				t.Fatalf("error updating OB: %v", err)
+			}
+
+			if tt.clearExisting {
+				obcs := c.libClientset.ObjectbucketV1alpha1().ObjectBucketClaims(testNamespace)
+				obc, err := obcs.Get(testName, metav1.GetOptions{})
+				if err != nil {
+					t.Fatalf("error getting OBC: %v", err)
+				}
+				obc.Spec.ExistingBucketName = ""
+				if _, err = obcs.Update(obc); err != nil {
+					t.Fatalf("error updating OBC: %v", err)
+				}
+			}
+			deleteTestClaim(t, c)
+			if err := c.syncHandler(context.Background(), key); err != nil {
+				t.Fatalf("error deleting: %v", err)
+			}
+
+			if !cmp.Equal(tt.wantCalls, p.calls) {
+				t.Errorf(cmp.Diff(tt.wantCalls, p.calls))
+			}
+			ob, err = obs.Get(obName, metav1.GetOptions{})
+			if tt.wantOBPhase == "" {
+				if !errors.IsNotFound(err) {
+					t.Errorf("want OB deleted, got error %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("want OB retained, got error %v", err)
+			}
+			if ob.Status.Phase != tt.wantOBPhase {
+				t.Errorf("want OB phase %q, got %q", tt.wantOBPhase, ob.Status.Phase)
+			}
+			if len(ob.Finalizers) != 0 {
+				t.Errorf("want no OB finalizers, got %v", ob.Finalizers)
+			}
+		})
+	}
+}
+
+func TestSyncHandlerDeleteImmutableChildren(t *testing.T) {
+	const key = testNamespace + "/" + testName
+	reclaimDelete := corev1.PersistentVolumeReclaimDelete
+
+	c := newTestController(&ControllerOptions{
+		RetryBaseInterval: time.Millisecond,
+		RetryTimeout:      time.Millisecond * 10,
+		ImmutableChildren: true,
+	})
+	c.recorder = record.NewFakeRecorder(20)
+	createTestClaim(t, c, &storagev1.StorageClass{
+		ObjectMeta:    metav1.ObjectMeta{Name: className},
+		Provisioner:   provisionerName,
+		ReclaimPolicy: &reclaimDelete,
+	})
+	if err := c.syncHandler(context.Background(), key); err != nil {
+		t.Fatalf("error provisioning: %v", err)
+	}
+	// the fake clientset does not set UIDs, which the OB requires to be deleted
+	obs := c.libClientset.ObjectbucketV1alpha1().ObjectBuckets()
+	obName, _ := objectBucketNameFromClaimKey(key)
+	ob, err := obs.Get(obName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("error getting OB: %v", err)
+	}
+	ob.UID = "test-uid"
+	if _, err = obs.Update(ob); err != nil {
+		t.Fatalf("error updating OB: %v", err)
+	}
+
+	// the API server rejects the updates of immutable objects, which the vendored types cannot express
+	client := c.clientset.(*fake.Clientset)
+	for _, resource := range []string{"secrets", "configmaps"} {
+		kind := map[string]string{"secrets": "Secret", "configmaps": "ConfigMap"}[resource]
+		client.PrependReactor("update", resource, func(action k8stesting.Action) (bool, runtime.Object, error) {
+			obj := action.(k8stesting.UpdateAction).GetObject().(metav1.Object)
+			return true, nil, errors.NewInvalid(schema.GroupKind{Kind: kind}, obj.GetName(), field.ErrorList{
+				field.Forbidden(field.NewPath("data"), "field is immutable when `immutable` is set"),
+			})
+		})
+	}
+
+	deleteTestClaim(t, c)
+	if err = c.syncHandler(context.Background(), key); err != nil {
+		t.Fatalf("error deleting: %v", err)
+	}
+
+	secret, err := client.CoreV1().Secrets(testNamespace).Get(testName, metav1.GetOptions{})
+	if err == nil && hasFinalizer(secret) {
+		t.Errorf("want secret released, got finalizers %v", secret.Finalizers)
+	} else if err != nil && !errors.IsNotFound(err) {
+		t.Fatalf("error getting secret: %v", err)
+	}
+	cm, err := client.CoreV1().ConfigMaps(testNamespace).Get(testName, metav1.GetOptions{})
+	if err == nil && hasFinalizer(cm) {
+		t.Errorf("want configmap released, got finalizers %v", cm.Finalizers)
+	} else if err != nil && !errors.IsNotFound(err) {
+		t.Fatalf("error getting configmap: %v", err)
+	}
+	obc, err := c.libClientset.ObjectbucketV1alpha1().ObjectBucketClaims(testNamespace).Get(testName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("error getting OBC: %v", err)
+	}
+	if hasFinalizer(obc) {
+		t.Errorf("want OBC released, got finalizers %v", obc.Finalizers)
+	}
+}
+
+func TestSyncHandlerProvisionerTimeout(t *testing.T) {
+	const key = testNamespace + "/" + testName
+	reclaimDelete := corev1.PersistentVolumeReclaimDelete
+
+	for _, method := range []string{"Provision", "Delete"} {
+		t.Run(method, func(t *testing.T) {
+			c := newTestController(&ControllerOptions{
+				RetryBaseInterval:      time.Millisecond,
+				RetryTimeout:           time.Millisecond * 10,
+				ProvisionerCallTimeout: time.Millisecond * 50,
+			})
+			recorder := record.NewFakeRecorder(20)
+			c.recorder = recorder
+			createTestClaim(t, c, &storagev1.StorageClass{
+				ObjectMeta:    metav1.ObjectMeta{Name: className},
+				Provisioner:   provisionerName,
+				ReclaimPolicy: &reclaimDelete,
+			})
+			if method == "Delete" {
+				if err := c.syncHandler(context.Background(), key); err != nil {
+					t.Fatalf("error provisioning: %v", err)
+				}
+				deleteTestClaim(t, c)
+			}
+			p := &hangingProvisioner{release: make(chan struct{})}
+			defer close(p.release)
+			c.provisioners[provisionerName].provisioner = p
+
+			done := make(chan error, 1)
+			go func() {
+				done <- c.syncHandler(context.Background(), key)
+			}()
+			select {
+			case err := <-done:
+				if err == nil {
+					t.Errorf("want an error for the claim to be requeued")
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatalf("worker still blocked in %s", method)
+			}
+
+			want := fmt.Sprintf("%s %s provisioner's %s did not return within 50ms", corev1.EventTypeWarning, eventReasonProvisionerTimeout, method)
+			close(recorder.Events)
+			var found bool
+			for e := range recorder.Events {
+				found = found || e == want
+			}
+			if !found {
+				t.Errorf("want event %q", want)
+			}
+		})
+	}
+}
+
+func TestSyncHandlerDeletionQuarantine(t *testing.T) {
+	const (
+		key        = testNamespace + "/" + testName
+		quarantine = time.Hour
+	)
+	reclaimDelete := corev1.PersistentVolumeReclaimDelete
+
+	tests := []struct {
+		name string
+		// recreateClass is the StorageClass of the claim re-created during the quarantine, if any
+		recreateClass string
+		// endQuarantine ends the quarantine after the claim is re-created
+		endQuarantine bool
+		wantCalls     []string
+		wantOB        bool
+		wantPhase     v1alpha1.ObjectBucketStatusPhase
+		wantClaimUID  types.UID
+		wantConfigMap bool
+		wantSecret    bool
+	}{
+		{
+			name:          "quarantine then delete",
+			endQuarantine: true,
+			wantCalls:     []string{"Provision", "Delete"},
+		},
+		{
+			name:          "quarantine then cancel",
+			recreateClass: className,
+			wantCalls:     []string{"Provision", "RotateCredentials"},
+			wantOB:        true,
+			wantPhase:     v1alpha1.ObjectBucketStatusPhaseBound,
+			wantClaimUID:  "new-uid",
+			wantConfigMap: true,
+			wantSecret:    true,
+		},
+		{
+			name:          "quarantine then delete despite a claim re-created in another StorageClass",
+			recreateClass: "other-class",
+			endQuarantine: true,
+			wantCalls:     []string{"Provision", "Delete", "Provision"},
+			wantOB:        true,
+			wantPhase:     v1alpha1.ObjectBucketStatusPhaseBound,
+			wantClaimUID:  "new-uid",
+			wantConfigMap: true,
+			wantSecret:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestController(&ControllerOptions{
+				RetryBaseInterval:  time.Millisecond,
+				RetryTimeout:       time.Millisecond * 10,
+				DeletionQuarantine: quarantine,
+			})
+			c.recorder = record.NewFakeRecorder(20)
+			// the restored claim's secret holds new credentials
+			p := &rotatingProvisioner{
+				secrets:    c.clientset.CoreV1().Secrets(testNamespace),
+				secretName: testName,
+			}
+			c.provisioners[provisionerName].provisioner = p
+			obs := c.libClientset.ObjectbucketV1alpha1().ObjectBuckets()
+			obcs := c.libClientset.ObjectbucketV1alpha1().ObjectBucketClaims(testNamespace)
+			obName, _ := objectBucketNameFromClaimKey(key)
+
+			createTestClaim(t, c, &storagev1.StorageClass{
+				ObjectMeta:    metav1.ObjectMeta{Name: className},
+				Provisioner:   provisionerName,
+				ReclaimPolicy: &reclaimDelete,
+			})
+			if err := c.syncHandler(context.Background(), key); err != nil {
+				t.Fatalf("error provisioning: %v", err)
+			}
+			// the fake clientset does not set UIDs, which the OB requires to be deleted
+			ob, err := obs.Get(obName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("error getting OB: %v", err)
+			}
+			ob.UID = "test-uid"
+			if _, err = obs.Update(ob); err != nil {
+				t.Fatalf("error updating OB: %v", err)
+			}
+
+			// the deleted claim is released, its bucket quarantined and its key requeued for the quarantine's end
+			deleteTestClaim(t, c)
+			err = c.syncHandler(context.Background(), key)
+			if rErr, ok := err.(requeueError); !ok || rErr.after <= 0 || rErr.after > quarantine {
+				t.Fatalf("want a requeue within %v, got %v", quarantine, err)
+			}
+			if ob, err = obs.Get(obName, metav1.GetOptions{}); err != nil {
+				t.Fatalf("want OB kept, got error %v", err)
+			}
+			if ob.Status.Phase != v1alpha1.ObjectBucketStatusPhaseQuarantined || ob.Status.QuarantinedAt == nil {
+				t.Fatalf("want OB quarantined, got phase %q since %v", ob.Status.Phase, ob.Status.QuarantinedAt)
+			}
+			obc, err := obcs.Get(testName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("error getting OBC: %v", err)
+			}
+			if len(obc.Finalizers) != 0 {
+				t.Fatalf("want OBC finalizers removed, got %v", obc.Finalizers)
+			}
+			// the API server and garbage collector remove the released claim and its resources
+			if err = obcs.Delete(testName, &metav1.DeleteOptions{}); err != nil {
+				t.Fatalf("error deleting OBC: %v", err)
+			}
+			if err = c.clientset.CoreV1().Secrets(testNamespace).Delete(testName, &metav1.DeleteOptions{}); err != nil {
+				t.Fatalf("error deleting Secret: %v", err)
+			}
+			if err = c.clientset.CoreV1().ConfigMaps(testNamespace).Delete(testName, &metav1.DeleteOptions{}); err != nil {
+				t.Fatalf("error deleting ConfigMap: %v", err)
+			}
+
+			// the bucket is not deleted during the quarantine
+			if _, ok := c.syncHandler(context.Background(), key).(requeueError); !ok {
+				t.Fatalf("want the quarantined bucket's key requeued")
+			}
+			if diff := cmp.Diff([]string{"Provision"}, p.calls); diff != "" {
+				t.Fatalf("provisioner calls during quarantine (-want +got):\n%s", diff)
+			}
+
+			if tt.recreateClass != "" {
+				if tt.recreateClass != className {
+					createTestClass(t, c, &storagev1.StorageClass{
+						ObjectMeta:    metav1.ObjectMeta{Name: tt.recreateClass},
+						Provisioner:   provisionerName,
+						ReclaimPolicy: &reclaimDelete,
+					})
+				}
+				if _, err = obcs.Create(&v1alpha1.ObjectBucketClaim{
+					ObjectMeta: metav1.ObjectMeta{Name: testName, Namespace: testNamespace, UID: "new-uid"},
+					Spec:       v1alpha1.ObjectBucketClaimSpec{StorageClassName: tt.recreateClass, GenerateBucketName: "test-bucket"},
+				}); err != nil {
+					t.Fatalf("error re-creating OBC: %v", err)
+				}
+			}
+			if tt.endQuarantine {
+				// a claim which cannot get the bucket back waits for the end of the quarantine
+				if tt.recreateClass != "" {
+					if _, ok := c.syncHandler(context.Background(), key).(requeueError); !ok {
+						t.Fatalf("want the re-created claim's key requeued")
+					}
+					if diff := cmp.Diff([]string{"Provision"}, p.calls); diff != "" {
+						t.Fatalf("provisioner calls during quarantine (-want +got):\n%s", diff)
+					}
+				}
+				past := metav1.NewTime(ob.Status.QuarantinedAt.Add(-quarantine))
+				ob.Status.QuarantinedAt = &past
+				if _, err = obs.UpdateStatus(ob); err != nil {
+					t.Fatalf("error ending quarantine: %v", err)
+				}
+			}
+			if err = c.syncHandler(context.Background(), key); err != nil {
+				t.Fatalf("error syncing: %v", err)
+			}
+
+			if diff := cmp.Diff(tt.wantCalls, p.calls); diff != "" {
+				t.Errorf("provisioner calls (-want +got):\n%s", diff)
+			}
+			ob, err = obs.Get(obName, metav1.GetOptions{})
+			if !tt.wantOB {
+				if !errors.IsNotFound(err) {
+					t.Errorf("want OB deleted, got error %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("want OB kept, got error %v", err)
+			}
+			if ob.Status.Phase != tt.wantPhase || ob.Status.QuarantinedAt != nil {
+				t.Errorf("want OB phase %q, got %q quarantined since %v", tt.wantPhase, ob.Status.Phase, ob.Status.QuarantinedAt)
+			}
+			if ob.Spec.ClaimRef == nil || ob.Spec.ClaimRef.UID != tt.wantClaimUID {
+				t.Errorf("want OB bound to claim %q, got %v", tt.wantClaimUID, ob.Spec.ClaimRef)
+			}
+			if obc, err = obcs.Get(testName, metav1.GetOptions{}); err != nil {
+				t.Fatalf("error getting OBC: %v", err)
+			}
+			if obc.Status.Phase != v1alpha1.ObjectBucketClaimStatusPhaseBound || obc.Spec.ObjectBucketName != obName {
+				t.Errorf("want OBC bound to %q, got phase %q and OB %q", obName, obc.Status.Phase, obc.Spec.ObjectBucketName)
+			}
+			if _, err = c.clientset.CoreV1().ConfigMaps(testNamespace).Get(testName, metav1.GetOptions{}); (err == nil) != tt.wantConfigMap {
+				t.Errorf("want ConfigMap %v, got error %v", tt.wantConfigMap, err)
+			}
+			if _, err = c.clientset.CoreV1().Secrets(testNamespace).Get(testName, metav1.GetOptions{}); (err == nil) != tt.wantSecret {
+				t.Errorf("want Secret %v, got error %v", tt.wantSecret, err)
+			}
+		})
+	}
+}
+
+func TestSyncHandlerDeletionQuarantineWithoutRotator(t *testing.T) {
+	const key = testNamespace + "/" + testName
+	reclaimDelete := corev1.PersistentVolumeReclaimDelete
+
+	c := newTestController(&ControllerOptions{
+		RetryBaseInterval:  time.Millisecond,
+		RetryTimeout:       time.Millisecond * 10,
+		DeletionQuarantine: time.Hour,
+	})
+	p := c.provisioners[provisionerName].provisioner.(*fakeProvisioner)
+	obName, _ := objectBucketNameFromClaimKey(key)
+
+	createTestClaim(t, c, &storagev1.StorageClass{
+		ObjectMeta:    metav1.ObjectMeta{Name: className},
+		Provisioner:   provisionerName,
+		ReclaimPolicy: &reclaimDelete,
+	})
+	if err := c.syncHandler(context.Background(), key); err != nil {
+		t.Fatalf("error provisioning: %v", err)
+	}
+	// the fake clientset does not set UIDs, which the OB requires to be deleted
+	obs := c.libClientset.ObjectbucketV1alpha1().ObjectBuckets()
+	ob, err := obs.Get(obName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("error getting OB: %v", err)
+	}
+	ob.UID = "test-uid"
+	if _, err = obs.Update(ob); err != nil {
+		t.Fatalf("error updating OB: %v", err)
+	}
+
+	// a restored claim could not get credentials, the bucket is deleted at once
+	deleteTestClaim(t, c)
+	if err = c.syncHandler(context.Background(), key); err != nil {
+		t.Fatalf("error deleting: %v", err)
+	}
+	if diff := cmp.Diff([]string{"Provision", "Delete"}, p.calls); diff != "" {
+		t.Errorf("provisioner calls (-want +got):\n%s", diff)
+	}
+	if _, err = obs.Get(obName, metav1.GetOptions{}); !errors.IsNotFound(err) {
+		t.Errorf("want OB deleted, got error %v", err)
+	}
+}
+
+func TestSyncHandlerProvisionerMatch(t *testing.T) {
+	const key = testNamespace + "/" + testName
+
+	tests := []struct {
+		name        string
+		provisioner string
+		wantCalls   []string
+		wantPhase   v1alpha1.ObjectBucketClaimStatusPhase
+	}{
+		{
+			name:        "class of the registered provisioner is reconciled",
+			provisioner: provisionerName,
+			wantCalls:   []string{"Provision"},
+			wantPhase:   v1alpha1.ObjectBucketClaimStatusPhaseBound,
+		},
+		{
+			name:        "class of another provisioner is skipped",
+			provisioner: "other.io/bucket",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestController(&ControllerOptions{
+				RetryBaseInterval: time.Millisecond,
+				RetryTimeout:      time.Millisecond * 10,
+			})
+			recorder := record.NewFakeRecorder(20)
+			c.recorder = recorder
+			p := c.provisioners[provisionerName].provisioner.(*fakeProvisioner)
+			createTestClaim(t, c, &storagev1.StorageClass{
+				ObjectMeta:  metav1.ObjectMeta{Name: className},
+				Provisioner: tt.provisioner,
+			})
+
+			if err := c.syncHandler(context.Background(), key); err != nil {
+				t.Fatalf("syncHandler() error = %v", err)
+			}
+			if diff := cmp.Diff(tt.wantCalls, p.calls); diff != "" {
+				t.Errorf("provisioner calls (-want +got):\n%s", diff)
+			}
+			obc, err := c.libClientset.ObjectbucketV1alpha1().ObjectBucketClaims(testNamespace).Get(testName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("error getting OBC: %v", err)
+			}
+			if obc.Status.Phase != tt.wantPhase {
+				t.Errorf("want phase %q, got %q", tt.wantPhase, obc.Status.Phase)
+			}
+			if tt.wantPhase != "" {
+				return
+			}
+			// a skipped claim is left untouched
+			if len(obc.Finalizers) != 0 {
+				t.Errorf("want no OBC finalizers, got %v", obc.Finalizers)
+			}
+			if len(recorder.Events) != 0 {
+				t.Errorf("want no events, got %d", len(recorder.Events))
+			}
+		})
+	}
+}
+
+func TestSyncHandlerStorageClassCache(t *testing.T) {
+	const key = testNamespace + "/" + testName
+
+	tests := []struct {
+		name        string
+		synced      bool
+		wantRequeue bool
+		wantCalls   []string
+		wantPhase   v1alpha1.ObjectBucketClaimStatusPhase
+	}{
+		{
+			name:      "class is read from the cache",
+			synced:    true,
+			wantCalls: []string{"Provision"},
+			wantPhase: v1alpha1.ObjectBucketClaimStatusPhaseBound,
+		},
+		{
+			name:        "cold cache requeues",
+			wantRequeue: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestController(&ControllerOptions{
+				RetryBaseInterval: time.Millisecond,
+				RetryTimeout:      time.Millisecond * 10,
+			})
+			c.recorder = record.NewFakeRecorder(20)
+			c.classHasSynced = func() bool { return tt.synced }
+			p := c.provisioners[provisionerName].provisioner.(*fakeProvisioner)
+			class := &storagev1.StorageClass{
+				ObjectMeta:  metav1.ObjectMeta{Name: className},
+				Provisioner: provisionerName,
+			}
+			// the class is only known to the cache, a live get would not find it
+			if err := c.classInformer.GetIndexer().Add(class); err != nil {
+				t.Fatalf("error caching StorageClass: %v", err)
+			}
+			if _, err := c.libClientset.ObjectbucketV1alpha1().ObjectBucketClaims(testNamespace).Create(&v1alpha1.ObjectBucketClaim{
+				ObjectMeta: objMeta,
+				Spec: v1alpha1.ObjectBucketClaimSpec{
+					StorageClassName:   className,
+					GenerateBucketName: "test-bucket",
+				},
+			}); err != nil {
+				t.Fatalf("error pre-creating OBC: %v", err)
+			}
+
+			err := c.syncHandler(context.Background(), key)
+			if _, requeue := err.(requeueError); requeue != tt.wantRequeue {
+				t.Fatalf("want requeue %v, got error %v", tt.wantRequeue, err)
+			}
+			if !tt.wantRequeue && err != nil {
+				t.Fatalf("syncHandler() error = %v", err)
+			}
+			if diff := cmp.Diff(tt.wantCalls, p.calls); diff != "" {
+				t.Errorf("provisioner calls (-want +got):\n%s", diff)
+			}
+			for _, a := range c.clientset.(*fake.Clientset).Actions() {
+				if a.GetResource().Resource == "storageclasses" {
+					t.Errorf("want no StorageClass API calls, got %s", a.GetVerb())
+				}
+			}
+			obc, err := c.libClientset.ObjectbucketV1alpha1().ObjectBucketClaims(testNamespace).Get(testName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("error getting OBC: %v", err)
+			}
+			if obc.Status.Phase != tt.wantPhase {
+				t.Errorf("want phase %q, got %q", tt.wantPhase, obc.Status.Phase)
+			}
+		})
+	}
+}
+
+func TestSyncHandlerProvisionerVersion(t *testing.T) {
+	const key = testNamespace + "/" + testName
+
+	defer func(v string) { Version = v }(Version)
+	Version = "v0.9.0"
+	tests := []struct {
+		name    string
+		version string
+		want    string
+	}{
+		{
+			name: "build version by default",
+			want: "v0.9.0",
+		},
+		{
+			name:    "configured version",
+			version: "v1.2.3",
+			want:    "v1.2.3",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestController(&ControllerOptions{
+				RetryBaseInterval:  time.Millisecond,
+				RetryTimeout:       time.Millisecond * 10,
+				ProvisionerVersion: tt.version,
+			})
+			c.recorder = record.NewFakeRecorder(20)
+			createTestClaim(t, c, &storagev1.StorageClass{
+				ObjectMeta:  metav1.ObjectMeta{Name: className},
+				Provisioner: provisionerName,
+			})
+
+			if err := c.syncHandler(context.Background(), key); err != nil {
+				t.Fatalf("syncHandler() error = %v", err)
+			}
+			obName, _ := objectBucketNameFromClaimKey(key)
+			ob, err := c.libClientset.ObjectbucketV1alpha1().ObjectBuckets().Get(obName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("error getting OB: %v", err)
+			}
+			if got := ob.Annotations[v1alpha1.ProvisionerVersionAnnotation]; got != tt.want {
+				t.Errorf("want version annotation %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestSyncHandlerChildCreationOrder(t *testing.T) {
+	const key = testNamespace + "/" + testName
+
+	tests := []struct {
+		name           string
+		configMapFirst bool
+		failConfigMap  bool
+		wantCreates    []string
+		wantPhase      v1alpha1.ObjectBucketClaimStatusPhase
+	}{
+		{
+			name:        "secret first by default",
+			wantCreates: []string{"secrets", "configmaps"},
+			wantPhase:   v1alpha1.ObjectBucketClaimStatusPhaseBound,
+		},
+		{
+			name:           "configmap first",
+			configMapFirst: true,
+			wantCreates:    []string{"configmaps", "secrets"},
+			wantPhase:      v1alpha1.ObjectBucketClaimStatusPhaseBound,
+		},
+		{
+			name:          "not bound without the configmap",
+			failConfigMap: true,
+			wantCreates:   []string{"secrets", "configmaps"},
+			wantPhase:     v1alpha1.ObjectBucketClaimStatusPhaseProvisioning,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestController(&ControllerOptions{
+				RetryBaseInterval: time.Millisecond,
+				RetryTimeout:      time.Millisecond * 10,
+				ConfigMapFirst:    tt.configMapFirst,
+			})
+			c.recorder = record.NewFakeRecorder(20)
+			client := c.clientset.(*fake.Clientset)
+			var creates []string
+			client.PrependReactor("create", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				resource := action.GetResource().Resource
+				if resource != "secrets" && resource != "configmaps" {
+					return false, nil, nil
+				}
+				// retried creates are recorded once
+				if len(creates) == 0 || creates[len(creates)-1] != resource {
+					creates = append(creates, resource)
+				}
+				if tt.failConfigMap && resource == "configmaps" {
+					return true, nil, fmt.Errorf("configmap create failure")
+				}
+				return false, nil, nil
+			})
+			// the claim is only bound once both its Secret and ConfigMap exist
+			var boundErr error
+			c.libClientset.(*externalFake.Clientset).PrependReactor("update", "objectbucketclaims", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				obc := action.(k8stesting.UpdateAction).GetObject().(*v1alpha1.ObjectBucketClaim)
+				if obc.Status.Phase != v1alpha1.ObjectBucketClaimStatusPhaseBound {
+					return false, nil, nil
+				}
+				if _, err := client.Tracker().Get(corev1.SchemeGroupVersion.WithResource("secrets"), testNamespace, testName); err != nil {
+					boundErr = fmt.Errorf("bound without a Secret: %v", err)
+				}
+				if _, err := client.Tracker().Get(corev1.SchemeGroupVersion.WithResource("configmaps"), testNamespace, testName); err != nil {
+					boundErr = fmt.Errorf("bound without a ConfigMap: %v", err)
+				}
+				return false, nil, nil
+			})
+			createTestClaim(t, c, &storagev1.StorageClass{
+				ObjectMeta:  metav1.ObjectMeta{Name: className},
+				Provisioner: provisionerName,
+			})
+
+			if err := c.syncHandler(context.Background(), key); (err != nil) != tt.failConfigMap {
+				t.Fatalf("syncHandler() error = %v, want error %v", err, tt.failConfigMap)
+			}
+			if boundErr != nil {
+				t.Error(boundErr)
+			}
+			if diff := cmp.Diff(tt.wantCreates, creates); diff != "" {
+				t.Errorf("creates (-want +got):\n%s", diff)
+			}
+			obc, err := c.libClientset.ObjectbucketV1alpha1().ObjectBucketClaims(testNamespace).Get(testName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("error getting OBC: %v", err)
+			}
+			if obc.Status.Phase != tt.wantPhase {
+				t.Errorf("want phase %q, got %q", tt.wantPhase, obc.Status.Phase)
+			}
+		})
+	}
+}
+
+func TestSyncHandlerKeepObjectBucket(t *testing.T) {
+	const key = testNamespace + "/" + testName
+	reclaimDelete := corev1.PersistentVolumeReclaimDelete
+
+	c := newTestController(&ControllerOptions{
+		RetryBaseInterval: time.Millisecond,
+		RetryTimeout:      time.Millisecond * 10,
+	})
+	recorder := record.NewFakeRecorder(20)
+	c.recorder = recorder
+	p := c.provisioners[provisionerName].provisioner.(*fakeProvisioner)
+	obs := c.libClientset.ObjectbucketV1alpha1().ObjectBuckets()
+	obcs := c.libClientset.ObjectbucketV1alpha1().ObjectBucketClaims(testNamespace)
+	obName, _ := objectBucketNameFromClaimKey(key)
+
+	createTestClaim(t, c, &storagev1.StorageClass{
+		ObjectMeta:    metav1.ObjectMeta{Name: className},
+		Provisioner:   provisionerName,
+		ReclaimPolicy: &reclaimDelete,
+	})
+	if err := c.syncHandler(context.Background(), key); err != nil {
+		t.Fatalf("error provisioning: %v", err)
+	}
+	// the fake clientset does not set UIDs, which the OB requires to be deleted
+	ob, err := obs.Get(obName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("error getting OB: %v", err)
+	}
+	ob.UID = "test-uid"
+	if _, err = obs.Update(ob); err != nil {
+		t.Fatalf("error updating OB: %v", err)
+	}
+	obc, err := obcs.Get(testName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("error getting OBC: %v", err)
+	}
+	obc.Annotations = map[string]string{v1alpha1.KeepObjectBucketAnnotation: "true"}
+	if _, err = obcs.Update(obc); err != nil {
+		t.Fatalf("error annotating OBC: %v", err)
+	}
+
+	deleteTestClaim(t, c)
+	if err = c.syncHandler(context.Background(), key); err != nil {
+		t.Fatalf("error deleting: %v", err)
+	}
+
+	// access is revoked but the bucket is not deleted, despite the Delete reclaim policy
+	if diff := cmp.Diff([]string{"Provision", "Revoke"}, p.calls); diff != "" {
+		t.Errorf("provisioner calls (-want +got):\n%s", diff)
+	}
+	if ob, err = obs.Get(obName, metav1.GetOptions{}); err != nil {
+		t.Fatalf("want OB kept, got error %v", err)
+	}
+	if ob.Status.Phase != v1alpha1.ObjectBucketStatusPhaseReleased {
+		t.Errorf("want OB phase %q, got %q", v1alpha1.ObjectBucketStatusPhaseReleased, ob.Status.Phase)
+	}
+	if len(ob.Finalizers) != 0 {
+		t.Errorf("want no OB finalizers, got %v", ob.Finalizers)
+	}
+	if ob.Spec.ClaimRef != nil {
+		t.Errorf("want OB claim reference cleared, got %v", ob.Spec.ClaimRef)
+	}
+	for _, l := range []string{v1alpha1.ClaimNamespaceLabel, v1alpha1.ClaimNameLabel} {
+		if v, ok := ob.Labels[l]; ok {
+			t.Errorf("want OB label %q removed, got %q", l, v)
+		}
+	}
+	if obc, err = obcs.Get(testName, metav1.GetOptions{}); err != nil {
+		t.Fatalf("error getting OBC: %v", err)
+	}
+	if len(obc.Finalizers) != 0 {
+		t.Errorf("want OBC finalizers removed, got %v", obc.Finalizers)
+	}
+	secret, err := c.clientset.CoreV1().Secrets(testNamespace).Get(testName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("error getting Secret: %v", err)
+	}
+	if len(secret.Finalizers) != 0 {
+		t.Errorf("want Secret finalizers removed, got %v", secret.Finalizers)
+	}
+	cm, err := c.clientset.CoreV1().ConfigMaps(testNamespace).Get(testName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("error getting ConfigMap: %v", err)
+	}
+	if len(cm.Finalizers) != 0 {
+		t.Errorf("want ConfigMap finalizers removed, got %v", cm.Finalizers)
+	}
+	close(recorder.Events)
+	var kept bool
+	for e := range recorder.Events {
+		kept = kept || strings.HasPrefix(e, corev1.EventTypeNormal+" "+eventReasonObjectBucketKept+" ")
+	}
+	if !kept {
+		t.Errorf("want a %s event", eventReasonObjectBucketKept)
+	}
+
+	// the released OB no longer belongs to the deleted claim, and can be bound to a new one
+	newClaim := obc.DeepCopy()
+	newClaim.UID = "new-obc-uid"
+	if err = c.objectBucketNameConflict(newClaim); err != nil {
+		t.Errorf("objectBucketNameConflict() = %v, want the OB free for a new claim", err)
+	}
+}
+
+func TestSyncHandlerObjectBucketVanished(t *testing.T) {
+	const key = testNamespace + "/" + testName
+	reclaimDelete := corev1.PersistentVolumeReclaimDelete
+
+	c := newTestController(&ControllerOptions{
+		RetryBaseInterval: time.Millisecond,
+		RetryTimeout:      time.Millisecond * 10,
+	})
+	c.recorder = record.NewFakeRecorder(20)
+	p := c.provisioners[provisionerName].provisioner.(*fakeProvisioner)
+
+	createTestClaim(t, c, &storagev1.StorageClass{
+		ObjectMeta:    metav1.ObjectMeta{Name: className},
+		Provisioner:   provisionerName,
+		ReclaimPolicy: &reclaimDelete,
+	})
+	if err := c.syncHandler(context.Background(), key); err != nil {
+		t.Fatalf("error provisioning: %v", err)
+	}
+
+	// the OB is deleted, e.g. by a concurrent reconcile, after the claim's resources are read
+	c.libClientset.(*externalFake.Clientset).PrependReactor("update", "objectbuckets", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.NewNotFound(action.GetResource().GroupResource(), action.(k8stesting.UpdateAction).GetObject().(metav1.Object).GetName())
+	})
+	deleteTestClaim(t, c)
+	if err := c.syncHandler(context.Background(), key); err != nil {
+		t.Fatalf("error deleting: %v", err)
+	}
+	// a repeated delete reconcile is a no-op
+	if err := c.syncHandler(context.Background(), key); err != nil {
+		t.Fatalf("error deleting again: %v", err)
+	}
+
+	if diff := cmp.Diff([]string{"Provision"}, p.calls); diff != "" {
+		t.Errorf("provisioner calls (-want +got):\n%s", diff)
+	}
+	obc, err := c.libClientset.ObjectbucketV1alpha1().ObjectBucketClaims(testNamespace).Get(testName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("error getting OBC: %v", err)
+	}
+	if len(obc.Finalizers) != 0 {
+		t.Errorf("want OBC finalizers removed, got %v", obc.Finalizers)
+	}
+}
+
+func TestSyncHandlerMigratesLegacyObjectBucket(t *testing.T) {
+	const (
+		key             = testNamespace + "/" + testName
+		legacyFinalizer = "example.com/legacy-finalizer"
+		obcUID          = "obc-uid"
+	)
+	c := newTestController(&ControllerOptions{
+		RetryBaseInterval: time.Millisecond,
+		RetryTimeout:      time.Millisecond * 10,
+	})
+	c.recorder = record.NewFakeRecorder(20)
+	obs := c.libClientset.ObjectbucketV1alpha1().ObjectBuckets()
+	obName, _ := objectBucketNameFromClaimKey(key)
+
+	createTestClass(t, c, &storagev1.StorageClass{
+		ObjectMeta:  metav1.ObjectMeta{Name: className},
+		Provisioner: provisionerName,
+	})
+	obc := &v1alpha1.ObjectBucketClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: testName, Namespace: testNamespace, UID: obcUID, Finalizers: []string{finalizer}},
+		Spec:       v1alpha1.ObjectBucketClaimSpec{StorageClassName: className, BucketName: "bucket", ObjectBucketName: obName},
+		Status:     v1alpha1.ObjectBucketClaimStatus{Phase: v1alpha1.ObjectBucketClaimStatusPhaseBound},
+	}
+	if _, err := c.libClientset.ObjectbucketV1alpha1().ObjectBucketClaims(testNamespace).Create(obc); err != nil {
+		t.Fatalf("error pre-creating OBC: %v", err)
+	}
+	// an OB as left by an earlier version of the library
+	if _, err := obs.Create(&v1alpha1.ObjectBucket{
+		ObjectMeta: metav1.ObjectMeta{Name: obName, Finalizers: []string{legacyFinalizer}},
+		Spec: v1alpha1.ObjectBucketSpec{
+			StorageClassName: className,
+			Connection:       &v1alpha1.Connection{Endpoint: &v1alpha1.Endpoint{BucketName: "bucket"}},
+		},
+	}); err != nil {
+		t.Fatalf("error pre-creating OB: %v", err)
+	}
+
+	if err := c.syncHandler(context.Background(), key); err != nil {
+		t.Fatalf("error reconciling: %v", err)
+	}
+
+	ob, err := obs.Get(obName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("error getting OB: %v", err)
+	}
+	if diff := cmp.Diff([]string{legacyFinalizer, finalizer}, ob.Finalizers); diff != "" {
+		t.Errorf("OB finalizers (-want +got):\n%s", diff)
+	}
+	if ob.Spec.ClaimRef == nil || ob.Spec.ClaimRef.UID != obcUID {
+		t.Errorf("want OB claim reference to UID %q, got %+v", obcUID, ob.Spec.ClaimRef)
+	}
+	if ob.Status.Phase != v1alpha1.ObjectBucketStatusPhaseBound {
+		t.Errorf("want OB phase %q, got %q", v1alpha1.ObjectBucketStatusPhaseBound, ob.Status.Phase)
+	}
+	if !hasClaimLabels(ob, ob.Spec.ClaimRef) {
+		t.Errorf("want OB claim labels, got %v", ob.Labels)
+	}
+	p := c.provisioners[provisionerName].provisioner.(*fakeProvisioner)
+	if len(p.calls) != 0 {
+		t.Errorf("want no provisioner calls, got %v", p.calls)
+	}
+}
+
+func TestSyncHandlerDryRun(t *testing.T) {
+	const key = testNamespace + "/" + testName
+
+	tests := []struct {
+		name       string
+		optionSet  bool
+		annotation string
+		bucketName string
+		wantStatus corev1.ConditionStatus
+	}{
+		{
+			name:       "annotated claim is validated",
+			annotation: "true",
+			wantStatus: corev1.ConditionTrue,
+		},
+		{
+			name:       "controller option validates every claim",
+			optionSet:  true,
+			wantStatus: corev1.ConditionTrue,
+		},
+		{
+			name:       "invalid claim is reported",
+			annotation: "true",
+			bucketName: "conflicts-with-generate-bucket-name",
+			wantStatus: corev1.ConditionFalse,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestController(&ControllerOptions{
+				RetryBaseInterval: time.Millisecond,
+				RetryTimeout:      time.Millisecond * 10,
+				DryRun:            tt.optionSet,
+			})
+			c.recorder = record.NewFakeRecorder(10)
+			p := c.provisioners[provisionerName].provisioner.(*fakeProvisioner)
+			obcs := c.libClientset.ObjectbucketV1alpha1().ObjectBucketClaims(testNamespace)
+
+			createTestClaim(t, c, &storagev1.StorageClass{
+				ObjectMeta:  metav1.ObjectMeta{Name: className},
+				Provisioner: provisionerName,
+			})
+			if tt.annotation != "" {
+				obc, err := obcs.Get(testName, metav1.GetOptions{})
+				if err != nil {
+					t.Fatalf("error getting OBC: %v", err)
+				}
+				obc.SetAnnotations(map[string]string{v1alpha1.DryRunAnnotation: tt.annotation})
+				obc.Spec.BucketName = tt.bucketName
+				if _, err = obcs.Update(obc); err != nil {
+					t.Fatalf("error annotating OBC: %v", err)
+				}
+			}
+
+			if err := c.syncHandler(context.Background(), key); err != nil {
+				t.Fatalf("error validating: %v", err)
+			}
+
+			if len(p.calls) != 0 {
+				t.Errorf("want no provisioner calls, got %v", p.calls)
+			}
+			if secrets, _ := c.clientset.CoreV1().Secrets(testNamespace).List(metav1.ListOptions{}); len(secrets.Items) != 0 {
+				t.Errorf("want no secrets, got %d", len(secrets.Items))
+			}
+			if cms, _ := c.clientset.CoreV1().ConfigMaps(testNamespace).List(metav1.ListOptions{}); len(cms.Items) != 0 {
+				t.Errorf("want no configmaps, got %d", len(cms.Items))
+			}
+			if obs, _ := c.libClientset.ObjectbucketV1alpha1().ObjectBuckets().List(metav1.ListOptions{}); len(obs.Items) != 0 {
+				t.Errorf("want no OBs, got %d", len(obs.Items))
+			}
+
+			obc, err := obcs.Get(testName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("error getting OBC: %v", err)
+			}
+			if len(obc.Finalizers) != 0 {
+				t.Errorf("want no OBC finalizers, got %v", obc.Finalizers)
+			}
+			if len(obc.Status.Conditions) != 1 {
+				t.Fatalf("want 1 condition, got %v", obc.Status.Conditions)
+			}
+			cond := obc.Status.Conditions[0]
+			if cond.Type != v1alpha1.ObjectBucketClaimConditionDryRun || cond.Status != tt.wantStatus {
+				t.Errorf("want condition %q %q, got %q %q", v1alpha1.ObjectBucketClaimConditionDryRun, tt.wantStatus, cond.Type, cond.Status)
+			}
+		})
+	}
+}
+
+func TestCollectOrphans(t *testing.T) {
+	const liveName = "live-claim"
+
+	newSecret := func(name string, ownerUID types.UID) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:       name,
+				Namespace:  testNamespace,
+				Finalizers: []string{finalizer},
+				Labels:     map[string]string{provisionerLabelKey: labelValue(provisionerName)},
+				OwnerReferences: []metav1.OwnerReference{
+					{Name: name, UID: ownerUID},
+				},
+			},
+		}
+	}
+
+	tests := []struct {
+		name           string
+		obcBucketName  string
+		wantFinalizers bool
+		wantDeleted    bool
+	}{
+		{
+			name:           "bound claim keeps its secret",
+			obcBucketName:  "ob-name",
+			wantFinalizers: true,
+		},
+		{
+			name:        "unbound claim has its secret deleted",
+			wantDeleted: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestController(nil)
+			secrets := c.clientset.CoreV1().Secrets(testNamespace)
+
+			if _, err := c.libClientset.ObjectbucketV1alpha1().ObjectBucketClaims(testNamespace).Create(&v1alpha1.ObjectBucketClaim{
+				ObjectMeta: metav1.ObjectMeta{Name: liveName, Namespace: testNamespace, UID: "live-uid"},
+				Spec:       v1alpha1.ObjectBucketClaimSpec{ObjectBucketName: tt.obcBucketName},
+			}); err != nil {
+				t.Fatalf("error pre-creating OBC: %v", err)
+			}
+			for _, s := range []*corev1.Secret{newSecret(liveName, "live-uid"), newSecret(testName, "dangling-uid")} {
+				if _, err := secrets.Create(s); err != nil {
+					t.Fatalf("error pre-creating secret: %v", err)
+				}
+			}
+
+			// collecting twice must have the same outcome, on behalf of the provisioner as on start
+			pc, _ := c.forProvisioner(provisionerName)
+			pc.collectOrphans()
+			pc.collectOrphans()
+
+			dangling, err := secrets.Get(testName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("error getting dangling secret: %v", err)
+			}
+			if len(dangling.Finalizers) != 0 {
+				t.Errorf("want dangling secret released, got finalizers %v", dangling.Finalizers)
+			}
+
+			live, err := secrets.Get(liveName, metav1.GetOptions{})
+			if tt.wantDeleted {
+				if !errors.IsNotFound(err) {
+					t.Errorf("want secret deleted, got error %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("error getting live secret: %v", err)
+			}
+			if hasFinalizer(live) != tt.wantFinalizers {
+				t.Errorf("want finalizer %v, got %v", tt.wantFinalizers, live.Finalizers)
+			}
+		})
+	}
+}
+
+func TestSyncHandlerMetrics(t *testing.T) {
+	const key = testNamespace + "/" + testName
+	reclaimDelete := corev1.PersistentVolumeReclaimDelete
+
+	registry := prometheus.NewRegistry()
+	c := newTestController(&ControllerOptions{
+		RetryBaseInterval: time.Millisecond,
+		RetryTimeout:      time.Millisecond * 10,
+		MetricsRegisterer: registry,
+	})
+	c.recorder = record.NewFakeRecorder(10)
+	obs := c.libClientset.ObjectbucketV1alpha1().ObjectBuckets()
+	obName, _ := objectBucketNameFromClaimKey(key)
+
+	createTestClaim(t, c, &storagev1.StorageClass{
+		ObjectMeta:    metav1.ObjectMeta{Name: className},
+		Provisioner:   provisionerName,
+		ReclaimPolicy: &reclaimDelete,
+	})
+	if err := c.syncHandler(context.Background(), key); err != nil {
+		t.Fatalf("error provisioning: %v", err)
+	}
+	// the fake clientset does not set UIDs, which the OB requires to be deleted
+	ob, err := obs.Get(obName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("error getting OB: %v", err)
+	}
+	ob.UID = "test-uid"
+	if _, err = obs.Update(ob); err != nil {
+		t.Fatalf("error updating OB: %v", err)
+	}
+	deleteTestClaim(t, c)
+	if err := c.syncHandler(context.Background(), key); err != nil {
+		t.Fatalf("error deleting: %v", err)
+	}
+
+	if got := testutil.ToFloat64(c.provisioners[provisionerName].metrics.provisionTotal.WithLabelValues(resultSuccess)); got != 1 {
+		t.Errorf("want 1 successful provision, got %v", got)
+	}
+	if got := testutil.ToFloat64(c.provisioners[provisionerName].metrics.deleteTotal.WithLabelValues(resultSuccess)); got != 1 {
+		t.Errorf("want 1 successful delete, got %v", got)
+	}
+	if got := testutil.ToFloat64(c.provisioners[provisionerName].metrics.provisionTotal.WithLabelValues(resultError)); got != 0 {
+		t.Errorf("want 0 failed provisions, got %v", got)
+	}
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("error gathering metrics: %v", err)
+	}
+	var got []string
+	for _, f := range families {
+		got = append(got, f.GetName())
+	}
+	want := []string{
+		"objectbucket_claim_phase_transitions_total",
+		"objectbucket_claims",
+		"objectbucket_delete_duration_seconds",
+		"objectbucket_delete_total",
+		"objectbucket_provision_duration_seconds",
+		"objectbucket_provision_total",
+	}
+	if !cmp.Equal(want, got) {
+		t.Errorf(cmp.Diff(want, got))
+	}
+}
+
+func TestSyncHandlerPhaseTransitionMetrics(t *testing.T) {
+	const key = testNamespace + "/" + testName
+
+	type transition struct{ from, to string }
+	tests := []struct {
+		name    string
+		subPath string
+		want    map[transition]float64
+	}{
+		{
+			name: "bound claim",
+			want: map[transition]float64{
+				{phaseNone, v1alpha1.ObjectBucketClaimStatusPhasePending}:                                         1,
+				{v1alpha1.ObjectBucketClaimStatusPhasePending, v1alpha1.ObjectBucketClaimStatusPhaseProvisioning}: 1,
+				{v1alpha1.ObjectBucketClaimStatusPhaseProvisioning, v1alpha1.ObjectBucketClaimStatusPhaseBound}:   1,
+				{v1alpha1.ObjectBucketClaimStatusPhasePending, v1alpha1.ObjectBucketClaimStatusPhaseFailed}:       0,
+			},
+		},
+		{
+			name:    "failed claim",
+			subPath: "/photos",
+			want: map[transition]float64{
+				{phaseNone, v1alpha1.ObjectBucketClaimStatusPhasePending}:                                       1,
+				{v1alpha1.ObjectBucketClaimStatusPhasePending, v1alpha1.ObjectBucketClaimStatusPhaseFailed}:     1,
+				{v1alpha1.ObjectBucketClaimStatusPhaseProvisioning, v1alpha1.ObjectBucketClaimStatusPhaseBound}: 0,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestController(&ControllerOptions{
+				RetryBaseInterval: time.Millisecond,
+				RetryTimeout:      time.Millisecond * 10,
+			})
+			c.recorder = record.NewFakeRecorder(20)
+			createTestClaim(t, c, &storagev1.StorageClass{
+				ObjectMeta:  metav1.ObjectMeta{Name: className},
+				Provisioner: provisionerName,
+			})
+			obcs := c.libClientset.ObjectbucketV1alpha1().ObjectBucketClaims(testNamespace)
+			obc, err := obcs.Get(testName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("error getting OBC: %v", err)
+			}
+			obc.Spec.BucketSubPath = tt.subPath
+			if _, err = obcs.Update(obc); err != nil {
+				t.Fatalf("error updating OBC: %v", err)
+			}
+
+			if err = c.syncHandler(context.Background(), key); err != nil {
+				t.Fatalf("syncHandler() error = %v", err)
+			}
+			transitions := c.provisioners[provisionerName].metrics.phaseTransitions
+			for tr, want := range tt.want {
+				if got := testutil.ToFloat64(transitions.WithLabelValues(tr.from, tr.to)); got != want {
+					t.Errorf("want %v transitions from %q to %q, got %v", want, tr.from, tr.to, got)
+				}
+			}
+		})
+	}
+}
+
+func TestSyncHandlerQuota(t *testing.T) {
+	const key = testNamespace + "/" + testName
+
+	tests := []struct {
+		name          string
+		parameters    map[string]string
+		wantPhase     v1alpha1.ObjectBucketClaimStatusPhase
+		wantCalls     []string
+		wantOBMaxSize string
+	}{
+		{
+			name:          "quota is recorded on the OB",
+			parameters:    map[string]string{v1alpha1.QuotaMaxSize: "5Gi"},
+			wantPhase:     v1alpha1.ObjectBucketClaimStatusPhaseBound,
+			wantCalls:     []string{"Provision"},
+			wantOBMaxSize: "5Gi",
+		},
+		{
+			name:       "invalid quota fails the claim",
+			parameters: map[string]string{v1alpha1.QuotaMaxSize: "lots"},
+			wantPhase:  v1alpha1.ObjectBucketClaimStatusPhaseFailed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestController(&ControllerOptions{
+				RetryBaseInterval: time.Millisecond,
+				RetryTimeout:      time.Millisecond * 10,
+			})
+			c.recorder = record.NewFakeRecorder(10)
+			p := c.provisioners[provisionerName].provisioner.(*fakeProvisioner)
+
+			createTestClaim(t, c, &storagev1.StorageClass{
+				ObjectMeta:  metav1.ObjectMeta{Name: className},
+				Provisioner: provisionerName,
+				Parameters:  tt.parameters,
+			})
+			if err := c.syncHandler(context.Background(), key); err != nil {
+				t.Fatalf("error syncing: %v", err)
+			}
+
+			if !cmp.Equal(tt.wantCalls, p.calls) {
+				t.Errorf(cmp.Diff(tt.wantCalls, p.calls))
+			}
+			obc, err := c.libClientset.ObjectbucketV1alpha1().ObjectBucketClaims(testNamespace).Get(testName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("error getting OBC: %v", err)
+			}
+			if obc.Status.Phase != tt.wantPhase {
+				t.Errorf("want OBC phase %q, got %q", tt.wantPhase, obc.Status.Phase)
+			}
+			if tt.wantOBMaxSize == "" {
+				return
+			}
+			ob, err := c.libClientset.ObjectbucketV1alpha1().ObjectBuckets().Get(obc.Spec.ObjectBucketName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("error getting OB: %v", err)
+			}
+			if ob.Spec.Quota == nil || ob.Spec.Quota.MaxSize == nil || ob.Spec.Quota.MaxSize.String() != tt.wantOBMaxSize {
+				t.Errorf("want OB maxSize %s, got %+v", tt.wantOBMaxSize, ob.Spec.Quota)
+			}
+		})
+	}
+}
+
+func TestConcurrentReconciles(t *testing.T) {
+	const (
+		workers = 3
+		claims  = 6
+	)
+	c := newTestController(&ControllerOptions{
+		RetryBaseInterval:       time.Millisecond,
+		RetryTimeout:            time.Millisecond * 10,
+		MaxConcurrentReconciles: workers,
+	})
+	c.recorder = record.NewFakeRecorder(claims * 10)
+	p := &blockingProvisioner{release: make(chan struct{})}
+	c.provisioners[provisionerName].provisioner = p
+
+	createTestClass(t, c, &storagev1.StorageClass{
+		ObjectMeta:  metav1.ObjectMeta{Name: className},
+		Provisioner: provisionerName,
+	})
+	for i := 0; i < claims; i++ {
+		name := fmt.Sprintf("%s-%d", testName, i)
+		if _, err := c.libClientset.ObjectbucketV1alpha1().ObjectBucketClaims(testNamespace).Create(&v1alpha1.ObjectBucketClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: testNamespace},
+			Spec: v1alpha1.ObjectBucketClaimSpec{
+				StorageClassName:   className,
+				GenerateBucketName: "test-bucket",
+			},
+		}); err != nil {
+			t.Fatalf("error pre-creating OBC: %v", err)
+		}
+		c.queue.Add(testNamespace + "/" + name)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	for i := 0; i < c.workers; i++ {
+		go c.runWorker(ctx)
+	}
+
+	// give every worker the time to pick up a claim before letting them complete
+	time.Sleep(time.Millisecond * 200)
+	close(p.release)
+	for deadline := time.Now().Add(time.Second * 5); c.queue.Len() > 0 && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond * 10)
+	}
+	c.queue.ShutDown()
+
+	if got := p.max(); got != workers {
+		t.Errorf("want %d concurrent reconciles, got %d", workers, got)
+	}
+}
+
+func TestSyncHandlerPhaseTransitions(t *testing.T) {
+	const key = testNamespace + "/" + testName
+
+	tests := []struct {
+		name    string
+		reactor k8stesting.ReactionFunc
+		want    []v1alpha1.ObjectBucketClaimStatusPhase
+	}{
+		{
+			name: "successful provisioning",
+			want: []v1alpha1.ObjectBucketClaimStatusPhase{
+				v1alpha1.ObjectBucketClaimStatusPhasePending,
+				v1alpha1.ObjectBucketClaimStatusPhaseProvisioning,
+				v1alpha1.ObjectBucketClaimStatusPhaseBound,
+			},
+		},
+		{
+			name: "failed secret creation leaves the claim provisioning",
+			reactor: func(action k8stesting.Action) (bool, runtime.Object, error) {
+				return true, nil, fmt.Errorf("injected error")
+			},
+			want: []v1alpha1.ObjectBucketClaimStatusPhase{
+				v1alpha1.ObjectBucketClaimStatusPhasePending,
+				v1alpha1.ObjectBucketClaimStatusPhaseProvisioning,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestController(&ControllerOptions{
+				RetryBaseInterval: time.Millisecond,
+				RetryTimeout:      time.Millisecond * 10,
+			})
+			c.recorder = record.NewFakeRecorder(10)
+			if tt.reactor != nil {
+				c.clientset.(*fake.Clientset).PrependReactor("create", "secrets", tt.reactor)
+			}
+			var got []v1alpha1.ObjectBucketClaimStatusPhase
+			c.libClientset.(*externalFake.Clientset).PrependReactor("update", "objectbucketclaims", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				if action.GetSubresource() == "status" {
+					// conditions are updated without a phase change
+					phase := action.(k8stesting.UpdateAction).GetObject().(*v1alpha1.ObjectBucketClaim).Status.Phase
+					if len(got) == 0 || got[len(got)-1] != phase {
+						got = append(got, phase)
+					}
+				}
+				return false, nil, nil
+			})
+
+			createTestClaim(t, c, &storagev1.StorageClass{
+				ObjectMeta:  metav1.ObjectMeta{Name: className},
+				Provisioner: provisionerName,
+			})
+			_ = c.syncHandler(context.Background(), key)
+
+			if !cmp.Equal(tt.want, got) {
+				t.Errorf(cmp.Diff(tt.want, got))
+			}
+		})
+	}
+}
+
+func TestSyncHandlerConditions(t *testing.T) {
+	const key = testNamespace + "/" + testName
+
+	tests := []struct {
+		name    string
+		reactor k8stesting.ReactionFunc
+		want    map[v1alpha1.ObjectBucketClaimConditionType]corev1.ConditionStatus
+	}{
+		{
+			name: "successful provisioning",
+			want: map[v1alpha1.ObjectBucketClaimConditionType]corev1.ConditionStatus{
+				v1alpha1.ObjectBucketClaimConditionBucketReady:    corev1.ConditionTrue,
+				v1alpha1.ObjectBucketClaimConditionSecretReady:    corev1.ConditionTrue,
+				v1alpha1.ObjectBucketClaimConditionConfigMapReady: corev1.ConditionTrue,
+			},
+		},
+		{
+			name: "secret creation fails",
+			reactor: func(action k8stesting.Action) (bool, runtime.Object, error) {
+				return true, nil, fmt.Errorf("injected error")
+			},
+			want: map[v1alpha1.ObjectBucketClaimConditionType]corev1.ConditionStatus{
+				v1alpha1.ObjectBucketClaimConditionBucketReady: corev1.ConditionTrue,
+				v1alpha1.ObjectBucketClaimConditionSecretReady: corev1.ConditionFalse,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestController(&ControllerOptions{
+				RetryBaseInterval: time.Millisecond,
+				RetryTimeout:      time.Millisecond * 10,
+			})
+			c.recorder = record.NewFakeRecorder(10)
+			if tt.reactor != nil {
+				c.clientset.(*fake.Clientset).PrependReactor("create", "secrets", tt.reactor)
+			}
+
+			createTestClaim(t, c, &storagev1.StorageClass{
+				ObjectMeta:  metav1.ObjectMeta{Name: className},
+				Provisioner: provisionerName,
+			})
+			_ = c.syncHandler(context.Background(), key)
+
+			obc, err := c.libClientset.ObjectbucketV1alpha1().ObjectBucketClaims(testNamespace).Get(testName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("error getting OBC: %v", err)
+			}
+			got := make(map[v1alpha1.ObjectBucketClaimConditionType]corev1.ConditionStatus)
+			for _, cond := range obc.Status.Conditions {
+				got[cond.Type] = cond.Status
+				if cond.Reason == "" || cond.Message == "" || cond.LastTransitionTime.IsZero() {
+					t.Errorf("want reason, message and transition time set, got %+v", cond)
+				}
+			}
+			if !cmp.Equal(tt.want, got) {
+				t.Errorf(cmp.Diff(tt.want, got))
+			}
+		})
+	}
+}
+
+func TestSyncHandlerCustomNames(t *testing.T) {
+	const (
+		key           = testNamespace + "/" + testName
+		secretName    = "app-credentials"
+		configMapName = "app-endpoint"
+	)
+
+	tests := []struct {
+		name       string
+		existing   *corev1.Secret
+		wantFailed bool
+		wantReason string
+	}{
+		{
+			name:       "secret and configmap use the custom names",
+			wantReason: corev1.EventTypeNormal + " " + eventReasonSecretCreated,
+		},
+		{
+			name: "secret name collides with an object not owned by the claim",
+			existing: &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: secretName, Namespace: testNamespace},
+			},
+			wantFailed: true,
+			wantReason: corev1.EventTypeWarning + " " + eventReasonSecretInUse,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestController(&ControllerOptions{
+				RetryBaseInterval: time.Millisecond,
+				RetryTimeout:      time.Millisecond * 10,
+			})
+			recorder := record.NewFakeRecorder(10)
+			c.recorder = recorder
+			obcs := c.libClientset.ObjectbucketV1alpha1().ObjectBucketClaims(testNamespace)
+
+			createTestClaim(t, c, &storagev1.StorageClass{
+				ObjectMeta:  metav1.ObjectMeta{Name: className},
+				Provisioner: provisionerName,
+			})
+			obc, err := obcs.Get(testName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("error getting OBC: %v", err)
+			}
+			obc.Spec.SecretName = secretName
+			obc.Spec.ConfigMapName = configMapName
+			if _, err = obcs.Update(obc); err != nil {
+				t.Fatalf("error updating OBC: %v", err)
+			}
+			if tt.existing != nil {
+				if _, err = c.clientset.CoreV1().Secrets(testNamespace).Create(tt.existing); err != nil {
+					t.Fatalf("error pre-creating secret: %v", err)
+				}
+			}
+
+			if err = c.syncHandler(context.Background(), key); err != nil {
+				t.Fatalf("syncHandler() error = %v", err)
+			}
+
+			close(recorder.Events)
+			var found bool
+			for e := range recorder.Events {
+				found = found || strings.HasPrefix(e, tt.wantReason+" ")
+			}
+			if !found {
+				t.Errorf("want event with reason %q", tt.wantReason)
+			}
+			if tt.wantFailed {
+				if obc, err = obcs.Get(testName, metav1.GetOptions{}); err != nil {
+					t.Fatalf("error getting OBC: %v", err)
+				}
+				if obc.Status.Phase != v1alpha1.ObjectBucketClaimStatusPhaseFailed {
+					t.Errorf("want phase %q, got %q", v1alpha1.ObjectBucketClaimStatusPhaseFailed, obc.Status.Phase)
+				}
+				return
+			}
+			if _, err = c.clientset.CoreV1().Secrets(testNamespace).Get(secretName, metav1.GetOptions{}); err != nil {
+				t.Errorf("want secret %q, got error %v", secretName, err)
+			}
+			if _, err = c.clientset.CoreV1().ConfigMaps(testNamespace).Get(configMapName, metav1.GetOptions{}); err != nil {
+				t.Errorf("want configmap %q, got error %v", configMapName, err)
+			}
+			if _, err = c.clientset.CoreV1().Secrets(testNamespace).Get(testName, metav1.GetOptions{}); !errors.IsNotFound(err) {
+				t.Errorf("want no secret named after the OBC, got error %v", err)
+			}
+		})
+	}
+}
+
+func TestSyncHandlerSecretInUse(t *testing.T) {
+	const key = testNamespace + "/" + testName
+
+	tests := []struct {
+		name string
+		// owner is the claim owning the pre-existing secret
+		owner *v1alpha1.ObjectBucketClaim
+		// race hides the secret from the check made before provisioning, as if it was created since
+		race        bool
+		wantCalls   []string
+		wantPhase   v1alpha1.ObjectBucketClaimStatusPhase
+		wantErr     bool
+		wantMessage string
+	}{
+		{
+			name:      "secret of the claim is adopted",
+			owner:     &v1alpha1.ObjectBucketClaim{ObjectMeta: metav1.ObjectMeta{Name: testName, Namespace: testNamespace, UID: "obc-uid"}},
+			wantCalls: []string{"Provision"},
+			wantPhase: v1alpha1.ObjectBucketClaimStatusPhaseBound,
+		},
+		{
+			name:        "secret of another claim fails the claim",
+			owner:       &v1alpha1.ObjectBucketClaim{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: testNamespace, UID: "other-uid"}},
+			wantPhase:   v1alpha1.ObjectBucketClaimStatusPhaseFailed,
+			wantMessage: `secret name already in use: Secret "test-name" belongs to OBC test-namespace/other`,
+		},
+		{
+			name:        "secret of another claim created during provisioning fails the claim",
+			owner:       &v1alpha1.ObjectBucketClaim{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: testNamespace, UID: "other-uid"}},
+			race:        true,
+			wantCalls:   []string{"Provision", "Delete"},
+			wantPhase:   v1alpha1.ObjectBucketClaimStatusPhaseFailed,
+			wantErr:     true,
+			wantMessage: `secret name already in use: Secret "test-name" belongs to OBC test-namespace/other`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestController(&ControllerOptions{
+				RetryBaseInterval: time.Millisecond,
+				RetryTimeout:      time.Millisecond * 10,
+			})
+			recorder := record.NewFakeRecorder(20)
+			c.recorder = recorder
+			p := c.provisioners[provisionerName].provisioner.(*fakeProvisioner)
+			obcs := c.libClientset.ObjectbucketV1alpha1().ObjectBucketClaims(testNamespace)
+
+			createTestClass(t, c, &storagev1.StorageClass{
+				ObjectMeta:  metav1.ObjectMeta{Name: className},
+				Provisioner: provisionerName,
+			})
+			if _, err := obcs.Create(&v1alpha1.ObjectBucketClaim{
+				ObjectMeta: metav1.ObjectMeta{Name: testName, Namespace: testNamespace, UID: "obc-uid"},
+				Spec:       v1alpha1.ObjectBucketClaimSpec{StorageClassName: className, GenerateBucketName: "test-bucket"},
+			}); err != nil {
+				t.Fatalf("error pre-creating OBC: %v", err)
+			}
+			// the secret's owner gave it credentials of its own, which must not be overwritten
+			existing, _ := newCredentialsSecret(tt.owner, &v1alpha1.Authentication{AccessKeys: &v1alpha1.AccessKeys{AccessKeyID: "owner-key"}}, nil, nil, defaultChildOptions)
+			existing.Name = testName
+			client := c.clientset.(*fake.Clientset)
+			if _, err := client.CoreV1().Secrets(testNamespace).Create(existing); err != nil {
+				t.Fatalf("error pre-creating secret: %v", err)
+			}
+			if tt.race {
+				hidden := false
+				client.PrependReactor("get", "secrets", func(action k8stesting.Action) (bool, runtime.Object, error) {
+					if hidden {
+						return false, nil, nil
+					}
+					hidden = true
+					return true, nil, errors.NewNotFound(corev1.Resource("secrets"), testName)
+				})
+			}
+
+			if err := c.syncHandler(context.Background(), key); (err != nil) != tt.wantErr {
+				t.Fatalf("wantErr %v, error = %v", tt.wantErr, err)
+			}
+
+			if diff := cmp.Diff(tt.wantCalls, p.calls); diff != "" {
+				t.Errorf("provisioner calls (-want +got):\n%s", diff)
+			}
+			obc, err := obcs.Get(testName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("error getting OBC: %v", err)
+			}
+			if obc.Status.Phase != tt.wantPhase {
+				t.Errorf("want phase %q, got %q", tt.wantPhase, obc.Status.Phase)
+			}
+			if obc.Status.FailureMessage != tt.wantMessage {
+				t.Errorf("want failure message %q, got %q", tt.wantMessage, obc.Status.FailureMessage)
+			}
+			if tt.wantPhase != v1alpha1.ObjectBucketClaimStatusPhaseFailed {
+				return
+			}
+			secret, err := client.Tracker().Get(corev1.SchemeGroupVersion.WithResource("secrets"), testNamespace, testName)
+			if err != nil {
+				t.Fatalf("want the other claim's secret kept, got error %v", err)
+			}
+			if diff := cmp.Diff(existing.StringData, secret.(*corev1.Secret).StringData); diff != "" {
+				t.Errorf("other claim's credentials changed (-want +got):\n%s", diff)
+			}
+			close(recorder.Events)
+			var inUse bool
+			for e := range recorder.Events {
+				inUse = inUse || strings.HasPrefix(e, corev1.EventTypeWarning+" "+eventReasonSecretInUse+" ")
+			}
+			if !inUse {
+				t.Errorf("want a %s event", eventReasonSecretInUse)
+			}
+		})
+	}
+}
+
+func TestSyncHandlerProvisionTimes(t *testing.T) {
+	const key = testNamespace + "/" + testName
+	c := newTestController(&ControllerOptions{
+		RetryBaseInterval: time.Millisecond,
+		RetryTimeout:      time.Millisecond * 10,
+	})
+	c.recorder = record.NewFakeRecorder(10)
+
+	before := metav1.Now()
+	createTestClaim(t, c, &storagev1.StorageClass{
+		ObjectMeta:  metav1.ObjectMeta{Name: className},
+		Provisioner: provisionerName,
+	})
+	if err := c.syncHandler(context.Background(), key); err != nil {
+		t.Fatalf("error syncing: %v", err)
+	}
+
+	obc, err := c.libClientset.ObjectbucketV1alpha1().ObjectBucketClaims(testNamespace).Get(testName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("error getting OBC: %v", err)
+	}
+	ob, err := c.libClientset.ObjectbucketV1alpha1().ObjectBuckets().Get(obc.Spec.ObjectBucketName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("error getting OB: %v", err)
+	}
+	status := ob.Status
+	if status.ProvisionedAt == nil || status.ProvisionedAt.Before(&before) {
+		t.Errorf("want OB provisioned after %v, got %v", before, status.ProvisionedAt)
+	}
+	if status.ProvisionDuration == nil || status.ProvisionDuration.Duration < 0 {
+		t.Errorf("want a provision duration, got %v", status.ProvisionDuration)
+	}
+	if status.LastTransitionTime == nil || (status.ProvisionedAt != nil && status.LastTransitionTime.Before(status.ProvisionedAt)) {
+		t.Errorf("want OB transitioned after it was provisioned at %v, got %v", status.ProvisionedAt, status.LastTransitionTime)
+	}
+}
+
+func TestSyncHandlerPostProvision(t *testing.T) {
+	const key = testNamespace + "/" + testName
+
+	tests := []struct {
+		name      string
+		hookErr   error
+		wantErr   bool
+		wantPhase v1alpha1.ObjectBucketClaimStatusPhase
+		wantCalls []string
+	}{
+		{
+			name:      "hook runs before the claim is bound",
+			wantPhase: v1alpha1.ObjectBucketClaimStatusPhaseBound,
+			wantCalls: []string{"Provision"},
+		},
+		{
+			name:      "hook error fails the reconcile",
+			hookErr:   fmt.Errorf("catalog unavailable"),
+			wantErr:   true,
+			wantPhase: v1alpha1.ObjectBucketClaimStatusPhaseProvisioning,
+			wantCalls: []string{"Provision", "Delete"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var c *obcController
+			var hookCalls int
+			c = newTestController(&ControllerOptions{
+				RetryBaseInterval: time.Millisecond,
+				RetryTimeout:      time.Millisecond * 10,
+				PostProvision: func(obc *v1alpha1.ObjectBucketClaim, ob *v1alpha1.ObjectBucket, cm *corev1.ConfigMap, secret *corev1.Secret) error {
+					hookCalls++
+					if diff := cmp.Diff([]string{"Provision"}, c.provisioners[provisionerName].provisioner.(*fakeProvisioner).calls); diff != "" {
+						t.Errorf("provisioner calls before the hook (-want +got):\n%s", diff)
+					}
+					if _, err := c.clientset.CoreV1().Secrets(testNamespace).Get(secret.Name, metav1.GetOptions{}); err != nil {
+						t.Errorf("want Secret created before the hook, got %v", err)
+					}
+					if _, err := c.clientset.CoreV1().ConfigMaps(testNamespace).Get(cm.Name, metav1.GetOptions{}); err != nil {
+						t.Errorf("want ConfigMap created before the hook, got %v", err)
+					}
+					if _, err := c.libClientset.ObjectbucketV1alpha1().ObjectBuckets().Get(ob.Name, metav1.GetOptions{}); err != nil {
+						t.Errorf("want OB created before the hook, got %v", err)
+					}
+					if obc.Status.Phase == v1alpha1.ObjectBucketClaimStatusPhaseBound {
+						t.Error("want OBC not bound before the hook")
+					}
+					return tt.hookErr
+				},
+			})
+			c.recorder = record.NewFakeRecorder(20)
+			p := c.provisioners[provisionerName].provisioner.(*fakeProvisioner)
+
+			createTestClaim(t, c, &storagev1.StorageClass{
+				ObjectMeta:  metav1.ObjectMeta{Name: className},
+				Provisioner: provisionerName,
+			})
+			if err := c.syncHandler(context.Background(), key); (err != nil) != tt.wantErr {
+				t.Errorf("wantErr %v, error = %v", tt.wantErr, err)
+			}
+
+			if hookCalls != 1 {
+				t.Errorf("want hook called once, got %d", hookCalls)
+			}
+			if diff := cmp.Diff(tt.wantCalls, p.calls); diff != "" {
+				t.Errorf("provisioner calls (-want +got):\n%s", diff)
+			}
+			obc, err := c.libClientset.ObjectbucketV1alpha1().ObjectBucketClaims(testNamespace).Get(testName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("error getting OBC: %v", err)
+			}
+			if obc.Status.Phase != tt.wantPhase {
+				t.Errorf("want OBC phase %q, got %q", tt.wantPhase, obc.Status.Phase)
+			}
+		})
+	}
+}
+
+func TestSyncHandlerGrantEndpoint(t *testing.T) {
+	const key = testNamespace + "/" + testName
+
+	tests := []struct {
+		name      string
+		endpoint  *v1alpha1.Endpoint
+		wantErr   bool
+		wantPhase v1alpha1.ObjectBucketClaimStatusPhase
+		wantCalls []string
+	}{
+		{
+			name: "populated endpoint is written verbatim",
+			endpoint: &v1alpha1.Endpoint{
+				BucketName: "existing-bucket",
+				BucketHost: "s3.eu-central-1.example.com",
+				Region:     "eu-central-1",
+				SubRegion:  "az-b",
+			},
+			wantPhase: v1alpha1.ObjectBucketClaimStatusPhaseBound,
+			wantCalls: []string{"Grant"},
+		},
+		{
+			name: "endpoint missing its region fails the claim",
+			endpoint: &v1alpha1.Endpoint{
+				BucketName: "existing-bucket",
+				BucketHost: "s3.example.com",
+			},
+			wantErr:   true,
+			wantPhase: v1alpha1.ObjectBucketClaimStatusPhaseFailed,
+			wantCalls: []string{"Grant", "Revoke"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestController(&ControllerOptions{
+				RetryBaseInterval: time.Millisecond,
+				RetryTimeout:      time.Millisecond * 10,
+			})
+			c.recorder = record.NewFakeRecorder(20)
+			p := c.provisioners[provisionerName].provisioner.(*fakeProvisioner)
+			p.grantEndpoint = tt.endpoint
+
+			createTestClaim(t, c, &storagev1.StorageClass{
+				ObjectMeta:  metav1.ObjectMeta{Name: className},
+				Provisioner: provisionerName,
+				Parameters: map[string]string{
+					v1alpha1.StorageClassBucket:    "existing-bucket",
+					v1alpha1.StorageClassRegion:    "us-east-1",
+					v1alpha1.StorageClassSubRegion: "az-a",
+				},
+			})
+			if err := c.syncHandler(context.Background(), key); (err != nil) != tt.wantErr {
+				t.Errorf("wantErr %v, error = %v", tt.wantErr, err)
+			}
+
+			if diff := cmp.Diff(tt.wantCalls, p.calls); diff != "" {
+				t.Errorf("provisioner calls (-want +got):\n%s", diff)
+			}
+			obc, err := c.libClientset.ObjectbucketV1alpha1().ObjectBucketClaims(testNamespace).Get(testName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("error getting OBC: %v", err)
+			}
+			if obc.Status.Phase != tt.wantPhase {
+				t.Errorf("want OBC phase %q, got %q", tt.wantPhase, obc.Status.Phase)
+			}
+			cm, err := c.clientset.CoreV1().ConfigMaps(testNamespace).Get(testName, metav1.GetOptions{})
+			if tt.wantErr {
+				if !errors.IsNotFound(err) {
+					t.Errorf("want no ConfigMap, got error %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("error getting ConfigMap: %v", err)
+			}
+			want, _ := newBucketConfigMap(obc, tt.endpoint, nil, nil, defaultChildOptions)
+			if diff := cmp.Diff(want.Data, cm.Data); diff != "" {
+				t.Errorf("ConfigMap data (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSyncHandlerChildFinalizers(t *testing.T) {
+	const key = testNamespace + "/" + testName
+	reclaimDelete := corev1.PersistentVolumeReclaimDelete
+
+	tests := []struct {
+		name           string
+		disabled       bool
+		wantFinalizers []string
+		wantReleases   int
+	}{
+		{
+			name:           "secret and configmap are finalized by default",
+			disabled:       false,
+			wantFinalizers: []string{finalizer},
+			wantReleases:   2,
+		},
+		{
+			name:           "disabled child finalizers leave the cleanup to the garbage collector",
+			disabled:       true,
+			wantFinalizers: nil,
+			wantReleases:   0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestController(&ControllerOptions{
+				RetryBaseInterval:      time.Millisecond,
+				RetryTimeout:           time.Millisecond * 10,
+				DisableChildFinalizers: tt.disabled,
+			})
+			c.recorder = record.NewFakeRecorder(20)
+			client := c.clientset.(*fake.Clientset)
+			obs := c.libClientset.ObjectbucketV1alpha1().ObjectBuckets()
+			obName, _ := objectBucketNameFromClaimKey(key)
+
+			createTestClaim(t, c, &storagev1.StorageClass{
+				ObjectMeta:    metav1.ObjectMeta{Name: className},
+				Provisioner:   provisionerName,
+				ReclaimPolicy: &reclaimDelete,
+			})
+			if err := c.syncHandler(context.Background(), key); err != nil {
+				t.Fatalf("error provisioning: %v", err)
+			}
+			secret, err := c.clientset.CoreV1().Secrets(testNamespace).Get(testName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("error getting secret: %v", err)
+			}
+			cm, err := c.clientset.CoreV1().ConfigMaps(testNamespace).Get(testName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("error getting configmap: %v", err)
+			}
+			if diff := cmp.Diff(tt.wantFinalizers, secret.Finalizers); diff != "" {
+				t.Errorf("secret finalizers (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tt.wantFinalizers, cm.Finalizers); diff != "" {
+				t.Errorf("configmap finalizers (-want +got):\n%s", diff)
+			}
+
+			// the fake clientset does not set UIDs, which the OB requires to be deleted
+			ob, err := obs.Get(obName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("error getting OB: %v", err)
+			}
+			ob.UID = "test-uid"
+			if _, err = obs.Update(ob); err != nil {
+				t.Fatalf("error updating OB: %v", err)
+			}
+			client.ClearActions()
+			deleteTestClaim(t, c)
+			if err := c.syncHandler(context.Background(), key); err != nil {
+				t.Fatalf("error deleting: %v", err)
+			}
+
+			releases := 0
+			for _, action := range client.Actions() {
+				if action.GetVerb() == "patch" && (action.GetResource().Resource == "secrets" || action.GetResource().Resource == "configmaps") {
+					releases++
+				}
+			}
+			if releases != tt.wantReleases {
+				t.Errorf("want %d secret and configmap patches, got %d", tt.wantReleases, releases)
+			}
+			// nothing but their ownerReference keeps the garbage collector from deleting them once the OBC is gone
+			obc, err := c.libClientset.ObjectbucketV1alpha1().ObjectBucketClaims(testNamespace).Get(testName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("error getting OBC: %v", err)
+			}
+			if len(obc.Finalizers) != 0 {
+				t.Errorf("want OBC finalizers removed, got %v", obc.Finalizers)
+			}
+			secret, _ = c.clientset.CoreV1().Secrets(testNamespace).Get(testName, metav1.GetOptions{})
+			cm, _ = c.clientset.CoreV1().ConfigMaps(testNamespace).Get(testName, metav1.GetOptions{})
+			for _, obj := range []metav1.Object{secret, cm} {
+				if len(obj.GetFinalizers()) != 0 {
+					t.Errorf("want %s finalizers removed, got %v", obj.GetName(), obj.GetFinalizers())
+				}
+				if refs := obj.GetOwnerReferences(); len(refs) != 1 || refs[0].Name != testName {
+					t.Errorf("want %s owned by the OBC, got %v", obj.GetName(), refs)
+				}
+			}
+		})
+	}
+}
+
+func TestSyncHandlerMultipleProvisioners(t *testing.T) {
+	const (
+		key          = testNamespace + "/" + testName
+		provisionerA = "a.objectbucket.io/provisioner"
+		provisionerB = "b.objectbucket.io/provisioner"
+	)
+	tests := []struct {
+		name        string
+		class       *storagev1.StorageClass
+		wantErr     bool
+		wantCallsA  []string
+		wantCallsB  []string
+		wantLabeled string
+	}{
+		{
+			name: "claim is dispatched to its class's provisioner",
+			class: &storagev1.StorageClass{
+				ObjectMeta:  metav1.ObjectMeta{Name: className},
+				Provisioner: provisionerB,
+			},
+			wantCallsB:  []string{"Provision"},
+			wantLabeled: labelValue(provisionerB),
+		},
+		{
+			name: "claim of an unregistered provisioner is ignored",
+			class: &storagev1.StorageClass{
+				ObjectMeta:  metav1.ObjectMeta{Name: className},
+				Provisioner: "c.objectbucket.io/provisioner",
+			},
+		},
+		{
+			name: "claim of a missing class is not provisioned",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pA, pB := &fakeProvisioner{}, &fakeProvisioner{}
+			extClient := externalFake.NewSimpleClientset()
+			factory := informers.NewSharedInformerFactory(extClient, 0)
+			c := NewMultiController(
+				map[string]api.Provisioner{provisionerA: pA, provisionerB: pB},
+				fake.NewSimpleClientset(),
+				extClient,
+				factory.Objectbucket().V1alpha1().ObjectBucketClaims(),
+				factory.Objectbucket().V1alpha1().ObjectBuckets(),
+				&ControllerOptions{
+					RetryBaseInterval: time.Millisecond,
+					RetryTimeout:      time.Millisecond * 10,
+				})
+			c.recorder = record.NewFakeRecorder(10)
+			c.classHasSynced = func() bool { return true }
+
+			if tt.class != nil {
+				createTestClaim(t, c, tt.class)
+			} else if _, err := c.libClientset.ObjectbucketV1alpha1().ObjectBucketClaims(testNamespace).Create(&v1alpha1.ObjectBucketClaim{
+				ObjectMeta: objMeta,
+				Spec:       v1alpha1.ObjectBucketClaimSpec{StorageClassName: className},
+			}); err != nil {
+				t.Fatalf("error pre-creating OBC: %v", err)
+			}
+
+			if err := c.syncHandler(context.Background(), key); (err != nil) != tt.wantErr {
+				t.Errorf("wantErr %v, error = %v", tt.wantErr, err)
+			}
+			if diff := cmp.Diff(tt.wantCallsA, pA.calls); diff != "" {
+				t.Errorf("provisioner A calls (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tt.wantCallsB, pB.calls); diff != "" {
+				t.Errorf("provisioner B calls (-want +got):\n%s", diff)
+			}
+
+			obs, err := c.libClientset.ObjectbucketV1alpha1().ObjectBuckets().List(metav1.ListOptions{})
+			if err != nil {
+				t.Fatalf("error listing OBs: %v", err)
+			}
+			if tt.wantLabeled == "" {
+				if len(obs.Items) != 0 {
+					t.Errorf("want no OB, got %d", len(obs.Items))
+				}
+				return
+			}
+			if len(obs.Items) != 1 {
+				t.Fatalf("want 1 OB, got %d", len(obs.Items))
+			}
+			if got := obs.Items[0].Labels[provisionerLabelKey]; got != tt.wantLabeled {
+				t.Errorf("want OB labeled %q, got %q", tt.wantLabeled, got)
+			}
+		})
+	}
+}
+
+func TestSyncHandlerMissingStorageClass(t *testing.T) {
+	const key = testNamespace + "/" + testName
+	reclaimDelete := corev1.PersistentVolumeReclaimDelete
+
+	tests := []struct {
+		name      string
+		provision bool
+		delete    bool
+		wantPhase v1alpha1.ObjectBucketClaimStatusPhase
+		wantCalls []string
+		wantGone  bool
+	}{
+		{
+			name:      "bound claim is skipped",
+			provision: true,
+			wantPhase: v1alpha1.ObjectBucketClaimStatusPhaseBound,
+			wantCalls: []string{"Provision"},
+		},
+		{
+			name:      "pending claim fails",
+			wantPhase: v1alpha1.ObjectBucketClaimStatusPhaseFailed,
+		},
+		{
+			// without its class a new bucket cannot be told from an existing one, so access is only revoked
+			name:      "deleted claim is cleaned up by its provisioner",
+			provision: true,
+			delete:    true,
+			wantCalls: []string{"Provision", "Revoke"},
+			wantGone:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestController(&ControllerOptions{
+				RetryBaseInterval: time.Millisecond,
+				RetryTimeout:      time.Millisecond * 10,
+			})
+			c.recorder = record.NewFakeRecorder(20)
+			p := c.provisioners[provisionerName].provisioner.(*fakeProvisioner)
+			obcs := c.libClientset.ObjectbucketV1alpha1().ObjectBucketClaims(testNamespace)
+			obs := c.libClientset.ObjectbucketV1alpha1().ObjectBuckets()
+
+			createTestClaim(t, c, &storagev1.StorageClass{
+				ObjectMeta:    metav1.ObjectMeta{Name: className},
+				Provisioner:   provisionerName,
+				ReclaimPolicy: &reclaimDelete,
+			})
+			if tt.provision {
+				if err := c.syncHandler(context.Background(), key); err != nil {
+					t.Fatalf("error provisioning: %v", err)
+				}
+			} else {
+				obc, err := obcs.Get(testName, metav1.GetOptions{})
+				if err != nil {
+					t.Fatalf("error getting OBC: %v", err)
+				}
+				obc.Status.Phase = v1alpha1.ObjectBucketClaimStatusPhasePending
+				if _, err = obcs.Update(obc); err != nil {
+					t.Fatalf("error updating OBC: %v", err)
+				}
+			}
+			if tt.delete {
+				// the fake clientset does not set UIDs, which the OB requires to be deleted
+				obc, err := obcs.Get(testName, metav1.GetOptions{})
+				if err != nil {
+					t.Fatalf("error getting OBC: %v", err)
+				}
+				ob, err := obs.Get(obc.Spec.ObjectBucketName, metav1.GetOptions{})
+				if err != nil {
+					t.Fatalf("error getting OB: %v", err)
+				}
+				ob.UID = "test-uid"
+				if _, err = obs.Update(ob); err != nil {
+					t.Fatalf("error updating OB: %v", err)
+				}
+				deleteTestClaim(t, c)
+			}
+			if err := c.clientset.StorageV1().StorageClasses().Delete(className, &metav1.DeleteOptions{}); err != nil {
+				t.Fatalf("error deleting StorageClass: %v", err)
+			}
+			if err := c.classInformer.GetIndexer().Delete(&storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: className}}); err != nil {
+				t.Fatalf("error uncaching StorageClass: %v", err)
+			}
+
+			if err := c.syncHandler(context.Background(), key); err != nil {
+				t.Errorf("error syncing: %v", err)
+			}
+
+			if diff := cmp.Diff(tt.wantCalls, p.calls); diff != "" {
+				t.Errorf("provisioner calls (-want +got):\n%s", diff)
+			}
+			obc, err := obcs.Get(testName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("error getting OBC: %v", err)
+			}
+			if tt.wantGone {
+				if len(obc.Finalizers) != 0 {
+					t.Errorf("want OBC released, got finalizers %v", obc.Finalizers)
+				}
+				return
+			}
+			if obc.Status.Phase != tt.wantPhase {
+				t.Errorf("want OBC phase %q, got %q", tt.wantPhase, obc.Status.Phase)
+			}
+		})
+	}
+}
+
+func TestSyncHandlerRotateCredentials(t *testing.T) {
+	const key = testNamespace + "/" + testName
+	newKeys := map[string]string{
+		v1alpha1.AwsKeyField:    "key-1",
+		v1alpha1.AwsSecretField: "secret-1",
+	}
+	oldKeys := map[string]string{
+		v1alpha1.AwsKeyField:    "",
+		v1alpha1.AwsSecretField: "",
+	}
+
+	tests := []struct {
+		name          string
+		initialRotate string
+		rotate        string
+		wantCalls     []string
+		wantRotation  bool
+	}{
+		{
+			name:      "bound claim without rotate annotation is left alone",
+			wantCalls: []string{"Provision"},
+		},
+		{
+			name:         "setting the rotate annotation rotates the credentials",
+			rotate:       "1",
+			wantCalls:    []string{"Provision", "RotateCredentials", "RevokeCredentials"},
+			wantRotation: true,
+		},
+		{
+			name:          "bumping the rotate annotation rotates the credentials",
+			initialRotate: "1",
+			rotate:        "2",
+			wantCalls:     []string{"Provision", "RotateCredentials", "RevokeCredentials"},
+			wantRotation:  true,
+		},
+		{
+			name:          "rotate annotation set before binding is satisfied by the new credentials",
+			initialRotate: "1",
+			rotate:        "1",
+			wantCalls:     []string{"Provision"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestController(&ControllerOptions{
+				RetryBaseInterval: time.Millisecond,
+				RetryTimeout:      time.Millisecond * 10,
+			})
+			c.recorder = record.NewFakeRecorder(20)
+			p := &rotatingProvisioner{
+				secrets:    c.clientset.CoreV1().Secrets(testNamespace),
+				secretName: testName,
+			}
+			c.provisioners[provisionerName].provisioner = p
+			obcs := c.libClientset.ObjectbucketV1alpha1().ObjectBucketClaims(testNamespace)
+
+			createTestClaim(t, c, &storagev1.StorageClass{
+				ObjectMeta:  metav1.ObjectMeta{Name: className},
+				Provisioner: provisionerName,
+			})
+			if tt.initialRotate != "" {
+				obc, err := obcs.Get(testName, metav1.GetOptions{})
+				if err != nil {
+					t.Fatalf("error getting OBC: %v", err)
+				}
+				obc.Annotations = map[string]string{v1alpha1.RotateAnnotation: tt.initialRotate}
+				if _, err = obcs.Update(obc); err != nil {
+					t.Fatalf("error annotating OBC: %v", err)
+				}
+			}
+			if err := c.syncHandler(context.Background(), key); err != nil {
+				t.Fatalf("error provisioning: %v", err)
+			}
+
+			obc, err := obcs.Get(testName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("error getting OBC: %v", err)
+			}
+			if tt.rotate != "" {
+				obc.Annotations = map[string]string{v1alpha1.RotateAnnotation: tt.rotate}
+				if _, err = obcs.Update(obc); err != nil {
+					t.Fatalf("error annotating OBC: %v", err)
+				}
+			}
+			// the second sync must not rotate again
+			for i := 0; i < 2; i++ {
+				if err := c.syncHandler(context.Background(), key); err != nil {
+					t.Fatalf("error syncing: %v", err)
+				}
+			}
+
+			if diff := cmp.Diff(tt.wantCalls, p.calls); diff != "" {
+				t.Errorf("provisioner calls (-want +got):\n%s", diff)
+			}
+			secret, err := c.clientset.CoreV1().Secrets(testNamespace).Get(testName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("error getting secret: %v", err)
+			}
+			obc, err = obcs.Get(testName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("error getting OBC: %v", err)
+			}
+			if !tt.wantRotation {
+				if diff := cmp.Diff(oldKeys, secretData(secret)); diff != "" {
+					t.Errorf("secret (-want +got):\n%s", diff)
+				}
+				if obc.Status.LastRotationTime != nil {
+					t.Errorf("want no rotation time, got %v", obc.Status.LastRotationTime)
+				}
+				return
+			}
+			if diff := cmp.Diff(newKeys, secretData(secret)); diff != "" {
+				t.Errorf("secret (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(newKeys, p.secretAtRevoke); diff != "" {
+				t.Errorf("previous credentials revoked before the secret was updated (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(oldKeys, p.revokedPrevious); diff != "" {
+				t.Errorf("revoked credentials (-want +got):\n%s", diff)
+			}
+			if obc.Status.LastRotation != tt.rotate || obc.Status.LastRotationTime == nil {
+				t.Errorf("want rotation %q recorded with its time, got %q at %v",
+					tt.rotate, obc.Status.LastRotation, obc.Status.LastRotationTime)
+			}
+		})
+	}
+}
+
+func TestSyncHandlerConfigMapDrift(t *testing.T) {
+	const key = testNamespace + "/" + testName
+
+	tests := []struct {
+		name        string
+		mutate      func(data map[string]string)
+		wantUpdated bool
+	}{
+		{
+			name:   "unchanged configmap is not updated",
+			mutate: func(data map[string]string) {},
+		},
+		{
+			name: "edited bucket host is restored",
+			mutate: func(data map[string]string) {
+				data[bucketHost] = "wrong.example.com"
+			},
+			wantUpdated: true,
+		},
+		{
+			name: "removed bucket name is restored",
+			mutate: func(data map[string]string) {
+				delete(data, bucketName)
+			},
+			wantUpdated: true,
+		},
+		{
+			name: "added reserved key is removed",
+			mutate: func(data map[string]string) {
+				data[bucketSSL] = "true"
+			},
+			wantUpdated: true,
+		},
+		{
+			name: "user added key is left alone",
+			mutate: func(data map[string]string) {
+				data["USER_KEY"] = "user-value"
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestController(&ControllerOptions{
+				RetryBaseInterval: time.Millisecond,
+				RetryTimeout:      time.Millisecond * 10,
+			})
+			recorder := record.NewFakeRecorder(20)
+			c.recorder = recorder
+			configMaps := c.clientset.CoreV1().ConfigMaps(testNamespace)
+
+			createTestClaim(t, c, &storagev1.StorageClass{
+				ObjectMeta:  metav1.ObjectMeta{Name: className},
+				Provisioner: provisionerName,
+			})
+			if err := c.syncHandler(context.Background(), key); err != nil {
+				t.Fatalf("error provisioning: %v", err)
+			}
+			cm, err := configMaps.Get(testName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("error getting configmap: %v", err)
+			}
+			provisioned := make(map[string]string, len(cm.Data))
+			for k, v := range cm.Data {
+				provisioned[k] = v
+			}
+			tt.mutate(cm.Data)
+			mutated := make(map[string]string, len(cm.Data))
+			for k, v := range cm.Data {
+				mutated[k] = v
+			}
+			if _, err = configMaps.Update(cm); err != nil {
+				t.Fatalf("error mutating configmap: %v", err)
+			}
+			// drain the provisioning events
+			for len(recorder.Events) > 0 {
+				<-recorder.Events
+			}
+
+			if err := c.syncHandler(context.Background(), key); err != nil {
+				t.Fatalf("error syncing bound claim: %v", err)
+			}
+
+			cm, err = configMaps.Get(testName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("error getting configmap: %v", err)
+			}
+			want := mutated
+			if tt.wantUpdated {
+				want = provisioned
+			}
+			if diff := cmp.Diff(want, cm.Data); diff != "" {
+				t.Errorf("configmap data (-want +got):\n%s", diff)
+			}
+			if got := len(recorder.Events) > 0; got != tt.wantUpdated {
+				t.Errorf("want update event %v, got %v", tt.wantUpdated, got)
+			}
+		})
+	}
+}
+
+func TestSyncHandlerEndpointChange(t *testing.T) {
+	const key = testNamespace + "/" + testName
+
+	c := newTestController(&ControllerOptions{
+		RetryBaseInterval: time.Millisecond,
+		RetryTimeout:      time.Millisecond * 10,
+	})
+	recorder := record.NewFakeRecorder(20)
+	c.recorder = recorder
+	createTestClaim(t, c, &storagev1.StorageClass{
+		ObjectMeta:  metav1.ObjectMeta{Name: className},
+		Provisioner: provisionerName,
+	})
+	if err := c.syncHandler(context.Background(), key); err != nil {
+		t.Fatalf("error provisioning: %v", err)
+	}
+	for len(recorder.Events) > 0 {
+		<-recorder.Events
+	}
+
+	obName := objectBucketName(testNamespace, testName)
+	obs := c.libClientset.ObjectbucketV1alpha1().ObjectBuckets()
+	old, err := obs.Get(obName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("error getting OB: %v", err)
+	}
+	// the provisioner migrates the bucket to a new host
+	ob := old.DeepCopy()
+	ob.Spec.Endpoint.BucketHost = "s3.new.example.com"
+	ob.Spec.Endpoint.BucketPort = 8443
+	ob.ResourceVersion = "2"
+	if _, err = obs.Update(ob); err != nil {
+		t.Fatalf("error updating OB: %v", err)
+	}
+
+	c.updateOB(old, ob)
+	if c.queue.Len() != 1 {
+		t.Fatalf("want the claim enqueued on endpoint change, got %d keys", c.queue.Len())
+	}
+	item, _ := c.queue.Get()
+	c.queue.Done(item)
+	if item != key {
+		t.Errorf("want key %q enqueued, got %v", key, item)
+	}
+	// other changes of the OB are ignored
+	relabeled := ob.DeepCopy()
+	relabeled.Labels = map[string]string{"team": "a"}
+	relabeled.ResourceVersion = "3"
+	c.updateOB(ob, relabeled)
+	if c.queue.Len() != 0 {
+		t.Errorf("want the claim not enqueued on other changes, got %d keys", c.queue.Len())
+	}
+
+	if err = c.syncHandler(context.Background(), key); err != nil {
+		t.Fatalf("error syncing bound claim: %v", err)
+	}
+	cm, err := c.clientset.CoreV1().ConfigMaps(testNamespace).Get(testName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("error getting configmap: %v", err)
+	}
+	for k, want := range map[string]string{
+		bucketHost:     "s3.new.example.com",
+		bucketPort:     "8443",
+		bucketURL:      "http://s3.new.example.com:8443",
+		bucketEndpoint: "http://s3.new.example.com:8443",
+	} {
+		if got := cm.Data[k]; got != want {
+			t.Errorf("want %s %q, got %q", k, want, got)
+		}
+	}
+	wantEvent := fmt.Sprintf("%s %s Updated ConfigMap %q to the endpoint of ObjectBucket %q",
+		corev1.EventTypeNormal, eventReasonEndpointChanged, testName, obName)
+	select {
+	case got := <-recorder.Events:
+		if got != wantEvent {
+			t.Errorf("want event %q, got %q", wantEvent, got)
+		}
+	default:
+		t.Errorf("want event %q, got none", wantEvent)
+	}
+
+	// the updated configmap is in sync
+	if err = c.syncHandler(context.Background(), key); err != nil {
+		t.Fatalf("error syncing bound claim: %v", err)
+	}
+	if len(recorder.Events) != 0 {
+		t.Errorf("want no event once in sync, got %q", <-recorder.Events)
+	}
+}
+
+func TestSyncHandlerParameterSchema(t *testing.T) {
+	const key = testNamespace + "/" + testName
+
+	tests := []struct {
+		name       string
+		schema     api.ParameterSchema
+		parameters map[string]string
+		wantPhase  v1alpha1.ObjectBucketClaimStatusPhase
+		wantCalls  []string
+		wantEvent  string
+	}{
+		{
+			name:       "strict schema accepts known and library parameters",
+			schema:     api.ParameterSchema{Keys: []string{"storageTier"}, Strict: true},
+			parameters: map[string]string{"storageTier": "cold", v1alpha1.StorageClassRegion: "us-east-1"},
+			wantPhase:  v1alpha1.ObjectBucketClaimStatusPhaseBound,
+			wantCalls:  []string{"Provision"},
+		},
+		{
+			name:       "strict schema rejects unknown parameters",
+			schema:     api.ParameterSchema{Keys: []string{"region"}, Strict: true},
+			parameters: map[string]string{"reigon": "us-east-1", "tier": "cold"},
+			wantPhase:  v1alpha1.ObjectBucketClaimStatusPhaseFailed,
+			wantEvent:  corev1.EventTypeWarning + " " + eventReasonInvalidParameters + " invalid StorageClass parameters: unknown parameters reigon, tier",
+		},
+		{
+			name:       "lenient schema passes unknown parameters through",
+			schema:     api.ParameterSchema{Keys: []string{"region"}},
+			parameters: map[string]string{"reigon": "us-east-1"},
+			wantPhase:  v1alpha1.ObjectBucketClaimStatusPhaseBound,
+			wantCalls:  []string{"Provision"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestController(&ControllerOptions{
+				RetryBaseInterval: time.Millisecond,
+				RetryTimeout:      time.Millisecond * 10,
+			})
+			recorder := record.NewFakeRecorder(20)
+			c.recorder = recorder
+			p := &schemaProvisioner{schema: tt.schema}
+			c.provisioners[provisionerName].provisioner = p
+
+			createTestClaim(t, c, &storagev1.StorageClass{
+				ObjectMeta:  metav1.ObjectMeta{Name: className},
+				Provisioner: provisionerName,
+				Parameters:  tt.parameters,
+			})
+			if err := c.syncHandler(context.Background(), key); err != nil {
+				t.Fatalf("error syncing: %v", err)
+			}
+
+			obc, err := c.libClientset.ObjectbucketV1alpha1().ObjectBucketClaims(testNamespace).Get(testName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("error getting OBC: %v", err)
+			}
+			if obc.Status.Phase != tt.wantPhase {
+				t.Errorf("want phase %q, got %q", tt.wantPhase, obc.Status.Phase)
+			}
+			if diff := cmp.Diff(tt.wantCalls, p.calls); diff != "" {
+				t.Errorf("provisioner calls (-want +got):\n%s", diff)
+			}
+			if tt.wantEvent == "" {
+				return
+			}
+			close(recorder.Events)
+			var found bool
+			for e := range recorder.Events {
+				found = found || e == tt.wantEvent
+			}
+			if !found {
+				t.Errorf("want event %q", tt.wantEvent)
+			}
+		})
+	}
+}
+
+func TestSyncHandlerDefaultParameters(t *testing.T) {
+	const key = testNamespace + "/" + testName
+	defaults := map[string]string{
+		v1alpha1.QuotaMaxObjects:    "10",
+		v1alpha1.StorageClassRegion: "default-region",
+		"storageTier":               "cold",
+	}
+
+	tests := []struct {
+		name           string
+		defaults       map[string]string
+		parameters     map[string]string
+		obcConfig      map[string]string
+		wantParameters map[string]string
+		wantMaxObjects int64
+	}{
+		{
+			name:           "defaults apply to parameters the StorageClass omits",
+			defaults:       defaults,
+			wantParameters: defaults,
+			wantMaxObjects: 10,
+		},
+		{
+			name:       "StorageClass parameters take precedence over defaults",
+			defaults:   defaults,
+			parameters: map[string]string{v1alpha1.QuotaMaxObjects: "20", "storageTier": "hot"},
+			wantParameters: map[string]string{
+				v1alpha1.QuotaMaxObjects:    "20",
+				v1alpha1.StorageClassRegion: "default-region",
+				"storageTier":               "hot",
+			},
+			wantMaxObjects: 20,
+		},
+		{
+			name:       "OBC values take precedence over the StorageClass and defaults",
+			defaults:   defaults,
+			parameters: map[string]string{v1alpha1.QuotaMaxObjects: "20"},
+			obcConfig:  map[string]string{v1alpha1.QuotaMaxObjects: "30"},
+			wantParameters: map[string]string{
+				v1alpha1.QuotaMaxObjects:    "20",
+				v1alpha1.StorageClassRegion: "default-region",
+				"storageTier":               "cold",
+			},
+			wantMaxObjects: 30,
+		},
+		{
+			name:           "a default bucket name is ignored",
+			defaults:       map[string]string{v1alpha1.StorageClassBucket: "existing-bucket", v1alpha1.QuotaMaxObjects: "10"},
+			wantParameters: map[string]string{v1alpha1.QuotaMaxObjects: "10"},
+			wantMaxObjects: 10,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestController(&ControllerOptions{
+				RetryBaseInterval: time.Millisecond,
+				RetryTimeout:      time.Millisecond * 10,
+			})
+			p := &defaultingProvisioner{defaults: tt.defaults}
+			c.provisioners[provisionerName].provisioner = p
+
+			createTestClass(t, c, &storagev1.StorageClass{
+				ObjectMeta:  metav1.ObjectMeta{Name: className},
+				Provisioner: provisionerName,
+				Parameters:  tt.parameters,
+			})
+			if _, err := c.libClientset.ObjectbucketV1alpha1().ObjectBucketClaims(testNamespace).Create(&v1alpha1.ObjectBucketClaim{
+				ObjectMeta: objMeta,
+				Spec: v1alpha1.ObjectBucketClaimSpec{
+					StorageClassName:   className,
+					GenerateBucketName: "test-bucket",
+					AdditionalConfig:   tt.obcConfig,
+				},
+			}); err != nil {
+				t.Fatalf("error pre-creating OBC: %v", err)
+			}
+			if err := c.syncHandler(context.Background(), key); err != nil {
+				t.Fatalf("error syncing: %v", err)
+			}
+
+			if diff := cmp.Diff([]string{"Provision"}, p.calls); diff != "" {
+				t.Fatalf("provisioner calls (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tt.wantParameters, p.options.Parameters); diff != "" {
+				t.Errorf("parameters mismatch (-want +got):\n%s", diff)
+			}
+			if q := p.options.Quota; q == nil || q.MaxObjects == nil || *q.MaxObjects != tt.wantMaxObjects {
+				t.Errorf("want quota of %d objects, got %v", tt.wantMaxObjects, q)
+			}
+		})
+	}
+}
+
+func TestSyncHandlerTags(t *testing.T) {
+	const key = testNamespace + "/" + testName
+
+	tests := []struct {
+		name      string
+		tags      string
+		wantPhase v1alpha1.ObjectBucketClaimStatusPhase
+		wantCalls []string
+		wantTags  map[string]string
+	}{
+		{
+			name:      "claim without tags",
+			wantPhase: v1alpha1.ObjectBucketClaimStatusPhaseBound,
+			wantCalls: []string{"Provision"},
+		},
+		{
+			name:      "tags are passed to the provisioner and recorded on the OB",
+			tags:      "cost-center=1234, environment=prod",
+			wantPhase: v1alpha1.ObjectBucketClaimStatusPhaseBound,
+			wantCalls: []string{"Provision"},
+			wantTags:  map[string]string{"cost-center": "1234", "environment": "prod"},
+		},
+		{
+			name:      "invalid tags fail the claim",
+			tags:      "cost-center",
+			wantPhase: v1alpha1.ObjectBucketClaimStatusPhaseFailed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestController(&ControllerOptions{
+				RetryBaseInterval: time.Millisecond,
+				RetryTimeout:      time.Millisecond * 10,
+			})
+			c.recorder = record.NewFakeRecorder(10)
+			p := c.provisioners[provisionerName].provisioner.(*fakeProvisioner)
+			obcs := c.libClientset.ObjectbucketV1alpha1().ObjectBucketClaims(testNamespace)
+
+			createTestClaim(t, c, &storagev1.StorageClass{
+				ObjectMeta:  metav1.ObjectMeta{Name: className},
+				Provisioner: provisionerName,
+			})
+			if tt.tags != "" {
+				obc, err := obcs.Get(testName, metav1.GetOptions{})
+				if err != nil {
+					t.Fatalf("error getting OBC: %v", err)
+				}
+				obc.Annotations = map[string]string{v1alpha1.TagsAnnotation: tt.tags}
+				if _, err = obcs.Update(obc); err != nil {
+					t.Fatalf("error annotating OBC: %v", err)
+				}
+			}
+			if err := c.syncHandler(context.Background(), key); err != nil {
+				t.Fatalf("error syncing: %v", err)
+			}
+
+			if diff := cmp.Diff(tt.wantCalls, p.calls); diff != "" {
+				t.Errorf("provisioner calls (-want +got):\n%s", diff)
+			}
+			obc, err := obcs.Get(testName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("error getting OBC: %v", err)
+			}
+			if obc.Status.Phase != tt.wantPhase {
+				t.Errorf("want OBC phase %q, got %q", tt.wantPhase, obc.Status.Phase)
+			}
+			if tt.wantPhase != v1alpha1.ObjectBucketClaimStatusPhaseBound {
+				return
+			}
+			if diff := cmp.Diff(tt.wantTags, p.options.Tags); diff != "" {
+				t.Errorf("provisioner options tags (-want +got):\n%s", diff)
+			}
+			ob, err := c.libClientset.ObjectbucketV1alpha1().ObjectBuckets().Get(obc.Spec.ObjectBucketName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("error getting OB: %v", err)
+			}
+			if diff := cmp.Diff(tt.wantTags, ob.Spec.Tags); diff != "" {
+				t.Errorf("OB tags (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSyncHandlerDeletionTimeout(t *testing.T) {
+	const key = testNamespace + "/" + testName
+	reclaimDelete := corev1.PersistentVolumeReclaimDelete
+
+	tests := []struct {
+		name           string
+		force          bool
+		deletedAgo     time.Duration
+		deleteFailures int
+		notReady       bool
+		syncs          int
+		wantErr        bool
+		wantRequeue    bool
+		wantCalls      []string
+		wantCondition  bool
+		wantFinalizers bool
+	}{
+		{
+			name:           "failed deletion is retried until it succeeds",
+			deletedAgo:     time.Second,
+			deleteFailures: 1,
+			syncs:          2,
+			wantCalls:      []string{"Provision", "Delete", "Delete"},
+		},
+		{
+			name:           "timed out deletion is reported and retried",
+			deletedAgo:     time.Hour,
+			deleteFailures: 2,
+			syncs:          1,
+			wantErr:        true,
+			wantCalls:      []string{"Provision", "Delete"},
+			wantCondition:  true,
+			wantFinalizers: true,
+		},
+		{
+			name:           "timed out deletion is forced",
+			force:          true,
+			deletedAgo:     time.Hour,
+			deleteFailures: 2,
+			syncs:          1,
+			wantCalls:      []string{"Provision", "Delete"},
+			wantCondition:  true,
+		},
+		{
+			name:           "bucket not ready for deletion is requeued and not forced",
+			force:          true,
+			deletedAgo:     time.Hour,
+			deleteFailures: 2,
+			notReady:       true,
+			syncs:          1,
+			wantErr:        true,
+			wantRequeue:    true,
+			wantCalls:      []string{"Provision", "Delete"},
+			wantFinalizers: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestController(&ControllerOptions{
+				RetryBaseInterval:         time.Millisecond,
+				RetryTimeout:              time.Millisecond * 10,
+				DeletionTimeout:           time.Minute,
+				ForceDeletionAfterTimeout: tt.force,
+			})
+			c.recorder = record.NewFakeRecorder(20)
+			p := &failingDeleteProvisioner{deleteFailures: tt.deleteFailures, notReady: tt.notReady}
+			c.provisioners[provisionerName].provisioner = p
+			obcs := c.libClientset.ObjectbucketV1alpha1().ObjectBucketClaims(testNamespace)
+			obs := c.libClientset.ObjectbucketV1alpha1().ObjectBuckets()
+
+			createTestClaim(t, c, &storagev1.StorageClass{
+				ObjectMeta:    metav1.ObjectMeta{Name: className},
+				Provisioner:   provisionerName,
+				ReclaimPolicy: &reclaimDelete,
+			})
+			if err := c.syncHandler(context.Background(), key); err != nil {
+				t.Fatalf("error provisioning: %v", err)
+			}
+			// the fake clientset does not set UIDs, which the OB requires to be deleted
+			obc, err := obcs.Get(testName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("error getting OBC: %v", err)
+			}
+			ob, err := obs.Get(obc.Spec.ObjectBucketName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("error getting OB: %v", err)
+			}
+			ob.UID = "test-uid"
+			if _, err = obs.Update(ob); err != nil {
+				t.Fatalf("error updating OB: %v", err)
+			}
+			deleted := metav1.NewTime(time.Now().Add(-tt.deletedAgo))
+			obc.SetDeletionTimestamp(&deleted)
+			if _, err = obcs.Update(obc); err != nil {
+				t.Fatalf("error marking OBC deleted: %v", err)
+			}
+
+			for i := 0; i < tt.syncs; i++ {
+				err = c.syncHandler(context.Background(), key)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("wantErr %v, error = %v", tt.wantErr, err)
+			}
+			if _, requeue := err.(requeueError); requeue != tt.wantRequeue {
+				t.Errorf("want requeue %v, error = %v", tt.wantRequeue, err)
+			}
+
+			if diff := cmp.Diff(tt.wantCalls, p.calls); diff != "" {
+				t.Errorf("provisioner calls (-want +got):\n%s", diff)
+			}
+			obc, err = obcs.Get(testName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("error getting OBC: %v", err)
+			}
+			var gotCondition bool
+			for _, cond := range obc.Status.Conditions {
+				gotCondition = gotCondition || cond.Type == v1alpha1.ObjectBucketClaimConditionDeletionFailed
+			}
+			if gotCondition != tt.wantCondition {
+				t.Errorf("want DeletionFailed condition %v, got %v", tt.wantCondition, gotCondition)
+			}
+			if gotFinalizers := len(obc.Finalizers) > 0; gotFinalizers != tt.wantFinalizers {
+				t.Errorf("want finalizers %v, got %v", tt.wantFinalizers, obc.Finalizers)
+			}
+		})
+	}
+}
+
+func TestSyncHandlerSecretCopies(t *testing.T) {
+	const key = testNamespace + "/" + testName
+	reclaimDelete := corev1.PersistentVolumeReclaimDelete
+
+	tests := []struct {
+		name       string
+		allow      bool
+		namespaces []string
+		// accepts is the secret-copies-from annotation of the existing namespaces
+		accepts   map[string]string
+		wantPhase v1alpha1.ObjectBucketClaimStatusPhase
+	}{
+		{
+			name:       "secret is copied to the additional namespaces",
+			allow:      true,
+			namespaces: []string{"team-a", "team-b"},
+			accepts:    map[string]string{"team-a": "other-namespace, " + testNamespace, "team-b": "*"},
+			wantPhase:  v1alpha1.ObjectBucketClaimStatusPhaseBound,
+		},
+		{
+			name:       "copies fail the claim if not allowed",
+			namespaces: []string{"team-a"},
+			accepts:    map[string]string{"team-a": "*"},
+			wantPhase:  v1alpha1.ObjectBucketClaimStatusPhaseFailed,
+		},
+		{
+			name:       "the claim's own namespace fails the claim",
+			allow:      true,
+			namespaces: []string{testNamespace},
+			wantPhase:  v1alpha1.ObjectBucketClaimStatusPhaseFailed,
+		},
+		{
+			name:       "a namespace not accepting copies from the claim's fails the claim",
+			allow:      true,
+			namespaces: []string{"team-a", "team-b"},
+			accepts:    map[string]string{"team-a": "*", "team-b": "other-namespace"},
+			wantPhase:  v1alpha1.ObjectBucketClaimStatusPhaseFailed,
+		},
+		{
+			name:       "a missing namespace fails the claim",
+			allow:      true,
+			namespaces: []string{"team-a"},
+			wantPhase:  v1alpha1.ObjectBucketClaimStatusPhaseFailed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestController(&ControllerOptions{
+				RetryBaseInterval:          time.Millisecond,
+				RetryTimeout:               time.Millisecond * 10,
+				AllowCrossNamespaceSecrets: tt.allow,
+			})
+			c.recorder = record.NewFakeRecorder(20)
+			obcs := c.libClientset.ObjectbucketV1alpha1().ObjectBucketClaims(testNamespace)
+			obs := c.libClientset.ObjectbucketV1alpha1().ObjectBuckets()
+			for name, accepts := range tt.accepts {
+				if _, err := c.clientset.CoreV1().Namespaces().Create(&corev1.Namespace{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:        name,
+						Annotations: map[string]string{v1alpha1.SecretCopiesFromAnnotation: accepts},
+					},
+				}); err != nil {
+					t.Fatalf("error creating namespace: %v", err)
+				}
+			}
+
+			createTestClaim(t, c, &storagev1.StorageClass{
+				ObjectMeta:    metav1.ObjectMeta{Name: className},
+				Provisioner:   provisionerName,
+				ReclaimPolicy: &reclaimDelete,
+			})
+			obc, err := obcs.Get(testName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("error getting OBC: %v", err)
+			}
+			obc.Spec.AdditionalSecretNamespaces = tt.namespaces
+			if _, err = obcs.Update(obc); err != nil {
+				t.Fatalf("error updating OBC: %v", err)
+			}
+			if err = c.syncHandler(context.Background(), key); err != nil {
+				t.Fatalf("error syncing: %v", err)
+			}
+
+			obc, err = obcs.Get(testName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("error getting OBC: %v", err)
+			}
+			if obc.Status.Phase != tt.wantPhase {
+				t.Errorf("want OBC phase %q, got %q", tt.wantPhase, obc.Status.Phase)
+			}
+			if tt.wantPhase != v1alpha1.ObjectBucketClaimStatusPhaseBound {
+				for _, ns := range tt.namespaces {
+					if _, err = c.clientset.CoreV1().Secrets(ns).Get(testName, metav1.GetOptions{}); err == nil && ns != testNamespace {
+						t.Errorf("want no secret copy in namespace %q", ns)
+					}
+				}
+				return
+			}
+
+			secret, err := c.clientset.CoreV1().Secrets(testNamespace).Get(testName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("error getting secret: %v", err)
+			}
+			for _, ns := range tt.namespaces {
+				secretCopy, err := c.clientset.CoreV1().Secrets(ns).Get(testName, metav1.GetOptions{})
+				if err != nil {
+					t.Fatalf("error getting secret copy in namespace %q: %v", ns, err)
+				}
+				if diff := cmp.Diff(secret.StringData, secretCopy.StringData); diff != "" {
+					t.Errorf("secret copy data in namespace %q (-want +got):\n%s", ns, diff)
+				}
+				if !isOwnedByClaim(secretCopy, obc) {
+					t.Errorf("secret copy in namespace %q is not owned by the OBC: %v", ns, secretCopy.Annotations)
+				}
+			}
+			if diff := cmp.Diff(tt.namespaces, obc.Status.SecretCopyNamespaces); diff != "" {
+				t.Errorf("recorded secret copy namespaces (-want +got):\n%s", diff)
+			}
+
+			// the copy in a namespace removed from the claim's spec is deleted
+			removed, kept := tt.namespaces[len(tt.namespaces)-1], tt.namespaces[:len(tt.namespaces)-1]
+			obc.Spec.AdditionalSecretNamespaces = kept
+			if obc, err = obcs.Update(obc); err != nil {
+				t.Fatalf("error updating OBC: %v", err)
+			}
+			if err = c.syncHandler(context.Background(), key); err != nil {
+				t.Fatalf("error syncing: %v", err)
+			}
+			if _, err = c.clientset.CoreV1().Secrets(removed).Get(testName, metav1.GetOptions{}); !errors.IsNotFound(err) {
+				t.Errorf("want secret copy in removed namespace %q deleted, got %v", removed, err)
+			}
+			for _, ns := range kept {
+				if _, err = c.clientset.CoreV1().Secrets(ns).Get(testName, metav1.GetOptions{}); err != nil {
+					t.Errorf("want secret copy in namespace %q kept, got %v", ns, err)
+				}
+			}
+			if obc, err = obcs.Get(testName, metav1.GetOptions{}); err != nil {
+				t.Fatalf("error getting OBC: %v", err)
+			}
+			if diff := cmp.Diff(kept, obc.Status.SecretCopyNamespaces); diff != "" {
+				t.Errorf("recorded secret copy namespaces after removal (-want +got):\n%s", diff)
+			}
+
+			// the fake clientset does not set UIDs, which the OB requires to be deleted
+			ob, err := obs.Get(obc.Spec.ObjectBucketName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("error getting OB: %v", err)
+			}
+			ob.UID = "test-uid"
+			if _, err = obs.Update(ob); err != nil {
+				t.Fatalf("error updating OB: %v", err)
+			}
+			now := metav1.Now()
+			obc.SetDeletionTimestamp(&now)
+			if _, err = obcs.Update(obc); err != nil {
+				t.Fatalf("error marking OBC deleted: %v", err)
+			}
+			if err = c.syncHandler(context.Background(), key); err != nil {
+				t.Fatalf("error deleting: %v", err)
+			}
+			for _, ns := range tt.namespaces {
+				if _, err = c.clientset.CoreV1().Secrets(ns).Get(testName, metav1.GetOptions{}); !errors.IsNotFound(err) {
+					t.Errorf("want secret copy in namespace %q deleted, got %v", ns, err)
+				}
+			}
+		})
+	}
+}
+
+func TestSyncHandlerAdditionalState(t *testing.T) {
+	const key = testNamespace + "/" + testName
+	reclaimDelete := corev1.PersistentVolumeReclaimDelete
+	state := map[string]string{"tenant": "tenant-1"}
+
+	c := newTestController(&ControllerOptions{
+		RetryBaseInterval: time.Millisecond,
+		RetryTimeout:      time.Millisecond * 10,
+	})
+	c.recorder = record.NewFakeRecorder(20)
+	p := &statefulProvisioner{state: state}
+	c.provisioners[provisionerName].provisioner = p
+	obs := c.libClientset.ObjectbucketV1alpha1().ObjectBuckets()
+	obName, _ := objectBucketNameFromClaimKey(key)
+
+	createTestClaim(t, c, &storagev1.StorageClass{
+		ObjectMeta:    metav1.ObjectMeta{Name: className},
+		Provisioner:   provisionerName,
+		ReclaimPolicy: &reclaimDelete,
+	})
+	if err := c.syncHandler(context.Background(), key); err != nil {
+		t.Fatalf("error provisioning: %v", err)
+	}
+	ob, err := obs.Get(obName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("error getting OB: %v", err)
+	}
+	if diff := cmp.Diff(state, ob.Spec.AdditionalState); diff != "" {
+		t.Errorf("persisted state (-want +got):\n%s", diff)
+	}
+	// the fake clientset does not set UIDs, which the OB requires to be deleted
+	ob.UID = "test-uid"
+	if _, err = obs.Update(ob); err != nil {
+		t.Fatalf("error updating OB: %v", err)
+	}
+
+	deleteTestClaim(t, c)
+	if err := c.syncHandler(context.Background(), key); err != nil {
+		t.Fatalf("error deleting: %v", err)
+	}
+	if diff := cmp.Diff([]string{"Provision", "Delete"}, p.calls); diff != "" {
+		t.Errorf("provisioner calls (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(state, p.deletedState); diff != "" {
+		t.Errorf("state given to Delete (-want +got):\n%s", diff)
+	}
+}
+
+func TestJitteredRateLimiter(t *testing.T) {
+	const (
+		base  = time.Second
+		calls = 1000
+	)
+
+	tests := []struct {
+		name    string
+		factor  float64
+		wantMax time.Duration
+	}{
+		{
+			name:    "jitter is added up to the factor",
+			factor:  0.5,
+			wantMax: base + base/2,
+		},
+		{
+			name:    "negative factor disables jitter",
+			factor:  -1,
+			wantMax: base,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newJitteredRateLimiter(workqueue.NewItemExponentialFailureRateLimiter(base, time.Minute), tt.factor)
+			seen := make(map[time.Duration]bool)
+			for i := 0; i < calls; i++ {
+				// distinct items are all at their first failure, so the wrapped delay is the base delay
+				d := r.When(fmt.Sprintf("item-%d", i))
+				if d < base || d > tt.wantMax {
+					t.Fatalf("want delay in [%v, %v], got %v", base, tt.wantMax, d)
+				}
+				seen[d] = true
+			}
+			if jittered := len(seen) > 1; jittered != (tt.wantMax > base) {
+				t.Errorf("want jittered delays %v, got %d distinct delays", tt.wantMax > base, len(seen))
+			}
+		})
+	}
+}
+
+func TestUpdateOBCIgnoresStatusOnlyUpdates(t *testing.T) {
+	old := &v1alpha1.ObjectBucketClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            testName,
+			Namespace:       testNamespace,
+			ResourceVersion: "1",
+			Generation:      1,
+		},
+		Spec: v1alpha1.ObjectBucketClaimSpec{StorageClassName: className},
+	}
+
+	tests := []struct {
+		name        string
+		update      func(obc *v1alpha1.ObjectBucketClaim)
+		wantEnqueue bool
+	}{
+		{
+			name: "status only",
+			update: func(obc *v1alpha1.ObjectBucketClaim) {
+				obc.Status.Phase = v1alpha1.ObjectBucketClaimStatusPhaseBound
+			},
+		},
+		{
+			name: "spec",
+			update: func(obc *v1alpha1.ObjectBucketClaim) {
+				obc.Generation++
+				obc.Spec.GenerateBucketName = "photos"
+			},
+			wantEnqueue: true,
+		},
+		{
+			name: "finalizer",
+			update: func(obc *v1alpha1.ObjectBucketClaim) {
+				obc.Finalizers = []string{finalizer}
+			},
+			wantEnqueue: true,
+		},
+		{
+			name: "annotation",
+			update: func(obc *v1alpha1.ObjectBucketClaim) {
+				obc.Annotations = map[string]string{v1alpha1.RotateAnnotation: "1"}
+			},
+			wantEnqueue: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestController(&ControllerOptions{RequeueJitterFactor: -1})
+			defer c.queue.ShutDown()
+			updated := old.DeepCopy()
+			updated.ResourceVersion = "2"
+			tt.update(updated)
+
+			c.updateOBC(old, updated)
+			// enqueued claims are rate limited, wait for them to be added
+			if err := wait.Poll(time.Millisecond*5, time.Millisecond*100, func() (bool, error) {
+				return c.queue.Len() > 0, nil
+			}); (err == nil) != tt.wantEnqueue {
+				t.Errorf("want claim enqueued %v, got queue length %d", tt.wantEnqueue, c.queue.Len())
+			}
+		})
+	}
+}
+
+func TestUpdateOBCResync(t *testing.T) {
+	tests := []struct {
+		name        string
+		phase       v1alpha1.ObjectBucketClaimStatusPhase
+		deleted     bool
+		wantEnqueue bool
+	}{
+		{
+			name:        "bound claim is re-verified",
+			phase:       v1alpha1.ObjectBucketClaimStatusPhaseBound,
+			wantEnqueue: true,
+		},
+		{
+			name:  "pending claim is ignored",
+			phase: v1alpha1.ObjectBucketClaimStatusPhasePending,
+		},
+		{
+			name:    "deleted claim is ignored",
+			phase:   v1alpha1.ObjectBucketClaimStatusPhaseBound,
+			deleted: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestController(&ControllerOptions{RequeueJitterFactor: -1})
+			defer c.queue.ShutDown()
+			obc := &v1alpha1.ObjectBucketClaim{
+				ObjectMeta: metav1.ObjectMeta{Name: testName, Namespace: testNamespace, ResourceVersion: "1"},
+				Status:     v1alpha1.ObjectBucketClaimStatus{Phase: tt.phase},
+			}
+			if tt.deleted {
+				now := metav1.Now()
+				obc.DeletionTimestamp = &now
+			}
+
+			// a periodic resync hands the same object as old and new
+			c.updateOBC(obc, obc.DeepCopy())
+			if err := wait.Poll(time.Millisecond*5, time.Millisecond*100, func() (bool, error) {
+				return c.queue.Len() > 0, nil
+			}); (err == nil) != tt.wantEnqueue {
+				t.Errorf("want claim enqueued %v, got queue length %d", tt.wantEnqueue, c.queue.Len())
+			}
+		})
+	}
+}
+
+func TestSyncHandlerPaused(t *testing.T) {
+	const key = testNamespace + "/" + testName
+
+	tests := []struct {
+		name    string
+		deleted bool
+	}{
+		{name: "paused claim is not provisioned"},
+		{name: "paused deleted claim is not cleaned up", deleted: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestController(&ControllerOptions{
+				RetryBaseInterval: time.Millisecond,
+				RetryTimeout:      time.Millisecond * 10,
+			})
+			p := &fakeProvisioner{}
+			c.provisioners[provisionerName].provisioner = p
+			createTestClaim(t, c, &storagev1.StorageClass{
+				ObjectMeta:  metav1.ObjectMeta{Name: className},
+				Provisioner: provisionerName,
+			})
+			obcs := c.libClientset.ObjectbucketV1alpha1().ObjectBucketClaims(testNamespace)
+			obc, err := obcs.Get(testName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("error getting OBC: %v", err)
+			}
+			obc.Annotations = map[string]string{v1alpha1.PausedAnnotation: "true"}
+			if _, err = obcs.Update(obc); err != nil {
+				t.Fatalf("error pausing OBC: %v", err)
+			}
+			if tt.deleted {
+				deleteTestClaim(t, c)
+			}
+
+			if err = c.syncHandler(context.Background(), key); err != nil {
+				t.Fatalf("syncHandler() error = %v", err)
+			}
+			if len(p.calls) != 0 {
+				t.Errorf("want no provisioner calls while paused, got %v", p.calls)
+			}
+			if obc, err = obcs.Get(testName, metav1.GetOptions{}); err != nil {
+				t.Fatalf("error getting OBC: %v", err)
+			}
+			if obc.Status.Phase != "" {
+				t.Errorf("want status untouched while paused, got phase %q", obc.Status.Phase)
+			}
+			if tt.deleted {
+				return
+			}
+
+			// resuming reconciles the claim as usual
+			delete(obc.Annotations, v1alpha1.PausedAnnotation)
+			if _, err = obcs.Update(obc); err != nil {
+				t.Fatalf("error resuming OBC: %v", err)
+			}
+			if err = c.syncHandler(context.Background(), key); err != nil {
+				t.Fatalf("syncHandler() error = %v", err)
+			}
+			if diff := cmp.Diff([]string{"Provision"}, p.calls); diff != "" {
+				t.Errorf("provisioner calls mismatch (-want +got):\n%s", diff)
+			}
+			if obc, err = obcs.Get(testName, metav1.GetOptions{}); err != nil {
+				t.Fatalf("error getting OBC: %v", err)
+			}
+			if obc.Status.Phase != v1alpha1.ObjectBucketClaimStatusPhaseBound {
+				t.Errorf("want phase %q, got %q", v1alpha1.ObjectBucketClaimStatusPhaseBound, obc.Status.Phase)
+			}
+		})
+	}
+}
+
+func TestSyncHandlerManagedBy(t *testing.T) {
+	const key = testNamespace + "/" + testName
+
+	tests := []struct {
+		name           string
+		controllerName string
+		managedBy      string
+		deleted        bool
+		wantManaged    bool
+	}{
+		{
+			name:        "unannotated claim is managed",
+			wantManaged: true,
+		},
+		{
+			name:        "claim annotated with the provisioner is managed",
+			managedBy:   provisionerName,
+			wantManaged: true,
+		},
+		{
+			name:           "claim annotated with the controller name is managed",
+			controllerName: "new-controller",
+			managedBy:      "new-controller",
+			wantManaged:    true,
+		},
+		{
+			name:      "claim of another controller is skipped",
+			managedBy: "other-controller",
+		},
+		{
+			name:      "deleted claim of another controller is skipped",
+			managedBy: "other-controller",
+			deleted:   true,
+		},
+		{
+			name:           "claim of the provisioner is skipped by a named controller",
+			controllerName: "new-controller",
+			managedBy:      provisionerName,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestController(&ControllerOptions{
+				RetryBaseInterval: time.Millisecond,
+				RetryTimeout:      time.Millisecond * 10,
+				ControllerName:    tt.controllerName,
+			})
+			p := &fakeProvisioner{}
+			c.provisioners[provisionerName].provisioner = p
+			createTestClaim(t, c, &storagev1.StorageClass{
+				ObjectMeta:  metav1.ObjectMeta{Name: className},
+				Provisioner: provisionerName,
+			})
+			obcs := c.libClientset.ObjectbucketV1alpha1().ObjectBucketClaims(testNamespace)
+			obc, err := obcs.Get(testName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("error getting OBC: %v", err)
+			}
+			if tt.managedBy != "" {
+				obc.Annotations = map[string]string{v1alpha1.ManagedByAnnotation: tt.managedBy}
+				if _, err = obcs.Update(obc); err != nil {
+					t.Fatalf("error annotating OBC: %v", err)
+				}
+			}
+			if tt.deleted {
+				deleteTestClaim(t, c)
+			}
+
+			if err = c.syncHandler(context.Background(), key); err != nil {
+				t.Fatalf("syncHandler() error = %v", err)
+			}
+			if obc, err = obcs.Get(testName, metav1.GetOptions{}); err != nil {
+				t.Fatalf("error getting OBC: %v", err)
+			}
+			if tt.wantManaged {
+				if diff := cmp.Diff([]string{"Provision"}, p.calls); diff != "" {
+					t.Errorf("provisioner calls mismatch (-want +got):\n%s", diff)
+				}
+				if obc.Status.Phase != v1alpha1.ObjectBucketClaimStatusPhaseBound {
+					t.Errorf("want phase %q, got %q", v1alpha1.ObjectBucketClaimStatusPhaseBound, obc.Status.Phase)
+				}
+				return
+			}
+			if len(p.calls) != 0 {
+				t.Errorf("want no provisioner calls, got %v", p.calls)
+			}
+			if obc.Status.Phase != "" || len(obc.Finalizers) != 0 {
+				t.Errorf("want claim untouched, got phase %q and finalizers %v", obc.Status.Phase, obc.Finalizers)
+			}
+		})
+	}
+}
+
+func TestUpdateOBCResumeDeleted(t *testing.T) {
+	c := newTestController(&ControllerOptions{RequeueJitterFactor: -1})
+	defer c.queue.ShutDown()
+	now := metav1.Now()
+	oldObc := &v1alpha1.ObjectBucketClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              testName,
+			Namespace:         testNamespace,
+			ResourceVersion:   "1",
+			DeletionTimestamp: &now,
+			Annotations:       map[string]string{v1alpha1.PausedAnnotation: "true"},
+		},
+	}
+	newObc := oldObc.DeepCopy()
+	newObc.ResourceVersion = "2"
+	newObc.Annotations = nil
+
+	c.updateOBC(oldObc, newObc)
+	if err := wait.Poll(time.Millisecond*5, time.Millisecond*100, func() (bool, error) {
+		return c.queue.Len() > 0, nil
+	}); err != nil {
+		t.Errorf("want resumed deleted claim enqueued, got queue length %d", c.queue.Len())
+	}
+}
+
+func TestSyncHandlerRestoresBoundClaimResources(t *testing.T) {
+	const key = testNamespace + "/" + testName
+
+	tests := []struct {
+		name            string
+		rotator         bool
+		wantSecret      bool
+		wantSecretReady corev1.ConditionStatus
+		wantReason      string
+	}{
+		{
+			name:            "secret is recreated with new credentials by a rotator",
+			rotator:         true,
+			wantSecret:      true,
+			wantSecretReady: corev1.ConditionTrue,
+			wantReason:      eventReasonSecretRecreated,
+		},
+		{
+			name:            "secret is reported missing without a rotator",
+			wantSecretReady: corev1.ConditionFalse,
+			wantReason:      eventReasonSecretMissing,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestController(&ControllerOptions{
+				RetryBaseInterval: time.Millisecond,
+				RetryTimeout:      time.Millisecond * 10,
+			})
+			c.recorder = record.NewFakeRecorder(20)
+			if tt.rotator {
+				c.provisioners[provisionerName].provisioner = &rotatingProvisioner{
+					secrets:    c.clientset.CoreV1().Secrets(testNamespace),
+					secretName: testName,
+				}
+			}
+			secrets := c.clientset.CoreV1().Secrets(testNamespace)
+			configMaps := c.clientset.CoreV1().ConfigMaps(testNamespace)
+
+			createTestClaim(t, c, &storagev1.StorageClass{
+				ObjectMeta:  metav1.ObjectMeta{Name: className},
+				Provisioner: provisionerName,
+			})
+			if err := c.syncHandler(context.Background(), key); err != nil {
+				t.Fatalf("error provisioning: %v", err)
+			}
+			provisioned, err := configMaps.Get(testName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("error getting configmap: %v", err)
+			}
+
+			// the secret and configmap are deleted out-of-band
+			if err = secrets.Delete(testName, &metav1.DeleteOptions{}); err != nil {
+				t.Fatalf("error deleting secret: %v", err)
+			}
+			if err = configMaps.Delete(testName, &metav1.DeleteOptions{}); err != nil {
+				t.Fatalf("error deleting configmap: %v", err)
+			}
+			// a resync reconciles the bound claim again
+			if err = c.syncHandler(context.Background(), key); err != nil {
+				t.Fatalf("error resyncing: %v", err)
+			}
+
+			cm, err := configMaps.Get(testName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("want configmap recreated, got error %v", err)
+			}
+			if diff := cmp.Diff(provisioned.Data, cm.Data); diff != "" {
+				t.Errorf("configmap data (-want +got):\n%s", diff)
+			}
+			secret, err := secrets.Get(testName, metav1.GetOptions{})
+			if tt.wantSecret {
+				if err != nil {
+					t.Fatalf("want secret recreated, got error %v", err)
+				}
+				if got := secretData(secret)[v1alpha1.AwsKeyField]; got != "key-1" {
+					t.Errorf("want new access key %q, got %q", "key-1", got)
+				}
+			} else if !errors.IsNotFound(err) {
+				t.Errorf("want secret missing, got error %v", err)
+			}
+
+			obc, err := c.libClientset.ObjectbucketV1alpha1().ObjectBucketClaims(testNamespace).Get(testName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("error getting OBC: %v", err)
+			}
+			for _, cond := range obc.Status.Conditions {
+				if cond.Type != v1alpha1.ObjectBucketClaimConditionSecretReady {
+					continue
+				}
+				if cond.Status != tt.wantSecretReady || cond.Reason != tt.wantReason {
+					t.Errorf("want %s condition %s %s, got %s %s", cond.Type, tt.wantSecretReady, tt.wantReason, cond.Status, cond.Reason)
+				}
+			}
+		})
+	}
+}
+
+func TestSyncHandlerMaxOBCsPerNamespace(t *testing.T) {
+	const key = testNamespace + "/" + testName
+
+	tests := []struct {
+		name      string
+		max       int
+		others    []v1alpha1.ObjectBucketClaimStatusPhase
+		wantPhase v1alpha1.ObjectBucketClaimStatusPhase
+	}{
+		{
+			name:      "unlimited by default",
+			max:       0,
+			others:    []v1alpha1.ObjectBucketClaimStatusPhase{v1alpha1.ObjectBucketClaimStatusPhaseBound},
+			wantPhase: v1alpha1.ObjectBucketClaimStatusPhaseBound,
+		},
+		{
+			name: "under the limit",
+			max:  2,
+			others: []v1alpha1.ObjectBucketClaimStatusPhase{
+				v1alpha1.ObjectBucketClaimStatusPhaseBound,
+				v1alpha1.ObjectBucketClaimStatusPhaseFailed,
+			},
+			wantPhase: v1alpha1.ObjectBucketClaimStatusPhaseBound,
+		},
+		{
+			name: "over the limit",
+			max:  2,
+			others: []v1alpha1.ObjectBucketClaimStatusPhase{
+				v1alpha1.ObjectBucketClaimStatusPhaseBound,
+				v1alpha1.ObjectBucketClaimStatusPhaseProvisioning,
+			},
+			wantPhase: v1alpha1.ObjectBucketClaimStatusPhaseFailed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestController(&ControllerOptions{
+				RetryBaseInterval:   time.Millisecond,
+				RetryTimeout:        time.Millisecond * 10,
+				MaxOBCsPerNamespace: tt.max,
+			})
+			recorder := record.NewFakeRecorder(20)
+			c.recorder = recorder
+			// the other claims are only known to the informer's cache, which the claims are counted from
+			for i, phase := range tt.others {
+				other := &v1alpha1.ObjectBucketClaim{
+					ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("other-%d", i), Namespace: testNamespace},
+					Status:     v1alpha1.ObjectBucketClaimStatus{Phase: phase},
+				}
+				if err := c.obcInformer.Informer().GetIndexer().Add(other); err != nil {
+					t.Fatalf("error caching OBC: %v", err)
+				}
+			}
+			obcs := c.libClientset.ObjectbucketV1alpha1().ObjectBucketClaims(testNamespace)
+
+			createTestClaim(t, c, &storagev1.StorageClass{
+				ObjectMeta:  metav1.ObjectMeta{Name: className},
+				Provisioner: provisionerName,
+			})
+			if err := c.syncHandler(context.Background(), key); err != nil {
+				t.Fatalf("syncHandler() error = %v", err)
+			}
+			obc, err := obcs.Get(testName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("error getting OBC: %v", err)
+			}
+			if obc.Status.Phase != tt.wantPhase {
+				t.Errorf("want phase %q, got %q", tt.wantPhase, obc.Status.Phase)
+			}
+			limited := false
+			for len(recorder.Events) > 0 {
+				if strings.Contains(<-recorder.Events, eventReasonNamespaceLimitExceeded) {
+					limited = true
+				}
+			}
+			if want := tt.wantPhase == v1alpha1.ObjectBucketClaimStatusPhaseFailed; limited != want {
+				t.Errorf("want %s event %v, got %v", eventReasonNamespaceLimitExceeded, want, limited)
+			}
+		})
+	}
+}
+
+func TestSyncHandlerFailureMessage(t *testing.T) {
+	const key = testNamespace + "/" + testName
+
+	c := newTestController(&ControllerOptions{
+		RetryBaseInterval: time.Millisecond,
+		RetryTimeout:      time.Millisecond * 10,
+	})
+	c.recorder = record.NewFakeRecorder(20)
+	p := &failingProvisioner{err: fmt.Errorf("backend rejected request: secret_access_key=abc123 is invalid")}
+	c.provisioners[provisionerName].provisioner = p
+	obcs := c.libClientset.ObjectbucketV1alpha1().ObjectBucketClaims(testNamespace)
+
+	createTestClaim(t, c, &storagev1.StorageClass{
+		ObjectMeta:  metav1.ObjectMeta{Name: className},
+		Provisioner: provisionerName,
+	})
+	if err := c.syncHandler(context.Background(), key); err == nil {
+		t.Fatal("want provisioning error, got nil")
+	}
+
+	obc, err := obcs.Get(testName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("error getting OBC: %v", err)
+	}
+	const want = "backend rejected request: secret_access_key=[REDACTED] is invalid"
+	if obc.Status.FailureMessage != want {
+		t.Errorf("want failure message %q, got %q", want, obc.Status.FailureMessage)
+	}
+	for _, cond := range obc.Status.Conditions {
+		if cond.Type == v1alpha1.ObjectBucketClaimConditionBucketReady && cond.Message != want {
+			t.Errorf("want %s condition message %q, got %q", cond.Type, want, cond.Message)
+		}
+	}
+
+	// the message is cleared once the provisioner succeeds
+	p.err = nil
+	c.provisioners[provisionerName].provisioner = &p.fakeProvisioner
+	obc.Status.Phase = ""
+	if _, err = obcs.UpdateStatus(obc); err != nil {
+		t.Fatalf("error resetting OBC phase: %v", err)
+	}
+	if err = c.syncHandler(context.Background(), key); err != nil {
+		t.Fatalf("error provisioning: %v", err)
+	}
+	if obc, err = obcs.Get(testName, metav1.GetOptions{}); err != nil {
+		t.Fatalf("error getting OBC: %v", err)
+	}
+	if obc.Status.FailureMessage != "" {
+		t.Errorf("want failure message cleared, got %q", obc.Status.FailureMessage)
+	}
+}
+
+func TestSyncHandlerBucketNameGenerator(t *testing.T) {
+	const key = testNamespace + "/" + testName
+
+	tests := []struct {
+		name           string
+		generator      BucketNameGenerator
+		skipValidation bool
+		wantPhase      v1alpha1.ObjectBucketClaimStatusPhase
+		wantBucket     string
+	}{
+		{
+			name: "custom name",
+			generator: func(obc *v1alpha1.ObjectBucketClaim) (string, error) {
+				return "prod-" + obc.Namespace + "-" + obc.Name, nil
+			},
+			wantPhase:  v1alpha1.ObjectBucketClaimStatusPhaseBound,
+			wantBucket: "prod-" + testNamespace + "-" + testName,
+		},
+		{
+			name: "invalid name",
+			generator: func(obc *v1alpha1.ObjectBucketClaim) (string, error) {
+				return "Prod_" + obc.Name, nil
+			},
+			wantPhase: v1alpha1.ObjectBucketClaimStatusPhaseFailed,
+		},
+		{
+			name: "invalid name is rejected without bucket name validation",
+			generator: func(obc *v1alpha1.ObjectBucketClaim) (string, error) {
+				return "Prod_" + obc.Name, nil
+			},
+			skipValidation: true,
+			wantPhase:      v1alpha1.ObjectBucketClaimStatusPhaseFailed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestController(&ControllerOptions{
+				RetryBaseInterval:        time.Millisecond,
+				RetryTimeout:             time.Millisecond * 10,
+				BucketNameGenerator:      tt.generator,
+				SkipBucketNameValidation: tt.skipValidation,
+			})
+			recorder := record.NewFakeRecorder(20)
+			c.recorder = recorder
+			createTestClaim(t, c, &storagev1.StorageClass{
+				ObjectMeta:  metav1.ObjectMeta{Name: className},
+				Provisioner: provisionerName,
+			})
+			if err := c.syncHandler(context.Background(), key); err != nil {
+				t.Fatalf("syncHandler() error = %v", err)
+			}
+			obc, err := c.libClientset.ObjectbucketV1alpha1().ObjectBucketClaims(testNamespace).Get(testName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("error getting OBC: %v", err)
+			}
+			if obc.Status.Phase != tt.wantPhase {
+				t.Errorf("want phase %q, got %q", tt.wantPhase, obc.Status.Phase)
+			}
+			if obc.Spec.BucketName != tt.wantBucket {
+				t.Errorf("want bucket name %q, got %q", tt.wantBucket, obc.Spec.BucketName)
+			}
+			invalid := false
+			for len(recorder.Events) > 0 {
+				if strings.Contains(<-recorder.Events, eventReasonInvalidBucketName) {
+					invalid = true
+				}
+			}
+			if want := tt.wantPhase == v1alpha1.ObjectBucketClaimStatusPhaseFailed; invalid != want {
+				t.Errorf("want %s event %v, got %v", eventReasonInvalidBucketName, want, invalid)
+			}
+		})
+	}
+}
+
+func TestSyncHandlerObjectBucketNameCollision(t *testing.T) {
+	const key = testNamespace + "/" + testName
+
+	tests := []struct {
+		name      string
+		claimRef  *corev1.ObjectReference
+		wantPhase v1alpha1.ObjectBucketClaimStatusPhase
+	}{
+		{
+			name:      "OB of the claim is adopted",
+			claimRef:  &corev1.ObjectReference{Namespace: testNamespace, Name: testName},
+			wantPhase: v1alpha1.ObjectBucketClaimStatusPhaseBound,
+		},
+		{
+			name:      "OB of another claim collides",
+			claimRef:  &corev1.ObjectReference{Namespace: testNamespace + "-x", Name: "y", UID: "other-uid"},
+			wantPhase: v1alpha1.ObjectBucketClaimStatusPhaseFailed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestController(&ControllerOptions{
+				RetryBaseInterval: time.Millisecond,
+				RetryTimeout:      time.Millisecond * 10,
+			})
+			recorder := record.NewFakeRecorder(20)
+			c.recorder = recorder
+			obName, _ := objectBucketNameFromClaimKey(key)
+			if _, err := c.libClientset.ObjectbucketV1alpha1().ObjectBuckets().Create(&v1alpha1.ObjectBucket{
+				ObjectMeta: metav1.ObjectMeta{Name: obName},
+				Spec:       v1alpha1.ObjectBucketSpec{ClaimRef: tt.claimRef},
+			}); err != nil {
+				t.Fatalf("error pre-creating OB: %v", err)
+			}
+
+			createTestClaim(t, c, &storagev1.StorageClass{
+				ObjectMeta:  metav1.ObjectMeta{Name: className},
+				Provisioner: provisionerName,
+			})
+			if err := c.syncHandler(context.Background(), key); err != nil {
+				t.Fatalf("syncHandler() error = %v", err)
+			}
+			obc, err := c.libClientset.ObjectbucketV1alpha1().ObjectBucketClaims(testNamespace).Get(testName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("error getting OBC: %v", err)
+			}
+			if obc.Status.Phase != tt.wantPhase {
+				t.Errorf("want phase %q, got %q", tt.wantPhase, obc.Status.Phase)
+			}
+			collided := tt.wantPhase == v1alpha1.ObjectBucketClaimStatusPhaseFailed
+			if got := strings.Contains(obc.Status.FailureMessage, "bucket name already in use"); got != collided {
+				t.Errorf("want collision failure message %v, got %q", collided, obc.Status.FailureMessage)
+			}
+			inUse := false
+			for len(recorder.Events) > 0 {
+				if strings.Contains(<-recorder.Events, eventReasonBucketNameInUse) {
+					inUse = true
+				}
+			}
+			if inUse != collided {
+				t.Errorf("want %s event %v, got %v", eventReasonBucketNameInUse, collided, inUse)
+			}
+		})
+	}
+}
+
+func TestClaimRetry(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		wantTimeout time.Duration
+		wantEvent   bool
+	}{
+		{
+			name:        "absent annotation",
+			wantTimeout: time.Second,
+		},
+		{
+			name:        "valid override",
+			annotations: map[string]string{v1alpha1.RetryTimeoutAnnotation: "2m"},
+			wantTimeout: time.Minute * 2,
+		},
+		{
+			name:        "invalid duration",
+			annotations: map[string]string{v1alpha1.RetryTimeoutAnnotation: "two minutes"},
+			wantTimeout: time.Second,
+			wantEvent:   true,
+		},
+		{
+			name:        "negative duration",
+			annotations: map[string]string{v1alpha1.RetryTimeoutAnnotation: "-1m"},
+			wantTimeout: time.Second,
+			wantEvent:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestController(&ControllerOptions{
+				RetryBaseInterval: time.Millisecond,
+				RetryTimeout:      time.Second,
+			})
+			recorder := record.NewFakeRecorder(20)
+			c.recorder = recorder
+			obc := &v1alpha1.ObjectBucketClaim{ObjectMeta: metav1.ObjectMeta{
+				Name:        testName,
+				Namespace:   testNamespace,
+				Annotations: tt.annotations,
+			}}
+
+			got := c.claimRetry(obc)
+			if got.timeout != tt.wantTimeout {
+				t.Errorf("want retry timeout %v, got %v", tt.wantTimeout, got.timeout)
+			}
+			if got.interval != c.retry.interval {
+				t.Errorf("want retry interval %v kept, got %v", c.retry.interval, got.interval)
+			}
+			invalid := false
+			for len(recorder.Events) > 0 {
+				if strings.Contains(<-recorder.Events, eventReasonInvalidRetryTimeout) {
+					invalid = true
+				}
+			}
+			if invalid != tt.wantEvent {
+				t.Errorf("want %s event %v, got %v", eventReasonInvalidRetryTimeout, tt.wantEvent, invalid)
+			}
+		})
+	}
+}
+
+func TestSyncHandlerProvisionConnection(t *testing.T) {
+	const key = testNamespace + "/" + testName
+
+	tests := []struct {
+		name      string
+		conn      *v1alpha1.Connection
+		wantPhase v1alpha1.ObjectBucketClaimStatusPhase
+		wantErr   bool
+		wantCalls []string
+	}{
+		{
+			name: "connection",
+			conn: &v1alpha1.Connection{
+				Endpoint:        &v1alpha1.Endpoint{BucketHost: "s3.example.com", BucketPort: 443},
+				Authentication:  &v1alpha1.Authentication{AccessKeys: &v1alpha1.AccessKeys{AccessKeyID: "id", SecretAccessKey: "key"}},
+				AdditionalState: map[string]string{"tenant": "t1"},
+			},
+			wantPhase: v1alpha1.ObjectBucketClaimStatusPhaseBound,
+			wantCalls: []string{"ProvisionConnection"},
+		},
+		{
+			name:      "nil connection",
+			conn:      nil,
+			wantPhase: v1alpha1.ObjectBucketClaimStatusPhaseProvisioning,
+			wantErr:   true,
+			wantCalls: []string{"ProvisionConnection"},
+		},
+		{
+			name: "connection without bucket host fails the claim",
+			conn: &v1alpha1.Connection{
+				Endpoint:       &v1alpha1.Endpoint{BucketPort: 443},
+				Authentication: &v1alpha1.Authentication{AccessKeys: &v1alpha1.AccessKeys{AccessKeyID: "id", SecretAccessKey: "key"}},
+			},
+			wantPhase: v1alpha1.ObjectBucketClaimStatusPhaseFailed,
+			wantErr:   true,
+			wantCalls: []string{"ProvisionConnection", "Delete"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestController(&ControllerOptions{
+				RetryBaseInterval: time.Millisecond,
+				RetryTimeout:      time.Millisecond * 10,
+			})
+			p := &connectionProvisioner{conn: tt.conn}
+			c.provisioners[provisionerName].provisioner = p
+			createTestClaim(t, c, &storagev1.StorageClass{
+				ObjectMeta:  metav1.ObjectMeta{Name: className},
+				Provisioner: provisionerName,
+			})
+
+			err := c.syncHandler(context.Background(), key)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("syncHandler() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if diff := cmp.Diff(tt.wantCalls, p.calls); diff != "" {
+				t.Errorf("provisioner calls mismatch (-want +got):\n%s", diff)
+			}
+			obc, err := c.libClientset.ObjectbucketV1alpha1().ObjectBucketClaims(testNamespace).Get(testName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("error getting OBC: %v", err)
+			}
+			if obc.Status.Phase != tt.wantPhase {
+				t.Errorf("want phase %q, got %q", tt.wantPhase, obc.Status.Phase)
+			}
+			if tt.wantErr {
+				if _, err = c.clientset.CoreV1().ConfigMaps(testNamespace).Get(testName, metav1.GetOptions{}); !errors.IsNotFound(err) {
+					t.Errorf("want no ConfigMap, got error %v", err)
+				}
+				return
+			}
+
+			cm, err := c.clientset.CoreV1().ConfigMaps(testNamespace).Get(testName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("error getting configmap: %v", err)
+			}
+			if cm.Data[bucketHost] != "s3.example.com" || cm.Data[bucketName] != p.options.BucketName {
+				t.Errorf("want configmap of the connection, got %v", cm.Data)
+			}
+			secret, err := c.clientset.CoreV1().Secrets(testNamespace).Get(testName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("error getting secret: %v", err)
+			}
+			if got := secretData(secret)[v1alpha1.AwsKeyField]; got != "id" {
+				t.Errorf("want secret access key %q, got %q", "id", got)
+			}
+			ob, err := c.objectBucketForClaimKey(key)
+			if err != nil {
+				t.Fatalf("error getting OB: %v", err)
+			}
+			if diff := cmp.Diff(tt.conn.AdditionalState, ob.Spec.AdditionalState); diff != "" {
+				t.Errorf("OB additional state mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSyncHandlerDelayedBind(t *testing.T) {
+	const key = testNamespace + "/" + testName
+
+	c := newTestController(&ControllerOptions{
+		RetryBaseInterval: time.Millisecond,
+		RetryTimeout:      time.Millisecond * 10,
+	})
+	recorder := record.NewFakeRecorder(20)
+	c.recorder = recorder
+	p := &readinessProvisioner{readyFrom: 3, requeueAfter: time.Second * 5}
+	c.provisioners[provisionerName].provisioner = p
+	createTestClaim(t, c, &storagev1.StorageClass{
+		ObjectMeta:  metav1.ObjectMeta{Name: className},
+		Provisioner: provisionerName,
+	})
+	obcs := c.libClientset.ObjectbucketV1alpha1().ObjectBucketClaims(testNamespace)
+
+	// the resources are created on the first reconcile, binding waits for the bucket to be ready
+	for _, wantCalls := range [][]string{
+		{"Provision", "IsReady"},
+		{"Provision", "IsReady", "IsReady"},
+	} {
+		err := c.syncHandler(context.Background(), key)
+		rErr, ok := err.(requeueError)
+		if !ok {
+			t.Fatalf("want requeueError, got %v", err)
+		}
+		if rErr.after != p.requeueAfter {
+			t.Errorf("want requeue after %v, got %v", p.requeueAfter, rErr.after)
+		}
+		if diff := cmp.Diff(wantCalls, p.calls); diff != "" {
+			t.Errorf("provisioner calls mismatch (-want +got):\n%s", diff)
+		}
+		obc, err := obcs.Get(testName, metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("error getting OBC: %v", err)
+		}
+		if obc.Status.Phase != v1alpha1.ObjectBucketClaimStatusPhaseProvisioning {
+			t.Errorf("want phase %q, got %q", v1alpha1.ObjectBucketClaimStatusPhaseProvisioning, obc.Status.Phase)
+		}
+		if cond := claimCondition(obc, v1alpha1.ObjectBucketClaimConditionBucketReady); cond == nil || cond.Reason != eventReasonBucketNotReady {
+			t.Errorf("want BucketReady condition with reason %q, got %+v", eventReasonBucketNotReady, cond)
+		}
+		if _, err = c.clientset.CoreV1().ConfigMaps(testNamespace).Get(testName, metav1.GetOptions{}); err != nil {
+			t.Errorf("error getting ConfigMap of unready claim: %v", err)
+		}
+		if _, err = c.clientset.CoreV1().Secrets(testNamespace).Get(testName, metav1.GetOptions{}); err != nil {
+			t.Errorf("error getting Secret of unready claim: %v", err)
+		}
+	}
+
+	if err := c.syncHandler(context.Background(), key); err != nil {
+		t.Fatalf("syncHandler() error = %v", err)
+	}
+	if diff := cmp.Diff([]string{"Provision", "IsReady", "IsReady", "IsReady"}, p.calls); diff != "" {
+		t.Errorf("provisioner calls mismatch (-want +got):\n%s", diff)
+	}
+	obc, err := obcs.Get(testName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("error getting OBC: %v", err)
+	}
+	if obc.Status.Phase != v1alpha1.ObjectBucketClaimStatusPhaseBound {
+		t.Errorf("want phase %q, got %q", v1alpha1.ObjectBucketClaimStatusPhaseBound, obc.Status.Phase)
+	}
+	if cond := claimCondition(obc, v1alpha1.ObjectBucketClaimConditionBucketReady); cond == nil || cond.Status != corev1.ConditionTrue {
+		t.Errorf("want True BucketReady condition, got %+v", cond)
+	}
+	bound := 0
+	for len(recorder.Events) > 0 {
+		if strings.Contains(<-recorder.Events, eventReasonBound) {
+			bound++
+		}
+	}
+	if bound != 1 {
+		t.Errorf("want 1 %s event, got %d", eventReasonBound, bound)
+	}
+}
+
+func TestProcessNextItemRequeueAfter(t *testing.T) {
+	const key = testNamespace + "/" + testName
+
+	c := newTestController(&ControllerOptions{
+		RetryBaseInterval: time.Millisecond,
+		RetryTimeout:      time.Millisecond * 10,
+	})
+	defer c.queue.ShutDown()
+	c.recorder = record.NewFakeRecorder(20)
+	c.provisioners[provisionerName].provisioner = &readinessProvisioner{readyFrom: 2, requeueAfter: time.Millisecond * 50}
+	createTestClaim(t, c, &storagev1.StorageClass{
+		ObjectMeta:  metav1.ObjectMeta{Name: className},
+		Provisioner: provisionerName,
+	})
+
+	c.queue.Add(key)
+	if !c.processNextItemInQueue(context.Background()) {
+		t.Fatalf("processNextItemInQueue() returned false")
+	}
+	// the claim is requeued after the provisioner's delay rather than the rate limiter's
+	if n := c.queue.Len(); n != 0 {
+		t.Errorf("want claim not requeued yet, got queue length %d", n)
+	}
+	if err := wait.Poll(time.Millisecond*10, time.Second, func() (bool, error) {
+		return c.queue.Len() > 0, nil
+	}); err != nil {
+		t.Errorf("want claim requeued, got queue length %d", c.queue.Len())
+	}
+}
+
+func TestSyncHandlerMissingClassName(t *testing.T) {
+	const key = testNamespace + "/" + testName
+
+	tests := []struct {
+		name         string
+		defaultClass string
+		wantClass    string
+		wantPhase    v1alpha1.ObjectBucketClaimStatusPhase
+		wantReason   string
+	}{
+		{
+			name:         "default StorageClass is applied",
+			defaultClass: className,
+			wantClass:    className,
+			wantPhase:    v1alpha1.ObjectBucketClaimStatusPhaseBound,
+			wantReason:   eventReasonDefaultStorageClass,
+		},
+		{
+			name:       "no default StorageClass fails the claim",
+			wantPhase:  v1alpha1.ObjectBucketClaimStatusPhaseFailed,
+			wantReason: eventReasonNoStorageClass,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestController(&ControllerOptions{
+				RetryBaseInterval:   time.Millisecond,
+				RetryTimeout:        time.Millisecond * 10,
+				DefaultStorageClass: tt.defaultClass,
+			})
+			recorder := record.NewFakeRecorder(20)
+			c.recorder = recorder
+			createTestClaim(t, c, &storagev1.StorageClass{
+				ObjectMeta:  metav1.ObjectMeta{Name: className},
+				Provisioner: provisionerName,
+			})
+			obcs := c.libClientset.ObjectbucketV1alpha1().ObjectBucketClaims(testNamespace)
+			obc, err := obcs.Get(testName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("error getting OBC: %v", err)
+			}
+			obc.Spec.StorageClassName = ""
+			if _, err = obcs.Update(obc); err != nil {
+				t.Fatalf("error updating OBC: %v", err)
+			}
+
+			if err = c.syncHandler(context.Background(), key); err != nil {
+				t.Fatalf("syncHandler() error = %v", err)
+			}
+			if obc, err = obcs.Get(testName, metav1.GetOptions{}); err != nil {
+				t.Fatalf("error getting OBC: %v", err)
+			}
+			if obc.Spec.StorageClassName != tt.wantClass {
+				t.Errorf("want StorageClass %q, got %q", tt.wantClass, obc.Spec.StorageClassName)
+			}
+			if obc.Status.Phase != tt.wantPhase {
+				t.Errorf("want phase %q, got %q", tt.wantPhase, obc.Status.Phase)
+			}
+			if tt.wantPhase == v1alpha1.ObjectBucketClaimStatusPhaseFailed && obc.Status.FailureMessage == "" {
+				t.Errorf("want a failure message")
+			}
+			found := false
+			for len(recorder.Events) > 0 {
+				if strings.Contains(<-recorder.Events, tt.wantReason) {
+					found = true
+				}
+			}
+			if !found {
+				t.Errorf("want %s event", tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestSyncHandlerBucketSubPath(t *testing.T) {
+	const key = testNamespace + "/" + testName
+
+	tests := []struct {
+		name      string
+		subPath   string
+		wantPhase v1alpha1.ObjectBucketClaimStatusPhase
+		wantCalls []string
+	}{
+		{
+			name:      "subpath is passed to the provisioner and the configmap",
+			subPath:   "team-a/photos",
+			wantPhase: v1alpha1.ObjectBucketClaimStatusPhaseBound,
+			wantCalls: []string{"Provision"},
+		},
+		{
+			name:      "subpath escaping the bucket fails the claim",
+			subPath:   "../photos",
+			wantPhase: v1alpha1.ObjectBucketClaimStatusPhaseFailed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestController(&ControllerOptions{
+				RetryBaseInterval: time.Millisecond,
+				RetryTimeout:      time.Millisecond * 10,
+			})
+			recorder := record.NewFakeRecorder(20)
+			c.recorder = recorder
+			p := &fakeProvisioner{}
+			c.provisioners[provisionerName].provisioner = p
+			createTestClaim(t, c, &storagev1.StorageClass{
+				ObjectMeta:  metav1.ObjectMeta{Name: className},
+				Provisioner: provisionerName,
+			})
+			obcs := c.libClientset.ObjectbucketV1alpha1().ObjectBucketClaims(testNamespace)
+			obc, err := obcs.Get(testName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("error getting OBC: %v", err)
+			}
+			obc.Spec.BucketSubPath = tt.subPath
+			if _, err = obcs.Update(obc); err != nil {
+				t.Fatalf("error updating OBC: %v", err)
+			}
+
+			if err = c.syncHandler(context.Background(), key); err != nil {
+				t.Fatalf("syncHandler() error = %v", err)
+			}
+			if diff := cmp.Diff(tt.wantCalls, p.calls); diff != "" {
+				t.Errorf("provisioner calls mismatch (-want +got):\n%s", diff)
+			}
+			if obc, err = obcs.Get(testName, metav1.GetOptions{}); err != nil {
+				t.Fatalf("error getting OBC: %v", err)
+			}
+			if obc.Status.Phase != tt.wantPhase {
+				t.Errorf("want phase %q, got %q", tt.wantPhase, obc.Status.Phase)
+			}
+			invalid := false
+			for len(recorder.Events) > 0 {
+				if strings.Contains(<-recorder.Events, eventReasonInvalidSubPath) {
+					invalid = true
+				}
+			}
+			if want := tt.wantPhase == v1alpha1.ObjectBucketClaimStatusPhaseFailed; invalid != want {
+				t.Errorf("want %s event %v, got %v", eventReasonInvalidSubPath, want, invalid)
+			}
+			if tt.wantPhase != v1alpha1.ObjectBucketClaimStatusPhaseBound {
+				return
+			}
+			if p.options.SubPath != tt.subPath {
+				t.Errorf("want BucketOptions.SubPath %q, got %q", tt.subPath, p.options.SubPath)
+			}
+			cm, err := c.clientset.CoreV1().ConfigMaps(testNamespace).Get(testName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("error getting configmap: %v", err)
+			}
+			if got := cm.Data[bucketSubPath]; got != tt.subPath {
+				t.Errorf("want %s %q, got %q", bucketSubPath, tt.subPath, got)
+			}
+		})
+	}
+}
+
+func TestSyncHandlerBucketPolicy(t *testing.T) {
+	const (
+		key    = testNamespace + "/" + testName
+		policy = `{"Version": "2012-10-17", "Statement": [{"Effect": "Allow", "Principal": "*", "Action": "s3:GetObject"}]}`
+	)
+
+	tests := []struct {
+		name      string
+		policy    string
+		wantPhase v1alpha1.ObjectBucketClaimStatusPhase
+		wantCalls []string
+	}{
+		{
+			name:      "policy is passed to the provisioner and its checksum recorded",
+			policy:    policy,
+			wantPhase: v1alpha1.ObjectBucketClaimStatusPhaseBound,
+			wantCalls: []string{"Provision"},
+		},
+		{
+			name:      "invalid policy fails the claim",
+			policy:    `{"Version": `,
+			wantPhase: v1alpha1.ObjectBucketClaimStatusPhaseFailed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestController(&ControllerOptions{
+				RetryBaseInterval: time.Millisecond,
+				RetryTimeout:      time.Millisecond * 10,
+			})
+			recorder := record.NewFakeRecorder(20)
+			c.recorder = recorder
+			p := &fakeProvisioner{}
+			c.provisioners[provisionerName].provisioner = p
+			createTestClaim(t, c, &storagev1.StorageClass{
+				ObjectMeta:  metav1.ObjectMeta{Name: className},
+				Provisioner: provisionerName,
+				Parameters:  map[string]string{v1alpha1.StorageClassBucketPolicy: tt.policy},
+			})
+
+			if err := c.syncHandler(context.Background(), key); err != nil {
+				t.Fatalf("syncHandler() error = %v", err)
+			}
+			if diff := cmp.Diff(tt.wantCalls, p.calls); diff != "" {
+				t.Errorf("provisioner calls mismatch (-want +got):\n%s", diff)
+			}
+			obc, err := c.libClientset.ObjectbucketV1alpha1().ObjectBucketClaims(testNamespace).Get(testName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("error getting OBC: %v", err)
+			}
+			if obc.Status.Phase != tt.wantPhase {
+				t.Errorf("want phase %q, got %q", tt.wantPhase, obc.Status.Phase)
+			}
+			invalid := false
+			for len(recorder.Events) > 0 {
+				if strings.Contains(<-recorder.Events, eventReasonInvalidBucketPolicy) {
+					invalid = true
+				}
+			}
+			if want := tt.wantPhase == v1alpha1.ObjectBucketClaimStatusPhaseFailed; invalid != want {
+				t.Errorf("want %s event %v, got %v", eventReasonInvalidBucketPolicy, want, invalid)
+			}
+			if tt.wantPhase != v1alpha1.ObjectBucketClaimStatusPhaseBound {
+				return
+			}
+			if p.options.BucketPolicy != tt.policy {
+				t.Errorf("want BucketOptions.BucketPolicy %q, got %q", tt.policy, p.options.BucketPolicy)
+			}
+			_, wantChecksum, _ := parseBucketPolicy(map[string]string{v1alpha1.StorageClassBucketPolicy: tt.policy})
+			ob, err := c.libClientset.ObjectbucketV1alpha1().ObjectBuckets().Get(obc.Spec.ObjectBucketName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("error getting OB: %v", err)
+			}
+			if ob.Spec.BucketPolicyChecksum != wantChecksum || wantChecksum == "" {
+				t.Errorf("want OB bucketPolicyChecksum %q, got %q", wantChecksum, ob.Spec.BucketPolicyChecksum)
+			}
+		})
+	}
+}
+
+func TestSyncHandlerService(t *testing.T) {
+	const (
+		key     = testNamespace + "/" + testName
+		svcNs   = "object-store"
+		svcName = "s3"
+		svcHost = svcName + "." + svcNs + ".svc"
+		svcRef  = svcNs + "/" + svcName
+	)
+
+	tests := []struct {
+		name       string
+		parameters map[string]string
+		noService  bool
+		wantPhase  v1alpha1.ObjectBucketClaimStatusPhase
+		wantReason string
+		wantPort   string
+	}{
+		{
+			name:       "host and first port are resolved from the service",
+			parameters: map[string]string{v1alpha1.StorageClassService: svcRef},
+			wantPhase:  v1alpha1.ObjectBucketClaimStatusPhaseBound,
+			wantPort:   "80",
+		},
+		{
+			name: "named port is resolved from the service",
+			parameters: map[string]string{
+				v1alpha1.StorageClassService:     svcRef,
+				v1alpha1.StorageClassServicePort: "https",
+			},
+			wantPhase: v1alpha1.ObjectBucketClaimStatusPhaseBound,
+			wantPort:  "443",
+		},
+		{
+			name:       "missing service fails the claim",
+			parameters: map[string]string{v1alpha1.StorageClassService: svcRef},
+			noService:  true,
+			wantPhase:  v1alpha1.ObjectBucketClaimStatusPhaseFailed,
+			wantReason: eventReasonServiceNotFound,
+		},
+		{
+			name:       "invalid service reference fails the claim",
+			parameters: map[string]string{v1alpha1.StorageClassService: svcName},
+			wantPhase:  v1alpha1.ObjectBucketClaimStatusPhaseFailed,
+			wantReason: eventReasonInvalidParameters,
+		},
+		{
+			name: "unknown port name fails the claim",
+			parameters: map[string]string{
+				v1alpha1.StorageClassService:     svcRef,
+				v1alpha1.StorageClassServicePort: "admin",
+			},
+			wantPhase:  v1alpha1.ObjectBucketClaimStatusPhaseFailed,
+			wantReason: eventReasonInvalidParameters,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestController(&ControllerOptions{
+				RetryBaseInterval: time.Millisecond,
+				RetryTimeout:      time.Millisecond * 10,
+			})
+			recorder := record.NewFakeRecorder(20)
+			c.recorder = recorder
+			p := &fakeProvisioner{}
+			c.provisioners[provisionerName].provisioner = p
+			if !tt.noService {
+				if _, err := c.clientset.CoreV1().Services(svcNs).Create(&corev1.Service{
+					ObjectMeta: metav1.ObjectMeta{Name: svcName, Namespace: svcNs},
+					Spec: corev1.ServiceSpec{
+						Ports: []corev1.ServicePort{
+							{Name: "http", Port: 80},
+							{Name: "https", Port: 443},
+						},
+					},
+				}); err != nil {
+					t.Fatalf("error pre-creating Service: %v", err)
+				}
+			}
+			createTestClaim(t, c, &storagev1.StorageClass{
+				ObjectMeta:  metav1.ObjectMeta{Name: className},
+				Provisioner: provisionerName,
+				Parameters:  tt.parameters,
+			})
+
+			if err := c.syncHandler(context.Background(), key); err != nil {
+				t.Fatalf("syncHandler() error = %v", err)
+			}
+			obc, err := c.libClientset.ObjectbucketV1alpha1().ObjectBucketClaims(testNamespace).Get(testName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("error getting OBC: %v", err)
+			}
+			if obc.Status.Phase != tt.wantPhase {
+				t.Errorf("want phase %q, got %q", tt.wantPhase, obc.Status.Phase)
+			}
+			if tt.wantPhase != v1alpha1.ObjectBucketClaimStatusPhaseBound {
+				if len(p.calls) != 0 {
+					t.Errorf("want no provisioner calls, got %v", p.calls)
+				}
+				found := false
+				for len(recorder.Events) > 0 {
+					if strings.Contains(<-recorder.Events, tt.wantReason) {
+						found = true
+					}
+				}
+				if !found {
+					t.Errorf("want a %s event", tt.wantReason)
+				}
+				return
+			}
+			cm, err := c.clientset.CoreV1().ConfigMaps(testNamespace).Get(testName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("error getting ConfigMap: %v", err)
+			}
+			if cm.Data[bucketHost] != svcHost {
+				t.Errorf("want %s %q, got %q", bucketHost, svcHost, cm.Data[bucketHost])
+			}
+			if cm.Data[bucketPort] != tt.wantPort {
+				t.Errorf("want %s %q, got %q", bucketPort, tt.wantPort, cm.Data[bucketPort])
+			}
+		})
+	}
+}
+
+func TestSyncHandlerClaimConfigData(t *testing.T) {
+	const key = testNamespace + "/" + testName
+
+	tests := []struct {
+		name      string
+		data      map[string]string
+		wantPhase v1alpha1.ObjectBucketClaimStatusPhase
+	}{
+		{
+			name:      "app keys are added to the configmap",
+			data:      map[string]string{"APP_CACHE_DIR": "app/data"},
+			wantPhase: v1alpha1.ObjectBucketClaimStatusPhaseBound,
+		},
+		{
+			name:      "reserved key fails the claim",
+			data:      map[string]string{bucketHost: "other.example.com"},
+			wantPhase: v1alpha1.ObjectBucketClaimStatusPhaseFailed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestController(&ControllerOptions{
+				RetryBaseInterval: time.Millisecond,
+				RetryTimeout:      time.Millisecond * 10,
+			})
+			recorder := record.NewFakeRecorder(20)
+			c.recorder = recorder
+			createTestClaim(t, c, &storagev1.StorageClass{
+				ObjectMeta:  metav1.ObjectMeta{Name: className},
+				Provisioner: provisionerName,
+			})
+			obcs := c.libClientset.ObjectbucketV1alpha1().ObjectBucketClaims(testNamespace)
+			obc, err := obcs.Get(testName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("error getting OBC: %v", err)
+			}
+			obc.Spec.ExtraConfigMapData = tt.data
+			if _, err = obcs.Update(obc); err != nil {
+				t.Fatalf("error updating OBC: %v", err)
+			}
+
+			if err = c.syncHandler(context.Background(), key); err != nil {
+				t.Fatalf("syncHandler() error = %v", err)
+			}
+			if obc, err = obcs.Get(testName, metav1.GetOptions{}); err != nil {
+				t.Fatalf("error getting OBC: %v", err)
+			}
+			if obc.Status.Phase != tt.wantPhase {
+				t.Errorf("want phase %q, got %q", tt.wantPhase, obc.Status.Phase)
+			}
+			invalid := false
+			for len(recorder.Events) > 0 {
+				if strings.Contains(<-recorder.Events, eventReasonInvalidConfigData) {
+					invalid = true
+				}
+			}
+			if want := tt.wantPhase == v1alpha1.ObjectBucketClaimStatusPhaseFailed; invalid != want {
+				t.Errorf("want %s event %v, got %v", eventReasonInvalidConfigData, want, invalid)
+			}
+			if tt.wantPhase != v1alpha1.ObjectBucketClaimStatusPhaseBound {
+				return
+			}
+			cm, err := c.clientset.CoreV1().ConfigMaps(testNamespace).Get(testName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("error getting configmap: %v", err)
+			}
+			if got := cm.Data["APP_CACHE_DIR"]; got != "app/data" {
+				t.Errorf("want APP_CACHE_DIR %q, got %q", "app/data", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2019 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/client/clientset/versioned/scheme"
+)
+
+// Reasons of the events recorded on the OBC while it is reconciled
+const (
+	eventReasonObjectBucketCreated      = "ObjectBucketCreated"
+	eventReasonObjectBucketCreateFailed = "ObjectBucketCreateFailed"
+	eventReasonSecretCreated            = "SecretCreated"
+	eventReasonSecretCreateFailed       = "SecretCreateFailed"
+	eventReasonConfigMapCreated         = "ConfigMapCreated"
+	eventReasonConfigMapCreateFailed    = "ConfigMapCreateFailed"
+	eventReasonConfigMapUpdated         = "ConfigMapUpdated"
+	eventReasonConfigMapRecreated       = "ConfigMapRecreated"
+	eventReasonEndpointChanged          = "EndpointChanged"
+	eventReasonSecretRecreated          = "SecretRecreated"
+	eventReasonSecretMissing            = "SecretMissing"
+	eventReasonBucketProvisioned        = "BucketProvisioned"
+	eventReasonBucketProvisionFailed    = "BucketProvisionFailed"
+	eventReasonBucketNotReady           = "BucketNotReady"
+	eventReasonBound                    = "Bound"
+	eventReasonInvalidBucketName        = "InvalidBucketName"
+	eventReasonInvalidQuota             = "InvalidQuota"
+	eventReasonInvalidParameters        = "InvalidParameters"
+	eventReasonInvalidTags              = "InvalidTags"
+	eventReasonInvalidBucketPolicy      = "InvalidBucketPolicy"
+	eventReasonInvalidSubPath           = "InvalidSubPath"
+	eventReasonInvalidSecretNamespaces  = "InvalidSecretNamespaces"
+	eventReasonInvalidConfigData        = "InvalidConfigData"
+	eventReasonInvalidEndpoint          = "InvalidEndpoint"
+	eventReasonNamespaceLimitExceeded   = "NamespaceLimitExceeded"
+	eventReasonBucketNameInUse          = "BucketNameInUse"
+	eventReasonSecretInUse              = "SecretInUse"
+	eventReasonInvalidRetryTimeout      = "InvalidRetryTimeout"
+	eventReasonSecretCopyFailed         = "SecretCopyFailed"
+	eventReasonSecretCopyDeleted        = "SecretCopyDeleted"
+	eventReasonStorageClassNotFound     = "StorageClassNotFound"
+	eventReasonServiceNotFound          = "ServiceNotFound"
+	eventReasonNoStorageClass           = "NoStorageClass"
+	eventReasonDefaultStorageClass      = "DefaultStorageClassApplied"
+	eventReasonPostProvisionFailed      = "PostProvisionFailed"
+	eventReasonDryRunSucceeded          = "DryRunSucceeded"
+	eventReasonDryRunFailed             = "DryRunFailed"
+	eventReasonCredentialsRotated       = "CredentialsRotated"
+	eventReasonRotationFailed           = "CredentialsRotationFailed"
+	eventReasonRotationUnsupported      = "CredentialsRotationUnsupported"
+	eventReasonDeletionFailed           = "DeletionFailed"
+	eventReasonDeletionPending          = "DeletionPending"
+	eventReasonObjectBucketKept         = "ObjectBucketKept"
+	eventReasonBucketQuarantined        = "BucketQuarantined"
+	eventReasonQuarantineCancelled      = "QuarantineCancelled"
+	eventReasonProvisionerTimeout       = "ProvisionerTimeout"
+)
+
+// newEventRecorder returns a recorder which writes events to sink, or to the API server if sink is nil, on
+// behalf of the named component.  The library's scheme is used so that OBCs can be referenced as the
+// involved object.
+func newEventRecorder(component string, c kubernetes.Interface, sink record.EventSink) record.EventRecorder {
+	if sink == nil {
+		sink = &typedcorev1.EventSinkImpl{Interface: c.CoreV1().Events("")}
+	}
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(sink)
+	return broadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: component})
+}
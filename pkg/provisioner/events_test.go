@@ -0,0 +1,97 @@
+/*
+Copyright 2019 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/apis/objectbucket.io/v1alpha1"
+)
+
+// fakeEventSink records the events created through it
+type fakeEventSink struct {
+	mu     sync.Mutex
+	events []*corev1.Event
+}
+
+func (s *fakeEventSink) Create(e *corev1.Event) (*corev1.Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, e)
+	return e, nil
+}
+
+func (s *fakeEventSink) Update(e *corev1.Event) (*corev1.Event, error) {
+	return e, nil
+}
+
+func (s *fakeEventSink) Patch(e *corev1.Event, data []byte) (*corev1.Event, error) {
+	return e, nil
+}
+
+func (s *fakeEventSink) recorded() []*corev1.Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*corev1.Event(nil), s.events...)
+}
+
+func TestEventRecorderOptions(t *testing.T) {
+	tests := []struct {
+		name          string
+		component     string
+		wantComponent string
+	}{
+		{
+			name:          "default component",
+			wantComponent: provisionerName,
+		},
+		{
+			name:          "configured component",
+			component:     "platform-buckets",
+			wantComponent: "platform-buckets",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink := &fakeEventSink{}
+			c := newTestController(&ControllerOptions{
+				EventComponent: tt.component,
+				EventSink:      sink,
+			})
+			obc := &v1alpha1.ObjectBucketClaim{ObjectMeta: objMeta}
+			c.recorder.Event(obc, corev1.EventTypeNormal, eventReasonBound, "bound")
+
+			if err := wait.Poll(time.Millisecond*10, time.Second, func() (bool, error) {
+				return len(sink.recorded()) > 0, nil
+			}); err != nil {
+				t.Fatalf("want an event written to the sink, got none")
+			}
+			e := sink.recorded()[0]
+			if e.Source.Component != tt.wantComponent {
+				t.Errorf("want component %q, got %q", tt.wantComponent, e.Source.Component)
+			}
+			if e.InvolvedObject.Name != obc.Name || e.Reason != eventReasonBound {
+				t.Errorf("want %s event on OBC %q, got %s event on %q", eventReasonBound, obc.Name, e.Reason, e.InvolvedObject.Name)
+			}
+		})
+	}
+}
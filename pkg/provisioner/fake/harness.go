@@ -0,0 +1,82 @@
+/*
+Copyright 2019 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+
+	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/apis/objectbucket.io/v1alpha1"
+	libfake "github.com/kube-object-storage/lib-bucket-provisioner/pkg/client/clientset/versioned/fake"
+	informers "github.com/kube-object-storage/lib-bucket-provisioner/pkg/client/informers/externalversions"
+	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/provisioner"
+	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/provisioner/api"
+)
+
+// Harness runs the library's claim controller for a provisioner against fake clientsets.  Tests create
+// StorageClasses and OBCs through the clientsets and inspect the resulting OBs, Secrets and ConfigMaps.
+type Harness struct {
+	Clientset    *k8sfake.Clientset
+	LibClientset *libfake.Clientset
+	stopCh       chan struct{}
+}
+
+// NewHarness starts the controller of the named provisioner.  A nil options applies the library defaults.
+// Stop must be called to release the controller.
+func NewHarness(provisionerName string, p api.Provisioner, options *provisioner.ControllerOptions) *Harness {
+	h := &Harness{
+		Clientset:    k8sfake.NewSimpleClientset(),
+		LibClientset: libfake.NewSimpleClientset(),
+		stopCh:       make(chan struct{}),
+	}
+	factory := informers.NewSharedInformerFactory(h.LibClientset, 0)
+	ctrl := provisioner.NewController(
+		provisionerName,
+		p,
+		h.Clientset,
+		h.LibClientset,
+		factory.Objectbucket().V1alpha1().ObjectBucketClaims(),
+		factory.Objectbucket().V1alpha1().ObjectBuckets(),
+		options)
+	factory.Start(h.stopCh)
+	go func() {
+		_ = ctrl.Start(h.stopCh)
+	}()
+	return h
+}
+
+// Stop stops the controller
+func (h *Harness) Stop() {
+	close(h.stopCh)
+}
+
+// WaitForClaimPhase polls the named OBC until it reaches the given phase, returning the OBC.  An error
+// is returned if the phase is not reached within timeout.
+func (h *Harness) WaitForClaimPhase(namespace, name string, phase v1alpha1.ObjectBucketClaimStatusPhase, timeout time.Duration) (obc *v1alpha1.ObjectBucketClaim, err error) {
+	err = wait.PollImmediate(time.Millisecond*50, timeout, func() (bool, error) {
+		obc, err = h.LibClientset.ObjectbucketV1alpha1().ObjectBucketClaims(namespace).Get(name, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			return false, nil
+		}
+		return err == nil && obc.Status.Phase == phase, err
+	})
+	return obc, err
+}
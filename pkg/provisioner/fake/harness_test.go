@@ -0,0 +1,98 @@
+/*
+Copyright 2019 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/apis/objectbucket.io/v1alpha1"
+)
+
+const (
+	testProvisioner = "fake.objectbucket.io/provisioner"
+	testNamespace   = "test-namespace"
+	testClaim       = "test-claim"
+	testTimeout     = 5 * time.Second
+)
+
+func createClaim(t *testing.T, h *Harness) {
+	if _, err := h.Clientset.StorageV1().StorageClasses().Create(&storagev1.StorageClass{
+		ObjectMeta:  metav1.ObjectMeta{Name: "test-class"},
+		Provisioner: testProvisioner,
+	}); err != nil {
+		t.Fatalf("error creating storage class: %v", err)
+	}
+	if _, err := h.LibClientset.ObjectbucketV1alpha1().ObjectBucketClaims(testNamespace).Create(&v1alpha1.ObjectBucketClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: testClaim, Namespace: testNamespace},
+		Spec: v1alpha1.ObjectBucketClaimSpec{
+			StorageClassName: "test-class",
+			BucketName:       "test-bucket",
+		},
+	}); err != nil {
+		t.Fatalf("error creating claim: %v", err)
+	}
+}
+
+func TestHarnessBound(t *testing.T) {
+	p := &Provisioner{}
+	h := NewHarness(testProvisioner, p, nil)
+	defer h.Stop()
+	createClaim(t, h)
+
+	obc, err := h.WaitForClaimPhase(testNamespace, testClaim, v1alpha1.ObjectBucketClaimStatusPhaseBound, testTimeout)
+	if err != nil {
+		t.Fatalf("error waiting for claim to be bound: %v", err)
+	}
+	if _, err := h.Clientset.CoreV1().Secrets(testNamespace).Get(obc.Name, metav1.GetOptions{}); err != nil {
+		t.Errorf("expected secret for bound claim: %v", err)
+	}
+	if _, err := h.Clientset.CoreV1().ConfigMaps(testNamespace).Get(obc.Name, metav1.GetOptions{}); err != nil {
+		t.Errorf("expected configmap for bound claim: %v", err)
+	}
+	if calls := p.Calls(); len(calls) != 1 || calls[0] != "Provision" {
+		t.Errorf("expected a single Provision call, got %v", calls)
+	}
+}
+
+func TestHarnessProvisionError(t *testing.T) {
+	p := &Provisioner{ProvisionErr: fmt.Errorf("provision failed")}
+	h := NewHarness(testProvisioner, p, nil)
+	defer h.Stop()
+	createClaim(t, h)
+
+	if err := wait.PollImmediate(time.Millisecond*50, testTimeout, func() (bool, error) {
+		return len(p.Calls()) > 0, nil
+	}); err != nil {
+		t.Fatalf("provisioner was never called: %v", err)
+	}
+	obc, err := h.LibClientset.ObjectbucketV1alpha1().ObjectBucketClaims(testNamespace).Get(testClaim, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("error getting claim: %v", err)
+	}
+	if obc.Status.Phase == v1alpha1.ObjectBucketClaimStatusPhaseBound {
+		t.Errorf("expected claim not to be bound")
+	}
+	if _, err := h.Clientset.CoreV1().Secrets(testNamespace).Get(testClaim, metav1.GetOptions{}); err == nil {
+		t.Errorf("expected no secret for failed claim")
+	}
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2019 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package fake provides a programmable Provisioner and a harness running the library's controller
+// against fake clientsets, so that provisioner authors can test their integration without a cluster.
+package fake
+
+import (
+	"context"
+	"sync"
+
+	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/apis/objectbucket.io/v1alpha1"
+	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/provisioner/api"
+)
+
+// Provisioner is an api.Provisioner whose results are set by the test.  It records the names of the
+// methods called on it.  It is safe for concurrent use.
+type Provisioner struct {
+	// Connection is returned by Provision and Grant.  If nil, an endpoint on localhost with empty
+	// access keys is returned.
+	Connection *v1alpha1.Connection
+	// ProvisionErr, GrantErr, DeleteErr and RevokeErr are returned by the matching methods
+	ProvisionErr error
+	GrantErr     error
+	DeleteErr    error
+	RevokeErr    error
+
+	mu    sync.Mutex
+	calls []string
+}
+
+var _ api.Provisioner = &Provisioner{}
+
+// Provision returns an ObjectBucket holding the Connection, or ProvisionErr if set
+func (p *Provisioner) Provision(ctx context.Context, options *api.BucketOptions) (*v1alpha1.ObjectBucket, error) {
+	p.record("Provision")
+	if p.ProvisionErr != nil {
+		return nil, p.ProvisionErr
+	}
+	return p.objectBucket(options), nil
+}
+
+// Grant returns an ObjectBucket holding the Connection, or GrantErr if set
+func (p *Provisioner) Grant(ctx context.Context, options *api.BucketOptions) (*v1alpha1.ObjectBucket, error) {
+	p.record("Grant")
+	if p.GrantErr != nil {
+		return nil, p.GrantErr
+	}
+	return p.objectBucket(options), nil
+}
+
+// Delete returns DeleteErr
+func (p *Provisioner) Delete(ctx context.Context, ob *v1alpha1.ObjectBucket) error {
+	p.record("Delete")
+	return p.DeleteErr
+}
+
+// Revoke returns RevokeErr
+func (p *Provisioner) Revoke(ctx context.Context, ob *v1alpha1.ObjectBucket) error {
+	p.record("Revoke")
+	return p.RevokeErr
+}
+
+// Calls returns the names of the methods called so far, in order
+func (p *Provisioner) Calls() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.calls...)
+}
+
+func (p *Provisioner) record(call string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.calls = append(p.calls, call)
+}
+
+func (p *Provisioner) objectBucket(options *api.BucketOptions) *v1alpha1.ObjectBucket {
+	conn := p.Connection.DeepCopy()
+	if conn == nil {
+		conn = &v1alpha1.Connection{
+			Endpoint: &v1alpha1.Endpoint{
+				BucketHost: "localhost",
+				BucketPort: 80,
+			},
+			Authentication: &v1alpha1.Authentication{
+				AccessKeys: &v1alpha1.AccessKeys{},
+			},
+		}
+	}
+	if conn.Endpoint != nil && conn.Endpoint.BucketName == "" {
+		conn.Endpoint.BucketName = options.BucketName
+	}
+	return &v1alpha1.ObjectBucket{
+		Spec: v1alpha1.ObjectBucketSpec{
+			Connection: conn,
+		},
+	}
+}
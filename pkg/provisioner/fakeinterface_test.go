@@ -17,35 +17,63 @@ limitations under the License.
 package provisioner
 
 import (
+	"context"
 	"fmt"
+	"sync"
+	"time"
 	// "sigs.k8s.io/Controller-runtime/pkg/client/fake"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+
 	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/apis/objectbucket.io/v1alpha1"
 	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/provisioner/api"
+	pErr "github.com/kube-object-storage/lib-bucket-provisioner/pkg/provisioner/api/errors"
 )
 
-type fakeProvisioner struct{}
+// fakeProvisioner records the names of the interface methods called on it and the options of the
+// last Provision or Grant
+type fakeProvisioner struct {
+	calls   []string
+	options *api.BucketOptions
+	// grantEndpoint, if set, is returned by Grant instead of a populated endpoint of the existing bucket
+	grantEndpoint *v1alpha1.Endpoint
+}
 
 var _ api.Provisioner = &fakeProvisioner{}
 
 // Provision provides a simple method for testing purposes
-func (p *fakeProvisioner) Provision(options *api.BucketOptions) (*v1alpha1.ObjectBucket, error) {
+func (p *fakeProvisioner) Provision(ctx context.Context, options *api.BucketOptions) (*v1alpha1.ObjectBucket, error) {
+	p.calls = append(p.calls, "Provision")
+	p.options = options
 	if options == nil || options.ObjectBucketClaim == nil {
 		return nil, fmt.Errorf("got nil ptr")
 	}
-	return &v1alpha1.ObjectBucket{}, nil
+	return newFakeObjectBucket(options), nil
 }
 
 // Grant provides a simple method for testing purposes
-func (p *fakeProvisioner) Grant(options *api.BucketOptions) (*v1alpha1.ObjectBucket, error) {
+func (p *fakeProvisioner) Grant(ctx context.Context, options *api.BucketOptions) (*v1alpha1.ObjectBucket, error) {
+	p.calls = append(p.calls, "Grant")
+	p.options = options
 	if options == nil || options.ObjectBucketClaim == nil {
 		return nil, fmt.Errorf("got nil ptr")
 	}
-	return &v1alpha1.ObjectBucket{}, nil
+	ob := newFakeObjectBucket(options)
+	ob.Spec.Endpoint = &v1alpha1.Endpoint{
+		BucketName: options.BucketName,
+		BucketHost: "s3.example.com",
+		Region:     "eu-west-1",
+	}
+	if p.grantEndpoint != nil {
+		ob.Spec.Endpoint = p.grantEndpoint.DeepCopy()
+	}
+	return ob, nil
 }
 
 // Delete provides a simple method for testing purposes
-func (p *fakeProvisioner) Delete(ob *v1alpha1.ObjectBucket) (err error) {
+func (p *fakeProvisioner) Delete(ctx context.Context, ob *v1alpha1.ObjectBucket) (err error) {
+	p.calls = append(p.calls, "Delete")
 	if ob == nil {
 		err = fmt.Errorf("got nil object bucket pointer")
 	}
@@ -53,9 +81,264 @@ func (p *fakeProvisioner) Delete(ob *v1alpha1.ObjectBucket) (err error) {
 }
 
 // Revoke provides a simple method for testing purposes
-func (p *fakeProvisioner) Revoke(ob *v1alpha1.ObjectBucket) (err error) {
+func (p *fakeProvisioner) Revoke(ctx context.Context, ob *v1alpha1.ObjectBucket) (err error) {
+	p.calls = append(p.calls, "Revoke")
 	if ob == nil {
 		err = fmt.Errorf("got nil object bucket pointer")
 	}
 	return err
-}
\ No newline at end of file
+}
+
+// newFakeObjectBucket returns the skeleton OB a provisioner is expected to return from Provision and Grant
+func newFakeObjectBucket(options *api.BucketOptions) *v1alpha1.ObjectBucket {
+	return &v1alpha1.ObjectBucket{
+		Spec: v1alpha1.ObjectBucketSpec{
+			Connection: &v1alpha1.Connection{
+				Endpoint:       &v1alpha1.Endpoint{BucketName: options.BucketName, BucketHost: "s3.example.com"},
+				Authentication: &v1alpha1.Authentication{AccessKeys: &v1alpha1.AccessKeys{}},
+			},
+		},
+	}
+}
+
+// connectionProvisioner returns the connection of the fake OB from ProvisionConnection
+type connectionProvisioner struct {
+	fakeProvisioner
+	conn *v1alpha1.Connection
+}
+
+var _ api.ConnectionProvisioner = &connectionProvisioner{}
+
+func (p *connectionProvisioner) ProvisionConnection(ctx context.Context, options *api.BucketOptions) (*v1alpha1.Connection, error) {
+	p.calls = append(p.calls, "ProvisionConnection")
+	p.options = options
+	return p.conn.DeepCopy(), nil
+}
+
+// grantingProvisioner grants access to existing buckets by GrantConnection, returning a connection without the
+// bucket name
+type grantingProvisioner struct {
+	fakeProvisioner
+}
+
+var _ api.GrantingProvisioner = &grantingProvisioner{}
+
+func (p *grantingProvisioner) GrantConnection(ctx context.Context, options *api.BucketOptions) (*v1alpha1.Connection, error) {
+	p.calls = append(p.calls, "GrantConnection")
+	p.options = options
+	return &v1alpha1.Connection{
+		Endpoint:       &v1alpha1.Endpoint{BucketHost: "s3.example.com", Region: "eu-west-1"},
+		Authentication: &v1alpha1.Authentication{AccessKeys: &v1alpha1.AccessKeys{}},
+	}, nil
+}
+
+func (p *grantingProvisioner) RevokeConnection(ctx context.Context, ob *v1alpha1.ObjectBucket) error {
+	p.calls = append(p.calls, "RevokeConnection")
+	return nil
+}
+
+// readinessProvisioner reports its buckets ready from the given IsReady call on, counting from 1, asking to be
+// called again after requeueAfter until then
+type readinessProvisioner struct {
+	fakeProvisioner
+	readyFrom    int
+	requeueAfter time.Duration
+	checks       int
+}
+
+var _ api.ReadinessChecker = &readinessProvisioner{}
+
+func (p *readinessProvisioner) IsReady(ctx context.Context, ob *v1alpha1.ObjectBucket) (bool, time.Duration, error) {
+	p.calls = append(p.calls, "IsReady")
+	p.checks++
+	if p.checks < p.readyFrom {
+		return false, p.requeueAfter, nil
+	}
+	return true, 0, nil
+}
+
+// blockingProvisioner blocks in Provision until release is closed, recording the highest number of
+// concurrent calls
+type blockingProvisioner struct {
+	fakeProvisioner
+	release chan struct{}
+
+	mu          sync.Mutex
+	inFlight    int
+	maxInFlight int
+}
+
+var _ api.Provisioner = &blockingProvisioner{}
+
+func (p *blockingProvisioner) Provision(ctx context.Context, options *api.BucketOptions) (*v1alpha1.ObjectBucket, error) {
+	p.mu.Lock()
+	p.inFlight++
+	if p.inFlight > p.maxInFlight {
+		p.maxInFlight = p.inFlight
+	}
+	p.mu.Unlock()
+
+	<-p.release
+
+	p.mu.Lock()
+	p.inFlight--
+	p.mu.Unlock()
+	return newFakeObjectBucket(options), nil
+}
+
+func (p *blockingProvisioner) max() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.maxInFlight
+}
+
+// rotatingProvisioner issues numbered access keys on rotation.  It records the secret's content when
+// asked to revoke the previous credentials, in order to check they were replaced beforehand.
+type rotatingProvisioner struct {
+	fakeProvisioner
+	secrets         corev1client.SecretInterface
+	secretName      string
+	rotations       int
+	secretAtRevoke  map[string]string
+	revokedPrevious map[string]string
+}
+
+var _ api.CredentialRotator = &rotatingProvisioner{}
+
+func (p *rotatingProvisioner) RotateCredentials(ctx context.Context, ob *v1alpha1.ObjectBucket) (*v1alpha1.Authentication, error) {
+	p.calls = append(p.calls, "RotateCredentials")
+	p.rotations++
+	return &v1alpha1.Authentication{
+		AccessKeys: &v1alpha1.AccessKeys{
+			AccessKeyID:     fmt.Sprintf("key-%d", p.rotations),
+			SecretAccessKey: fmt.Sprintf("secret-%d", p.rotations),
+		},
+	}, nil
+}
+
+func (p *rotatingProvisioner) RevokeCredentials(ctx context.Context, ob *v1alpha1.ObjectBucket, previous map[string]string) error {
+	p.calls = append(p.calls, "RevokeCredentials")
+	p.revokedPrevious = previous
+	secret, err := p.secrets.Get(p.secretName, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	p.secretAtRevoke = secretData(secret)
+	return nil
+}
+
+// schemaProvisioner declares a parameter schema
+type schemaProvisioner struct {
+	fakeProvisioner
+	schema api.ParameterSchema
+}
+
+var _ api.ParameterSchemaProvider = &schemaProvisioner{}
+
+func (p *schemaProvisioner) ParameterSchema() api.ParameterSchema {
+	return p.schema
+}
+
+// defaultingProvisioner declares default parameters
+type defaultingProvisioner struct {
+	fakeProvisioner
+	defaults map[string]string
+}
+
+var _ api.ParameterDefaulter = &defaultingProvisioner{}
+
+func (p *defaultingProvisioner) DefaultParameters() map[string]string {
+	return p.defaults
+}
+
+// hangingProvisioner hangs in Provision and Delete until release is closed, ignoring its context as a buggy
+// provisioner would
+type hangingProvisioner struct {
+	fakeProvisioner
+	release chan struct{}
+}
+
+func (p *hangingProvisioner) Provision(ctx context.Context, options *api.BucketOptions) (*v1alpha1.ObjectBucket, error) {
+	<-p.release
+	return nil, fmt.Errorf("released")
+}
+
+func (p *hangingProvisioner) Delete(ctx context.Context, ob *v1alpha1.ObjectBucket) error {
+	<-p.release
+	return fmt.Errorf("released")
+}
+
+// checkedProvisioner reports err from HealthCheck, or hangs until its context is done if hang is set
+type checkedProvisioner struct {
+	fakeProvisioner
+	mu   sync.Mutex
+	err  error
+	hang bool
+}
+
+func (p *checkedProvisioner) HealthCheck(ctx context.Context) error {
+	p.mu.Lock()
+	err, hang := p.err, p.hang
+	p.mu.Unlock()
+	if hang {
+		<-ctx.Done()
+	}
+	return err
+}
+
+func (p *checkedProvisioner) setHealth(err error, hang bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.err, p.hang = err, hang
+}
+
+// failingProvisioner fails Provision with err
+type failingProvisioner struct {
+	fakeProvisioner
+	err error
+}
+
+func (p *failingProvisioner) Provision(ctx context.Context, options *api.BucketOptions) (*v1alpha1.ObjectBucket, error) {
+	p.calls = append(p.calls, "Provision")
+	return nil, p.err
+}
+
+// failingDeleteProvisioner fails its first deleteFailures Delete calls, with a DeleteNotReadyErr if notReady
+type failingDeleteProvisioner struct {
+	fakeProvisioner
+	deleteFailures int
+	notReady       bool
+}
+
+func (p *failingDeleteProvisioner) Delete(ctx context.Context, ob *v1alpha1.ObjectBucket) error {
+	p.calls = append(p.calls, "Delete")
+	if p.deleteFailures > 0 {
+		p.deleteFailures--
+		if p.notReady {
+			return pErr.NewDeleteNotReadyError("bucket not empty")
+		}
+		return fmt.Errorf("injected error")
+	}
+	return nil
+}
+
+// statefulProvisioner returns additional state from Provision and records the state given to Delete
+type statefulProvisioner struct {
+	fakeProvisioner
+	state        map[string]string
+	deletedState map[string]string
+}
+
+func (p *statefulProvisioner) Provision(ctx context.Context, options *api.BucketOptions) (*v1alpha1.ObjectBucket, error) {
+	ob, err := p.fakeProvisioner.Provision(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+	ob.Spec.AdditionalState = p.state
+	return ob, nil
+}
+
+func (p *statefulProvisioner) Delete(ctx context.Context, ob *v1alpha1.ObjectBucket) error {
+	p.deletedState = ob.Spec.AdditionalState
+	return p.fakeProvisioner.Delete(ctx, ob)
+}
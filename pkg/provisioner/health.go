@@ -0,0 +1,147 @@
+/*
+Copyright 2019 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/provisioner/api"
+)
+
+// errNotChecked is the health of a provisioner whose first check has not completed yet
+var errNotChecked = fmt.Errorf("health not checked yet")
+
+// healthMonitor periodically calls the HealthCheck of the provisioners implementing api.HealthChecker and keeps
+// the last result of each.  Provisioners which do not implement it are always healthy.
+type healthMonitor struct {
+	// interval spaces the checks of each provisioner, and bounds each check unless timeout is set
+	interval time.Duration
+	timeout  time.Duration
+	checkers map[string]api.HealthChecker
+
+	mu   sync.RWMutex
+	errs map[string]error
+}
+
+func newHealthMonitor(provisioners map[string]*registeredProvisioner, interval, timeout time.Duration) *healthMonitor {
+	h := &healthMonitor{
+		interval: interval,
+		timeout:  timeout,
+		checkers: map[string]api.HealthChecker{},
+		errs:     map[string]error{},
+	}
+	for name, rp := range provisioners {
+		if checker, ok := rp.provisioner.(api.HealthChecker); ok {
+			h.checkers[name] = checker
+			h.errs[name] = errNotChecked
+		}
+	}
+	return h
+}
+
+// run checks each provisioner every interval until stopCh is closed
+func (h *healthMonitor) run(stopCh <-chan struct{}) {
+	if len(h.checkers) == 0 {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-stopCh
+		cancel()
+	}()
+	wait.Until(func() { h.checkAll(ctx) }, h.interval, stopCh)
+}
+
+// checkAll checks the provisioners in parallel, so that a hanging object store does not delay the others
+func (h *healthMonitor) checkAll(ctx context.Context) {
+	var wg sync.WaitGroup
+	for name, checker := range h.checkers {
+		wg.Add(1)
+		go func(name string, checker api.HealthChecker) {
+			defer wg.Done()
+			h.check(ctx, name, checker)
+		}(name, checker)
+	}
+	wg.Wait()
+}
+
+func (h *healthMonitor) check(ctx context.Context, name string, checker api.HealthChecker) {
+	timeout := h.timeout
+	if timeout == 0 {
+		timeout = h.interval
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		done <- checker.HealthCheck(ctx)
+	}()
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = fmt.Errorf("health check did not return within %v", timeout)
+	}
+
+	h.mu.Lock()
+	prev := h.errs[name]
+	h.errs[name] = err
+	h.mu.Unlock()
+	switch {
+	case err != nil && prev == nil:
+		log.Error(err, "provisioner unhealthy", "provisioner", name)
+	case err == nil && prev != nil:
+		log.Info("provisioner healthy", "provisioner", name)
+	}
+}
+
+// healthy returns an error naming the provisioners whose last check failed, or nil if none did
+func (h *healthMonitor) healthy() error {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	var failed []string
+	for name, err := range h.errs {
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", name, err))
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	sort.Strings(failed)
+	return fmt.Errorf("unhealthy provisioners: %s", strings.Join(failed, "; "))
+}
+
+// readinessHandler serves 200 while healthy returns nil, and 503 with its error otherwise
+func readinessHandler(healthy func() error) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if err := healthy(); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintln(w, "ok")
+	})
+}
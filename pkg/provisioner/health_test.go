@@ -0,0 +1,138 @@
+/*
+Copyright 2019 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/kubernetes/fake"
+
+	externalFake "github.com/kube-object-storage/lib-bucket-provisioner/pkg/client/clientset/versioned/fake"
+	informers "github.com/kube-object-storage/lib-bucket-provisioner/pkg/client/informers/externalversions"
+	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/provisioner/api"
+)
+
+func newHealthTestController(p api.Provisioner) *obcController {
+	extClient := externalFake.NewSimpleClientset()
+	factory := informers.NewSharedInformerFactory(extClient, 0)
+	return NewController(
+		provisionerName,
+		p,
+		fake.NewSimpleClientset(),
+		extClient,
+		factory.Objectbucket().V1alpha1().ObjectBucketClaims(),
+		factory.Objectbucket().V1alpha1().ObjectBuckets(),
+		&ControllerOptions{HealthCheckInterval: time.Millisecond * 10})
+}
+
+func readinessStatus(t *testing.T, c *obcController) int {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	readinessHandler(c.Healthy).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	return rec.Code
+}
+
+func TestHealthyWithoutHealthChecker(t *testing.T) {
+	c := newHealthTestController(&fakeProvisioner{})
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	// returns at once as there is nothing to check
+	c.RunHealthChecks(stopCh)
+	if err := c.Healthy(); err != nil {
+		t.Errorf("want healthy, got %v", err)
+	}
+	if code := readinessStatus(t, c); code != http.StatusOK {
+		t.Errorf("want status %d, got %d", http.StatusOK, code)
+	}
+}
+
+func TestHealthCheck(t *testing.T) {
+	p := &checkedProvisioner{}
+	c := newHealthTestController(p)
+	if err := c.Healthy(); err == nil {
+		t.Fatalf("want unhealthy before the first check")
+	}
+
+	ctx := context.Background()
+	c.health.checkAll(ctx)
+	if err := c.Healthy(); err != nil {
+		t.Errorf("want healthy, got %v", err)
+	}
+	if code := readinessStatus(t, c); code != http.StatusOK {
+		t.Errorf("want status %d, got %d", http.StatusOK, code)
+	}
+
+	p.setHealth(fmt.Errorf("connection refused"), false)
+	c.health.checkAll(ctx)
+	err := c.Healthy()
+	if err == nil || !strings.Contains(err.Error(), provisionerName) || !strings.Contains(err.Error(), "connection refused") {
+		t.Errorf("want the provisioner reported unhealthy, got %v", err)
+	}
+	if code := readinessStatus(t, c); code != http.StatusServiceUnavailable {
+		t.Errorf("want status %d, got %d", http.StatusServiceUnavailable, code)
+	}
+
+	// a hanging check is abandoned after the interval
+	p.setHealth(nil, true)
+	c.health.checkAll(ctx)
+	if err := c.Healthy(); err == nil || !strings.Contains(err.Error(), "did not return") {
+		t.Errorf("want the hanging check reported, got %v", err)
+	}
+
+	p.setHealth(nil, false)
+	c.health.checkAll(ctx)
+	if err := c.Healthy(); err != nil {
+		t.Errorf("want healthy again, got %v", err)
+	}
+}
+
+func TestRunHealthChecks(t *testing.T) {
+	p := &checkedProvisioner{}
+	c := newHealthTestController(p)
+	stopCh := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		c.RunHealthChecks(stopCh)
+		close(done)
+	}()
+	defer func() {
+		close(stopCh)
+		<-done
+	}()
+
+	waitForHealth := func(wantHealthy bool) {
+		t.Helper()
+		deadline := time.Now().Add(time.Second * 5)
+		for (c.Healthy() == nil) != wantHealthy {
+			if time.Now().After(deadline) {
+				t.Fatalf("want healthy %v, got %v", wantHealthy, c.Healthy())
+			}
+			time.Sleep(time.Millisecond * 5)
+		}
+	}
+	waitForHealth(true)
+	p.setHealth(fmt.Errorf("connection refused"), false)
+	waitForHealth(false)
+	p.setHealth(nil, false)
+	waitForHealth(true)
+}
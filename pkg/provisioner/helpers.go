@@ -17,20 +17,37 @@ limitations under the License.
 package provisioner
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
-
-	"github.com/google/uuid"
+	"time"
+	"unicode/utf8"
 
 	corev1 "k8s.io/api/core/v1"
 	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/client-go/kubernetes"
+	storagelisters "k8s.io/client-go/listers/storage/v1"
 	"k8s.io/client-go/tools/cache"
 
 	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/apis/objectbucket.io/v1alpha1"
 	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/client/clientset/versioned"
+	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/provisioner/api"
 )
 
 func makeObjectReference(claim *v1alpha1.ObjectBucketClaim) *corev1.ObjectReference {
@@ -44,10 +61,25 @@ func makeObjectReference(claim *v1alpha1.ObjectBucketClaim) *corev1.ObjectRefere
 	}
 }
 
-func makeOwnerReference(claim *v1alpha1.ObjectBucketClaim) metav1.OwnerReference {
+// refersToClaim returns true if the OB's claim reference is the claim's.  The reference of OBs created by earlier
+// versions of the library may lack the claim's UID, or be missing: those OBs are matched by their name, which is
+// derived from the claim's key.
+func refersToClaim(ob *v1alpha1.ObjectBucket, claim *corev1.ObjectReference) bool {
+	ref := ob.Spec.ClaimRef
+	if ref != nil && ref.UID != "" {
+		return ref.UID == claim.UID
+	}
+	if ref != nil && (ref.Namespace != claim.Namespace || ref.Name != claim.Name) {
+		return false
+	}
+	return ob.Name == objectBucketName(claim.Namespace, claim.Name)
+}
 
-	blockOwnerDeletion := true
-	isController := true
+// makeOwnerReference returns the ownerReference of the claim's Secret and ConfigMap
+func makeOwnerReference(claim *v1alpha1.ObjectBucketClaim, opts OwnerReferenceOptions) metav1.OwnerReference {
+
+	blockOwnerDeletion := opts.BlockOwnerDeletion
+	isController := opts.Controller
 
 	return metav1.OwnerReference{
 		APIVersion:         v1alpha1.SchemeGroupVersion.String(),
@@ -68,7 +100,20 @@ func shouldProvision(obc *v1alpha1.ObjectBucketClaim) bool {
 	return true
 }
 
-func claimRefForKey(key string, c versioned.Interface) (*corev1.ObjectReference, error) {
+// isStatusOnlyUpdate returns true if an update of the claim only changed its status.  Spec changes are
+// told by the generation, which status subresource updates leave unchanged, and by the spec itself for
+// clients which do not maintain the generation, e.g. fake clientsets.  The metadata the controller reacts
+// to, e.g. finalizers and annotations, is compared too.
+func isStatusOnlyUpdate(old, new *v1alpha1.ObjectBucketClaim) bool {
+	return old.Generation == new.Generation &&
+		equality.Semantic.DeepEqual(old.Spec, new.Spec) &&
+		equality.Semantic.DeepEqual(old.Labels, new.Labels) &&
+		equality.Semantic.DeepEqual(old.Annotations, new.Annotations) &&
+		equality.Semantic.DeepEqual(old.Finalizers, new.Finalizers) &&
+		equality.Semantic.DeepEqual(old.DeletionTimestamp, new.DeletionTimestamp)
+}
+
+func claimRefForKey(key string, c bucketClient) (*corev1.ObjectReference, error) {
 	claim, err := claimForKey(key, c)
 	if err != nil {
 		return nil, err
@@ -76,14 +121,14 @@ func claimRefForKey(key string, c versioned.Interface) (*corev1.ObjectReference,
 	return makeObjectReference(claim), nil
 }
 
-func claimForKey(key string, c versioned.Interface) (obc *v1alpha1.ObjectBucketClaim, err error) {
+func claimForKey(key string, c bucketClient) (obc *v1alpha1.ObjectBucketClaim, err error) {
 	logD.Info("getting claim for key")
 
 	ns, name, err := cache.SplitMetaNamespaceKey(key)
 	if err != nil {
 		return nil, err
 	}
-	return c.ObjectbucketV1alpha1().ObjectBucketClaims(ns).Get(name, metav1.GetOptions{})
+	return c.GetClaim(ns, name)
 }
 
 // Return true if this storage class is for a new bucket vs an existing bucket.
@@ -92,40 +137,635 @@ func isNewBucketByStorageClass(sc *storagev1.StorageClass) bool {
 }
 
 // Return true if this OB is for a new bucket vs an existing bucket.
-func isNewBucketByObjectBucket(c kubernetes.Interface, ob *v1alpha1.ObjectBucket) bool {
+func isNewBucketByObjectBucket(l storagelisters.StorageClassLister, ob *v1alpha1.ObjectBucket) bool {
 	// temp: get bucket name from OB's storage class
-	class, err := storageClassForObjectBucket(ob, c)
-	if err != nil || class == nil {
+	class, err := storageClassForObjectBucket(l, ob)
+	if errors.IsNotFound(err) {
+		log.Info("StorageClass of ObjectBucket not found, treating its bucket as existing", "name", ob.Spec.StorageClassName)
+		return false
+	}
+	if err != nil {
 		log.Error(err, "unable to get StorageClass of ObjectBucket")
 		return false
 	}
 	return len(class.Parameters[v1alpha1.StorageClassBucket]) == 0
 }
 
-func configMapForClaimKey(key string, c kubernetes.Interface) (*corev1.ConfigMap, error) {
-	logD.Info("getting configMap for key", "key", key)
-	ns, name, err := cache.SplitMetaNamespaceKey(key)
+// isExistingBucketOfClaim returns true if the OB grants access to the existing bucket named by its claim
+func isExistingBucketOfClaim(ob *v1alpha1.ObjectBucket) bool {
+	return ob.Annotations[v1alpha1.ExistingBucketAnnotation] == "true"
+}
+
+// validateExistingBucketName returns an error if the OBC names an existing bucket its storage class does not grant
+// access to: a class naming a bucket restricts its claims to that bucket, bucketName, and other classes must
+// allow their claims to name any existing bucket.
+func validateExistingBucketName(obc *v1alpha1.ObjectBucketClaim, class *storagev1.StorageClass, bucketName string) error {
+	existing := obc.Spec.ExistingBucketName
+	if existing == "" {
+		return nil
+	}
+	if !isNewBucketByStorageClass(class) {
+		if existing != bucketName {
+			return fmt.Errorf("storage class %q only grants access to bucket %q, not %q", class.Name, bucketName, existing)
+		}
+		return nil
+	}
+	if class.Parameters[v1alpha1.StorageClassAllowExistingBuckets] != "true" {
+		return fmt.Errorf("storage class %q does not allow claims to name an existing bucket, see its %s parameter",
+			class.Name, v1alpha1.StorageClassAllowExistingBuckets)
+	}
+	return nil
+}
+
+// isQuarantined returns true if the OB's claim was deleted and the deletion of its bucket is deferred
+func isQuarantined(ob *v1alpha1.ObjectBucket) bool {
+	return ob.Status.Phase == v1alpha1.ObjectBucketStatusPhaseQuarantined
+}
+
+// quarantineEnd returns when the quarantine of the OB ends, which is immediate if its start is unknown
+func quarantineEnd(ob *v1alpha1.ObjectBucket, period time.Duration) time.Time {
+	if ob.Status.QuarantinedAt == nil {
+		return time.Time{}
+	}
+	return ob.Status.QuarantinedAt.Add(period)
+}
+
+// quarantineEnded returns true if the quarantine of the OB has ended, see quarantineEnd
+func quarantineEnded(ob *v1alpha1.ObjectBucket, period time.Duration) bool {
+	return !time.Now().Before(quarantineEnd(ob, period))
+}
+
+// Return true if this OB was released by its claim and must be kept per its reclaimPolicy. An OB which
+// has not been released, e.g. one being cleaned up after a failed provisioning, is never retained.
+func isRetained(ob *v1alpha1.ObjectBucket) bool {
+	return ob.Status.Phase == v1alpha1.ObjectBucketStatusPhaseReleased &&
+		ob.Spec.ReclaimPolicy != nil &&
+		*ob.Spec.ReclaimPolicy == corev1.PersistentVolumeReclaimRetain
+}
+
+// setEndpointDefaults fills the endpoint's empty region and subregion from the storage class
+// parameters. Values set by the provisioner are never overridden.
+func setEndpointDefaults(ep *v1alpha1.Endpoint, parameters map[string]string) {
+	if ep.Region == "" {
+		ep.Region = parameters[v1alpha1.StorageClassRegion]
+	}
+	if ep.SubRegion == "" {
+		ep.SubRegion = parameters[v1alpha1.StorageClassSubRegion]
+	}
+}
+
+// validateEndpoint checks that the endpoint returned by Provision or Grant holds the fields without which
+// the ConfigMap is useless, e.g. its bucket host.  The region is only required of a granted endpoint, as
+// unlike a provisioned bucket's it is not completed from the storage class parameters.
+func validateEndpoint(ep *v1alpha1.Endpoint, granted bool) error {
+	var missing []string
+	if ep.BucketName == "" {
+		missing = append(missing, "bucketName")
+	}
+	switch ep.Kind {
+	case "", v1alpha1.EndpointKindS3:
+		if ep.BucketHost == "" {
+			missing = append(missing, "bucketHost")
+		}
+		if ep.Region == "" && granted {
+			missing = append(missing, "region")
+		}
+	case v1alpha1.EndpointKindAzure:
+		if ep.AccountName == "" {
+			missing = append(missing, "accountName")
+		}
+	default:
+		return fmt.Errorf("unknown endpoint kind %q", ep.Kind)
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing required fields %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// Limits on the bucket tags, as enforced by S3
+const (
+	maxTags           = 50
+	maxTagKeyLength   = 128
+	maxTagValueLength = 256
+)
+
+// validateBucketSubPath returns an error if the claim's bucket subpath is not relative to the bucket or could
+// escape its prefix.  An empty subpath is valid.
+func validateBucketSubPath(path string) error {
+	switch {
+	case strings.HasPrefix(path, "/"):
+		return fmt.Errorf("invalid bucket subpath %q: must not start with a slash", path)
+	case strings.Contains(path, ".."):
+		return fmt.Errorf("invalid bucket subpath %q: must not contain %q", path, "..")
+	}
+	return nil
+}
+
+// parseTags returns the tags requested by the claim's tags annotation, a comma separated list of key=value
+// pairs.  Keys must be unique and non-empty.  Returns nil if the annotation is not set.
+func parseTags(obc *v1alpha1.ObjectBucketClaim) (map[string]string, error) {
+	annotation := strings.TrimSpace(obc.Annotations[v1alpha1.TagsAnnotation])
+	if annotation == "" {
+		return nil, nil
+	}
+	pairs := strings.Split(annotation, ",")
+	if len(pairs) > maxTags {
+		return nil, fmt.Errorf("invalid tags: at most %d tags are allowed, got %d", maxTags, len(pairs))
+	}
+	tags := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid tag %q: must be of the form key=value", strings.TrimSpace(pair))
+		}
+		k, v := strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1])
+		switch {
+		case k == "":
+			return nil, fmt.Errorf("invalid tag %q: key must not be empty", strings.TrimSpace(pair))
+		case len(k) > maxTagKeyLength:
+			return nil, fmt.Errorf("invalid tag key %q: must be no more than %d characters", k, maxTagKeyLength)
+		case len(v) > maxTagValueLength:
+			return nil, fmt.Errorf("invalid value of tag %q: must be no more than %d characters", k, maxTagValueLength)
+		}
+		if _, ok := tags[k]; ok {
+			return nil, fmt.Errorf("invalid tags: duplicate key %q", k)
+		}
+		tags[k] = v
+	}
+	return tags, nil
+}
+
+// parseBucketPolicy returns the bucket policy of the storage class parameters and its checksum, both empty if the
+// policy is not set.  The policy must be a JSON object.  The checksum is the hex encoded SHA-256 sum of the
+// compacted policy, so that reformatting the policy does not change it.
+func parseBucketPolicy(parameters map[string]string) (policy, checksum string, err error) {
+	policy = strings.TrimSpace(parameters[v1alpha1.StorageClassBucketPolicy])
+	if policy == "" {
+		return "", "", nil
+	}
+	var doc map[string]interface{}
+	if err = json.Unmarshal([]byte(policy), &doc); err != nil {
+		return "", "", fmt.Errorf("invalid %s: must be a JSON object: %v", v1alpha1.StorageClassBucketPolicy, err)
+	}
+	var compact bytes.Buffer
+	if err = json.Compact(&compact, []byte(policy)); err != nil {
+		return "", "", fmt.Errorf("invalid %s: %v", v1alpha1.StorageClassBucketPolicy, err)
+	}
+	sum := sha256.Sum256(compact.Bytes())
+	return policy, hex.EncodeToString(sum[:]), nil
+}
+
+// validateSecretNamespaces checks the claim's additional secret namespaces, which are only allowed if
+// cross-namespace secrets are.
+func validateSecretNamespaces(obc *v1alpha1.ObjectBucketClaim, allowed bool) error {
+	if len(obc.Spec.AdditionalSecretNamespaces) == 0 {
+		return nil
+	}
+	if !allowed {
+		return fmt.Errorf("additional secret namespaces are not allowed by the provisioner")
+	}
+	seen := make(map[string]bool, len(obc.Spec.AdditionalSecretNamespaces))
+	for _, ns := range obc.Spec.AdditionalSecretNamespaces {
+		switch {
+		case ns == obc.Namespace:
+			return fmt.Errorf("additional secret namespace %q is the OBC's namespace", ns)
+		case seen[ns]:
+			return fmt.Errorf("duplicate additional secret namespace %q", ns)
+		}
+		if errs := validation.IsDNS1123Label(ns); len(errs) > 0 {
+			return fmt.Errorf("invalid additional secret namespace %q: %s", ns, strings.Join(errs, ", "))
+		}
+		seen[ns] = true
+	}
+	return nil
+}
+
+// acceptsSecretCopies returns true if the namespace accepts copies of the secrets of the claims in claimNamespace,
+// see v1alpha1.SecretCopiesFromAnnotation
+func acceptsSecretCopies(ns *corev1.Namespace, claimNamespace string) bool {
+	for _, from := range strings.Split(ns.Annotations[v1alpha1.SecretCopiesFromAnnotation], ",") {
+		if from = strings.TrimSpace(from); from == "*" || from == claimNamespace {
+			return true
+		}
+	}
+	return false
+}
+
+// checkSecretCopyConsent returns an error if one of the claim's additional secret namespaces, or a missing one, does
+// not accept copies of the claim's secret, see acceptsSecretCopies.  Errors getting the namespaces are returned as is.
+func checkSecretCopyConsent(obc *v1alpha1.ObjectBucketClaim, c kubernetes.Interface) error {
+	for _, name := range obc.Spec.AdditionalSecretNamespaces {
+		ns, err := c.CoreV1().Namespaces().Get(name, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			return fmt.Errorf("additional secret namespace %q not found", name)
+		}
+		if err != nil {
+			return err
+		}
+		if !acceptsSecretCopies(ns, obc.Namespace) {
+			return fmt.Errorf("namespace %q does not accept secret copies from namespace %q, see its %s annotation",
+				name, obc.Namespace, v1alpha1.SecretCopiesFromAnnotation)
+		}
+	}
+	return nil
+}
+
+// secretCopyNamespaces returns the namespaces which may hold a copy of the claim's secret: those of its spec, and
+// those its status records the secret was copied into
+func secretCopyNamespaces(obc *v1alpha1.ObjectBucketClaim) []string {
+	return sets.NewString(obc.Spec.AdditionalSecretNamespaces...).Insert(obc.Status.SecretCopyNamespaces...).List()
+}
+
+// staleSecretCopyNamespaces returns the namespaces the claim's secret was copied into which have since been removed
+// from its spec
+func staleSecretCopyNamespaces(obc *v1alpha1.ObjectBucketClaim) []string {
+	return sets.NewString(obc.Status.SecretCopyNamespaces...).Delete(obc.Spec.AdditionalSecretNamespaces...).List()
+}
+
+// libraryParameters are the storage class parameters interpreted by the library
+var libraryParameters = []string{
+	v1alpha1.StorageClassBucket,
+	v1alpha1.StorageClassRegion,
+	v1alpha1.StorageClassSubRegion,
+	v1alpha1.StorageClassBucketNamePrefix,
+	v1alpha1.QuotaMaxObjects,
+	v1alpha1.QuotaMaxSize,
+	v1alpha1.StorageClassBucketPolicy,
+	v1alpha1.StorageClassVersioning,
+	v1alpha1.StorageClassService,
+	v1alpha1.StorageClassServicePort,
+	v1alpha1.StorageClassAllowExistingBuckets,
+}
+
+// mergeParameters returns the storage class parameters completed with defaults, the parameters taking
+// precedence.  A default bucket name is dropped, so that defaults cannot make a storage class one of an existing
+// bucket.  parameters is returned as is if there are no defaults.
+func mergeParameters(defaults, parameters map[string]string) map[string]string {
+	if len(defaults) == 0 {
+		return parameters
+	}
+	merged := make(map[string]string, len(defaults)+len(parameters))
+	for k, v := range defaults {
+		if k == v1alpha1.StorageClassBucket {
+			log.Info("ignoring default parameter", "key", k)
+			continue
+		}
+		merged[k] = v
+	}
+	for k, v := range parameters {
+		merged[k] = v
+	}
+	return merged
+}
+
+// unknownParameters returns the sorted keys of parameters known neither to the library nor to schema.
+func unknownParameters(parameters map[string]string, schema api.ParameterSchema) []string {
+	known := make(map[string]bool, len(libraryParameters)+len(schema.Keys))
+	for _, k := range libraryParameters {
+		known[k] = true
+	}
+	for _, k := range schema.Keys {
+		known[k] = true
+	}
+	var unknown []string
+	for k := range parameters {
+		if !known[k] {
+			unknown = append(unknown, k)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
+// endpointURL returns the endpoint as a scheme://host[:port] URL, the scheme being https for SSL endpoints.
+// The port is omitted if unset or the scheme's default, and a scheme prefixing the host is dropped.
+// Returns an empty string if the host is not set.
+func endpointURL(ep *v1alpha1.Endpoint) string {
+	host := ep.BucketHost
+	if i := strings.Index(host, "://"); i >= 0 {
+		host = host[i+len("://"):]
+	}
+	if host == "" {
+		return ""
+	}
+	scheme, defaultPort := "http", 80
+	if ep.SSL {
+		scheme, defaultPort = "https", 443
+	}
+	if ep.BucketPort != 0 && ep.BucketPort != defaultPort {
+		host = net.JoinHostPort(host, strconv.Itoa(ep.BucketPort))
+	} else if ip := net.ParseIP(host); ip != nil && ip.To4() == nil {
+		host = "[" + host + "]"
+	}
+	return (&url.URL{Scheme: scheme, Host: host}).String()
+}
+
+// tlsVersions are the TLS versions recognized as Endpoint.TLSMinVersion
+var tlsVersions = []string{"1.0", "1.1", "1.2", "1.3"}
+
+// validateTLSMinVersion returns an error if version is set but is not one of tlsVersions
+func validateTLSMinVersion(version string) error {
+	if version == "" {
+		return nil
+	}
+	for _, v := range tlsVersions {
+		if version == v {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid TLS minimum version %q, must be one of %s", version, strings.Join(tlsVersions, ", "))
+}
+
+// claimConfigParameter returns the value of key from the OBC's additionalConfig or, if not set there, from the
+// storage class parameters.  It also serves the other keys an OBC may override, e.g. versioning.
+func claimConfigParameter(key string, obc *v1alpha1.ObjectBucketClaim, parameters map[string]string) string {
+	if v, ok := obc.Spec.AdditionalConfig[key]; ok {
+		return v
+	}
+	return parameters[key]
+}
+
+// parseQuota returns the bucket quota requested by the OBC or its storage class, the OBC taking precedence.
+// Both limits must be positive.  Returns nil if neither is defined.
+func parseQuota(obc *v1alpha1.ObjectBucketClaim, parameters map[string]string) (*v1alpha1.Quota, error) {
+	var quota *v1alpha1.Quota
+
+	if v := claimConfigParameter(v1alpha1.QuotaMaxObjects, obc, parameters); v != "" {
+		maxObjects, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %q: must be an integer", v1alpha1.QuotaMaxObjects, v)
+		}
+		if maxObjects <= 0 {
+			return nil, fmt.Errorf("invalid %s %q: must be positive", v1alpha1.QuotaMaxObjects, v)
+		}
+		quota = &v1alpha1.Quota{MaxObjects: &maxObjects}
+	}
+	if v := claimConfigParameter(v1alpha1.QuotaMaxSize, obc, parameters); v != "" {
+		maxSize, err := resource.ParseQuantity(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %q: %v", v1alpha1.QuotaMaxSize, v, err)
+		}
+		if maxSize.Sign() <= 0 {
+			return nil, fmt.Errorf("invalid %s %q: must be positive", v1alpha1.QuotaMaxSize, v)
+		}
+		if quota == nil {
+			quota = &v1alpha1.Quota{}
+		}
+		quota.MaxSize = &maxSize
+	}
+	return quota, nil
+}
+
+// parseVersioning returns whether the OBC or its storage class, the OBC taking precedence, enables versioning on
+// the bucket.  Versioning is disabled if neither sets it.
+func parseVersioning(obc *v1alpha1.ObjectBucketClaim, parameters map[string]string) (bool, error) {
+	v := claimConfigParameter(v1alpha1.StorageClassVersioning, obc, parameters)
+	if v == "" {
+		return false, nil
+	}
+	versioning, err := strconv.ParseBool(v)
 	if err != nil {
-		return nil, err
+		return false, fmt.Errorf("invalid %s %q: must be true or false", v1alpha1.StorageClassVersioning, v)
+	}
+	return versioning, nil
+}
+
+// parseService returns the namespace and name of the Service the storage class's service parameter refers to,
+// both empty if it is unset
+func parseService(parameters map[string]string) (namespace, name string, err error) {
+	ref := parameters[v1alpha1.StorageClassService]
+	if ref == "" {
+		return "", "", nil
+	}
+	parts := strings.Split(ref, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("invalid %s %q: must be namespace/name", v1alpha1.StorageClassService, ref)
+	}
+	return parts[0], parts[1], nil
+}
+
+// serviceEndpoint returns the cluster DNS name of the Service, <name>.<namespace>.svc, and its port named portName,
+// or its first port if portName is empty
+func serviceEndpoint(svc *corev1.Service, portName string) (string, int, error) {
+	if len(svc.Spec.Ports) == 0 {
+		return "", 0, fmt.Errorf("service %s/%s has no ports", svc.Namespace, svc.Name)
 	}
-	cm, err := c.CoreV1().ConfigMaps(ns).Get(name, metav1.GetOptions{})
+	port := svc.Spec.Ports[0]
+	if portName != "" {
+		found := false
+		for _, p := range svc.Spec.Ports {
+			if p.Name == portName {
+				port, found = p, true
+				break
+			}
+		}
+		if !found {
+			return "", 0, fmt.Errorf("service %s/%s has no port named %q", svc.Namespace, svc.Name, portName)
+		}
+	}
+	return fmt.Sprintf("%s.%s.svc", svc.Name, svc.Namespace), int(port.Port), nil
+}
+
+// Return true if the claim requests, via annotation, to only be validated.
+func isDryRun(obc *v1alpha1.ObjectBucketClaim) bool {
+	return obc.Annotations[v1alpha1.DryRunAnnotation] == "true"
+}
+
+// isPaused returns true if the claim's reconciliation is paused, see v1alpha1.PausedAnnotation
+func isPaused(obc *v1alpha1.ObjectBucketClaim) bool {
+	return obc.Annotations[v1alpha1.PausedAnnotation] == "true"
+}
+
+// isManagedBy returns true if the claim's managed-by annotation, see v1alpha1.ManagedByAnnotation, is unset or
+// is one of names
+func isManagedBy(obc *v1alpha1.ObjectBucketClaim, names []string) bool {
+	managedBy := obc.Annotations[v1alpha1.ManagedByAnnotation]
+	if managedBy == "" {
+		return true
+	}
+	for _, name := range names {
+		if managedBy == name {
+			return true
+		}
+	}
+	return false
+}
+
+// keepsObjectBucket returns true if the claim's OB is kept when it is deleted, see v1alpha1.KeepObjectBucketAnnotation
+func keepsObjectBucket(obc *v1alpha1.ObjectBucketClaim) bool {
+	return obc.Annotations[v1alpha1.KeepObjectBucketAnnotation] == "true"
+}
+
+// Return true if the claim is bound and its rotate annotation changed since its credentials were issued.
+func rotationRequested(obc *v1alpha1.ObjectBucketClaim) bool {
+	rotation := obc.Annotations[v1alpha1.RotateAnnotation]
+	return rotation != "" && rotation != obc.Status.LastRotation &&
+		obc.Status.Phase == v1alpha1.ObjectBucketClaimStatusPhaseBound
+}
+
+// secretData returns the secret's data as strings, including the stringData not yet merged by the API server.
+func secretData(secret *corev1.Secret) map[string]string {
+	data := make(map[string]string, len(secret.Data)+len(secret.StringData))
+	for k, v := range secret.Data {
+		data[k] = string(v)
+	}
+	for k, v := range secret.StringData {
+		data[k] = v
+	}
+	return data
+}
+
+// setClaimCondition adds or replaces the claim's condition of the same type.  The transition time is only
+// updated when the condition's status changes.  Returns false if the condition was already present as given.
+func setClaimCondition(obc *v1alpha1.ObjectBucketClaim, cond v1alpha1.ObjectBucketClaimCondition) bool {
+	for i := range obc.Status.Conditions {
+		existing := &obc.Status.Conditions[i]
+		if existing.Type != cond.Type {
+			continue
+		}
+		if existing.Status == cond.Status && existing.Reason == cond.Reason && existing.Message == cond.Message {
+			return false
+		}
+		if existing.Status == cond.Status {
+			cond.LastTransitionTime = existing.LastTransitionTime
+		} else {
+			cond.LastTransitionTime = metav1.Now()
+		}
+		*existing = cond
+		return true
+	}
+	cond.LastTransitionTime = metav1.Now()
+	obc.Status.Conditions = append(obc.Status.Conditions, cond)
+	return true
+}
+
+// maxFailureMessageLength is the length in bytes past which failure messages are truncated
+const maxFailureMessageLength = 512
+
+// redacted replaces the credentials scrubbed from failure messages
+const redacted = "[REDACTED]"
+
+// credentialScrubbers replace the credentials commonly found in object store errors: credential-like
+// key/value pairs, the user info of URLs and AWS access key IDs
+var credentialScrubbers = []struct {
+	pattern     *regexp.Regexp
+	replacement string
+}{
+	{
+		pattern:     regexp.MustCompile(`(?i)((?:access[_-]?key(?:[_-]?id)?|secret(?:[_-]?access[_-]?key)?|password|passwd|token|signature|credential|authorization)["']?\s*[:=]\s*["']?(?:bearer\s+|basic\s+)?)[^\s"',&;]+`),
+		replacement: "${1}" + redacted,
+	},
+	{
+		pattern:     regexp.MustCompile(`(://)[^/\s:@]+:[^/\s@]+@`),
+		replacement: "${1}" + redacted + "@",
+	},
+	{
+		pattern:     regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`),
+		replacement: redacted,
+	},
+}
+
+// failureMessage returns err's message as recorded on the claim's status: credentials are scrubbed and
+// it is truncated to maxFailureMessageLength.
+func failureMessage(err error) string {
+	msg := err.Error()
+	for _, s := range credentialScrubbers {
+		msg = s.pattern.ReplaceAllString(msg, s.replacement)
+	}
+	if len(msg) <= maxFailureMessageLength {
+		return msg
+	}
+	const ellipsis = "..."
+	i := maxFailureMessageLength - len(ellipsis)
+	for i > 0 && !utf8.RuneStart(msg[i]) {
+		i--
+	}
+	return msg[:i] + ellipsis
+}
+
+// secretNameForClaim returns the name of the claim's Secret: spec.secretName if set, the claim's name otherwise.
+func secretNameForClaim(obc *v1alpha1.ObjectBucketClaim) string {
+	if obc.Spec.SecretName != "" {
+		return obc.Spec.SecretName
+	}
+	return obc.Name
+}
+
+// configMapNameForClaim returns the name of the claim's ConfigMap: spec.configMapName if set, the claim's
+// name otherwise.
+func configMapNameForClaim(obc *v1alpha1.ObjectBucketClaim) string {
+	if obc.Spec.ConfigMapName != "" {
+		return obc.Spec.ConfigMapName
+	}
+	return obc.Name
+}
+
+func configMapForClaim(obc *v1alpha1.ObjectBucketClaim, c kubernetes.Interface) (*corev1.ConfigMap, error) {
+	name := configMapNameForClaim(obc)
+	logD.Info("getting configMap for claim", "name", name)
+	cm, err := c.CoreV1().ConfigMaps(obc.Namespace).Get(name, metav1.GetOptions{})
 	if err != nil {
 		return nil, err
 	}
 	return cm, nil
 }
 
-func secretForClaimKey(key string, c kubernetes.Interface) (sec *corev1.Secret, err error) {
-	logD.Info("getting secret for key", "key", key)
-	ns, name, err := cache.SplitMetaNamespaceKey(key)
+func secretForClaim(obc *v1alpha1.ObjectBucketClaim, c kubernetes.Interface) (sec *corev1.Secret, err error) {
+	name := secretNameForClaim(obc)
+	logD.Info("getting secret for claim", "name", name)
+	sec, err = c.CoreV1().Secrets(obc.Namespace).Get(name, metav1.GetOptions{})
 	if err != nil {
 		return nil, err
 	}
-	sec, err = c.CoreV1().Secrets(ns).Get(name, metav1.GetOptions{})
+	return
+}
+
+// ObjectBucketsForClaim lists the OBs of the named claim, selected by their claim namespace and name labels.
+// OBs created by earlier versions of the library are only labeled once their claim is next reconciled.
+func ObjectBucketsForClaim(c versioned.Interface, namespace, name string) ([]v1alpha1.ObjectBucket, error) {
+	selector := labels.SelectorFromSet(labels.Set{
+		v1alpha1.ClaimNamespaceLabel: labelValue(namespace),
+		v1alpha1.ClaimNameLabel:      labelValue(name),
+	})
+	list, err := c.ObjectbucketV1alpha1().ObjectBuckets().List(metav1.ListOptions{LabelSelector: selector.String()})
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("error listing OBs of OBC %s/%s: %v", namespace, name, err)
 	}
-	return
+	// truncated label values may be shared by other claims
+	var obs []v1alpha1.ObjectBucket
+	for _, ob := range list.Items {
+		if ref := ob.Spec.ClaimRef; ref != nil && ref.Namespace == namespace && ref.Name == name {
+			obs = append(obs, ob)
+		}
+	}
+	return obs, nil
+}
+
+// GetChildrenForClaim returns the ConfigMap and Secret of the claim, selected by their claim-uid label, e.g. for
+// external controllers correlating them.  The label is only set by controllers with the ClaimUIDLabel option.
+// Either is nil if not found, and an error is returned if the label selects more than one.
+func GetChildrenForClaim(c kubernetes.Interface, obc *v1alpha1.ObjectBucketClaim) (cm *corev1.ConfigMap, secret *corev1.Secret, err error) {
+	selector := labels.SelectorFromSet(labels.Set{v1alpha1.ClaimUIDLabel: string(obc.UID)}).String()
+	configMaps, err := c.CoreV1().ConfigMaps(obc.Namespace).List(metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, nil, fmt.Errorf("error listing configmaps of OBC %s/%s: %v", obc.Namespace, obc.Name, err)
+	}
+	secrets, err := c.CoreV1().Secrets(obc.Namespace).List(metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, nil, fmt.Errorf("error listing secrets of OBC %s/%s: %v", obc.Namespace, obc.Name, err)
+	}
+	if len(configMaps.Items) > 1 || len(secrets.Items) > 1 {
+		return nil, nil, fmt.Errorf("OBC %s/%s has %d configmaps and %d secrets, want at most one of each",
+			obc.Namespace, obc.Name, len(configMaps.Items), len(secrets.Items))
+	}
+	if len(configMaps.Items) == 1 {
+		cm = &configMaps.Items[0]
+	}
+	if len(secrets.Items) == 1 {
+		secret = &secrets.Items[0]
+	}
+	return cm, secret, nil
 }
 
 func setObjectBucketName(ob *v1alpha1.ObjectBucket, key string) {
@@ -141,10 +781,33 @@ func objectBucketNameFromClaimKey(key string) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	return fmt.Sprintf(objectBucketNameFormat, ns, name), nil
+	return objectBucketName(ns, name), nil
+}
+
+const (
+	// maxObjectBucketNameLen is the longest name of a Kubernetes resource, a DNS-1123 subdomain
+	maxObjectBucketNameLen = validation.DNS1123SubdomainMaxLength
+	nameHashLen            = 10
+)
+
+// objectBucketName returns the name of the claim's OB, "obc-<namespace>-<name>".  A name longer than
+// maxObjectBucketNameLen is shortened and suffixed with a hash of the claim's namespace and name, so that
+// it stays unique and is the same on every reconcile.  Shorter names are unchanged, as are the names of
+// the OBs created before.
+func objectBucketName(namespace, name string) string {
+	obName := fmt.Sprintf(objectBucketNameFormat, namespace, name)
+	if len(obName) <= maxObjectBucketNameLen {
+		return obName
+	}
+	sum := sha256.Sum256([]byte(namespace + "/" + name))
+	hash := hex.EncodeToString(sum[:])[:nameHashLen]
+	obName = strings.TrimRight(obName[:maxObjectBucketNameLen-nameHashLen-1], ".-")
+	return obName + "-" + hash
 }
 
-func composeBucketName(obc *v1alpha1.ObjectBucketClaim) (string, error) {
+// composeBucketName returns the claim's bucket name, or generates one from its generateBucketName.  A
+// generated name starts with the StorageClass's bucketNamePrefix parameter if set, joined by a hyphen.
+func composeBucketName(obc *v1alpha1.ObjectBucketClaim, parameters map[string]string) (string, error) {
 	if obc.Spec.BucketName == "" && obc.Spec.GenerateBucketName == "" {
 		return "", fmt.Errorf("expected either bucketName or generateBucketName defined")
 	}
@@ -153,25 +816,67 @@ func composeBucketName(obc *v1alpha1.ObjectBucketClaim) (string, error) {
 	}
 	bucketName := obc.Spec.BucketName
 	if bucketName == "" {
-		bucketName = generateBucketName(obc.Spec.GenerateBucketName)
+		base := obc.Spec.GenerateBucketName
+		if prefix := strings.TrimRight(parameters[v1alpha1.StorageClassBucketNamePrefix], "-"); prefix != "" {
+			base = prefix + "-" + base
+		}
+		// the name is shortened from its end, so the class's prefix is kept over the claim's
+		bucketName = generateBucketName(base)
 	}
 	return bucketName, nil
 }
 
 const (
-	maxNameLen     = 63
-	uuidSuffixLen  = 36
-	maxBaseNameLen = maxNameLen - uuidSuffixLen
+	maxNameLen      = 63
+	randomSuffixLen = 5
+	maxBaseNameLen  = maxNameLen - randomSuffixLen - 1
 )
 
+// invalidBucketNameChars matches the characters not allowed in a DNS-1123 subdomain
+var invalidBucketNameChars = regexp.MustCompile(`[^a-z0-9.-]`)
+
+// generateBucketName returns the prefix followed by a hyphen and a short random suffix. The prefix is
+// lowercased, invalid characters are replaced with hyphens, and it is shortened as needed so that the
+// result is a valid DNS-1123 subdomain of no more than maxNameLen characters.
 func generateBucketName(prefix string) string {
+	prefix = invalidBucketNameChars.ReplaceAllString(strings.ToLower(prefix), "-")
 	if len(prefix) > maxBaseNameLen {
-		prefix = prefix[:maxBaseNameLen-1]
+		prefix = prefix[:maxBaseNameLen]
+	}
+	prefix = strings.Trim(prefix, ".-")
+	suffix := rand.String(randomSuffixLen)
+	if prefix == "" {
+		return suffix
 	}
-	return fmt.Sprintf("%s-%s", prefix, uuid.New())
+	return fmt.Sprintf("%s-%s", prefix, suffix)
 }
 
-func storageClassForClaim(c kubernetes.Interface, obc *v1alpha1.ObjectBucketClaim) (*storagev1.StorageClass, error) {
+const minNameLen = 3
+
+// validBucketNameChars matches names made of lowercase letters, numbers, dots and hyphens which begin
+// and end with a letter or number
+var validBucketNameChars = regexp.MustCompile(`^[a-z0-9]([a-z0-9.-]*[a-z0-9])?$`)
+
+// validateBucketName returns an error describing the first S3 bucket naming rule violated by name.
+func validateBucketName(name string) error {
+	if len(name) < minNameLen || len(name) > maxNameLen {
+		return fmt.Errorf("must be between %d and %d characters long", minNameLen, maxNameLen)
+	}
+	if !validBucketNameChars.MatchString(name) {
+		return fmt.Errorf("must consist of lowercase letters, numbers, dots and hyphens, and begin and end with a letter or number")
+	}
+	if strings.Contains(name, "..") {
+		return fmt.Errorf("must not contain adjacent periods")
+	}
+	if net.ParseIP(name) != nil {
+		return fmt.Errorf("must not be formatted as an IP address")
+	}
+	return nil
+}
+
+// storageClassForClaim gets the claim's StorageClass from the informer cache.  The returned class is a copy
+// which the caller may modify.
+func storageClassForClaim(l storagelisters.StorageClassLister, obc *v1alpha1.ObjectBucketClaim) (*storagev1.StorageClass, error) {
 	if obc == nil {
 		return nil, fmt.Errorf("got nil ObjectBucketClaim pointer")
 	}
@@ -179,15 +884,21 @@ func storageClassForClaim(c kubernetes.Interface, obc *v1alpha1.ObjectBucketClai
 		return nil, fmt.Errorf("no StorageClass defined for ObjectBucketClaim \"%s/%s\"", obc.Namespace, obc.Name)
 	}
 	logD.Info("getting ObjectBucketClaim's StorageClass")
-	class, err := c.StorageV1().StorageClasses().Get(obc.Spec.StorageClassName, metav1.GetOptions{})
+	class, err := l.Get(obc.Spec.StorageClassName)
 	if err != nil {
+		// NotFound is returned as is so that a deleted StorageClass can be told apart
+		if errors.IsNotFound(err) {
+			return nil, err
+		}
 		return nil, fmt.Errorf("error getting StorageClass %q: %v", obc.Spec.StorageClassName, err)
 	}
 	log.Info("got StorageClass", "name", class.Name)
-	return class, nil
+	return class.DeepCopy(), nil
 }
 
-func storageClassForObjectBucket(ob *v1alpha1.ObjectBucket, c kubernetes.Interface) (*storagev1.StorageClass, error) {
+// storageClassForObjectBucket gets the OB's StorageClass from the informer cache.  The returned class is a copy
+// which the caller may modify.
+func storageClassForObjectBucket(l storagelisters.StorageClassLister, ob *v1alpha1.ObjectBucket) (*storagev1.StorageClass, error) {
 	if ob == nil {
 		return nil, fmt.Errorf("got nil ObjectBucket pointer")
 	}
@@ -195,26 +906,201 @@ func storageClassForObjectBucket(ob *v1alpha1.ObjectBucket, c kubernetes.Interfa
 		return nil, fmt.Errorf("no StorageClass defined for ObjectBucket %q", ob.Name)
 	}
 	logD.Info("getting ObjectBucket's storage class", "name", ob.Spec.StorageClassName)
-	class, err := c.StorageV1().StorageClasses().Get(ob.Spec.StorageClassName, metav1.GetOptions{})
+	class, err := l.Get(ob.Spec.StorageClassName)
 	if err != nil {
+		// NotFound is returned as is so that a deleted StorageClass can be told apart
+		if errors.IsNotFound(err) {
+			return nil, err
+		}
 		return nil, fmt.Errorf("error getting StorageClass %q: %v", ob.Spec.StorageClassName, err)
 	}
-	log.Info("got StorageClass", "name")
+	log.Info("got StorageClass", "name", class.Name)
+	return class.DeepCopy(), nil
+}
 
-	return class, nil
+// isLibraryFinalizer returns true for the library's finalizer and the legacy ones, see ControllerOptions.LegacyFinalizers
+func isLibraryFinalizer(f string, legacyFinalizers []string) bool {
+	if f == finalizer {
+		return true
+	}
+	for _, legacy := range legacyFinalizers {
+		if f == legacy {
+			return true
+		}
+	}
+	return false
 }
 
-func removeFinalizer(obj metav1.Object) {
-	finalizers := obj.GetFinalizers()
-	for i, f := range finalizers {
+// removeFinalizer removes the library's finalizer and the legacy ones from obj.  Returns false, leaving obj as is,
+// if it has none of them, so that callers can skip a needless update.
+func removeFinalizer(obj metav1.Object, legacyFinalizers []string) bool {
+	var kept []string
+	removed := false
+	for _, f := range obj.GetFinalizers() {
+		if isLibraryFinalizer(f, legacyFinalizers) {
+			removed = true
+			continue
+		}
+		kept = append(kept, f)
+	}
+	if removed {
+		obj.SetFinalizers(kept)
+	}
+	return removed
+}
+
+// hasLibraryFinalizer returns true if obj has the library's finalizer or a legacy one, see removeFinalizer
+func hasLibraryFinalizer(obj metav1.Object, legacyFinalizers []string) bool {
+	for _, f := range obj.GetFinalizers() {
+		if isLibraryFinalizer(f, legacyFinalizers) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasFinalizer(obj metav1.Object) bool {
+	for _, f := range obj.GetFinalizers() {
 		if f == finalizer {
-			obj.SetFinalizers(append(finalizers[:i], finalizers[i+1:]...))
-			break
+			return true
+		}
+	}
+	return false
+}
+
+// Return true if one of the object's owner references, or for a secret copy its claim annotations, refers to
+// the given claim.  Names are reused when a claim is re-created, so the reference is matched by UID.
+func isOwnedByClaim(obj metav1.Object, obc *v1alpha1.ObjectBucketClaim) bool {
+	if uid, ok := obj.GetAnnotations()[claimUIDAnnotation]; ok {
+		return uid == string(obc.UID) && obj.GetAnnotations()[claimAnnotation] == obc.Namespace+"/"+obc.Name
+	}
+	for _, ref := range obj.GetOwnerReferences() {
+		if ref.UID == obc.UID {
+			return true
 		}
 	}
+	return false
+}
+
+// convergeMeta sets the labels, annotations, finalizers and ownerReferences of desired on obj.  Those of obj
+// which desired does not set are kept.
+func convergeMeta(obj, desired metav1.Object) {
+	obj.SetLabels(mergeStringMaps(obj.GetLabels(), desired.GetLabels()))
+	obj.SetAnnotations(mergeStringMaps(obj.GetAnnotations(), desired.GetAnnotations()))
+next:
+	for _, want := range desired.GetFinalizers() {
+		for _, f := range obj.GetFinalizers() {
+			if f == want {
+				continue next
+			}
+		}
+		obj.SetFinalizers(append(obj.GetFinalizers(), want))
+	}
+	refs := obj.GetOwnerReferences()
+	for _, want := range desired.GetOwnerReferences() {
+		replaced := false
+		for i := range refs {
+			if refs[i].UID == want.UID {
+				refs[i], replaced = want, true
+			}
+		}
+		if !replaced {
+			refs = append(refs, want)
+		}
+	}
+	obj.SetOwnerReferences(refs)
+}
+
+// mergeStringMaps returns the keys of base overridden by those of override, or nil if both are empty
+func mergeStringMaps(base, override map[string]string) map[string]string {
+	if len(base) == 0 && len(override) == 0 {
+		return nil
+	}
+	merged := make(map[string]string, len(base)+len(override))
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range override {
+		merged[k] = v
+	}
+	return merged
+}
+
+// claimFor returns the namespace and name of the claim owning obj, which is a secret copy in another
+// namespace if it has a claim annotation.
+func claimFor(obj metav1.Object) (namespace, name string) {
+	if key, ok := obj.GetAnnotations()[claimAnnotation]; ok {
+		if ns, n, err := cache.SplitMetaNamespaceKey(key); err == nil {
+			return ns, n
+		}
+	}
+	return obj.GetNamespace(), claimNameFor(obj)
+}
+
+// claimNameFor returns the name of the claim owning obj.  Objects without a claim owner reference are
+// assumed to be named after their claim.
+func claimNameFor(obj metav1.Object) string {
+	for _, ref := range obj.GetOwnerReferences() {
+		if ref.Kind == v1alpha1.ObjectBucketClaimKind {
+			return ref.Name
+		}
+	}
+	return obj.GetName()
+}
+
+// childLabels returns the labels to be applied to a resource generated for the claim: the OBC's
+// labels merged with the provisioner labels. Provisioner labels take precedence so that the
+// library's reserved labels cannot be overwritten. If the OBC has no labels, the provisioner
+// labels are returned as is.
+func childLabels(obc *v1alpha1.ObjectBucketClaim, provisionerLabels map[string]string) map[string]string {
+	if obc.Labels == nil {
+		return provisionerLabels
+	}
+	labels := make(map[string]string, len(obc.Labels)+len(provisionerLabels))
+	for k, v := range obc.Labels {
+		labels[k] = v
+	}
+	for k, v := range provisionerLabels {
+		labels[k] = v
+	}
+	return labels
+}
+
+// childAnnotations returns the OBC's annotations whose keys begin with one of the given prefixes.
+// The kubectl last-applied-configuration annotation is always skipped. Returns nil if no
+// annotation matches.
+func childAnnotations(obc *v1alpha1.ObjectBucketClaim, prefixes []string) map[string]string {
+	var annotations map[string]string
+	for k, v := range obc.Annotations {
+		if k == lastAppliedAnnotation {
+			continue
+		}
+		for _, p := range prefixes {
+			if strings.HasPrefix(k, p) {
+				if annotations == nil {
+					annotations = make(map[string]string)
+				}
+				annotations[k] = v
+				break
+			}
+		}
+	}
+	return annotations
+}
+
+// maxFieldManagerLength is the maximum length of a field manager accepted by the API server
+const maxFieldManagerLength = 128
+
+// fieldManagerFor returns the field manager of the Secrets and ConfigMaps of the named provisioner's claims
+func fieldManagerFor(provisionerName string) string {
+	if len(provisionerName) > maxFieldManagerLength {
+		return provisionerName[:maxFieldManagerLength]
+	}
+	return provisionerName
 }
 
-// replace illegal label value characters with "-".
+// replace illegal label value characters with "-".  A label value must begin and end with an alphanumeric
+// character, so those left at either end, e.g. by the truncation, are trimmed.
 // Note: the only substitution is replacing "/" with "-". This needs improvement.
 func labelValue(v string) string {
 	if errs := validation.IsValidLabelValue(v); len(errs) == 0 {
@@ -223,5 +1109,5 @@ func labelValue(v string) string {
 	if len(v) > validation.LabelValueMaxLength {
 		v = v[0:validation.LabelValueMaxLength]
 	}
-	return strings.Replace(v, "/", "-", -1)
+	return strings.Trim(strings.Replace(v, "/", "-", -1), "-_.")
 }
@@ -0,0 +1,137 @@
+/*
+Copyright 2019 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+const (
+	defaultLeaseDuration = time.Second * 15
+	defaultRenewDeadline = time.Second * 10
+	defaultRetryPeriod   = time.Second * 2
+)
+
+// LeaderElectionOptions elect a leader among the replicas of a provisioner, so that only one of them
+// reconciles claims.  The other replicas keep their caches warm and take over once the leader's lease
+// expires.  Zero durations are replaced by the library defaults.
+type LeaderElectionOptions struct {
+	// LeaseName is the name of the coordination.k8s.io Lease held by the leader.  Required.
+	LeaseName string
+	// LeaseNamespace is the namespace of the Lease.  Required.
+	LeaseNamespace string
+	// Identity identifies the replica in the Lease.  Defaults to the host name with a random suffix.
+	Identity string
+	// LeaseDuration is how long replicas wait for a lease to expire before trying to acquire it
+	LeaseDuration time.Duration
+	// RenewDeadline is how long the leader retries renewing its lease before giving it up
+	RenewDeadline time.Duration
+	// RetryPeriod is the wait between attempts to acquire or renew the lease
+	RetryPeriod time.Duration
+}
+
+// newLeaderElectionConfig returns the leader election configuration for the options, running the given
+// callbacks.
+func newLeaderElectionConfig(clientset kubernetes.Interface, o *LeaderElectionOptions, callbacks leaderelection.LeaderCallbacks) (leaderelection.LeaderElectionConfig, error) {
+	if o.LeaseName == "" || o.LeaseNamespace == "" {
+		return leaderelection.LeaderElectionConfig{}, fmt.Errorf("leader election requires a lease name and namespace")
+	}
+	identity := o.Identity
+	if identity == "" {
+		host, err := os.Hostname()
+		if err != nil {
+			return leaderelection.LeaderElectionConfig{}, fmt.Errorf("error getting leader election identity: %v", err)
+		}
+		identity = host + "_" + rand.String(5)
+	}
+	config := leaderelection.LeaderElectionConfig{
+		Lock: &resourcelock.LeaseLock{
+			LeaseMeta: metav1.ObjectMeta{
+				Name:      o.LeaseName,
+				Namespace: o.LeaseNamespace,
+			},
+			Client:     clientset.CoordinationV1(),
+			LockConfig: resourcelock.ResourceLockConfig{Identity: identity},
+		},
+		LeaseDuration: o.LeaseDuration,
+		RenewDeadline: o.RenewDeadline,
+		RetryPeriod:   o.RetryPeriod,
+		Callbacks:     callbacks,
+		Name:          o.LeaseNamespace + "/" + o.LeaseName,
+	}
+	if config.LeaseDuration == 0 {
+		config.LeaseDuration = defaultLeaseDuration
+	}
+	if config.RenewDeadline == 0 {
+		config.RenewDeadline = defaultRenewDeadline
+	}
+	if config.RetryPeriod == 0 {
+		config.RetryPeriod = defaultRetryPeriod
+	}
+	return config, nil
+}
+
+// runLeaderElected runs the claim controller while this replica holds the lease.  A leader losing its
+// lease stops reconciling and returns an error, so that its process restarts as a candidate rather than
+// racing the new leader.
+func (p *Provisioner) runLeaderElected(stopCh <-chan struct{}) error {
+	config, err := newLeaderElectionConfig(p.clientset, p.leaderElection, leaderelection.LeaderCallbacks{
+		OnStartedLeading: func(ctx context.Context) {
+			log.Info("started leading", "lease", p.leaderElection.LeaseNamespace+"/"+p.leaderElection.LeaseName)
+			if err := p.claimController.Start(ctx.Done()); err != nil {
+				log.Error(err, "error running claim controller")
+			}
+		},
+		OnStoppedLeading: func() {
+			log.Info("stopped leading", "lease", p.leaderElection.LeaseNamespace+"/"+p.leaderElection.LeaseName)
+		},
+	})
+	if err != nil {
+		return err
+	}
+	elector, err := leaderelection.NewLeaderElector(config)
+	if err != nil {
+		return fmt.Errorf("invalid leader election options: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-stopCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	elector.Run(ctx)
+
+	select {
+	case <-stopCh:
+		return nil
+	default:
+		return fmt.Errorf("lost leader election lease %s", config.Name)
+	}
+}
@@ -0,0 +1,137 @@
+/*
+Copyright 2019 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/apis/objectbucket.io/v1alpha1"
+	externalFake "github.com/kube-object-storage/lib-bucket-provisioner/pkg/client/clientset/versioned/fake"
+	informers "github.com/kube-object-storage/lib-bucket-provisioner/pkg/client/informers/externalversions"
+)
+
+func TestRunLeaderElection(t *testing.T) {
+	const (
+		leaseName      = "test-lease"
+		leaseNamespace = "test-system"
+	)
+
+	tests := []struct {
+		name      string
+		heldBy    string
+		wantPhase v1alpha1.ObjectBucketClaimStatusPhase
+		wantCalls []string
+	}{
+		{
+			name:      "leader provisions",
+			wantPhase: v1alpha1.ObjectBucketClaimStatusPhaseBound,
+			wantCalls: []string{"Provision"},
+		},
+		{
+			name:   "non-leader does not provision",
+			heldBy: "other-replica",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientset := fake.NewSimpleClientset()
+			extClient := externalFake.NewSimpleClientset()
+			factory := informers.NewSharedInformerFactory(extClient, 0)
+			fp := &fakeProvisioner{}
+			c := NewController(
+				provisionerName,
+				fp,
+				clientset,
+				extClient,
+				factory.Objectbucket().V1alpha1().ObjectBucketClaims(),
+				factory.Objectbucket().V1alpha1().ObjectBuckets(),
+				&ControllerOptions{
+					RetryBaseInterval: time.Millisecond,
+					RetryTimeout:      time.Millisecond * 10,
+				})
+			c.recorder = record.NewFakeRecorder(10)
+			p := &Provisioner{
+				Name:            provisionerName,
+				claimController: c,
+				informerFactory: factory,
+				clientset:       clientset,
+				leaderElection: &LeaderElectionOptions{
+					LeaseName:      leaseName,
+					LeaseNamespace: leaseNamespace,
+					Identity:       "test-replica",
+					RetryPeriod:    time.Millisecond * 100,
+				},
+			}
+
+			if tt.heldBy != "" {
+				now := metav1.NewMicroTime(time.Now())
+				duration := int32(60)
+				if _, err := clientset.CoordinationV1().Leases(leaseNamespace).Create(&coordinationv1.Lease{
+					ObjectMeta: metav1.ObjectMeta{Name: leaseName, Namespace: leaseNamespace},
+					Spec: coordinationv1.LeaseSpec{
+						HolderIdentity:       &tt.heldBy,
+						LeaseDurationSeconds: &duration,
+						AcquireTime:          &now,
+						RenewTime:            &now,
+					},
+				}); err != nil {
+					t.Fatalf("error pre-creating Lease: %v", err)
+				}
+			}
+			createTestClaim(t, c, &storagev1.StorageClass{
+				ObjectMeta:  metav1.ObjectMeta{Name: className},
+				Provisioner: provisionerName,
+			})
+
+			stopCh := make(chan struct{})
+			done := make(chan error)
+			go func() {
+				done <- p.Run(stopCh)
+			}()
+			var phase v1alpha1.ObjectBucketClaimStatusPhase
+			_ = wait.Poll(time.Millisecond*50, time.Second, func() (bool, error) {
+				obc, err := extClient.ObjectbucketV1alpha1().ObjectBucketClaims(testNamespace).Get(testName, metav1.GetOptions{})
+				if err != nil {
+					return false, err
+				}
+				phase = obc.Status.Phase
+				return phase == v1alpha1.ObjectBucketClaimStatusPhaseBound, nil
+			})
+			close(stopCh)
+			if err := <-done; err != nil {
+				t.Errorf("Run() error = %v", err)
+			}
+
+			if phase != tt.wantPhase {
+				t.Errorf("want OBC phase %q, got %q", tt.wantPhase, phase)
+			}
+			if diff := cmp.Diff(tt.wantCalls, fp.calls); diff != "" {
+				t.Errorf("provisioner calls (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -17,31 +17,139 @@ limitations under the License.
 package provisioner
 
 import (
+	"context"
+	"sync"
+
 	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/klog/klogr"
 
 	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/provisioner/api"
 )
 
 var (
-	// log is a short lived variable that is overwritten each Reconcile() iteration with the current Request injected
-	// into it.  This is for convenience of identifying which log lines were generated by which request without having
-	// to pass the request to every method call.
-	log logr.Logger
-
-	// logD is a short lived variable that is overwritten each Reconcile() iteraion with the current Request injected
-	// into it.  This is for convenience of identifying which log lines were generated by which request without having
-	// to pass the request to every log method call.
-	logD logr.InfoLogger
+	// log is the logger of the lines not tied to a request, e.g. of the informers' event handlers.  The functions
+	// reconciling a request shadow it with requestLog, so that their lines identify the request.
+	log logr.Logger = baseLog{}
+
+	// logD is the debug logger of the lines not tied to a request, shadowed by requestLogD like log
+	logD logr.InfoLogger = baseLogD{}
+
+	// baseMu guards baseLogger and debugVerbosity, which a new controller may set while others run
+	baseMu sync.RWMutex
+
+	// baseLogger is the logger log and logD write to, see ControllerOptions.Logger
+	baseLogger logr.Logger
+
+	// debugVerbosity is the verbosity logD logs at, see ControllerOptions.DebugVerbosity
+	debugVerbosity = defaultDebugVerbosity
 )
 
+// requestLoggerKey is the context key of the logger of the request being reconciled, see withRequestLogger
+type requestLoggerKey struct{}
+
 func init() {
-	log = klogr.New().WithName(api.Domain + "/claim-reconciler")
-	logD = log.V(1)
+	setBaseLogger(klogr.New().WithName(api.Domain+"/claim-reconciler"), defaultDebugVerbosity)
+}
+
+// initLoggers sets the logger and debug verbosity of the options, or the klog defaults if unset
+func initLoggers(options *ControllerOptions) {
+	opts := options.withDefaults()
+	logger := opts.Logger
+	if logger == nil {
+		logger = klogr.New().WithName(api.Domain + "/provisioner-manager")
+	}
+	setBaseLogger(logger, opts.DebugVerbosity)
+}
+
+// setBaseLogger replaces the logger all log lines are written to
+func setBaseLogger(logger logr.Logger, verbosity int) {
+	baseMu.Lock()
+	defer baseMu.Unlock()
+	baseLogger = logger
+	debugVerbosity = verbosity
 }
 
-// setLoggerWith request overwrites log and logD with a new logger.  The passed in request is injected into the loggers.
-func setLoggersWithRequest(key string) {
-	log = klogr.New().WithValues("key", key)
-	logD = klogr.New().WithValues("key", key)
-}
\ No newline at end of file
+// currentBaseLogger returns the logger all log lines are written to and the verbosity of the debug lines
+func currentBaseLogger() (logr.Logger, int) {
+	baseMu.RLock()
+	defer baseMu.RUnlock()
+	return baseLogger, debugVerbosity
+}
+
+// withRequestLogger returns ctx carrying a logger with the request's key injected.  This is for convenience of
+// identifying which log lines were generated by which request without having to pass the request to every method
+// call.  Each request gets its own logger as several requests may be reconciled concurrently.
+func withRequestLogger(ctx context.Context, key string) context.Context {
+	logger, _ := currentBaseLogger()
+	return context.WithValue(ctx, requestLoggerKey{}, logger.WithValues("key", key))
+}
+
+// requestLog returns the logger of the request ctx was derived from, or log outside of a request
+func requestLog(ctx context.Context) logr.Logger {
+	if l, ok := ctx.Value(requestLoggerKey{}).(logr.Logger); ok {
+		return l
+	}
+	return log
+}
+
+// requestLogD returns the debug logger of the request ctx was derived from, or logD outside of a request
+func requestLogD(ctx context.Context) logr.InfoLogger {
+	_, verbosity := currentBaseLogger()
+	return requestLog(ctx).V(verbosity)
+}
+
+// baseLog is the logr.Logger of log, writing to the current base logger
+type baseLog struct{}
+
+func (baseLog) Info(msg string, keysAndValues ...interface{}) {
+	logger, _ := currentBaseLogger()
+	logger.Info(msg, keysAndValues...)
+}
+
+func (baseLog) Enabled() bool {
+	logger, _ := currentBaseLogger()
+	return logger.Enabled()
+}
+
+func (baseLog) Error(err error, msg string, keysAndValues ...interface{}) {
+	logger, _ := currentBaseLogger()
+	logger.Error(err, msg, keysAndValues...)
+}
+
+func (baseLog) V(level int) logr.InfoLogger {
+	logger, _ := currentBaseLogger()
+	return logger.V(level)
+}
+
+func (baseLog) WithValues(keysAndValues ...interface{}) logr.Logger {
+	logger, _ := currentBaseLogger()
+	return logger.WithValues(keysAndValues...)
+}
+
+func (baseLog) WithName(name string) logr.Logger {
+	logger, _ := currentBaseLogger()
+	return logger.WithName(name)
+}
+
+// baseLogD is the logr.InfoLogger of logD, writing to the current base logger at the debug verbosity
+type baseLogD struct{}
+
+func (baseLogD) Info(msg string, keysAndValues ...interface{}) {
+	logger, verbosity := currentBaseLogger()
+	logger.V(verbosity).Info(msg, keysAndValues...)
+}
+
+func (baseLogD) Enabled() bool {
+	logger, verbosity := currentBaseLogger()
+	return logger.V(verbosity).Enabled()
+}
+
+// logSafeSecretRef returns the namespace/name of the secret, the only part of a secret which may be logged.
+// Secrets, or any part of them, must never be passed to a logger directly.
+func logSafeSecretRef(sec *corev1.Secret) string {
+	if sec == nil {
+		return "<nil>"
+	}
+	return sec.Namespace + "/" + sec.Name
+}
@@ -0,0 +1,222 @@
+/*
+Copyright 2019 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/apis/objectbucket.io/v1alpha1"
+)
+
+// recordingLogger records the lines logged at or below its maximum verbosity
+type recordingLogger struct {
+	lines      *[]string
+	level      int
+	maxLevel   int
+	keysValues []interface{}
+}
+
+var _ logr.Logger = &recordingLogger{}
+
+func newRecordingLogger(maxLevel int) *recordingLogger {
+	return &recordingLogger{lines: &[]string{}, maxLevel: maxLevel}
+}
+
+func (l *recordingLogger) record(msg string, keysAndValues []interface{}) {
+	kv := append(append([]interface{}{}, l.keysValues...), keysAndValues...)
+	*l.lines = append(*l.lines, fmt.Sprintf("%d %s %v", l.level, msg, kv))
+}
+
+func (l *recordingLogger) Info(msg string, keysAndValues ...interface{}) {
+	if l.Enabled() {
+		l.record(msg, keysAndValues)
+	}
+}
+
+func (l *recordingLogger) Enabled() bool {
+	return l.level <= l.maxLevel
+}
+
+func (l *recordingLogger) Error(err error, msg string, keysAndValues ...interface{}) {
+	l.record(msg+": "+err.Error(), keysAndValues)
+}
+
+func (l *recordingLogger) V(level int) logr.InfoLogger {
+	v := *l
+	v.level = level
+	return &v
+}
+
+func (l *recordingLogger) WithValues(keysAndValues ...interface{}) logr.Logger {
+	v := *l
+	v.keysValues = append(append([]interface{}{}, l.keysValues...), keysAndValues...)
+	return &v
+}
+
+func (l *recordingLogger) WithName(name string) logr.Logger {
+	return l
+}
+
+func TestDebugVerbosity(t *testing.T) {
+	tests := []struct {
+		name      string
+		verbosity int
+		maxLevel  int
+		wantLines int
+	}{
+		{
+			name:      "debug lines are logged at the default verbosity",
+			verbosity: 0,
+			maxLevel:  1,
+			wantLines: 2,
+		},
+		{
+			name:      "debug lines above the logger's verbosity are silenced",
+			verbosity: 4,
+			maxLevel:  3,
+			wantLines: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := newRecordingLogger(tt.maxLevel)
+			initLoggers(&ControllerOptions{Logger: logger, DebugVerbosity: tt.verbosity})
+			defer initLoggers(nil)
+
+			ctx := withRequestLogger(context.Background(), testNamespace+"/"+testName)
+			requestLog(ctx).Info("info")
+			requestLogD(ctx).Info("debug")
+
+			if len(*logger.lines) != tt.wantLines {
+				t.Errorf("want %d lines logged, got %v", tt.wantLines, *logger.lines)
+			}
+		})
+	}
+}
+
+func TestRequestLogger(t *testing.T) {
+	logger := newRecordingLogger(1)
+	initLoggers(&ControllerOptions{Logger: logger})
+	defer initLoggers(nil)
+
+	first := withRequestLogger(context.Background(), testNamespace+"/first")
+	second := withRequestLogger(context.Background(), testNamespace+"/second")
+	requestLog(first).Info("first request")
+	requestLogD(second).Info("second request")
+	requestLog(context.Background()).Info("no request")
+
+	want := []string{
+		"0 first request [key " + testNamespace + "/first]",
+		"1 second request [key " + testNamespace + "/second]",
+		"0 no request []",
+	}
+	if !cmp.Equal(want, *logger.lines) {
+		t.Errorf(cmp.Diff(want, *logger.lines))
+	}
+}
+
+func TestCreateSecretDoesNotLogSecretData(t *testing.T) {
+	const (
+		accessKeyID     = "test-access-key-id"
+		secretAccessKey = "test-secret-access-key"
+	)
+	logger := newRecordingLogger(10)
+	initLoggers(&ControllerOptions{Logger: logger})
+	defer initLoggers(nil)
+	ctx := withRequestLogger(context.Background(), testNamespace+"/"+testName)
+
+	// fail the first create so that the retry is logged too
+	client := fake.NewSimpleClientset()
+	failed := false
+	client.PrependReactor("create", "secrets", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if failed {
+			return false, nil, nil
+		}
+		failed = true
+		return true, nil, fmt.Errorf("injected error")
+	})
+	obc := &v1alpha1.ObjectBucketClaim{ObjectMeta: metav1.ObjectMeta{Name: testName, Namespace: testNamespace}}
+	auth := &v1alpha1.Authentication{AccessKeys: &v1alpha1.AccessKeys{AccessKeyID: accessKeyID, SecretAccessKey: secretAccessKey}}
+	b := retryBackoff{
+		interval: time.Millisecond,
+		timeout:  time.Second,
+	}
+
+	if _, err := createSecret(ctx, obc, auth, nil, nil, defaultChildOptions, client, newFakeChildApplier(client), b); err != nil {
+		t.Fatalf("createSecret() error = %v", err)
+	}
+	if len(*logger.lines) == 0 {
+		t.Fatal("want createSecret to log")
+	}
+	ref := testNamespace + "/" + testName
+	refLogged := false
+	for _, line := range *logger.lines {
+		if strings.Contains(line, accessKeyID) || strings.Contains(line, secretAccessKey) {
+			t.Errorf("want no secret data logged, got %q", line)
+		}
+		refLogged = refLogged || strings.Contains(line, ref)
+	}
+	if !refLogged {
+		t.Errorf("want secret %q referenced by the log, got %v", ref, *logger.lines)
+	}
+}
+
+func TestLogSafeSecretRef(t *testing.T) {
+	tests := []struct {
+		name   string
+		secret *corev1.Secret
+		want   string
+	}{
+		{
+			name:   "nil secret",
+			secret: nil,
+			want:   "<nil>",
+		},
+		{
+			name: "only the namespace and name are kept",
+			secret: &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        testName,
+					Namespace:   testNamespace,
+					Annotations: map[string]string{"note": "annotation-value"},
+				},
+				Data:       map[string][]byte{v1alpha1.AwsKeyField: []byte("data-value")},
+				StringData: map[string]string{v1alpha1.AwsSecretField: "string-data-value"},
+			},
+			want: testNamespace + "/" + testName,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := logSafeSecretRef(tt.secret); got != tt.want {
+				t.Errorf("logSafeSecretRef() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -18,12 +18,15 @@ package provisioner
 
 import (
 	"flag"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
 	"time"
 	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/klog"
-	"k8s.io/klog/klogr"
 
 	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/client/clientset/versioned"
 	informers "github.com/kube-object-storage/lib-bucket-provisioner/pkg/client/informers/externalversions"
@@ -36,11 +39,9 @@ type Provisioner struct {
 	Provisioner     api.Provisioner
 	claimController controller
 	informerFactory informers.SharedInformerFactory
-}
-
-func initLoggers() {
-	log = klogr.New().WithName(api.Domain + "/provisioner-manager")
-	logD = log.V(1)
+	clientset       kubernetes.Interface
+	// leaderElection is nil if every replica reconciles
+	leaderElection *LeaderElectionOptions
 }
 
 func initFlags() {
@@ -70,18 +71,31 @@ func NewProvisioner(
 	provisioner api.Provisioner,
 	namespace string,
 ) (*Provisioner, error) {
+	return NewProvisionerWithOptions(cfg, provisionerName, provisioner, namespace, nil)
+}
+
+// NewProvisionerWithOptions behaves like NewProvisioner but allows the caller to tune the
+// controller via options. A nil options applies the library defaults.
+func NewProvisionerWithOptions(
+	cfg *rest.Config,
+	provisionerName string,
+	provisioner api.Provisioner,
+	namespace string,
+	options *ControllerOptions,
+) (*Provisioner, error) {
 
 	initFlags()
-	initLoggers()
 
 	libClientset := versioned.NewForConfigOrDie(cfg)
 	clientset := kubernetes.NewForConfigOrDie(cfg)
 
-	informerFactory := setupInformerFactory(libClientset, 0, namespace)
+	informerFactory := setupInformerFactory(libClientset, resyncPeriod(options), namespace)
 
 	p := &Provisioner{
 		Name:            provisionerName,
 		informerFactory: informerFactory,
+		clientset:       clientset,
+		leaderElection:  leaderElectionOptions(options),
 
 		claimController: NewController(
 			provisionerName,
@@ -89,7 +103,52 @@ func NewProvisioner(
 			clientset,
 			libClientset,
 			informerFactory.Objectbucket().V1alpha1().ObjectBucketClaims(),
-			informerFactory.Objectbucket().V1alpha1().ObjectBuckets()),
+			informerFactory.Objectbucket().V1alpha1().ObjectBuckets(),
+			options),
+	}
+
+	return p, nil
+}
+
+// NewMultiProvisioner behaves like NewProvisionerWithOptions but serves several provisioners, keyed by
+// the StorageClass provisioner name they serve.  Each OBC is handled by the provisioner named by its
+// StorageClass.
+func NewMultiProvisioner(
+	cfg *rest.Config,
+	provisioners map[string]api.Provisioner,
+	namespace string,
+	options *ControllerOptions,
+) (*Provisioner, error) {
+
+	initFlags()
+
+	if len(provisioners) == 0 {
+		return nil, fmt.Errorf("no provisioner registered")
+	}
+	names := make([]string, 0, len(provisioners))
+	for name := range provisioners {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	libClientset := versioned.NewForConfigOrDie(cfg)
+	clientset := kubernetes.NewForConfigOrDie(cfg)
+
+	informerFactory := setupInformerFactory(libClientset, resyncPeriod(options), namespace)
+
+	p := &Provisioner{
+		Name:            strings.Join(names, ","),
+		informerFactory: informerFactory,
+		clientset:       clientset,
+		leaderElection:  leaderElectionOptions(options),
+
+		claimController: NewMultiController(
+			provisioners,
+			clientset,
+			libClientset,
+			informerFactory.Objectbucket().V1alpha1().ObjectBucketClaims(),
+			informerFactory.Objectbucket().V1alpha1().ObjectBuckets(),
+			options),
 	}
 
 	return p, nil
@@ -112,13 +171,19 @@ func (p *Provisioner) SetLabels(labels map[string]string) []string {
 	return nil
 }
 
-// Run starts the claim and bucket controllers.
+// Run starts the claim and bucket controllers.  With leader election, the claim controller only runs
+// while this replica is the leader, and an error is returned if the leader loses its lease.
 func (p *Provisioner) Run(stopCh <-chan struct{}) (err error) {
 	defer klog.Flush()
 	log.Info("starting provisioner", "name", p.Name)
 
+	// the informers of all replicas run so that a new leader starts with warm caches
 	p.informerFactory.Start(stopCh)
+	go p.claimController.RunHealthChecks(stopCh)
 
+	if p.leaderElection != nil {
+		return p.runLeaderElected(stopCh)
+	}
 	go func() {
 		err = p.claimController.Start(stopCh)
 	}()
@@ -126,6 +191,32 @@ func (p *Provisioner) Run(stopCh <-chan struct{}) (err error) {
 	return
 }
 
+// Healthy returns an error if the last health check of a provisioner implementing api.HealthChecker failed,
+// e.g. for a readiness probe.  It always returns nil if no provisioner implements it.
+func (p *Provisioner) Healthy() error {
+	return p.claimController.Healthy()
+}
+
+// ReadinessHandler returns an http.Handler for a readiness endpoint, e.g. /readyz, reporting Healthy: it serves
+// 200 if healthy and 503 with the failed checks otherwise.
+func (p *Provisioner) ReadinessHandler() http.Handler {
+	return readinessHandler(p.Healthy)
+}
+
+func leaderElectionOptions(options *ControllerOptions) *LeaderElectionOptions {
+	if options == nil {
+		return nil
+	}
+	return options.LeaderElection
+}
+
+func resyncPeriod(options *ControllerOptions) time.Duration {
+	if options == nil {
+		return 0
+	}
+	return options.ResyncPeriod
+}
+
 // setupInformerFactory generates an informer factory scoped to the given namespace if provided or
 // to the cluster if empty.
 func setupInformerFactory(c versioned.Interface, resyncPeriod time.Duration, ns string) (inf informers.SharedInformerFactory) {
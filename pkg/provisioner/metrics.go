@@ -0,0 +1,182 @@
+/*
+Copyright 2019 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/apis/objectbucket.io/v1alpha1"
+	listers "github.com/kube-object-storage/lib-bucket-provisioner/pkg/client/listers/objectbucket.io/v1alpha1"
+	pErr "github.com/kube-object-storage/lib-bucket-provisioner/pkg/provisioner/api/errors"
+)
+
+const metricsNamespace = "objectbucket"
+
+// Values of the result label of the provision and delete counters
+const (
+	resultSuccess       = "success"
+	resultError         = "error"
+	resultAlreadyExists = "alreadyexists"
+)
+
+// phaseNone is the from_phase label of the transitions of new claims, which have no phase yet
+const phaseNone = "None"
+
+// claimPhases are the phases reported by the claims gauge, so that a phase without claims reads 0
+var claimPhases = []v1alpha1.ObjectBucketClaimStatusPhase{
+	v1alpha1.ObjectBucketClaimStatusPhasePending,
+	v1alpha1.ObjectBucketClaimStatusPhaseProvisioning,
+	v1alpha1.ObjectBucketClaimStatusPhaseBound,
+	v1alpha1.ObjectBucketClaimStatusPhaseReleased,
+	v1alpha1.ObjectBucketClaimStatusPhaseFailed,
+}
+
+// metrics instruments the calls made by a controller to its provisioner.  Provision and Grant are
+// reported as provisions, Delete and Revoke as deletes.
+type metrics struct {
+	provisionDuration prometheus.Histogram
+	provisionTotal    *prometheus.CounterVec
+	deleteDuration    prometheus.Histogram
+	deleteTotal       *prometheus.CounterVec
+	claims            *claimPhaseCollector
+	phaseTransitions  *prometheus.CounterVec
+}
+
+func newMetrics(provisionerName string, obcLister listers.ObjectBucketClaimLister, claimSelector labels.Selector) *metrics {
+	constLabels := prometheus.Labels{"provisioner": provisionerName}
+	return &metrics{
+		provisionDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace:   metricsNamespace,
+			Name:        "provision_duration_seconds",
+			Help:        "Duration of the provisioner's Provision and Grant calls.",
+			ConstLabels: constLabels,
+		}),
+		provisionTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   metricsNamespace,
+			Name:        "provision_total",
+			Help:        "Number of Provision and Grant calls, partitioned by result.",
+			ConstLabels: constLabels,
+		}, []string{"result"}),
+		deleteDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace:   metricsNamespace,
+			Name:        "delete_duration_seconds",
+			Help:        "Duration of the provisioner's Delete and Revoke calls.",
+			ConstLabels: constLabels,
+		}),
+		deleteTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   metricsNamespace,
+			Name:        "delete_total",
+			Help:        "Number of Delete and Revoke calls, partitioned by result.",
+			ConstLabels: constLabels,
+		}, []string{"result"}),
+		phaseTransitions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   metricsNamespace,
+			Name:        "claim_phase_transitions_total",
+			Help:        "Number of ObjectBucketClaim phase transitions, partitioned by the phase left and entered.",
+			ConstLabels: constLabels,
+		}, []string{"from_phase", "to_phase"}),
+		claims: &claimPhaseCollector{
+			lister:   obcLister,
+			selector: claimSelector,
+			desc: prometheus.NewDesc(
+				prometheus.BuildFQName(metricsNamespace, "", "claims"),
+				"Number of ObjectBucketClaims in each phase.",
+				[]string{"phase"},
+				constLabels),
+		},
+	}
+}
+
+// register adds all collectors to r.  Collectors which are already registered, e.g. by a previous
+// controller of the same provisioner, are skipped.
+func (m *metrics) register(r prometheus.Registerer) error {
+	for _, c := range []prometheus.Collector{m.provisionDuration, m.provisionTotal, m.deleteDuration, m.deleteTotal, m.claims, m.phaseTransitions} {
+		if err := r.Register(c); err != nil {
+			if _, ok := err.(prometheus.AlreadyRegisteredError); !ok {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (m *metrics) observeProvision(d time.Duration, err error) {
+	m.provisionDuration.Observe(d.Seconds())
+	m.provisionTotal.WithLabelValues(resultFor(err)).Inc()
+}
+
+func (m *metrics) observeDelete(d time.Duration, err error) {
+	m.deleteDuration.Observe(d.Seconds())
+	m.deleteTotal.WithLabelValues(resultFor(err)).Inc()
+}
+
+// observePhaseTransition counts a claim moving between phases.  Phases written again unchanged are not
+// counted.  The metrics of the controller not bound to a provisioner are nil, see forProvisioner.
+func (m *metrics) observePhaseTransition(from, to v1alpha1.ObjectBucketClaimStatusPhase) {
+	if m == nil || from == to {
+		return
+	}
+	fromLabel := string(from)
+	if fromLabel == "" {
+		fromLabel = phaseNone
+	}
+	m.phaseTransitions.WithLabelValues(fromLabel, string(to)).Inc()
+}
+
+func resultFor(err error) string {
+	switch {
+	case err == nil:
+		return resultSuccess
+	case pErr.IsBucketExists(err):
+		return resultAlreadyExists
+	default:
+		return resultError
+	}
+}
+
+// claimPhaseCollector counts the claims in the informer cache by phase when scraped, rather than
+// tracking phase transitions, so that the gauge cannot drift from the cluster state.
+type claimPhaseCollector struct {
+	lister   listers.ObjectBucketClaimLister
+	selector labels.Selector
+	desc     *prometheus.Desc
+}
+
+var _ prometheus.Collector = &claimPhaseCollector{}
+
+func (c *claimPhaseCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *claimPhaseCollector) Collect(ch chan<- prometheus.Metric) {
+	obcs, err := c.lister.List(c.selector)
+	if err != nil {
+		log.Error(err, "error listing OBCs for metrics")
+		return
+	}
+	counts := make(map[v1alpha1.ObjectBucketClaimStatusPhase]int, len(claimPhases))
+	for _, obc := range obcs {
+		counts[obc.Status.Phase]++
+	}
+	for _, phase := range claimPhases {
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(counts[phase]), string(phase))
+	}
+}
@@ -17,19 +17,24 @@ limitations under the License.
 package provisioner
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"net"
 	"strconv"
 	"time"
 
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
 
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/wait"
 
 	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/apis/objectbucket.io/v1alpha1"
-	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/client/clientset/versioned"
 	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/provisioner/api"
 )
 
@@ -39,23 +44,82 @@ const (
 	// defaultRetryTimeout defines how long in total to try to create an API object before ending the reconciliation
 	// attempt
 	defaultRetryTimeout = time.Second * 30
+	// defaultRetryBackoffFactor is the multiplier applied to the wait between consecutive create attempts
+	defaultRetryBackoffFactor = 2.0
+	// defaultRetryBackoffCap is the longest wait between consecutive create attempts
+	defaultRetryBackoffCap = time.Second * 12
+	// defaultRequeueJitterFactor is the largest fraction of a claim's requeue delay added at random
+	defaultRequeueJitterFactor = 0.1
+	// defaultDebugVerbosity is the verbosity of the debug log lines
+	defaultDebugVerbosity = 1
+	// defaultMaxConcurrentReconciles is the number of claims reconciled in parallel
+	defaultMaxConcurrentReconciles = 1
+	// defaultHealthCheckInterval is how often the provisioners' health is checked
+	defaultHealthCheckInterval = time.Second * 30
+	// threadsEnvVar overrides defaultMaxConcurrentReconciles
+	threadsEnvVar = "LIB_BUCKET_PROVISIONER_THREADS"
 
-	bucketName      = "BUCKET_NAME"
-	bucketHost      = "BUCKET_HOST"
-	bucketPort      = "BUCKET_PORT"
-	bucketRegion    = "BUCKET_REGION"
-	bucketSubRegion = "BUCKET_SUBREGION"
+	bucketName       = "BUCKET_NAME"
+	bucketHost       = "BUCKET_HOST"
+	bucketPort       = "BUCKET_PORT"
+	bucketRegion     = "BUCKET_REGION"
+	bucketSubRegion  = "BUCKET_SUBREGION"
+	bucketSSL        = "BUCKET_SSL"
+	bucketCACert     = "BUCKET_CA_CERT"
+	bucketPathStyle  = "BUCKET_PATH_STYLE"
+	bucketURL        = "BUCKET_URL"
+	bucketEndpoint   = "BUCKET_ENDPOINT"
+	bucketTLSMin     = "BUCKET_TLS_MIN_VERSION"
+	bucketSubPath    = "BUCKET_SUBPATH"
+	bucketVersioning = "BUCKET_VERSIONING"
+	// keys of the ConfigMap of an Azure endpoint
+	azureStorageAccount = "AZURE_STORAGE_ACCOUNT"
+	azureContainer      = "AZURE_CONTAINER"
+	azureEndpointSuffix = "AZURE_ENDPOINT_SUFFIX"
+	// lastAppliedAnnotation is written by kubectl apply and is never propagated to generated resources
+	lastAppliedAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
 	// finalizer is applied to all resources generated by the provisioner and to the obc
 	finalizer = api.Domain + "/finalizer"
+	// claimAnnotation and claimUIDAnnotation identify the claim of the secret copies in other namespaces,
+	// which cannot refer to it by an ownerReference
+	claimAnnotation    = api.Domain + "/claim"
+	claimUIDAnnotation = api.Domain + "/claim-uid"
 	// label applied to all resources generated by the provisioner and to the obc
 	provisionerLabelKey    = "bucket-provisioner"
 	objectBucketNameFormat = "obc-%s-%s"
 )
 
+// reservedConfigMapKeys are the ConfigMap data keys set by the library which provisioners may not
+// override via Endpoint.AdditionalConfigData, see reservedKeys for their renamed names
+var reservedConfigMapKeys = []string{bucketName, bucketHost, bucketPort, bucketRegion, bucketSubRegion, bucketSSL, bucketCACert, bucketPathStyle, bucketURL,
+	bucketEndpoint, bucketTLSMin, bucketSubPath, bucketVersioning, azureStorageAccount, azureContainer, azureEndpointSuffix}
+
+// endpointConfigMapKeys are the ConfigMap data keys locating the object store.  A ConfigMap differing from its
+// OB's endpoint in one of them is reported as an endpoint change rather than as drift.
+var endpointConfigMapKeys = []string{bucketHost, bucketPort, bucketSSL, azureStorageAccount, azureEndpointSuffix}
+
+// configMapDrift tells how the data of a claim's configMap differed from the one desired, see reconcileConfigMap
+type configMapDrift int
+
+const (
+	// configMapInSync is a configMap holding the desired data
+	configMapInSync configMapDrift = iota
+	// configMapDrifted is a configMap whose reserved keys were edited, added or removed
+	configMapDrifted
+	// configMapEndpointChanged is a configMap locating another object store than the OB's endpoint, e.g. after
+	// the provisioner migrated the bucket
+	configMapEndpointChanged
+)
+
 // newBucketConfigMap returns a config map from a given endpoint and ObjectBucketClaim.
-// A finalizer is added to reduce chances of the CM being accidentally deleted. An OwnerReference
-// is added so that the CM is automatically garbage collected when the parent OBC is deleted.
-func newBucketConfigMap(obc *v1alpha1.ObjectBucketClaim, ep *v1alpha1.Endpoint, labels map[string]string) (*corev1.ConfigMap, error) {
+// The OBC's labels are copied to the CM alongside the provisioner labels, as are the OBC's
+// annotations matching one of annotationPrefixes. The data keys depend on the endpoint's kind, see
+// s3ConfigMapData and azureConfigMapData, less the empty region keys opts omits, plus the subpath
+// key if the OBC owns a prefix of the bucket and the versioning key if versioning is enabled on the
+// bucket. Unless disabled by opts, a finalizer is added to reduce chances of the CM being
+// accidentally deleted. An OwnerReference is added so that the CM is automatically garbage
+// collected when the parent OBC is deleted.
+func newBucketConfigMap(obc *v1alpha1.ObjectBucketClaim, ep *v1alpha1.Endpoint, labels map[string]string, annotationPrefixes []string, opts childOptions) (*corev1.ConfigMap, error) {
 	if ep == nil {
 		return nil, fmt.Errorf("cannot construct configMap, got nil Endpoint")
 	}
@@ -63,32 +127,197 @@ func newBucketConfigMap(obc *v1alpha1.ObjectBucketClaim, ep *v1alpha1.Endpoint,
 		return nil, fmt.Errorf("cannot construct configMap, got nil OBC")
 	}
 
+	var data map[string]string
+	switch ep.Kind {
+	case "", v1alpha1.EndpointKindS3:
+		if err := validateTLSMinVersion(ep.TLSMinVersion); err != nil {
+			return nil, fmt.Errorf("cannot construct configMap: %v", err)
+		}
+		data = s3ConfigMapData(ep)
+		if opts.omitEmptySubRegion && ep.SubRegion == "" {
+			delete(data, bucketSubRegion)
+		}
+		if opts.omitEmptyRegion && ep.Region == "" {
+			delete(data, bucketRegion)
+		}
+	case v1alpha1.EndpointKindAzure:
+		if ep.AccountName == "" {
+			return nil, fmt.Errorf("cannot construct configMap, got Azure endpoint without account name")
+		}
+		data = azureConfigMapData(ep)
+	default:
+		return nil, fmt.Errorf("cannot construct configMap, unknown endpoint kind %q", ep.Kind)
+	}
+	if obc.Spec.BucketSubPath != "" {
+		data[bucketSubPath] = obc.Spec.BucketSubPath
+	}
+	if ep.Versioning {
+		data[bucketVersioning] = strconv.FormatBool(ep.Versioning)
+	}
+	data = renameKeys(data, opts.keyNames)
+	if err := mergeAdditionalConfigData(data, ep.AdditionalConfigData, reservedKeys(opts.keyNames)); err != nil {
+		return nil, fmt.Errorf("cannot construct configMap: %v", err)
+	}
+	if err := mergeClaimConfigData(data, obc.Spec.ExtraConfigMapData, reservedKeys(opts.keyNames)); err != nil {
+		return nil, fmt.Errorf("cannot construct configMap: %v", err)
+	}
+
 	return &corev1.ConfigMap{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:       obc.Name,
-			Namespace:  obc.Namespace,
-			Finalizers: []string{finalizer},
-			Labels:     labels,
+			Name:        configMapNameForClaim(obc),
+			Namespace:   obc.Namespace,
+			Finalizers:  opts.finalizers(),
+			Labels:      opts.labels(obc, labels),
+			Annotations: childAnnotations(obc, annotationPrefixes),
 			OwnerReferences: []metav1.OwnerReference{
-				makeOwnerReference(obc),
+				makeOwnerReference(obc, opts.ownerReference),
 			},
 		},
-		Data: map[string]string{
-			bucketName:      ep.BucketName,
-			bucketHost:      ep.BucketHost,
-			bucketPort:      strconv.Itoa(ep.BucketPort),
-			bucketRegion:    ep.Region,
-			bucketSubRegion: ep.SubRegion,
-		},
+		Data: data,
 	}, nil
 }
 
-// newCredentialsSecret returns a secret with data appropriate to the supported authenticaion
-// method. Even if the values for the Authentication keys are empty, we generate the secret.
-// A finalizer is added to reduce chances of the secret being accidentally deleted.
+// s3ConfigMapData returns the ConfigMap keys of an S3 endpoint.  The URL and endpoint keys combine the host, port
+// and SSL keys, see endpointURL. The port key is omitted for an unset port, the SSL and CA certificate keys are only
+// set for SSL endpoints, and the path style key for path-style endpoints.
+func s3ConfigMapData(ep *v1alpha1.Endpoint) map[string]string {
+	data := map[string]string{
+		bucketName:      ep.BucketName,
+		bucketHost:      ep.BucketHost,
+		bucketRegion:    ep.Region,
+		bucketSubRegion: ep.SubRegion,
+	}
+	// an unset port is omitted rather than written as "0", so that consumers use the scheme's default port
+	if ep.BucketPort != 0 {
+		data[bucketPort] = strconv.Itoa(ep.BucketPort)
+	}
+	if ep.SSL {
+		data[bucketSSL] = strconv.FormatBool(ep.SSL)
+		if ep.CABundle != "" {
+			data[bucketCACert] = ep.CABundle
+		}
+		if ep.TLSMinVersion != "" {
+			data[bucketTLSMin] = ep.TLSMinVersion
+		}
+	}
+	if u := endpointURL(ep); u != "" {
+		data[bucketURL] = u
+		// S3 SDKs add the bucket to their endpoint, as a virtual host or a path depending on the addressing
+		// style, so the endpoint is the bare scheme://host[:port] of both styles
+		data[bucketEndpoint] = u
+	}
+	// virtual-hosted-style addressing prepends the bucket name to the host, which cannot resolve if
+	// the host is an IP address
+	if ep.PathStyle || net.ParseIP(ep.BucketHost) != nil {
+		data[bucketPathStyle] = "true"
+	}
+	return data
+}
+
+// azureConfigMapData returns the ConfigMap keys of an Azure Blob endpoint, whose bucket is a container of a
+// storage account.  The bucket name key is kept for consumers of any kind of endpoint.  The endpoint suffix
+// key is omitted if unset, for clients to default to the public cloud.
+func azureConfigMapData(ep *v1alpha1.Endpoint) map[string]string {
+	data := map[string]string{
+		bucketName:          ep.BucketName,
+		azureStorageAccount: ep.AccountName,
+		azureContainer:      ep.BucketName,
+	}
+	if ep.EndpointSuffix != "" {
+		data[azureEndpointSuffix] = ep.EndpointSuffix
+	}
+	return data
+}
+
+// renameKeys returns a copy of data whose keys are renamed by renames.  Keys without a new name are kept.
+func renameKeys(data, renames map[string]string) map[string]string {
+	renamed := make(map[string]string, len(data))
+	for k, v := range data {
+		if name, ok := renames[k]; ok {
+			k = name
+		}
+		renamed[k] = v
+	}
+	return renamed
+}
+
+// reservedKeys returns the reserved ConfigMap keys as renamed by renames
+func reservedKeys(renames map[string]string) []string {
+	keys := make([]string, len(reservedConfigMapKeys))
+	for i, k := range reservedConfigMapKeys {
+		if name, ok := renames[k]; ok {
+			k = name
+		}
+		keys[i] = k
+	}
+	return keys
+}
+
+// mergeAdditionalConfigData copies the provisioner-supplied key/values into data. An error is
+// returned if a key collides with one of the reserved BUCKET_* keys.
+func mergeAdditionalConfigData(data, additional map[string]string, reserved []string) error {
+	if err := checkReservedKeys(additional, reserved); err != nil {
+		return err
+	}
+	for k, v := range additional {
+		data[k] = v
+	}
+	return nil
+}
+
+// mergeClaimConfigData copies the claim's extra configmap data into data, below the keys already set by the
+// library and the provisioner.  An error is returned if a key collides with one of the reserved BUCKET_* keys.
+func mergeClaimConfigData(data, additional map[string]string, reserved []string) error {
+	if err := checkReservedKeys(additional, reserved); err != nil {
+		return err
+	}
+	for k, v := range additional {
+		if _, ok := data[k]; !ok {
+			data[k] = v
+		}
+	}
+	return nil
+}
+
+// checkReservedKeys returns an error if a key of additional is one of the reserved keys
+func checkReservedKeys(additional map[string]string, reserved []string) error {
+	for _, k := range reserved {
+		if _, ok := additional[k]; ok {
+			return fmt.Errorf("additional config key %q collides with a reserved key", k)
+		}
+	}
+	return nil
+}
+
+// syncReservedConfigMapData sets the reserved BUCKET_* keys of data to their value in desired, removing
+// those desired lacks.  Other keys, e.g. the provisioner's additional config data or keys added by users,
+// are left alone.  Returns true if data was changed.
+func syncReservedConfigMapData(data, desired map[string]string, reserved []string) bool {
+	changed := false
+	for _, k := range reserved {
+		want, ok := desired[k]
+		got, exists := data[k]
+		switch {
+		case ok && (!exists || got != want):
+			data[k] = want
+			changed = true
+		case !ok && exists:
+			delete(data, k)
+			changed = true
+		}
+	}
+	return changed
+}
+
+// newCredentialsSecret returns a secret with data and type appropriate to the supported authenticaion
+// method. Even if the values for the Authentication keys are empty, we generate the secret.  If the controller
+// stores references, the secret holds the reference to the credentials instead, see credentialsData.
+// The OBC's labels are copied to the secret alongside the provisioner labels, as are the OBC's
+// annotations matching one of annotationPrefixes.
+// Unless disabled by opts, a finalizer is added to reduce chances of the secret being accidentally deleted.
 // An OwnerReference is added so that the secret is automatically garbage collected when the
 // parent OBC is deleted.
-func newCredentialsSecret(obc *v1alpha1.ObjectBucketClaim, auth *v1alpha1.Authentication, labels map[string]string) (*corev1.Secret, error) {
+func newCredentialsSecret(obc *v1alpha1.ObjectBucketClaim, auth *v1alpha1.Authentication, labels map[string]string, annotationPrefixes []string, opts childOptions) (*corev1.Secret, error) {
 	if obc == nil {
 		return nil, fmt.Errorf("ObjectBucketClaim required to generate secret")
 	}
@@ -98,29 +327,146 @@ func newCredentialsSecret(obc *v1alpha1.ObjectBucketClaim, auth *v1alpha1.Authen
 
 	secret := &corev1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:       obc.Name,
-			Namespace:  obc.Namespace,
-			Finalizers: []string{finalizer},
-			Labels:     labels,
+			Name:        secretNameForClaim(obc),
+			Namespace:   obc.Namespace,
+			Finalizers:  opts.finalizers(),
+			Labels:      opts.labels(obc, labels),
+			Annotations: childAnnotations(obc, annotationPrefixes),
 			OwnerReferences: []metav1.OwnerReference{
-				makeOwnerReference(obc),
+				makeOwnerReference(obc, opts.ownerReference),
 			},
 		},
 	}
 
-	secret.StringData = auth.ToMap()
+	if opts.credentialsReference {
+		data, err := credentialsData(auth, opts)
+		if err != nil {
+			return nil, fmt.Errorf("cannot construct secret: %v", err)
+		}
+		if secret.Annotations == nil {
+			secret.Annotations = map[string]string{}
+		}
+		secret.Annotations[v1alpha1.CredentialsReferenceAnnotation] = "true"
+		secret.StringData = data
+		secret.Type = corev1.SecretTypeOpaque
+		return secret, nil
+	}
+
+	data, err := auth.ToMap()
+	if err != nil {
+		return nil, fmt.Errorf("cannot construct secret: %v", err)
+	}
+	if len(auth.ServiceAccountJSON) > 0 {
+		// the service account key, the only auth type defined, is written as is rather than as a string
+		secret.Data = map[string][]byte{v1alpha1.GcsCredentialsField: auth.ServiceAccountJSON.DeepCopy()}
+	} else {
+		secret.StringData = renameKeys(data, opts.keyNames)
+	}
+	secret.Type = auth.SecretType()
 	return secret, nil
 }
 
-// createObjectBucket creates an OB based on the passed-in ob spec.
+// credentialsData returns the renamed keys of the claim's Secret for auth: the credentials, or their reference
+// if the controller stores references.  A reference requires a path, and keys other than the path's.
+func credentialsData(auth *v1alpha1.Authentication, opts childOptions) (map[string]string, error) {
+	if !opts.credentialsReference {
+		data, err := auth.ToMap()
+		if err != nil {
+			return nil, err
+		}
+		return renameKeys(data, opts.keyNames), nil
+	}
+	ref := auth.SecretReference
+	if ref == nil {
+		return nil, fmt.Errorf("expected a secret reference, got none")
+	}
+	if ref.Path == "" {
+		return nil, fmt.Errorf("secret reference has no path")
+	}
+	data := map[string]string{v1alpha1.SecretReferencePathField: ref.Path}
+	for k, v := range renameKeys(ref.Keys, opts.keyNames) {
+		if k == v1alpha1.SecretReferencePathField {
+			return nil, fmt.Errorf("secret reference key %q is reserved", k)
+		}
+		data[k] = v
+	}
+	return data, nil
+}
+
+// retryBackoff defines how retried API calls are spaced out. The first attempt is immediate, the first retry
+// waits interval and each subsequent wait is multiplied by factor, never exceeding maxInterval. Retrying stops
+// once the next wait would exceed timeout.
+type retryBackoff struct {
+	interval    time.Duration
+	factor      float64
+	maxInterval time.Duration
+	timeout     time.Duration
+}
+
+// next returns the wait following the given one.
+func (b retryBackoff) next(delay time.Duration) time.Duration {
+	if b.factor > 1 {
+		delay = time.Duration(float64(delay) * b.factor)
+	}
+	if b.maxInterval > 0 && delay > b.maxInterval {
+		delay = b.maxInterval
+	}
+	return delay
+}
+
+// retryWithBackoff calls condition until it returns true or an error, waiting between attempts as
+// defined by b. wait.ErrWaitTimeout is returned if the condition is never satisfied. If ctx is
+// cancelled while waiting, ctx.Err() is returned.
+func retryWithBackoff(ctx context.Context, b retryBackoff, condition wait.ConditionFunc) error {
+	deadline := time.Now().Add(b.timeout)
+	delay := b.interval
+	for {
+		if done, err := condition(); err != nil || done {
+			return err
+		}
+		if time.Now().Add(delay).After(deadline) {
+			return wait.ErrWaitTimeout
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay = b.next(delay)
+	}
+}
+
+// pollImmediate calls condition every interval until it returns true or an error, or timeout elapses.
+func pollImmediate(ctx context.Context, interval, timeout time.Duration, condition wait.ConditionFunc) error {
+	return retryWithBackoff(ctx, retryBackoff{interval: interval, timeout: timeout}, condition)
+}
+
+// createObjectBucket creates an OB based on the passed-in ob spec, annotated with the version of the
+// controller unless empty.  An existing OB of the same name is adopted if it refers to the same claim, e.g.
+// when an earlier reconcile was interrupted.
 // Note: a finalizer has been added to reduce chances of the ob being accidentally deleted.
-func createObjectBucket(ob *v1alpha1.ObjectBucket, c versioned.Interface, retryInterval, retryTimeout time.Duration) (result *v1alpha1.ObjectBucket, err error) {
+func createObjectBucket(ctx context.Context, ob *v1alpha1.ObjectBucket, c bucketClient, backoff retryBackoff, version string) (result *v1alpha1.ObjectBucket, err error) {
+	log := requestLog(ctx)
+	logD := requestLogD(ctx)
 	logD.Info("creating ObjectBucket", "name", ob.Name)
 
-	err = wait.PollImmediate(retryInterval, retryTimeout, func() (bool, error) {
-		result, err = c.ObjectbucketV1alpha1().ObjectBuckets().Create(ob)
+	if ob.Spec.ClaimRef != nil || version != "" {
+		ob = ob.DeepCopy()
+	}
+	if ob.Spec.ClaimRef != nil {
+		setClaimLabels(ob, ob.Spec.ClaimRef)
+	}
+	if version != "" {
+		if ob.Annotations == nil {
+			ob.Annotations = map[string]string{}
+		}
+		ob.Annotations[v1alpha1.ProvisionerVersionAnnotation] = version
+	}
+
+	err = retryWithBackoff(ctx, backoff, func() (bool, error) {
+		result, err = c.CreateObjectBucket(ob)
 		if errors.IsAlreadyExists(err) {
-			err = nil
+			result, err = adoptObjectBucket(ctx, ob, c, backoff)
 		} else if err != nil {
 			// could be intermittent api error
 			log.Error(err, "probably not fatal, retrying")
@@ -130,17 +476,91 @@ func createObjectBucket(ob *v1alpha1.ObjectBucket, c versioned.Interface, retryI
 	return
 }
 
-func createSecret(obc *v1alpha1.ObjectBucketClaim, auth *v1alpha1.Authentication, labels map[string]string, c kubernetes.Interface, retryInterval, retryTimeout time.Duration) (*corev1.Secret, error) {
-	secret, err := newCredentialsSecret(obc, auth, labels)
+// setClaimLabels sets the claim namespace and name labels of the OB, by which ObjectBucketsForClaim selects it
+func setClaimLabels(ob *v1alpha1.ObjectBucket, claim *corev1.ObjectReference) {
+	labels := make(map[string]string, len(ob.Labels)+2)
+	for k, v := range ob.Labels {
+		labels[k] = v
+	}
+	labels[v1alpha1.ClaimNamespaceLabel] = labelValue(claim.Namespace)
+	labels[v1alpha1.ClaimNameLabel] = labelValue(claim.Name)
+	ob.SetLabels(labels)
+}
+
+// hasClaimLabels returns true if the OB's claim namespace and name labels are the claim's
+func hasClaimLabels(ob *v1alpha1.ObjectBucket, claim *corev1.ObjectReference) bool {
+	return ob.Labels[v1alpha1.ClaimNamespaceLabel] == labelValue(claim.Namespace) &&
+		ob.Labels[v1alpha1.ClaimNameLabel] == labelValue(claim.Name)
+}
+
+// adoptObjectBucket returns the existing OB of the same name as ob if both refer to the same claim.
+func adoptObjectBucket(ctx context.Context, ob *v1alpha1.ObjectBucket, c bucketClient, backoff retryBackoff) (*v1alpha1.ObjectBucket, error) {
+	logD := requestLogD(ctx)
+	existing, err := c.GetObjectBucket(ob.Name)
+	if err != nil {
+		return nil, err
+	}
+	if ob.Spec.ClaimRef == nil || !refersToClaim(existing, ob.Spec.ClaimRef) {
+		return nil, fmt.Errorf("ObjectBucket %q already exists and is bound to another OBC", ob.Name)
+	}
+	logD.Info("adopting existing ObjectBucket", "name", ob.Name)
+	return migrateObjectBucket(ctx, existing, ob.Spec.ClaimRef, c, backoff)
+}
+
+// migrateObjectBucket updates an OB of the claim created by an earlier version of the library, which may lack
+// the library's finalizer, the claim's UID in its claim reference, the claim labels, or a phase.  Up to date
+// OBs are returned unchanged.
+func migrateObjectBucket(ctx context.Context, ob *v1alpha1.ObjectBucket, claim *corev1.ObjectReference, c bucketClient, backoff retryBackoff) (*v1alpha1.ObjectBucket, error) {
+	logD := requestLogD(ctx)
+	if !hasFinalizer(ob) || ob.Spec.ClaimRef == nil || ob.Spec.ClaimRef.UID != claim.UID || !hasClaimLabels(ob, claim) {
+		logD.Info("migrating ObjectBucket", "name", ob.Name)
+		ob = ob.DeepCopy()
+		if !hasFinalizer(ob) {
+			ob.SetFinalizers(append(ob.GetFinalizers(), finalizer))
+		}
+		ob.Spec.ClaimRef = claim.DeepCopy()
+		setClaimLabels(ob, claim)
+		var err error
+		if ob, err = c.UpdateObjectBucket(ob); err != nil {
+			return nil, fmt.Errorf("error migrating ObjectBucket: %v", err)
+		}
+	}
+	if ob.Status.Phase == "" {
+		return updateObjectBucketPhase(ctx, c, ob, v1alpha1.ObjectBucketStatusPhaseBound, backoff.interval, backoff.timeout)
+	}
+	return ob, nil
+}
+
+// createSecret creates the claim's Secret.  An existing Secret owned by the claim is adopted, e.g. when an
+// earlier reconcile was interrupted.
+func createSecret(ctx context.Context, obc *v1alpha1.ObjectBucketClaim, auth *v1alpha1.Authentication, labels map[string]string, annotationPrefixes []string, opts childOptions, c kubernetes.Interface, a childApplier, backoff retryBackoff) (*corev1.Secret, error) {
+	log := requestLog(ctx)
+	logD := requestLogD(ctx)
+	secret, err := newCredentialsSecret(obc, auth, labels, annotationPrefixes, opts)
 	if err != nil {
 		return nil, err
 	}
-	logD.Info("creating Secret", "name", secret.Namespace+"/"+secret.Name)
-	err = wait.PollImmediate(retryInterval, retryTimeout, func() (done bool, err error) {
-		secret, err = c.CoreV1().Secrets(obc.Namespace).Create(secret)
+	logD.Info("creating Secret", "name", logSafeSecretRef(secret))
+	name := secret.Name
+	var result *corev1.Secret
+	err = retryWithBackoff(ctx, backoff, func() (done bool, err error) {
+		// a failed create returns no object, keep secret for the next attempt
+		result, err = c.CoreV1().Secrets(obc.Namespace).Create(secret)
 		if err != nil {
 			if errors.IsAlreadyExists(err) {
-				// The object already exists don't spam the logs, instead let the request be requeued
+				result, err = c.CoreV1().Secrets(obc.Namespace).Get(name, metav1.GetOptions{})
+				if err != nil {
+					return true, err
+				}
+				// another claim's secret must not be overwritten
+				if !isOwnedByClaim(result, obc) {
+					return true, newSecretInUseError(result)
+				}
+				result, err = convergeSecret(ctx, result, secret, opts, c, a, backoff)
+				if errors.IsConflict(err) {
+					logD.Info("conflict updating existing secret, retrying", "name", logSafeSecretRef(secret))
+					return false, nil
+				}
 				return true, err
 			}
 			// The error could be intermittent, log and try again
@@ -149,21 +569,254 @@ func createSecret(obc *v1alpha1.ObjectBucketClaim, auth *v1alpha1.Authentication
 		}
 		return true, nil
 	})
-	return secret, err
+	if err != nil {
+		// never hand back an object which is not the claim's, it would be cleaned up as such
+		return nil, err
+	}
+	if opts.immutable {
+		return markSecretImmutable(ctx, result, c, backoff)
+	}
+	return result, nil
 }
 
-func createConfigMap(obc *v1alpha1.ObjectBucketClaim, ep *v1alpha1.Endpoint, labels map[string]string, c kubernetes.Interface, retryInterval, retryTimeout time.Duration) (*corev1.ConfigMap, error) {
-	configMap, err := newBucketConfigMap(obc, ep, labels)
+// convergeSecret applies desired to existing, the claim's secret left by an earlier reconcile, see childApplier.
+// Metadata not set by the library is kept.  The secret is only applied if it drifted, or replaced if immutable.
+func convergeSecret(ctx context.Context, existing, desired *corev1.Secret, opts childOptions, c kubernetes.Interface, a childApplier, backoff retryBackoff) (*corev1.Secret, error) {
+	logD := requestLogD(ctx)
+	updated := existing.DeepCopy()
+	convergeMeta(updated, desired)
+	data := secretData(desired)
+	updated.Data = make(map[string][]byte, len(data))
+	for k, v := range data {
+		updated.Data[k] = []byte(v)
+	}
+	updated.StringData = nil
+	if equality.Semantic.DeepEqual(existing, updated) {
+		return existing, nil
+	}
+	if opts.immutable {
+		return replaceSecret(ctx, updated, opts.legacyFinalizers, c, backoff)
+	}
+	logD.Info("applying drifted Secret", "name", logSafeSecretRef(updated))
+	applied := desired.DeepCopy()
+	applied.Data, applied.StringData = updated.Data, nil
+	return a.ApplySecret(applied, opts.fieldManager)
+}
+
+// convergeConfigMap applies desired to existing, the claim's configmap left by an earlier reconcile, see
+// childApplier.  Metadata not set by the library is kept.  The configmap is only applied if it drifted, or
+// replaced if immutable.
+func convergeConfigMap(ctx context.Context, existing, desired *corev1.ConfigMap, opts childOptions, c kubernetes.Interface, a childApplier, backoff retryBackoff) (*corev1.ConfigMap, error) {
+	logD := requestLogD(ctx)
+	updated := existing.DeepCopy()
+	convergeMeta(updated, desired)
+	updated.Data = desired.Data
+	if equality.Semantic.DeepEqual(existing, updated) {
+		return existing, nil
+	}
+	if opts.immutable {
+		return replaceConfigMap(ctx, updated, opts.legacyFinalizers, c, backoff)
+	}
+	logD.Info("applying drifted ConfigMap", "name", updated.Namespace+"/"+updated.Name)
+	return a.ApplyConfigMap(desired, opts.fieldManager)
+}
+
+// immutablePatch marks a Secret or ConfigMap immutable.  The vendored API types predate their immutable
+// field, which therefore cannot be set on create.
+var immutablePatch = []byte(`{"immutable":true}`)
+
+// markSecretImmutable marks the claim's secret immutable, see ControllerOptions.ImmutableChildren
+func markSecretImmutable(ctx context.Context, secret *corev1.Secret, c kubernetes.Interface, backoff retryBackoff) (result *corev1.Secret, err error) {
+	err = retryWithBackoff(ctx, backoff, func() (bool, error) {
+		result, err = c.CoreV1().Secrets(secret.Namespace).Patch(secret.Name, types.MergePatchType, immutablePatch)
+		return err == nil, err
+	})
+	return
+}
+
+// markConfigMapImmutable marks the claim's configmap immutable, see ControllerOptions.ImmutableChildren
+func markConfigMapImmutable(ctx context.Context, cm *corev1.ConfigMap, c kubernetes.Interface, backoff retryBackoff) (result *corev1.ConfigMap, err error) {
+	err = retryWithBackoff(ctx, backoff, func() (bool, error) {
+		result, err = c.CoreV1().ConfigMaps(cm.Namespace).Patch(cm.Name, types.MergePatchType, immutablePatch)
+		return err == nil, err
+	})
+	return
+}
+
+// replaceSecret deletes the claim's immutable secret and creates secret, its updated content, in its place.
+// The secret is released first so that it is deleted at once.
+func replaceSecret(ctx context.Context, secret *corev1.Secret, legacyFinalizers []string, c kubernetes.Interface, backoff retryBackoff) (result *corev1.Secret, err error) {
+	logD := requestLogD(ctx)
+	logD.Info("replacing immutable Secret", "name", logSafeSecretRef(secret))
+	if err = releaseSecret(secret, legacyFinalizers, c); err != nil && !errors.IsNotFound(err) {
+		return nil, err
+	}
+	err = c.CoreV1().Secrets(secret.Namespace).Delete(secret.Name, &metav1.DeleteOptions{Preconditions: metav1.NewUIDPreconditions(string(secret.UID))})
+	if err != nil && !errors.IsNotFound(err) {
+		return nil, err
+	}
+	replacement := secret.DeepCopy()
+	replacement.ResourceVersion = ""
+	replacement.UID = ""
+	replacement.CreationTimestamp = metav1.Time{}
+	err = retryWithBackoff(ctx, backoff, func() (bool, error) {
+		// the deleted secret may linger while the API server removes it
+		result, err = c.CoreV1().Secrets(replacement.Namespace).Create(replacement)
+		if errors.IsAlreadyExists(err) {
+			return false, nil
+		}
+		return err == nil, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return markSecretImmutable(ctx, result, c, backoff)
+}
+
+// replaceConfigMap deletes the claim's immutable configmap and creates cm, its updated content, in its place.
+// The configmap is released first so that it is deleted at once.
+func replaceConfigMap(ctx context.Context, cm *corev1.ConfigMap, legacyFinalizers []string, c kubernetes.Interface, backoff retryBackoff) (result *corev1.ConfigMap, err error) {
+	logD := requestLogD(ctx)
+	logD.Info("replacing immutable ConfigMap", "name", cm.Namespace+"/"+cm.Name)
+	if err = releaseConfigMap(cm, legacyFinalizers, c); err != nil && !errors.IsNotFound(err) {
+		return nil, err
+	}
+	err = c.CoreV1().ConfigMaps(cm.Namespace).Delete(cm.Name, &metav1.DeleteOptions{Preconditions: metav1.NewUIDPreconditions(string(cm.UID))})
+	if err != nil && !errors.IsNotFound(err) {
+		return nil, err
+	}
+	replacement := cm.DeepCopy()
+	replacement.ResourceVersion = ""
+	replacement.UID = ""
+	replacement.CreationTimestamp = metav1.Time{}
+	err = retryWithBackoff(ctx, backoff, func() (bool, error) {
+		// the deleted configmap may linger while the API server removes it
+		result, err = c.CoreV1().ConfigMaps(replacement.Namespace).Create(replacement)
+		if errors.IsAlreadyExists(err) {
+			return false, nil
+		}
+		return err == nil, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return markConfigMapImmutable(ctx, result, c, backoff)
+}
+
+// newSecretCopy returns a copy of the claim's secret in namespace, finalized like the secret.  Cross-namespace
+// ownerReferences are not allowed, so the copy identifies its claim by annotations and must be deleted explicitly.
+func newSecretCopy(obc *v1alpha1.ObjectBucketClaim, secret *corev1.Secret, namespace string) *corev1.Secret {
+	annotations := make(map[string]string, len(secret.Annotations)+2)
+	for k, v := range secret.Annotations {
+		annotations[k] = v
+	}
+	annotations[claimAnnotation] = obc.Namespace + "/" + obc.Name
+	annotations[claimUIDAnnotation] = string(obc.UID)
+
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        secret.Name,
+			Namespace:   namespace,
+			Finalizers:  secret.Finalizers,
+			Labels:      secret.Labels,
+			Annotations: annotations,
+		},
+		Type:       secret.Type,
+		Data:       secret.Data,
+		StringData: secret.StringData,
+	}
+}
+
+// createSecretCopies copies the claim's secret into each of its additional secret namespaces.  Existing
+// copies of the claim are updated, so that they follow the secret's credentials.  Nothing is copied unless
+// every namespace accepts the copies, see checkSecretCopyConsent.
+func createSecretCopies(ctx context.Context, obc *v1alpha1.ObjectBucketClaim, secret *corev1.Secret, c kubernetes.Interface, backoff retryBackoff) error {
+	logD := requestLogD(ctx)
+	if err := checkSecretCopyConsent(obc, c); err != nil {
+		return err
+	}
+	for _, ns := range obc.Spec.AdditionalSecretNamespaces {
+		secretCopy := newSecretCopy(obc, secret, ns)
+		logD.Info("creating Secret copy", "name", logSafeSecretRef(secretCopy))
+		err := retryWithBackoff(ctx, backoff, func() (bool, error) {
+			_, err := c.CoreV1().Secrets(ns).Create(secretCopy)
+			if errors.IsAlreadyExists(err) {
+				var existing *corev1.Secret
+				existing, err = c.CoreV1().Secrets(ns).Get(secretCopy.Name, metav1.GetOptions{})
+				if err != nil {
+					return false, err
+				}
+				if !isOwnedByClaim(existing, obc) {
+					return false, fmt.Errorf("secret %q already exists in namespace %q and is not owned by the OBC", secretCopy.Name, ns)
+				}
+				existing.Data, existing.StringData = secretCopy.Data, secretCopy.StringData
+				_, err = c.CoreV1().Secrets(ns).Update(existing)
+			}
+			return err == nil, err
+		})
+		if err != nil {
+			return fmt.Errorf("error copying secret into namespace %q: %v", ns, err)
+		}
+	}
+	return nil
+}
+
+// deleteSecretCopies releases and deletes the copies of the claim's secret in the given namespaces.  Secrets
+// not owned by the claim are left alone.
+func deleteSecretCopies(obc *v1alpha1.ObjectBucketClaim, namespaces []string, legacyFinalizers []string, c kubernetes.Interface) (err error) {
+	name := secretNameForClaim(obc)
+	for _, ns := range namespaces {
+		secretCopy, gErr := c.CoreV1().Secrets(ns).Get(name, metav1.GetOptions{})
+		if gErr != nil {
+			if !errors.IsNotFound(gErr) {
+				err = gErr
+			}
+			continue
+		}
+		if !isOwnedByClaim(secretCopy, obc) {
+			continue
+		}
+		if rErr := releaseSecret(secretCopy, legacyFinalizers, c); rErr != nil {
+			err = rErr
+			continue
+		}
+		if dErr := c.CoreV1().Secrets(ns).Delete(name, &metav1.DeleteOptions{}); dErr != nil && !errors.IsNotFound(dErr) {
+			err = dErr
+		}
+	}
+	return err
+}
+
+// createConfigMap creates the claim's ConfigMap.  An existing ConfigMap owned by the claim is adopted and
+// updated to the desired content, see convergeConfigMap.
+func createConfigMap(ctx context.Context, obc *v1alpha1.ObjectBucketClaim, ep *v1alpha1.Endpoint, labels map[string]string, annotationPrefixes []string, opts childOptions, c kubernetes.Interface, a childApplier, backoff retryBackoff) (*corev1.ConfigMap, error) {
+	log := requestLog(ctx)
+	logD := requestLogD(ctx)
+	configMap, err := newBucketConfigMap(obc, ep, labels, annotationPrefixes, opts)
 	if err != nil {
 		return nil, err
 	}
 
 	logD.Info("creating ConfigMap", "name", configMap.Namespace+"/"+configMap.Name)
-	err = wait.PollImmediate(retryInterval, retryTimeout, func() (done bool, err error) {
-		configMap, err = c.CoreV1().ConfigMaps(obc.Namespace).Create(configMap)
+	name := configMap.Name
+	var result *corev1.ConfigMap
+	err = retryWithBackoff(ctx, backoff, func() (done bool, err error) {
+		// a failed create returns no object, keep configMap for the next attempt
+		result, err = c.CoreV1().ConfigMaps(obc.Namespace).Create(configMap)
 		if err != nil {
 			if errors.IsAlreadyExists(err) {
-				// The object already exists don't spam the logs, instead let the request be requeued
+				result, err = c.CoreV1().ConfigMaps(obc.Namespace).Get(name, metav1.GetOptions{})
+				if err != nil {
+					return true, err
+				}
+				if !isOwnedByClaim(result, obc) {
+					return true, fmt.Errorf("configmap %q already exists and is not owned by the OBC", name)
+				}
+				result, err = convergeConfigMap(ctx, result, configMap, opts, c, a, backoff)
+				if errors.IsConflict(err) {
+					logD.Info("conflict updating existing configmap, retrying", "name", name)
+					return false, nil
+				}
 				return true, err
 			}
 			// The error could be intermittent, log and try again
@@ -172,66 +825,124 @@ func createConfigMap(obc *v1alpha1.ObjectBucketClaim, ep *v1alpha1.Endpoint, lab
 		}
 		return true, nil
 	})
-	return configMap, err
+	if err != nil {
+		// never hand back an object which is not the claim's, it would be cleaned up as such
+		return nil, err
+	}
+	if opts.immutable {
+		return markConfigMapImmutable(ctx, result, c, backoff)
+	}
+	return result, nil
+}
+
+// removeFinalizerPatch returns the merge patch removing the library's finalizer, and the legacy
+// ones, from obj.  Only the finalizers are patched, so that the fields the vendored API types lack,
+// e.g. immutable, are neither dropped nor rejected as by an update.  The patch is conditional on the
+// resourceVersion obj was read at, the finalizers being replaced as a whole.
+func removeFinalizerPatch(obj metav1.Object, legacyFinalizers []string) ([]byte, error) {
+	var finalizers []string
+	for _, f := range obj.GetFinalizers() {
+		if !isLibraryFinalizer(f, legacyFinalizers) {
+			finalizers = append(finalizers, f)
+		}
+	}
+	return json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"finalizers":      finalizers,
+			"resourceVersion": obj.GetResourceVersion(),
+		},
+	})
 }
 
 // Only the finalizer needs to be removed. The CM will be garbage collected since its
-// ownerReference refers to the parent OBC.
-func releaseConfigMap(cm *corev1.ConfigMap, c kubernetes.Interface) (err error) {
+// ownerReference refers to the parent OBC. A CM created without the finalizer is left alone.
+func releaseConfigMap(cm *corev1.ConfigMap, legacyFinalizers []string, c kubernetes.Interface) (err error) {
 	if cm == nil {
 		logD.Info("got nil configmap, skipping")
 		return nil
 	}
-	cm, err = c.CoreV1().ConfigMaps(cm.Namespace).Get(cm.Name, metav1.GetOptions{})
-	if err != nil {
-		return err
+	if !hasLibraryFinalizer(cm, legacyFinalizers) {
+		return nil
 	}
-	logD.Info("removing configmap finalizer")
-	removeFinalizer(cm)
-	cm, err = c.CoreV1().ConfigMaps(cm.Namespace).Update(cm)
-	if err != nil {
+	// the configmap is re-read on each attempt, as conflicts are common when racing other deletions
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		latest, err := c.CoreV1().ConfigMaps(cm.Namespace).Get(cm.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		logD.Info("removing configmap finalizer")
+		patch, err := removeFinalizerPatch(latest, legacyFinalizers)
+		if err != nil {
+			return err
+		}
+		_, err = c.CoreV1().ConfigMaps(latest.Namespace).Patch(latest.Name, types.MergePatchType, patch)
 		return err
-	}
-
-	return nil
+	})
 }
 
 // Only the finalizer needs to be removed. The Secret will be garbage collected since its
-// ownerReference refers to the parent OBC.
-func releaseSecret(sec *corev1.Secret, c kubernetes.Interface) (err error) {
+// ownerReference refers to the parent OBC. A Secret created without the finalizer is left alone.
+func releaseSecret(sec *corev1.Secret, legacyFinalizers []string, c kubernetes.Interface) (err error) {
 	if sec == nil {
 		logD.Info("got nil secret, skipping")
 		return nil
 	}
-	sec, err = c.CoreV1().Secrets(sec.Namespace).Get(sec.Name, metav1.GetOptions{})
-	if err != nil {
-		return err
+	if !hasLibraryFinalizer(sec, legacyFinalizers) {
+		return nil
 	}
-	logD.Info("removing secret finalizer")
-	removeFinalizer(sec)
-	sec, err = c.CoreV1().Secrets(sec.Namespace).Update(sec)
-	if err != nil {
+	// the secret is re-read on each attempt, as conflicts are common when racing other deletions
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		latest, err := c.CoreV1().Secrets(sec.Namespace).Get(sec.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		logD.Info("removing secret finalizer", "name", logSafeSecretRef(latest))
+		patch, err := removeFinalizerPatch(latest, legacyFinalizers)
+		if err != nil {
+			return err
+		}
+		_, err = c.CoreV1().Secrets(latest.Namespace).Patch(latest.Name, types.MergePatchType, patch)
 		return err
-	}
+	})
+}
 
+// releaseObjectBucket removes the OB's finalizer and its link to its claim, the claim reference and the claim
+// labels, so that the OB outlives the claim and can be bound to another.
+func releaseObjectBucket(ob *v1alpha1.ObjectBucket, legacyFinalizers []string, c bucketClient) error {
+	name := ob.Name
+	logD.Info("releasing ObjectBucket from its claim", "name", name)
+	ob = ob.DeepCopy()
+	removeFinalizer(ob, legacyFinalizers)
+	ob.Spec.ClaimRef = nil
+	delete(ob.Labels, v1alpha1.ClaimNamespaceLabel)
+	delete(ob.Labels, v1alpha1.ClaimNameLabel)
+	if _, err := c.UpdateObjectBucket(ob); err != nil {
+		if errors.IsNotFound(err) {
+			logD.Info("ObjectBucket already deleted", "name", name)
+			return nil
+		}
+		return err
+	}
 	return nil
 }
 
 // Remove the finalizer allowing the OBC to finally be deleted.
-func releaseOBC(obc *v1alpha1.ObjectBucketClaim, c versioned.Interface) (err error) {
+func releaseOBC(obc *v1alpha1.ObjectBucketClaim, legacyFinalizers []string, c bucketClient) (err error) {
 	if obc == nil {
 		logD.Info("got nil obc, skipping")
 		return nil
 	}
 	obcNsName := obc.Namespace + "/" + obc.Name
-	obc, err = c.ObjectbucketV1alpha1().ObjectBucketClaims(obc.Namespace).Get(obc.Name, metav1.GetOptions{})
+	obc, err = c.GetClaim(obc.Namespace, obc.Name)
 	if err != nil {
 		return fmt.Errorf("unable to Get obc %q in order to remove finalizer: %v", obcNsName, err)
 	}
+	if !removeFinalizer(obc, legacyFinalizers) {
+		logD.Info("obc has no finalizer to remove")
+		return nil
+	}
 	logD.Info("removing obc finalizer")
-	removeFinalizer(obc)
-
-	obc, err = c.ObjectbucketV1alpha1().ObjectBucketClaims(obc.Namespace).Update(obc)
+	obc, err = c.UpdateClaim(obc)
 	if err != nil {
 		return fmt.Errorf("unable to Update obc %q to reflect removed finalizer: %v", obcNsName, err)
 	}
@@ -240,66 +951,214 @@ func releaseOBC(obc *v1alpha1.ObjectBucketClaim, c versioned.Interface) (err err
 }
 
 // The OB does not have an ownerReference and must be explicitly deleted after its
+// finalizer is removed. A released OB whose reclaimPolicy is Retain is kept, only its
 // finalizer is removed.
 // Uses Update() because Patch Strategies are not supported for CRDs
 // https://github.com/kubernetes/kubernetes/issues/50037
-func deleteObjectBucket(ob *v1alpha1.ObjectBucket, c versioned.Interface) error {
+func deleteObjectBucket(ob *v1alpha1.ObjectBucket, legacyFinalizers []string, c bucketClient) error {
 	// skip if ob is nil or otherwise wasn't instantiated.
 	// note: the ob is returned by Provision and Grant, partially filled
 	if ob == nil || ob.ObjectMeta.UID == "" {
 		return nil
 	}
 
-	logD.Info("removing ObjectBucket finalizer", "name", ob.Name)
-	removeFinalizer(ob)
-	ob, err := c.ObjectbucketV1alpha1().ObjectBuckets().Update(ob)
-	if err != nil {
-		return err
+	// an OB which is already gone, e.g. deleted by an earlier reconcile, leaves nothing to do
+	name := ob.Name
+	var err error
+	if removeFinalizer(ob, legacyFinalizers) {
+		logD.Info("removing ObjectBucket finalizer", "name", name)
+		if ob, err = c.UpdateObjectBucket(ob); err != nil {
+			if errors.IsNotFound(err) {
+				logD.Info("ObjectBucket already deleted", "name", name)
+				return nil
+			}
+			return err
+		}
+	}
+
+	if isRetained(ob) {
+		log.Info("reclaimPolicy is Retain, keeping released ObjectBucket", "name", name)
+		return nil
 	}
 
-	logD.Info("deleting ObjectBucket", "name", ob.Name)
-	err = c.ObjectbucketV1alpha1().ObjectBuckets().Delete(ob.Name, &metav1.DeleteOptions{})
+	logD.Info("deleting ObjectBucket", "name", name)
+	err = c.DeleteObjectBucket(name)
 	if err != nil {
 		if errors.IsNotFound(err) {
-			log.Error(err, "ObjectBucket vanished before we could delete it, skipping", "name", ob.Name)
+			logD.Info("ObjectBucket already deleted", "name", name)
 			return nil
 		}
-		return fmt.Errorf("error deleting ObjectBucket %q: %v", ob.Name, err)
+		return fmt.Errorf("error deleting ObjectBucket %q: %v", name, err)
 	}
-	logD.Info("ObjectBucket deleted", "name", ob.Name)
+	logD.Info("ObjectBucket deleted", "name", name)
 	return nil
 }
 
-func updateClaim(c versioned.Interface, obc *v1alpha1.ObjectBucketClaim, retryInterval, retryTimeout time.Duration) (result *v1alpha1.ObjectBucketClaim, err error) {
+// updateClaim persists the claim's metadata and spec.  The claim's status is persisted by updateClaimStatus,
+// the API server ignores it here as the CRD enables the status subresource.  On a conflict the spec, labels and
+// finalizers are set on the latest claim, so that a concurrent edit of its other fields is kept.
+func updateClaim(ctx context.Context, c bucketClient, obc *v1alpha1.ObjectBucketClaim, retryInterval, retryTimeout time.Duration) (result *v1alpha1.ObjectBucketClaim, err error) {
+	logD := requestLogD(ctx)
 
 	logD.Info("updating", "obc", obc.Namespace+"/"+obc.Name)
-	err = wait.PollImmediate(retryInterval, retryTimeout, func() (bool, error) {
-		result, err = c.ObjectbucketV1alpha1().ObjectBucketClaims(obc.Namespace).Update(obc)
-		return (err == nil), err
+	err = pollImmediate(ctx, retryInterval, retryTimeout, func() (bool, error) {
+		result, err = c.UpdateClaim(obc)
+		if !errors.IsConflict(err) {
+			return (err == nil), err
+		}
+		logD.Info("OBC changed, updating the latest", "obc", obc.Namespace+"/"+obc.Name)
+		latest, gErr := c.GetClaim(obc.Namespace, obc.Name)
+		if gErr != nil {
+			return false, gErr
+		}
+		latest.Spec = *obc.Spec.DeepCopy()
+		latest.SetLabels(obc.GetLabels())
+		latest.SetFinalizers(obc.GetFinalizers())
+		obc = latest
+		return false, nil
+	})
+	return
+}
+
+// reconcileConfigMap restores the reserved keys of the claim's existing configMap to the values derived
+// from ep.  Returns the configMap and whether it had drifted.
+func reconcileConfigMap(ctx context.Context, obc *v1alpha1.ObjectBucketClaim, ep *v1alpha1.Endpoint, labels map[string]string, annotationPrefixes []string, opts childOptions, c kubernetes.Interface, backoff retryBackoff) (*corev1.ConfigMap, configMapDrift, error) {
+	logD := requestLogD(ctx)
+	// only the data of the desired configmap is compared
+	desired, err := newBucketConfigMap(obc, ep, labels, annotationPrefixes, opts)
+	if err != nil {
+		return nil, configMapInSync, err
+	}
+	configMap, err := configMapForClaim(obc, c)
+	if err != nil {
+		return nil, configMapInSync, err
+	}
+	if configMap.Data == nil {
+		configMap.Data = make(map[string]string, len(desired.Data))
+	}
+	drift := configMapDrifted
+	if endpointChanged(configMap.Data, desired.Data, opts.keyNames) {
+		drift = configMapEndpointChanged
+	}
+	if !syncReservedConfigMapData(configMap.Data, desired.Data, reservedKeys(opts.keyNames)) {
+		return configMap, configMapInSync, nil
+	}
+
+	if opts.immutable {
+		configMap, err = replaceConfigMap(ctx, configMap, opts.legacyFinalizers, c, backoff)
+		return configMap, drift, err
+	}
+	logD.Info("updating drifted", "configMap", configMap.Namespace+"/"+configMap.Name)
+	err = retryWithBackoff(ctx, backoff, func() (bool, error) {
+		configMap, err = c.CoreV1().ConfigMaps(configMap.Namespace).Update(configMap)
+		return err == nil, err
+	})
+	if err != nil {
+		return nil, drift, err
+	}
+	return configMap, drift, nil
+}
+
+// endpointChanged returns true if data and desired differ in one of the endpointConfigMapKeys, as renamed by
+// renames
+func endpointChanged(data, desired, renames map[string]string) bool {
+	for _, k := range endpointConfigMapKeys {
+		if name, ok := renames[k]; ok {
+			k = name
+		}
+		if data[k] != desired[k] {
+			return true
+		}
+	}
+	return false
+}
+
+// updateSecret replaces the secret's data with the given credentials.
+func updateSecret(ctx context.Context, secret *corev1.Secret, auth *v1alpha1.Authentication, opts childOptions, c kubernetes.Interface, backoff retryBackoff) (result *corev1.Secret, err error) {
+	logD := requestLogD(ctx)
+	data, err := credentialsData(auth, opts)
+	if err != nil {
+		return nil, fmt.Errorf("cannot construct secret: %v", err)
+	}
+	// stringData is merged into the existing data by the API server, set data to drop the stale keys
+	secret.Data = make(map[string][]byte, len(data))
+	for k, v := range data {
+		secret.Data[k] = []byte(v)
+	}
+	secret.StringData = nil
+	if opts.immutable {
+		return replaceSecret(ctx, secret, opts.legacyFinalizers, c, backoff)
+	}
+
+	logD.Info("updating", "secret", logSafeSecretRef(secret))
+	err = retryWithBackoff(ctx, backoff, func() (bool, error) {
+		result, err = c.CoreV1().Secrets(secret.Namespace).Update(secret)
+		return err == nil, err
 	})
 	return
 }
 
-func updateObjectBucketClaimPhase(c versioned.Interface, obc *v1alpha1.ObjectBucketClaim, phase v1alpha1.ObjectBucketClaimStatusPhase, retryInterval, retryTimeout time.Duration) (result *v1alpha1.ObjectBucketClaim, err error) {
+// updateObjectBucketClaimPhase persists the claim's new phase, counting the transition in m unless nil.
+func updateObjectBucketClaimPhase(ctx context.Context, c bucketClient, m *metrics, obc *v1alpha1.ObjectBucketClaim, phase v1alpha1.ObjectBucketClaimStatusPhase, retryInterval, retryTimeout time.Duration) (result *v1alpha1.ObjectBucketClaim, err error) {
+	logD := requestLogD(ctx)
 	logD.Info("updating status:", "obc", obc.Namespace+"/"+obc.Name, "old status",
 		obc.Status.Phase, "new status", phase)
+	from := obc.Status.Phase
 	obc.Status.Phase = phase
 
-	err = wait.PollImmediate(retryInterval, retryTimeout, func() (bool, error) {
-		result, err = c.ObjectbucketV1alpha1().ObjectBucketClaims(obc.Namespace).UpdateStatus(obc)
-		return (err == nil), err
+	result, err = updateClaimStatus(ctx, c, obc, retryInterval, retryTimeout)
+	if err == nil {
+		m.observePhaseTransition(from, phase)
+	}
+	return result, err
+}
+
+// updateClaimStatus persists the claim's status, i.e. its phase and conditions, through the status
+// subresource.  On a conflict the status is set on the latest claim, so that a concurrent edit of the
+// claim's spec or metadata is never reverted by the stale copy.
+func updateClaimStatus(ctx context.Context, c bucketClient, obc *v1alpha1.ObjectBucketClaim, retryInterval, retryTimeout time.Duration) (result *v1alpha1.ObjectBucketClaim, err error) {
+	logD := requestLogD(ctx)
+	err = pollImmediate(ctx, retryInterval, retryTimeout, func() (bool, error) {
+		result, err = c.UpdateClaimStatus(obc)
+		if !errors.IsConflict(err) {
+			return (err == nil), err
+		}
+		logD.Info("OBC changed, updating status of the latest", "obc", obc.Namespace+"/"+obc.Name)
+		latest, gErr := c.GetClaim(obc.Namespace, obc.Name)
+		if gErr != nil {
+			return false, gErr
+		}
+		latest.Status = *obc.Status.DeepCopy()
+		obc = latest
+		return false, nil
 	})
 	return
 }
 
-func updateObjectBucketPhase(c versioned.Interface, ob *v1alpha1.ObjectBucket, phase v1alpha1.ObjectBucketStatusPhase, retryInterval, retryTimeout time.Duration) (result *v1alpha1.ObjectBucket, err error) {
+func updateObjectBucketPhase(ctx context.Context, c bucketClient, ob *v1alpha1.ObjectBucket, phase v1alpha1.ObjectBucketStatusPhase, retryInterval, retryTimeout time.Duration) (result *v1alpha1.ObjectBucket, err error) {
+	logD := requestLogD(ctx)
 	logD.Info("updating status:", "ob", ob.Name, "old status", ob.Status.Phase,
 		"new status", phase)
+	if ob.Status.Phase != phase || ob.Status.LastTransitionTime == nil {
+		now := metav1.Now()
+		ob.Status.LastTransitionTime = &now
+	}
 	ob.Status.Phase = phase
 
-	err = wait.PollImmediate(retryInterval, retryTimeout, func() (bool, error) {
-		result, err = c.ObjectbucketV1alpha1().ObjectBuckets().UpdateStatus(ob)
-		return err == nil, err
+	// on a conflict the status is set on the latest OB, as by updateClaimStatus
+	err = pollImmediate(ctx, retryInterval, retryTimeout, func() (bool, error) {
+		result, err = c.UpdateObjectBucketStatus(ob)
+		if !errors.IsConflict(err) {
+			return err == nil, err
+		}
+		logD.Info("OB changed, updating status of the latest", "ob", ob.Name)
+		latest, gErr := c.GetObjectBucket(ob.Name)
+		if gErr != nil {
+			return false, gErr
+		}
+		latest.Status = *ob.Status.DeepCopy()
+		ob = latest
+		return false, nil
 	})
 	return
 }
@@ -17,18 +17,30 @@ limitations under the License.
 package provisioner
 
 import (
+	"context"
+	"fmt"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 
 	corev1 "k8s.io/api/core/v1"
 
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
 
 	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/apis/objectbucket.io/v1alpha1"
+	externalFake "github.com/kube-object-storage/lib-bucket-provisioner/pkg/client/clientset/versioned/fake"
 )
 
+// defaultChildOptions are the options of the claims' Secrets and ConfigMaps with the default ControllerOptions
+var defaultChildOptions = (&ControllerOptions{}).childOptions()
+
 func TestNewCredentialsSecret(t *testing.T) {
 	const (
 		obcName      = "obc-testname"
@@ -104,9 +116,92 @@ func TestNewCredentialsSecret(t *testing.T) {
 					v1alpha1.AwsKeyField:    authKey,
 					v1alpha1.AwsSecretField: authSecret,
 				},
+				Type: corev1.SecretTypeOpaque,
+			},
+			wantErr: false,
+		},
+		{
+			name: "with an authentication type defined (GCS HMAC keys)",
+			args: args{
+				obc: &v1alpha1.ObjectBucketClaim{
+					ObjectMeta: testObjectMeta,
+				},
+				authentication: &v1alpha1.Authentication{
+					AccessKeys: &v1alpha1.AccessKeys{
+						AccessKeyID:     "GOOG1EXAMPLE",
+						SecretAccessKey: authSecret,
+					},
+				},
+			},
+			want: &corev1.Secret{
+				ObjectMeta: testObjectMeta,
+				StringData: map[string]string{
+					v1alpha1.AwsKeyField:    "GOOG1EXAMPLE",
+					v1alpha1.AwsSecretField: authSecret,
+				},
+				Type: corev1.SecretTypeOpaque,
+			},
+			wantErr: false,
+		},
+		{
+			name: "with an authentication type defined (GCS service account JSON)",
+			args: args{
+				obc: &v1alpha1.ObjectBucketClaim{
+					ObjectMeta: testObjectMeta,
+				},
+				authentication: &v1alpha1.Authentication{
+					ServiceAccountJSON: v1alpha1.ServiceAccountJSON(`{"type": "service_account", "client_email": "sa@example.iam.gserviceaccount.com"}`),
+				},
+			},
+			want: &corev1.Secret{
+				ObjectMeta: testObjectMeta,
+				Data: map[string][]byte{
+					v1alpha1.GcsCredentialsField: []byte(`{"type": "service_account", "client_email": "sa@example.iam.gserviceaccount.com"}`),
+				},
+				Type: corev1.SecretTypeOpaque,
+			},
+			wantErr: false,
+		},
+		{
+			name: "with an authentication type defined (Azure account key)",
+			args: args{
+				obc: &v1alpha1.ObjectBucketClaim{
+					ObjectMeta: testObjectMeta,
+				},
+				authentication: &v1alpha1.Authentication{
+					AzureAccountKey: &v1alpha1.AzureAccountKey{
+						AccountKey: authSecret,
+					},
+				},
+			},
+			want: &corev1.Secret{
+				ObjectMeta: testObjectMeta,
+				StringData: map[string]string{
+					v1alpha1.AzureAccountKeyField: authSecret,
+				},
+				Type: corev1.SecretTypeOpaque,
 			},
 			wantErr: false,
 		},
+		{
+			name: "with more than one authentication type defined",
+			args: args{
+				obc: &v1alpha1.ObjectBucketClaim{
+					ObjectMeta: testObjectMeta,
+				},
+				authentication: &v1alpha1.Authentication{
+					AccessKeys: &v1alpha1.AccessKeys{
+						AccessKeyID:     authKey,
+						SecretAccessKey: authSecret,
+					},
+					PlainToken: &v1alpha1.PlainToken{
+						Token: authSecret,
+					},
+				},
+			},
+			want:    nil,
+			wantErr: true,
+		},
 		{
 			name: "with empty access keys",
 			args: args{
@@ -126,6 +221,75 @@ func TestNewCredentialsSecret(t *testing.T) {
 					v1alpha1.AwsKeyField:    "",
 					v1alpha1.AwsSecretField: "",
 				},
+				Type: corev1.SecretTypeOpaque,
+			},
+			wantErr: false,
+		},
+		{
+			name: "with a token",
+			args: args{
+				obc: &v1alpha1.ObjectBucketClaim{
+					ObjectMeta: testObjectMeta,
+				},
+				authentication: &v1alpha1.Authentication{
+					PlainToken: &v1alpha1.PlainToken{
+						Token: authSecret,
+					},
+				},
+			},
+			want: &corev1.Secret{
+				ObjectMeta: testObjectMeta,
+				StringData: map[string]string{
+					v1alpha1.TokenField: authSecret,
+				},
+				Type: corev1.SecretTypeOpaque,
+			},
+			wantErr: false,
+		},
+		{
+			name: "with username and password",
+			args: args{
+				obc: &v1alpha1.ObjectBucketClaim{
+					ObjectMeta: testObjectMeta,
+				},
+				authentication: &v1alpha1.Authentication{
+					UserPass: &v1alpha1.UserPass{
+						Username: authKey,
+						Password: authSecret,
+					},
+				},
+			},
+			want: &corev1.Secret{
+				ObjectMeta: testObjectMeta,
+				StringData: map[string]string{
+					corev1.BasicAuthUsernameKey: authKey,
+					corev1.BasicAuthPasswordKey: authSecret,
+				},
+				Type: corev1.SecretTypeBasicAuth,
+			},
+			wantErr: false,
+		},
+		{
+			name: "with an overridden secret type",
+			args: args{
+				obc: &v1alpha1.ObjectBucketClaim{
+					ObjectMeta: testObjectMeta,
+				},
+				authentication: &v1alpha1.Authentication{
+					UserPass: &v1alpha1.UserPass{
+						Username: authKey,
+						Password: authSecret,
+					},
+					Type: "example.com/credentials",
+				},
+			},
+			want: &corev1.Secret{
+				ObjectMeta: testObjectMeta,
+				StringData: map[string]string{
+					corev1.BasicAuthUsernameKey: authKey,
+					corev1.BasicAuthPasswordKey: authSecret,
+				},
+				Type: "example.com/credentials",
 			},
 			wantErr: false,
 		},
@@ -133,7 +297,7 @@ func TestNewCredentialsSecret(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := newCredentialsSecret(tt.args.obc, tt.args.authentication, dummyLabels)
+			got, err := newCredentialsSecret(tt.args.obc, tt.args.authentication, dummyLabels, nil, defaultChildOptions)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("NewCredentailsSecret() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -145,6 +309,94 @@ func TestNewCredentialsSecret(t *testing.T) {
 	}
 }
 
+func TestNewCredentialsSecretReference(t *testing.T) {
+	obc := &v1alpha1.ObjectBucketClaim{ObjectMeta: metav1.ObjectMeta{Name: testName, Namespace: testNamespace}}
+	opts := (&ControllerOptions{CredentialsReferences: true}).childOptions()
+
+	tests := []struct {
+		name     string
+		auth     *v1alpha1.Authentication
+		keyNames *KeyNames
+		want     map[string]string
+		wantErr  bool
+	}{
+		{
+			name: "reference replaces the credentials",
+			auth: &v1alpha1.Authentication{
+				AccessKeys: &v1alpha1.AccessKeys{AccessKeyID: "id", SecretAccessKey: "key"},
+				SecretReference: &v1alpha1.SecretReference{
+					Path: "secret/buckets/test",
+					Keys: map[string]string{v1alpha1.AwsKeyField: "access_key", v1alpha1.AwsSecretField: "secret_key"},
+				},
+			},
+			want: map[string]string{
+				v1alpha1.SecretReferencePathField: "secret/buckets/test",
+				v1alpha1.AwsKeyField:              "access_key",
+				v1alpha1.AwsSecretField:           "secret_key",
+			},
+		},
+		{
+			name: "reference keys are renamed",
+			auth: &v1alpha1.Authentication{
+				SecretReference: &v1alpha1.SecretReference{
+					Path: "secret/buckets/test",
+					Keys: map[string]string{v1alpha1.AwsKeyField: "access_key"},
+				},
+			},
+			keyNames: &KeyNames{AccessKeyID: "ACCESS_KEY_ID"},
+			want: map[string]string{
+				v1alpha1.SecretReferencePathField: "secret/buckets/test",
+				"ACCESS_KEY_ID":                   "access_key",
+			},
+		},
+		{
+			name:    "missing reference",
+			auth:    &v1alpha1.Authentication{AccessKeys: &v1alpha1.AccessKeys{AccessKeyID: "id", SecretAccessKey: "key"}},
+			wantErr: true,
+		},
+		{
+			name:    "reference without path",
+			auth:    &v1alpha1.Authentication{SecretReference: &v1alpha1.SecretReference{}},
+			wantErr: true,
+		},
+		{
+			name: "reference key shadowing the path",
+			auth: &v1alpha1.Authentication{
+				SecretReference: &v1alpha1.SecretReference{
+					Path: "secret/buckets/test",
+					Keys: map[string]string{v1alpha1.SecretReferencePathField: "path"},
+				},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := opts
+			opts.keyNames = tt.keyNames.renames()
+			got, err := newCredentialsSecret(obc, tt.auth, nil, nil, opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newCredentialsSecret() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if diff := cmp.Diff(tt.want, got.StringData); diff != "" {
+				t.Errorf("secret data (-want +got):\n%s", diff)
+			}
+			if got.Data != nil {
+				t.Errorf("want no raw credentials, got %v", got.Data)
+			}
+			if got.Annotations[v1alpha1.CredentialsReferenceAnnotation] != "true" {
+				t.Errorf("want secret marked as a reference, got annotations %v", got.Annotations)
+			}
+			if got.Type != corev1.SecretTypeOpaque {
+				t.Errorf("want type %q, got %q", corev1.SecretTypeOpaque, got.Type)
+			}
+		})
+	}
+}
+
 func TestNewBucketConfigMap(t *testing.T) {
 
 	const (
@@ -154,6 +406,7 @@ func TestNewBucketConfigMap(t *testing.T) {
 		port      = 11111
 		region    = "region"
 		subRegion = "sub-region"
+		caBundle  = "-----BEGIN CERTIFICATE-----"
 	)
 	var isTrue = true
 
@@ -210,6 +463,8 @@ func TestNewBucketConfigMap(t *testing.T) {
 					bucketPort:      strconv.Itoa(port),
 					bucketRegion:    region,
 					bucketSubRegion: subRegion,
+					bucketURL:       "http://www.test.com:11111",
+					bucketEndpoint:  "http://www.test.com:11111",
 				},
 			},
 			wantErr: false,
@@ -239,6 +494,8 @@ func TestNewBucketConfigMap(t *testing.T) {
 					bucketPort:      strconv.Itoa(port),
 					bucketRegion:    region,
 					bucketSubRegion: "",
+					bucketURL:       "http://www.test.com:11111",
+					bucketEndpoint:  "http://www.test.com:11111",
 				},
 			},
 			wantErr: false,
@@ -268,18 +525,1387 @@ func TestNewBucketConfigMap(t *testing.T) {
 					bucketPort:      strconv.Itoa(port),
 					bucketRegion:    region,
 					bucketSubRegion: subRegion,
+					bucketURL:       "http://www.test.com:11111",
+					bucketEndpoint:  "http://www.test.com:11111",
 				},
 			},
 			wantErr: false,
 		},
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			got, err := newBucketConfigMap(tt.args.obc, tt.args.ep, dummyLabels)
-			if (err != nil) == !tt.wantErr {
-				t.Errorf("newBucketConfigMap() error = %v, wantErr %v", err, tt.wantErr)
-			} else if !cmp.Equal(tt.want, got) {
-				t.Errorf(cmp.Diff(tt.want, got))
+		{
+			name: "with additional config data",
+			args: args{
+				ep: &v1alpha1.Endpoint{
+					BucketHost: host,
+					BucketPort: port,
+					BucketName: name,
+					Region:     region,
+					SubRegion:  subRegion,
+					AdditionalConfigData: map[string]string{
+						"BUCKET_TENANT": "tenant",
+					},
+				},
+				obc: &v1alpha1.ObjectBucketClaim{
+					ObjectMeta: objMeta,
+					Spec: v1alpha1.ObjectBucketClaimSpec{
+						BucketName: name,
+					},
+				},
+			},
+			want: &corev1.ConfigMap{
+				ObjectMeta: objMeta,
+				Data: map[string]string{
+					bucketName:      name,
+					bucketHost:      host,
+					bucketPort:      strconv.Itoa(port),
+					bucketRegion:    region,
+					bucketSubRegion: subRegion,
+					bucketURL:       "http://www.test.com:11111",
+					bucketEndpoint:  "http://www.test.com:11111",
+					"BUCKET_TENANT": "tenant",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "with bucket subpath",
+			args: args{
+				ep: &v1alpha1.Endpoint{
+					BucketHost: host,
+					BucketPort: port,
+					BucketName: name,
+					Region:     region,
+					SubRegion:  subRegion,
+				},
+				obc: &v1alpha1.ObjectBucketClaim{
+					ObjectMeta: objMeta,
+					Spec: v1alpha1.ObjectBucketClaimSpec{
+						BucketName:    name,
+						BucketSubPath: "team-a/photos",
+					},
+				},
+			},
+			want: &corev1.ConfigMap{
+				ObjectMeta: objMeta,
+				Data: map[string]string{
+					bucketName:      name,
+					bucketHost:      host,
+					bucketPort:      strconv.Itoa(port),
+					bucketRegion:    region,
+					bucketSubRegion: subRegion,
+					bucketURL:       "http://www.test.com:11111",
+					bucketEndpoint:  "http://www.test.com:11111",
+					bucketSubPath:   "team-a/photos",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "with bucket versioning",
+			args: args{
+				ep: &v1alpha1.Endpoint{
+					BucketHost: host,
+					BucketPort: port,
+					BucketName: name,
+					Region:     region,
+					SubRegion:  subRegion,
+					Versioning: true,
+				},
+				obc: &v1alpha1.ObjectBucketClaim{
+					ObjectMeta: objMeta,
+				},
+			},
+			want: &corev1.ConfigMap{
+				ObjectMeta: objMeta,
+				Data: map[string]string{
+					bucketName:       name,
+					bucketHost:       host,
+					bucketPort:       strconv.Itoa(port),
+					bucketRegion:     region,
+					bucketSubRegion:  subRegion,
+					bucketURL:        "http://www.test.com:11111",
+					bucketEndpoint:   "http://www.test.com:11111",
+					bucketVersioning: "true",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "ssl endpoint with ca bundle",
+			args: args{
+				ep: &v1alpha1.Endpoint{
+					BucketHost: host,
+					BucketPort: port,
+					BucketName: name,
+					SSL:        true,
+					CABundle:   caBundle,
+				},
+				obc: &v1alpha1.ObjectBucketClaim{
+					ObjectMeta: objMeta,
+				},
+			},
+			want: &corev1.ConfigMap{
+				ObjectMeta: objMeta,
+				Data: map[string]string{
+					bucketName:      name,
+					bucketHost:      host,
+					bucketPort:      strconv.Itoa(port),
+					bucketRegion:    "",
+					bucketSubRegion: "",
+					bucketURL:       "https://www.test.com:11111",
+					bucketEndpoint:  "https://www.test.com:11111",
+					bucketSSL:       "true",
+					bucketCACert:    caBundle,
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "ssl endpoint on the default port",
+			args: args{
+				ep: &v1alpha1.Endpoint{
+					BucketHost: host,
+					BucketPort: 443,
+					BucketName: name,
+					SSL:        true,
+				},
+				obc: &v1alpha1.ObjectBucketClaim{
+					ObjectMeta: objMeta,
+				},
+			},
+			want: &corev1.ConfigMap{
+				ObjectMeta: objMeta,
+				Data: map[string]string{
+					bucketName:      name,
+					bucketHost:      host,
+					bucketPort:      "443",
+					bucketRegion:    "",
+					bucketSubRegion: "",
+					bucketURL:       "https://www.test.com",
+					bucketEndpoint:  "https://www.test.com",
+					bucketSSL:       "true",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "non ssl endpoint with ca bundle",
+			args: args{
+				ep: &v1alpha1.Endpoint{
+					BucketHost: host,
+					BucketPort: port,
+					BucketName: name,
+					SSL:        false,
+					CABundle:   caBundle,
+				},
+				obc: &v1alpha1.ObjectBucketClaim{
+					ObjectMeta: objMeta,
+				},
+			},
+			want: &corev1.ConfigMap{
+				ObjectMeta: objMeta,
+				Data: map[string]string{
+					bucketName:      name,
+					bucketHost:      host,
+					bucketPort:      strconv.Itoa(port),
+					bucketRegion:    "",
+					bucketSubRegion: "",
+					bucketURL:       "http://www.test.com:11111",
+					bucketEndpoint:  "http://www.test.com:11111",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "ssl endpoint with tls minimum version",
+			args: args{
+				ep: &v1alpha1.Endpoint{
+					BucketHost:    host,
+					BucketPort:    port,
+					BucketName:    name,
+					SSL:           true,
+					TLSMinVersion: "1.2",
+				},
+				obc: &v1alpha1.ObjectBucketClaim{
+					ObjectMeta: objMeta,
+				},
+			},
+			want: &corev1.ConfigMap{
+				ObjectMeta: objMeta,
+				Data: map[string]string{
+					bucketName:      name,
+					bucketHost:      host,
+					bucketPort:      strconv.Itoa(port),
+					bucketRegion:    "",
+					bucketSubRegion: "",
+					bucketURL:       "https://www.test.com:11111",
+					bucketEndpoint:  "https://www.test.com:11111",
+					bucketSSL:       "true",
+					bucketTLSMin:    "1.2",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "non ssl endpoint with tls minimum version",
+			args: args{
+				ep: &v1alpha1.Endpoint{
+					BucketHost:    host,
+					BucketPort:    port,
+					BucketName:    name,
+					SSL:           false,
+					TLSMinVersion: "1.2",
+				},
+				obc: &v1alpha1.ObjectBucketClaim{
+					ObjectMeta: objMeta,
+				},
+			},
+			want: &corev1.ConfigMap{
+				ObjectMeta: objMeta,
+				Data: map[string]string{
+					bucketName:      name,
+					bucketHost:      host,
+					bucketPort:      strconv.Itoa(port),
+					bucketRegion:    "",
+					bucketSubRegion: "",
+					bucketURL:       "http://www.test.com:11111",
+					bucketEndpoint:  "http://www.test.com:11111",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid tls minimum version",
+			args: args{
+				ep: &v1alpha1.Endpoint{
+					BucketHost:    host,
+					BucketPort:    port,
+					BucketName:    name,
+					SSL:           true,
+					TLSMinVersion: "TLS1.2",
+				},
+				obc: &v1alpha1.ObjectBucketClaim{
+					ObjectMeta: objMeta,
+				},
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "ssl endpoint without ca bundle",
+			args: args{
+				ep: &v1alpha1.Endpoint{
+					BucketHost: host,
+					BucketPort: port,
+					BucketName: name,
+					SSL:        true,
+				},
+				obc: &v1alpha1.ObjectBucketClaim{
+					ObjectMeta: objMeta,
+				},
+			},
+			want: &corev1.ConfigMap{
+				ObjectMeta: objMeta,
+				Data: map[string]string{
+					bucketName:      name,
+					bucketHost:      host,
+					bucketPort:      strconv.Itoa(port),
+					bucketRegion:    "",
+					bucketSubRegion: "",
+					bucketURL:       "https://www.test.com:11111",
+					bucketEndpoint:  "https://www.test.com:11111",
+					bucketSSL:       "true",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "path-style endpoint",
+			args: args{
+				ep: &v1alpha1.Endpoint{
+					BucketHost: host,
+					BucketPort: port,
+					BucketName: name,
+					PathStyle:  true,
+				},
+				obc: &v1alpha1.ObjectBucketClaim{
+					ObjectMeta: objMeta,
+				},
+			},
+			want: &corev1.ConfigMap{
+				ObjectMeta: objMeta,
+				Data: map[string]string{
+					bucketName:      name,
+					bucketHost:      host,
+					bucketPort:      strconv.Itoa(port),
+					bucketRegion:    "",
+					bucketSubRegion: "",
+					bucketURL:       "http://www.test.com:11111",
+					bucketEndpoint:  "http://www.test.com:11111",
+					bucketPathStyle: "true",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "virtual-hosted-style endpoint with an IP host uses path-style",
+			args: args{
+				ep: &v1alpha1.Endpoint{
+					BucketHost: "10.0.0.1",
+					BucketPort: port,
+					BucketName: name,
+				},
+				obc: &v1alpha1.ObjectBucketClaim{
+					ObjectMeta: objMeta,
+				},
+			},
+			want: &corev1.ConfigMap{
+				ObjectMeta: objMeta,
+				Data: map[string]string{
+					bucketName:      name,
+					bucketHost:      "10.0.0.1",
+					bucketPort:      strconv.Itoa(port),
+					bucketRegion:    "",
+					bucketSubRegion: "",
+					bucketURL:       "http://10.0.0.1:11111",
+					bucketEndpoint:  "http://10.0.0.1:11111",
+					bucketPathStyle: "true",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "endpoint without port",
+			args: args{
+				ep: &v1alpha1.Endpoint{
+					BucketHost: host,
+					BucketName: name,
+				},
+				obc: &v1alpha1.ObjectBucketClaim{
+					ObjectMeta: objMeta,
+				},
+			},
+			want: &corev1.ConfigMap{
+				ObjectMeta: objMeta,
+				Data: map[string]string{
+					bucketName:      name,
+					bucketHost:      host,
+					bucketRegion:    "",
+					bucketSubRegion: "",
+					bucketURL:       "http://www.test.com",
+					bucketEndpoint:  "http://www.test.com",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Azure endpoint",
+			args: args{
+				ep: &v1alpha1.Endpoint{
+					Kind:           v1alpha1.EndpointKindAzure,
+					BucketName:     name,
+					AccountName:    "account",
+					EndpointSuffix: "core.usgovcloudapi.net",
+					Region:         region,
+				},
+				obc: &v1alpha1.ObjectBucketClaim{
+					ObjectMeta: objMeta,
+				},
+			},
+			want: &corev1.ConfigMap{
+				ObjectMeta: objMeta,
+				Data: map[string]string{
+					bucketName:          name,
+					azureStorageAccount: "account",
+					azureContainer:      name,
+					azureEndpointSuffix: "core.usgovcloudapi.net",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Azure endpoint without endpoint suffix",
+			args: args{
+				ep: &v1alpha1.Endpoint{
+					Kind:        v1alpha1.EndpointKindAzure,
+					BucketName:  name,
+					AccountName: "account",
+				},
+				obc: &v1alpha1.ObjectBucketClaim{
+					ObjectMeta: objMeta,
+				},
+			},
+			want: &corev1.ConfigMap{
+				ObjectMeta: objMeta,
+				Data: map[string]string{
+					bucketName:          name,
+					azureStorageAccount: "account",
+					azureContainer:      name,
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Azure endpoint without account name",
+			args: args{
+				ep: &v1alpha1.Endpoint{
+					Kind:       v1alpha1.EndpointKindAzure,
+					BucketName: name,
+				},
+				obc: &v1alpha1.ObjectBucketClaim{
+					ObjectMeta: objMeta,
+				},
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "endpoint of an unknown kind",
+			args: args{
+				ep: &v1alpha1.Endpoint{
+					Kind:       "Swift",
+					BucketHost: host,
+					BucketName: name,
+				},
+				obc: &v1alpha1.ObjectBucketClaim{
+					ObjectMeta: objMeta,
+				},
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "with additional config data colliding with a reserved key",
+			args: args{
+				ep: &v1alpha1.Endpoint{
+					BucketHost: host,
+					BucketPort: port,
+					BucketName: name,
+					AdditionalConfigData: map[string]string{
+						bucketHost: "http://www.other.com",
+					},
+				},
+				obc: &v1alpha1.ObjectBucketClaim{
+					ObjectMeta: objMeta,
+				},
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "with claim config data below the provisioner's",
+			args: args{
+				ep: &v1alpha1.Endpoint{
+					BucketHost: host,
+					BucketPort: port,
+					BucketName: name,
+					AdditionalConfigData: map[string]string{
+						"BUCKET_TENANT": "tenant",
+					},
+				},
+				obc: &v1alpha1.ObjectBucketClaim{
+					ObjectMeta: objMeta,
+					Spec: v1alpha1.ObjectBucketClaimSpec{
+						ExtraConfigMapData: map[string]string{
+							"APP_CACHE_DIR": "app/data",
+							"BUCKET_TENANT": "other-tenant",
+						},
+					},
+				},
+			},
+			want: &corev1.ConfigMap{
+				ObjectMeta: objMeta,
+				Data: map[string]string{
+					bucketName:      name,
+					bucketHost:      host,
+					bucketPort:      strconv.Itoa(port),
+					bucketRegion:    "",
+					bucketSubRegion: "",
+					bucketURL:       "http://www.test.com:11111",
+					bucketEndpoint:  "http://www.test.com:11111",
+					"BUCKET_TENANT": "tenant",
+					"APP_CACHE_DIR": "app/data",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "with claim config data colliding with a reserved key",
+			args: args{
+				ep: &v1alpha1.Endpoint{
+					BucketHost: host,
+					BucketPort: port,
+					BucketName: name,
+				},
+				obc: &v1alpha1.ObjectBucketClaim{
+					ObjectMeta: objMeta,
+					Spec: v1alpha1.ObjectBucketClaimSpec{
+						ExtraConfigMapData: map[string]string{
+							bucketName: "other-bucket",
+						},
+					},
+				},
+			},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newBucketConfigMap(tt.args.obc, tt.args.ep, dummyLabels, nil, defaultChildOptions)
+			if (err != nil) == !tt.wantErr {
+				t.Errorf("newBucketConfigMap() error = %v, wantErr %v", err, tt.wantErr)
+			} else if !cmp.Equal(tt.want, got) {
+				t.Errorf(cmp.Diff(tt.want, got))
+			}
+		})
+	}
+}
+
+func TestNewBucketConfigMapOmitEmptyRegion(t *testing.T) {
+	obc := &v1alpha1.ObjectBucketClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: testName, Namespace: testNamespace},
+	}
+	tests := []struct {
+		name          string
+		options       ControllerOptions
+		ep            v1alpha1.Endpoint
+		wantRegion    bool
+		wantSubRegion bool
+	}{
+		{
+			name:          "empty keys emitted by default",
+			ep:            v1alpha1.Endpoint{BucketHost: "s3.example.com", BucketName: "bucket"},
+			wantRegion:    true,
+			wantSubRegion: true,
+		},
+		{
+			name:          "empty subregion omitted",
+			options:       ControllerOptions{OmitEmptySubRegion: true},
+			ep:            v1alpha1.Endpoint{BucketHost: "s3.example.com", BucketName: "bucket"},
+			wantRegion:    true,
+			wantSubRegion: false,
+		},
+		{
+			name:          "empty region and subregion omitted",
+			options:       ControllerOptions{OmitEmptySubRegion: true, OmitEmptyRegion: true},
+			ep:            v1alpha1.Endpoint{BucketHost: "s3.example.com", BucketName: "bucket"},
+			wantRegion:    false,
+			wantSubRegion: false,
+		},
+		{
+			name:          "set region and subregion emitted",
+			options:       ControllerOptions{OmitEmptySubRegion: true, OmitEmptyRegion: true},
+			ep:            v1alpha1.Endpoint{BucketHost: "s3.example.com", BucketName: "bucket", Region: "us-east-1", SubRegion: "zone-a"},
+			wantRegion:    true,
+			wantSubRegion: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cm, err := newBucketConfigMap(obc, &tt.ep, nil, nil, tt.options.childOptions())
+			if err != nil {
+				t.Fatalf("newBucketConfigMap() error = %v", err)
+			}
+			if region, ok := cm.Data[bucketRegion]; ok != tt.wantRegion || region != tt.ep.Region {
+				t.Errorf("want %s present %v, got %q present %v", bucketRegion, tt.wantRegion, region, ok)
+			}
+			if subRegion, ok := cm.Data[bucketSubRegion]; ok != tt.wantSubRegion || subRegion != tt.ep.SubRegion {
+				t.Errorf("want %s present %v, got %q present %v", bucketSubRegion, tt.wantSubRegion, subRegion, ok)
+			}
+		})
+	}
+}
+
+func TestChildClaimUIDLabel(t *testing.T) {
+	obc := &v1alpha1.ObjectBucketClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: testName, Namespace: testNamespace, UID: "test-uid"},
+	}
+	ep := &v1alpha1.Endpoint{BucketHost: "s3.example.com", BucketName: "bucket"}
+	auth := &v1alpha1.Authentication{AccessKeys: &v1alpha1.AccessKeys{AccessKeyID: "id", SecretAccessKey: "key"}}
+
+	for _, label := range []bool{false, true} {
+		provisionerLabels := map[string]string{provisionerLabelKey: provisionerName}
+		opts := (&ControllerOptions{ClaimUIDLabel: label}).childOptions()
+		cm, err := newBucketConfigMap(obc, ep, provisionerLabels, nil, opts)
+		if err != nil {
+			t.Fatalf("newBucketConfigMap() error = %v", err)
+		}
+		secret, err := newCredentialsSecret(obc, auth, provisionerLabels, nil, opts)
+		if err != nil {
+			t.Fatalf("newCredentialsSecret() error = %v", err)
+		}
+		for _, labels := range []map[string]string{cm.Labels, secret.Labels} {
+			uid, ok := labels[v1alpha1.ClaimUIDLabel]
+			if ok != label || (label && uid != "test-uid") {
+				t.Errorf("with ClaimUIDLabel %v, got labels %v", label, labels)
+			}
+			if labels[provisionerLabelKey] != provisionerName {
+				t.Errorf("want the provisioner label kept, got %v", labels)
+			}
+		}
+		if _, ok := provisionerLabels[v1alpha1.ClaimUIDLabel]; ok {
+			t.Errorf("want the provisioner labels left alone, got %v", provisionerLabels)
+		}
+	}
+}
+
+func TestUpdateObjectBucketPhaseTransitionTime(t *testing.T) {
+	libClient := externalFake.NewSimpleClientset()
+	ob, err := libClient.ObjectbucketV1alpha1().ObjectBuckets().Create(&v1alpha1.ObjectBucket{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-ob"},
+	})
+	if err != nil {
+		t.Fatalf("error pre-creating OB: %v", err)
+	}
+
+	var last *metav1.Time
+	for _, step := range []struct {
+		phase   v1alpha1.ObjectBucketStatusPhase
+		stamped bool
+	}{
+		{phase: v1alpha1.ObjectBucketStatusPhaseBound, stamped: true},
+		{phase: v1alpha1.ObjectBucketStatusPhaseBound, stamped: false},
+		{phase: v1alpha1.ObjectBucketStatusPhaseReleased, stamped: true},
+	} {
+		ob, err = updateObjectBucketPhase(context.Background(), newBucketClient(libClient), ob, step.phase, time.Millisecond, time.Millisecond*10)
+		if err != nil {
+			t.Fatalf("updateObjectBucketPhase(%q) error = %v", step.phase, err)
+		}
+		got := ob.Status.LastTransitionTime
+		if got == nil {
+			t.Fatalf("updateObjectBucketPhase(%q) left the transition time unset", step.phase)
+		}
+		if last != nil {
+			if got.Before(last) {
+				t.Errorf("updateObjectBucketPhase(%q) moved the transition time back from %v to %v", step.phase, last, got)
+			}
+			if !step.stamped && !got.Equal(last) {
+				t.Errorf("updateObjectBucketPhase(%q) changed the transition time without a phase change", step.phase)
+			}
+		}
+		last = got
+	}
+}
+
+func TestUpdateClaimStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		// userEdit, if set, is applied to the claim's spec after the controller read it
+		userEdit  func(*v1alpha1.ObjectBucketClaim)
+		wantSpec  v1alpha1.ObjectBucketClaimSpec
+		wantCalls int
+	}{
+		{
+			name:      "status only",
+			wantSpec:  v1alpha1.ObjectBucketClaimSpec{StorageClassName: className},
+			wantCalls: 1,
+		},
+		{
+			name: "concurrent spec edit is kept",
+			userEdit: func(obc *v1alpha1.ObjectBucketClaim) {
+				obc.Spec.ExtraConfigMapData = map[string]string{"prefix": "photos"}
+			},
+			wantSpec: v1alpha1.ObjectBucketClaimSpec{
+				StorageClassName:   className,
+				ExtraConfigMapData: map[string]string{"prefix": "photos"},
+			},
+			wantCalls: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			libClient := externalFake.NewSimpleClientset()
+			claims := libClient.ObjectbucketV1alpha1().ObjectBucketClaims(testNamespace)
+			stale, err := claims.Create(&v1alpha1.ObjectBucketClaim{
+				ObjectMeta: metav1.ObjectMeta{Name: testName, Namespace: testNamespace, ResourceVersion: "1"},
+				Spec:       v1alpha1.ObjectBucketClaimSpec{StorageClassName: className},
+			})
+			if err != nil {
+				t.Fatalf("error pre-creating OBC: %v", err)
+			}
+			current := stale.ResourceVersion
+			if tt.userEdit != nil {
+				edited := stale.DeepCopy()
+				tt.userEdit(edited)
+				edited.ResourceVersion = "2"
+				if _, err = claims.Update(edited); err != nil {
+					t.Fatalf("error editing OBC: %v", err)
+				}
+				current = edited.ResourceVersion
+			}
+			// the fake clientset neither checks resource versions nor restricts status updates to the status
+			var calls int
+			libClient.PrependReactor("update", "objectbucketclaims", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				if action.GetSubresource() != "status" {
+					t.Errorf("want status subresource update, got %q", action.GetSubresource())
+				}
+				calls++
+				obc := action.(k8stesting.UpdateAction).GetObject().(*v1alpha1.ObjectBucketClaim)
+				if obc.ResourceVersion != current {
+					return true, nil, errors.NewConflict(v1alpha1.Resource("objectbucketclaims"), obc.Name, fmt.Errorf("stale"))
+				}
+				return false, nil, nil
+			})
+
+			stale.Status.Phase = v1alpha1.ObjectBucketClaimStatusPhaseBound
+			if _, err = updateClaimStatus(context.Background(), newBucketClient(libClient), stale, time.Millisecond, time.Millisecond*10); err != nil {
+				t.Fatalf("updateClaimStatus() error = %v", err)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("want %d status updates, got %d", tt.wantCalls, calls)
+			}
+			got, err := claims.Get(testName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("error getting OBC: %v", err)
+			}
+			if got.Status.Phase != v1alpha1.ObjectBucketClaimStatusPhaseBound {
+				t.Errorf("want phase %q, got %q", v1alpha1.ObjectBucketClaimStatusPhaseBound, got.Status.Phase)
+			}
+			if diff := cmp.Diff(tt.wantSpec, got.Spec); diff != "" {
+				t.Errorf("spec mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestUpdateRetriesOnConflict(t *testing.T) {
+	const obName = "obc-" + testNamespace + "-" + testName
+	conflict := errors.NewConflict(v1alpha1.SchemeGroupVersion.WithResource("objectbuckets").GroupResource(), obName, fmt.Errorf("changed"))
+
+	tests := []struct {
+		name      string
+		errs      map[string][]error
+		wantErr   bool
+		wantCalls []string
+	}{
+		{
+			name: "conflict then success",
+			errs: map[string][]error{
+				"UpdateClaim":              {conflict},
+				"UpdateObjectBucketStatus": {conflict},
+			},
+			wantCalls: []string{"UpdateClaim", "GetClaim", "UpdateClaim", "UpdateObjectBucketStatus", "GetObjectBucket", "UpdateObjectBucketStatus"},
+		},
+		{
+			name: "other errors are returned",
+			errs: map[string][]error{
+				"UpdateClaim": {fmt.Errorf("connection refused")},
+			},
+			wantErr:   true,
+			wantCalls: []string{"UpdateClaim"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			m := newMockBucketClient()
+			m.errs = tt.errs
+			// the claim and OB as changed concurrently, after the controller read them
+			m.obcs[testNamespace+"/"+testName] = &v1alpha1.ObjectBucketClaim{
+				ObjectMeta: metav1.ObjectMeta{Name: testName, Namespace: testNamespace, Annotations: map[string]string{"team": "a"}},
+			}
+			m.obs[obName] = &v1alpha1.ObjectBucket{
+				ObjectMeta: metav1.ObjectMeta{Name: obName, Annotations: map[string]string{"team": "a"}},
+			}
+
+			obc := &v1alpha1.ObjectBucketClaim{
+				ObjectMeta: metav1.ObjectMeta{Name: testName, Namespace: testNamespace, Finalizers: []string{finalizer}},
+				Spec:       v1alpha1.ObjectBucketClaimSpec{StorageClassName: className},
+			}
+			_, err := updateClaim(ctx, m, obc, time.Millisecond, time.Millisecond*10)
+			if err == nil {
+				ob := &v1alpha1.ObjectBucket{ObjectMeta: metav1.ObjectMeta{Name: obName}}
+				_, err = updateObjectBucketPhase(ctx, m, ob, v1alpha1.ObjectBucketStatusPhaseBound, time.Millisecond, time.Millisecond*10)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if diff := cmp.Diff(tt.wantCalls, m.calls); diff != "" {
+				t.Errorf("calls mismatch (-want +got):\n%s", diff)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			// the desired changes are applied to the latest objects, keeping their concurrent changes
+			gotOBC := m.obcs[testNamespace+"/"+testName]
+			if gotOBC.Spec.StorageClassName != className || !hasFinalizer(gotOBC) || gotOBC.Annotations["team"] != "a" {
+				t.Errorf("want the claim's spec and finalizer on the latest claim, got %+v", gotOBC)
+			}
+			gotOB := m.obs[obName]
+			if gotOB.Status.Phase != v1alpha1.ObjectBucketStatusPhaseBound || gotOB.Status.LastTransitionTime == nil || gotOB.Annotations["team"] != "a" {
+				t.Errorf("want the Bound phase on the latest OB, got %+v", gotOB)
+			}
+		})
+	}
+}
+
+func TestRetryWithBackoff(t *testing.T) {
+	b := retryBackoff{
+		interval:    time.Millisecond * 10,
+		factor:      2,
+		maxInterval: time.Second,
+		timeout:     time.Millisecond * 200,
+	}
+
+	// waits of 10, 20, 40 and 80ms fit in the timeout, the following wait of 160ms does not.
+	const wantCalls = 5
+	var calls []time.Time
+	err := retryWithBackoff(context.Background(), b, func() (bool, error) {
+		calls = append(calls, time.Now())
+		return false, nil
+	})
+	if err != wait.ErrWaitTimeout {
+		t.Errorf("want error %v, got %v", wait.ErrWaitTimeout, err)
+	}
+	if len(calls) != wantCalls {
+		t.Fatalf("want %d calls, got %d", wantCalls, len(calls))
+	}
+	for i := 2; i < len(calls); i++ {
+		prev, cur := calls[i-1].Sub(calls[i-2]), calls[i].Sub(calls[i-1])
+		if cur <= prev {
+			t.Errorf("want interval %d to be greater than %v, got %v", i, prev, cur)
+		}
+	}
+}
+
+func TestCreateSecretCancelled(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("create", "secrets", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, fmt.Errorf("injected error")
+	})
+	obc := &v1alpha1.ObjectBucketClaim{ObjectMeta: metav1.ObjectMeta{Name: "test-obc", Namespace: "test-ns"}}
+	b := retryBackoff{
+		interval: time.Millisecond * 10,
+		timeout:  time.Minute,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(time.Millisecond*50, cancel)
+
+	start := time.Now()
+	_, err := createSecret(ctx, obc, &v1alpha1.Authentication{}, nil, nil, defaultChildOptions, client, newFakeChildApplier(client), b)
+	if err != context.Canceled {
+		t.Errorf("want error %v, got %v", context.Canceled, err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("want return shortly after cancellation, took %v", elapsed)
+	}
+}
+
+func TestRetryBackoff_next(t *testing.T) {
+	b := retryBackoff{
+		interval:    time.Second,
+		factor:      3,
+		maxInterval: time.Second * 5,
+	}
+	want := []time.Duration{time.Second * 3, time.Second * 5, time.Second * 5}
+	delay := b.interval
+	for i, w := range want {
+		delay = b.next(delay)
+		if delay != w {
+			t.Errorf("step %d: want %v, got %v", i, w, delay)
+		}
+	}
+}
+
+func TestCreateExistingResources(t *testing.T) {
+	obc := &v1alpha1.ObjectBucketClaim{ObjectMeta: metav1.ObjectMeta{Name: testName, Namespace: testNamespace, UID: "obc-uid"}}
+	other := &v1alpha1.ObjectBucketClaim{ObjectMeta: metav1.ObjectMeta{Name: testName, Namespace: testNamespace, UID: "other-uid"}}
+	b := retryBackoff{
+		interval: time.Millisecond,
+		timeout:  time.Millisecond * 10,
+	}
+
+	tests := []struct {
+		name     string
+		existing *v1alpha1.ObjectBucketClaim
+		wantErr  bool
+	}{
+		{
+			name:     "resources owned by the OBC are adopted",
+			existing: obc,
+			wantErr:  false,
+		},
+		{
+			name:     "resources owned by another OBC conflict",
+			existing: other,
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := fake.NewSimpleClientset()
+			libClient := externalFake.NewSimpleClientset()
+			ep := &v1alpha1.Endpoint{BucketName: "bucket"}
+
+			// pre-create the resources of the existing claim, as left by an interrupted reconcile
+			secret, _ := newCredentialsSecret(tt.existing, &v1alpha1.Authentication{}, nil, nil, defaultChildOptions)
+			if _, err := client.CoreV1().Secrets(testNamespace).Create(secret); err != nil {
+				t.Fatalf("error pre-creating secret: %v", err)
+			}
+			cm, _ := newBucketConfigMap(tt.existing, ep, nil, nil, defaultChildOptions)
+			if _, err := client.CoreV1().ConfigMaps(testNamespace).Create(cm); err != nil {
+				t.Fatalf("error pre-creating configmap: %v", err)
+			}
+			ob := &v1alpha1.ObjectBucket{
+				ObjectMeta: metav1.ObjectMeta{Name: "obc-" + testNamespace + "-" + testName},
+				Spec:       v1alpha1.ObjectBucketSpec{ClaimRef: makeObjectReference(tt.existing)},
+			}
+			if _, err := libClient.ObjectbucketV1alpha1().ObjectBuckets().Create(ob); err != nil {
+				t.Fatalf("error pre-creating OB: %v", err)
+			}
+
+			gotSecret, err := createSecret(context.Background(), obc, &v1alpha1.Authentication{}, nil, nil, defaultChildOptions, client, newFakeChildApplier(client), b)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("createSecret() error = %v, wantErr %v", err, tt.wantErr)
+			} else if err == nil && gotSecret.Name != testName {
+				t.Errorf("want adopted secret %q, got %+v", testName, gotSecret)
+			} else if _, inUse := err.(*secretInUseError); err != nil && !inUse {
+				t.Errorf("want a secretInUseError, got %v", err)
+			}
+			gotCM, err := createConfigMap(context.Background(), obc, ep, nil, nil, defaultChildOptions, client, newFakeChildApplier(client), b)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("createConfigMap() error = %v, wantErr %v", err, tt.wantErr)
+			} else if err == nil && gotCM.Name != testName {
+				t.Errorf("want adopted configmap %q, got %+v", testName, gotCM)
+			}
+			newOB := &v1alpha1.ObjectBucket{
+				ObjectMeta: metav1.ObjectMeta{Name: ob.Name},
+				Spec:       v1alpha1.ObjectBucketSpec{ClaimRef: makeObjectReference(obc)},
+			}
+			gotOB, err := createObjectBucket(context.Background(), newOB, newBucketClient(libClient), b, "")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("createObjectBucket() error = %v, wantErr %v", err, tt.wantErr)
+			} else if err == nil && gotOB.Name != ob.Name {
+				t.Errorf("want adopted OB %q, got %+v", ob.Name, gotOB)
+			}
+		})
+	}
+}
+
+func TestCreateConvergesDriftedResources(t *testing.T) {
+	obc := &v1alpha1.ObjectBucketClaim{ObjectMeta: metav1.ObjectMeta{Name: testName, Namespace: testNamespace, UID: "obc-uid"}}
+	b := retryBackoff{
+		interval: time.Millisecond,
+		timeout:  time.Millisecond * 10,
+	}
+	client := fake.NewSimpleClientset()
+	auth := &v1alpha1.Authentication{AccessKeys: &v1alpha1.AccessKeys{AccessKeyID: "new-id", SecretAccessKey: "new-key"}}
+	ep := &v1alpha1.Endpoint{BucketName: "new-bucket"}
+
+	// pre-create stale resources with a foreign label, which must be kept
+	secret, _ := newCredentialsSecret(obc, &v1alpha1.Authentication{AccessKeys: &v1alpha1.AccessKeys{AccessKeyID: "old-id"}}, nil, nil, defaultChildOptions)
+	secret.Labels = map[string]string{"foreign": "label"}
+	if _, err := client.CoreV1().Secrets(testNamespace).Create(secret); err != nil {
+		t.Fatalf("error pre-creating secret: %v", err)
+	}
+	cm, _ := newBucketConfigMap(obc, &v1alpha1.Endpoint{BucketName: "old-bucket"}, nil, nil, defaultChildOptions)
+	cm.Labels = map[string]string{"foreign": "label"}
+	if _, err := client.CoreV1().ConfigMaps(testNamespace).Create(cm); err != nil {
+		t.Fatalf("error pre-creating configmap: %v", err)
+	}
+
+	labels := map[string]string{"app": "test"}
+	if _, err := createSecret(context.Background(), obc, auth, labels, nil, defaultChildOptions, client, newFakeChildApplier(client), b); err != nil {
+		t.Fatalf("createSecret() error = %v", err)
+	}
+	if _, err := createConfigMap(context.Background(), obc, ep, labels, nil, defaultChildOptions, client, newFakeChildApplier(client), b); err != nil {
+		t.Fatalf("createConfigMap() error = %v", err)
+	}
+
+	wantLabels := map[string]string{"app": "test", "foreign": "label"}
+	gotSecret, _ := client.CoreV1().Secrets(testNamespace).Get(testName, metav1.GetOptions{})
+	if got := string(gotSecret.Data[v1alpha1.AwsKeyField]); got != "new-id" {
+		t.Errorf("want converged secret access key %q, got %q", "new-id", got)
+	}
+	if diff := cmp.Diff(wantLabels, gotSecret.Labels); diff != "" {
+		t.Errorf("secret labels mismatch (-want +got):\n%s", diff)
+	}
+	gotCM, _ := client.CoreV1().ConfigMaps(testNamespace).Get(testName, metav1.GetOptions{})
+	if got := gotCM.Data[bucketName]; got != "new-bucket" {
+		t.Errorf("want converged configmap bucket name %q, got %q", "new-bucket", got)
+	}
+	if diff := cmp.Diff(wantLabels, gotCM.Labels); diff != "" {
+		t.Errorf("configmap labels mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestReleaseRetriesOnConflict(t *testing.T) {
+	obc := &v1alpha1.ObjectBucketClaim{ObjectMeta: metav1.ObjectMeta{Name: testName, Namespace: testNamespace}}
+	secret, _ := newCredentialsSecret(obc, &v1alpha1.Authentication{}, nil, nil, defaultChildOptions)
+	cm, _ := newBucketConfigMap(obc, &v1alpha1.Endpoint{BucketName: "bucket"}, nil, nil, defaultChildOptions)
+	client := fake.NewSimpleClientset(secret, cm)
+
+	// fail the first patch of each resource with a conflict
+	conflicted := map[string]bool{}
+	client.PrependReactor("patch", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		resource := action.GetResource().Resource
+		if conflicted[resource] {
+			return false, nil, nil
+		}
+		conflicted[resource] = true
+		return true, nil, errors.NewConflict(action.GetResource().GroupResource(), testName, fmt.Errorf("injected conflict"))
+	})
+
+	if err := releaseSecret(secret, nil, client); err != nil {
+		t.Errorf("releaseSecret() error = %v", err)
+	}
+	if err := releaseConfigMap(cm, nil, client); err != nil {
+		t.Errorf("releaseConfigMap() error = %v", err)
+	}
+	if !conflicted["secrets"] || !conflicted["configmaps"] {
+		t.Errorf("want a conflict injected for each resource, got %v", conflicted)
+	}
+
+	gotSecret, err := client.CoreV1().Secrets(testNamespace).Get(testName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("error getting secret: %v", err)
+	}
+	if len(gotSecret.Finalizers) != 0 {
+		t.Errorf("want secret finalizers removed, got %v", gotSecret.Finalizers)
+	}
+	gotCM, err := client.CoreV1().ConfigMaps(testNamespace).Get(testName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("error getting configmap: %v", err)
+	}
+	if len(gotCM.Finalizers) != 0 {
+		t.Errorf("want configmap finalizers removed, got %v", gotCM.Finalizers)
+	}
+}
+
+func TestChildOwnerReference(t *testing.T) {
+	obc := &v1alpha1.ObjectBucketClaim{ObjectMeta: metav1.ObjectMeta{Name: testName, Namespace: testNamespace, UID: "obc-uid"}}
+	isTrue, isFalse := true, false
+
+	tests := []struct {
+		name    string
+		options *ControllerOptions
+		want    metav1.OwnerReference
+	}{
+		{
+			name:    "claim is the blocking controller by default",
+			options: &ControllerOptions{},
+			want: metav1.OwnerReference{
+				APIVersion:         "objectbucket.io/v1alpha1",
+				Kind:               "ObjectBucketClaim",
+				Name:               testName,
+				UID:                "obc-uid",
+				Controller:         &isTrue,
+				BlockOwnerDeletion: &isTrue,
+			},
+		},
+		{
+			name: "owner deletion is not blocked if overridden",
+			options: &ControllerOptions{
+				OwnerReference: &OwnerReferenceOptions{Controller: true, BlockOwnerDeletion: false},
+			},
+			want: metav1.OwnerReference{
+				APIVersion:         "objectbucket.io/v1alpha1",
+				Kind:               "ObjectBucketClaim",
+				Name:               testName,
+				UID:                "obc-uid",
+				Controller:         &isTrue,
+				BlockOwnerDeletion: &isFalse,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := tt.options.childOptions()
+			secret, err := newCredentialsSecret(obc, &v1alpha1.Authentication{}, nil, nil, opts)
+			if err != nil {
+				t.Fatalf("newCredentialsSecret() error = %v", err)
+			}
+			cm, err := newBucketConfigMap(obc, &v1alpha1.Endpoint{BucketName: "bucket"}, nil, nil, opts)
+			if err != nil {
+				t.Fatalf("newBucketConfigMap() error = %v", err)
+			}
+			want := []metav1.OwnerReference{tt.want}
+			if diff := cmp.Diff(want, secret.OwnerReferences); diff != "" {
+				t.Errorf("secret ownerReferences (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(want, cm.OwnerReferences); diff != "" {
+				t.Errorf("configmap ownerReferences (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestChildKeyNames(t *testing.T) {
+	obc := &v1alpha1.ObjectBucketClaim{ObjectMeta: metav1.ObjectMeta{Name: testName, Namespace: testNamespace}}
+	ep := &v1alpha1.Endpoint{BucketHost: "s3.example.com", BucketPort: 443, BucketName: "bucket", Region: "us-east-1", SSL: true}
+	auth := &v1alpha1.Authentication{AccessKeys: &v1alpha1.AccessKeys{AccessKeyID: "key", SecretAccessKey: "secret"}}
+	opts := (&ControllerOptions{
+		KeyNames: &KeyNames{
+			BucketName:      "S3_BUCKET",
+			BucketHost:      "S3_HOST",
+			AccessKeyID:     "ACCESS_KEY_ID",
+			SecretAccessKey: "SECRET_ACCESS_KEY",
+		},
+	}).childOptions()
+
+	cm, err := newBucketConfigMap(obc, ep, nil, nil, opts)
+	if err != nil {
+		t.Fatalf("newBucketConfigMap() error = %v", err)
+	}
+	wantData := map[string]string{
+		"S3_BUCKET":     "bucket",
+		"S3_HOST":       "s3.example.com",
+		bucketPort:      "443",
+		bucketRegion:    "us-east-1",
+		bucketSubRegion: "",
+		bucketSSL:       "true",
+		bucketURL:       "https://s3.example.com",
+		bucketEndpoint:  "https://s3.example.com",
+	}
+	if diff := cmp.Diff(wantData, cm.Data); diff != "" {
+		t.Errorf("configmap data (-want +got):\n%s", diff)
+	}
+
+	secret, err := newCredentialsSecret(obc, auth, nil, nil, opts)
+	if err != nil {
+		t.Fatalf("newCredentialsSecret() error = %v", err)
+	}
+	wantSecret := map[string]string{"ACCESS_KEY_ID": "key", "SECRET_ACCESS_KEY": "secret"}
+	if diff := cmp.Diff(wantSecret, secret.StringData); diff != "" {
+		t.Errorf("secret data (-want +got):\n%s", diff)
+	}
+
+	// provisioner data may not shadow the renamed keys
+	ep.AdditionalConfigData = map[string]string{"S3_HOST": "other"}
+	if _, err := newBucketConfigMap(obc, ep, nil, nil, opts); err == nil {
+		t.Errorf("newBucketConfigMap() expected error for additional data shadowing a renamed key")
+	}
+}
+
+func TestDeleteObjectBucketVanished(t *testing.T) {
+	notFound := func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.NewNotFound(action.GetResource().GroupResource(), testName)
+	}
+
+	tests := []struct {
+		name     string
+		verb     string
+		existing bool
+	}{
+		{
+			name:     "OB already deleted",
+			existing: false,
+		},
+		{
+			name:     "OB vanished before its finalizer was removed",
+			verb:     "update",
+			existing: true,
+		},
+		{
+			name:     "OB vanished before it was deleted",
+			verb:     "delete",
+			existing: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ob := &v1alpha1.ObjectBucket{
+				ObjectMeta: metav1.ObjectMeta{Name: testName, UID: "ob-uid", Finalizers: []string{finalizer}},
+			}
+			libClient := externalFake.NewSimpleClientset()
+			if tt.existing {
+				if _, err := libClient.ObjectbucketV1alpha1().ObjectBuckets().Create(ob.DeepCopy()); err != nil {
+					t.Fatalf("error pre-creating OB: %v", err)
+				}
+			}
+			if tt.verb != "" {
+				libClient.PrependReactor(tt.verb, "objectbuckets", notFound)
+			}
+
+			if err := deleteObjectBucket(ob, nil, newBucketClient(libClient)); err != nil {
+				t.Errorf("deleteObjectBucket() error = %v, want nil", err)
+			}
+		})
+	}
+}
+
+func TestCreateObjectBucketClaimLabels(t *testing.T) {
+	b := retryBackoff{
+		interval: time.Millisecond,
+		timeout:  time.Millisecond * 10,
+	}
+	client := externalFake.NewSimpleClientset()
+	obc := &v1alpha1.ObjectBucketClaim{ObjectMeta: metav1.ObjectMeta{Name: testName, Namespace: testNamespace, UID: "obc-uid"}}
+	ob := &v1alpha1.ObjectBucket{
+		ObjectMeta: metav1.ObjectMeta{Name: "obc-" + testNamespace + "-" + testName, Labels: map[string]string{provisionerLabelKey: "test"}},
+		Spec:       v1alpha1.ObjectBucketSpec{ClaimRef: makeObjectReference(obc)},
+	}
+
+	got, err := createObjectBucket(context.Background(), ob, newBucketClient(client), b, "")
+	if err != nil {
+		t.Fatalf("createObjectBucket() error = %v", err)
+	}
+	want := map[string]string{
+		provisionerLabelKey:          "test",
+		v1alpha1.ClaimNamespaceLabel: testNamespace,
+		v1alpha1.ClaimNameLabel:      testName,
+	}
+	if diff := cmp.Diff(want, got.Labels); diff != "" {
+		t.Errorf("OB labels mismatch (-want +got):\n%s", diff)
+	}
+	if len(ob.Labels) != 1 {
+		t.Errorf("want the passed OB unchanged, got labels %v", ob.Labels)
+	}
+}
+
+func TestCreateObjectBucketVersion(t *testing.T) {
+	b := retryBackoff{
+		interval: time.Millisecond,
+		timeout:  time.Millisecond * 10,
+	}
+	tests := []struct {
+		name            string
+		annotations     map[string]string
+		version         string
+		wantAnnotations map[string]string
+	}{
+		{
+			name: "no version",
+		},
+		{
+			name:            "version is recorded",
+			version:         "v1.2.3",
+			wantAnnotations: map[string]string{v1alpha1.ProvisionerVersionAnnotation: "v1.2.3"},
+		},
+		{
+			name:        "other annotations are kept",
+			annotations: map[string]string{"example.com/note": "x"},
+			version:     "v1.2.3",
+			wantAnnotations: map[string]string{
+				"example.com/note":                    "x",
+				v1alpha1.ProvisionerVersionAnnotation: "v1.2.3",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := externalFake.NewSimpleClientset()
+			ob := &v1alpha1.ObjectBucket{
+				ObjectMeta: metav1.ObjectMeta{Name: "obc-" + testNamespace + "-" + testName, Annotations: tt.annotations},
+			}
+
+			got, err := createObjectBucket(context.Background(), ob, newBucketClient(client), b, tt.version)
+			if err != nil {
+				t.Fatalf("createObjectBucket() error = %v", err)
+			}
+			if diff := cmp.Diff(tt.wantAnnotations, got.Annotations); diff != "" {
+				t.Errorf("OB annotations mismatch (-want +got):\n%s", diff)
+			}
+			if _, ok := ob.Annotations[v1alpha1.ProvisionerVersionAnnotation]; ok {
+				t.Errorf("want the passed OB unchanged, got annotations %v", ob.Annotations)
+			}
+		})
+	}
+}
+
+// childVerbs returns the verbs of the client's actions on the given resource
+func childVerbs(client *fake.Clientset, resource string) []string {
+	var verbs []string
+	for _, a := range client.Actions() {
+		if a.GetResource().Resource == resource {
+			verbs = append(verbs, a.GetVerb())
+		}
+	}
+	return verbs
+}
+
+func TestImmutableChildren(t *testing.T) {
+	obc := &v1alpha1.ObjectBucketClaim{ObjectMeta: metav1.ObjectMeta{Name: testName, Namespace: testNamespace, UID: "obc-uid"}}
+	b := retryBackoff{
+		interval: time.Millisecond,
+		timeout:  time.Millisecond * 10,
+	}
+	ep := &v1alpha1.Endpoint{BucketName: "bucket", BucketHost: "s3.example.com"}
+	auth := &v1alpha1.Authentication{AccessKeys: &v1alpha1.AccessKeys{AccessKeyID: "id", SecretAccessKey: "key"}}
+	newAuth := &v1alpha1.Authentication{AccessKeys: &v1alpha1.AccessKeys{AccessKeyID: "new-id", SecretAccessKey: "new-key"}}
+
+	tests := []struct {
+		name            string
+		immutable       bool
+		wantCreateVerbs []string
+		wantChangeVerbs []string
+	}{
+		{
+			name:            "mutable by default",
+			wantCreateVerbs: []string{"create"},
+			wantChangeVerbs: []string{"update"},
+		},
+		{
+			name:            "immutable children are replaced",
+			immutable:       true,
+			wantCreateVerbs: []string{"create", "patch"},
+			wantChangeVerbs: []string{"get", "patch", "delete", "create", "patch"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := (&ControllerOptions{ImmutableChildren: tt.immutable}).childOptions()
+
+			client := fake.NewSimpleClientset()
+			secret, err := createSecret(context.Background(), obc, auth, nil, nil, opts, client, newFakeChildApplier(client), b)
+			if err != nil {
+				t.Fatalf("createSecret() error = %v", err)
+			}
+			if _, err = createConfigMap(context.Background(), obc, ep, nil, nil, opts, client, newFakeChildApplier(client), b); err != nil {
+				t.Fatalf("createConfigMap() error = %v", err)
+			}
+			for _, resource := range []string{"secrets", "configmaps"} {
+				if diff := cmp.Diff(tt.wantCreateVerbs, childVerbs(client, resource)); diff != "" {
+					t.Errorf("%s create verbs mismatch (-want +got):\n%s", resource, diff)
+				}
+			}
+			for _, a := range client.Actions() {
+				if p, ok := a.(k8stesting.PatchAction); ok && string(p.GetPatch()) != string(immutablePatch) {
+					t.Errorf("want patch %s, got %s", immutablePatch, p.GetPatch())
+				}
+			}
+
+			// rotated credentials and a drifted BUCKET_HOST change the content of both
+			client.ClearActions()
+			if _, err = updateSecret(context.Background(), secret, newAuth, opts, client, b); err != nil {
+				t.Fatalf("updateSecret() error = %v", err)
+			}
+			if diff := cmp.Diff(tt.wantChangeVerbs, childVerbs(client, "secrets")); diff != "" {
+				t.Errorf("secret change verbs mismatch (-want +got):\n%s", diff)
+			}
+			cm, err := client.CoreV1().ConfigMaps(testNamespace).Get(testName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("error getting configmap: %v", err)
+			}
+			cm.Data[bucketHost] = "drifted.example.com"
+			if _, err = client.CoreV1().ConfigMaps(testNamespace).Update(cm); err != nil {
+				t.Fatalf("error updating configmap: %v", err)
+			}
+			client.ClearActions()
+			if _, _, err = reconcileConfigMap(context.Background(), obc, ep, nil, nil, opts, client, b); err != nil {
+				t.Fatalf("reconcileConfigMap() error = %v", err)
+			}
+			// the configmap is read for its drift first
+			wantConfigMapVerbs := append([]string{"get"}, tt.wantChangeVerbs...)
+			if diff := cmp.Diff(wantConfigMapVerbs, childVerbs(client, "configmaps")); diff != "" {
+				t.Errorf("configmap change verbs mismatch (-want +got):\n%s", diff)
+			}
+
+			gotSecret, err := client.CoreV1().Secrets(testNamespace).Get(testName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("error getting secret: %v", err)
+			}
+			if got := secretData(gotSecret)[v1alpha1.AwsKeyField]; got != "new-id" {
+				t.Errorf("want rotated access key %q, got %q", "new-id", got)
+			}
+			gotCM, err := client.CoreV1().ConfigMaps(testNamespace).Get(testName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("error getting configmap: %v", err)
+			}
+			if got := gotCM.Data[bucketHost]; got != ep.BucketHost {
+				t.Errorf("want restored bucket host %q, got %q", ep.BucketHost, got)
 			}
 		})
 	}
@@ -0,0 +1,77 @@
+/*
+Copyright 2019 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/apis/objectbucket.io/v1alpha1"
+	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/client/clientset/versioned"
+)
+
+// waitForBoundBackoff spaces the polls of WaitForClaimBound, whose timeout is the caller's
+var waitForBoundBackoff = retryBackoff{
+	interval:    time.Millisecond * 100,
+	factor:      1.5,
+	maxInterval: time.Second * 2,
+}
+
+// WaitForClaimBound polls the named OBC with backoff until it is Bound, and returns it, e.g. for e2e tests
+// creating claims.  A missing OBC is waited for.  An error is returned as soon as the OBC is Failed, or if it
+// is not Bound within timeout or before ctx is done.
+func WaitForClaimBound(ctx context.Context, client versioned.Interface, namespace, name string, timeout time.Duration) (*v1alpha1.ObjectBucketClaim, error) {
+	var obc *v1alpha1.ObjectBucketClaim
+	backoff := waitForBoundBackoff
+	backoff.timeout = timeout
+	err := retryWithBackoff(ctx, backoff, func() (bool, error) {
+		got, err := client.ObjectbucketV1alpha1().ObjectBucketClaims(namespace).Get(name, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		obc = got
+		switch obc.Status.Phase {
+		case v1alpha1.ObjectBucketClaimStatusPhaseBound:
+			return true, nil
+		case v1alpha1.ObjectBucketClaimStatusPhaseFailed:
+			if obc.Status.FailureMessage != "" {
+				return false, fmt.Errorf("OBC %s/%s failed: %s", namespace, name, obc.Status.FailureMessage)
+			}
+			return false, fmt.Errorf("OBC %s/%s failed", namespace, name)
+		}
+		return false, nil
+	})
+	if err == wait.ErrWaitTimeout {
+		phase := v1alpha1.ObjectBucketClaimStatusPhase("")
+		if obc != nil {
+			phase = obc.Status.Phase
+		}
+		return nil, fmt.Errorf("timed out waiting for OBC %s/%s to be bound, last phase %q", namespace, name, phase)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return obc, nil
+}
@@ -0,0 +1,133 @@
+/*
+Copyright 2019 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/apis/objectbucket.io/v1alpha1"
+	externalFake "github.com/kube-object-storage/lib-bucket-provisioner/pkg/client/clientset/versioned/fake"
+)
+
+func TestWaitForClaimBound(t *testing.T) {
+	backoff := waitForBoundBackoff
+	waitForBoundBackoff = retryBackoff{interval: time.Millisecond}
+	defer func() { waitForBoundBackoff = backoff }()
+
+	tests := []struct {
+		name    string
+		phases  []v1alpha1.ObjectBucketClaimStatusPhase
+		message string
+		wantErr string
+	}{
+		{
+			name: "bound after provisioning",
+			phases: []v1alpha1.ObjectBucketClaimStatusPhase{
+				"",
+				v1alpha1.ObjectBucketClaimStatusPhasePending,
+				v1alpha1.ObjectBucketClaimStatusPhaseProvisioning,
+				v1alpha1.ObjectBucketClaimStatusPhaseBound,
+			},
+		},
+		{
+			name: "failed",
+			phases: []v1alpha1.ObjectBucketClaimStatusPhase{
+				v1alpha1.ObjectBucketClaimStatusPhasePending,
+				v1alpha1.ObjectBucketClaimStatusPhaseFailed,
+			},
+			message: "invalid quota",
+			wantErr: "failed: invalid quota",
+		},
+		{
+			name:    "timed out",
+			phases:  []v1alpha1.ObjectBucketClaimStatusPhase{v1alpha1.ObjectBucketClaimStatusPhasePending},
+			wantErr: `timed out waiting for OBC ` + testNamespace + "/" + testName + ` to be bound, last phase "Pending"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := externalFake.NewSimpleClientset()
+			// each get returns the OBC in the next phase, the last phase being kept
+			gets := 0
+			client.PrependReactor("get", "objectbucketclaims", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				i := gets
+				if i >= len(tt.phases) {
+					i = len(tt.phases) - 1
+				}
+				gets++
+				return true, &v1alpha1.ObjectBucketClaim{
+					ObjectMeta: objMeta,
+					Status:     v1alpha1.ObjectBucketClaimStatus{Phase: tt.phases[i], FailureMessage: tt.message},
+				}, nil
+			})
+
+			obc, err := WaitForClaimBound(context.Background(), client, testNamespace, testName, time.Millisecond*50)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("WaitForClaimBound() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("WaitForClaimBound() error = %v", err)
+			}
+			if obc.Status.Phase != v1alpha1.ObjectBucketClaimStatusPhaseBound {
+				t.Errorf("want phase %q, got %q", v1alpha1.ObjectBucketClaimStatusPhaseBound, obc.Status.Phase)
+			}
+			if gets != len(tt.phases) {
+				t.Errorf("want %d polls, got %d", len(tt.phases), gets)
+			}
+		})
+	}
+}
+
+func TestWaitForClaimBoundMissingClaim(t *testing.T) {
+	backoff := waitForBoundBackoff
+	waitForBoundBackoff = retryBackoff{interval: time.Millisecond}
+	defer func() { waitForBoundBackoff = backoff }()
+
+	// the OBC is only found from the second poll on
+	client := externalFake.NewSimpleClientset()
+	gets := 0
+	client.PrependReactor("get", "objectbucketclaims", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		gets++
+		if gets == 1 {
+			return true, nil, errors.NewNotFound(v1alpha1.Resource("objectbucketclaims"), testName)
+		}
+		return true, &v1alpha1.ObjectBucketClaim{
+			ObjectMeta: objMeta,
+			Status:     v1alpha1.ObjectBucketClaimStatus{Phase: v1alpha1.ObjectBucketClaimStatusPhaseBound},
+		}, nil
+	})
+	if _, err := WaitForClaimBound(context.Background(), client, testNamespace, testName, time.Millisecond*50); err != nil {
+		t.Errorf("WaitForClaimBound() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	client = externalFake.NewSimpleClientset()
+	if _, err := WaitForClaimBound(ctx, client, testNamespace, testName, time.Second); err != context.Canceled {
+		t.Errorf("want %v waiting for a missing OBC, got %v", context.Canceled, err)
+	}
+}